@@ -0,0 +1,56 @@
+package auth
+
+import "context"
+
+// Role is what a principal is allowed to do
+type Role string
+
+const (
+	// RoleAdmin runs the service, it may act on any business
+	RoleAdmin Role = "admin"
+	// RoleBusiness is a business owner, it may act on its own businesses
+	RoleBusiness Role = "business"
+	// RoleDevice is the anonymous app, identified by its phone id at most
+	RoleDevice Role = "device"
+)
+
+// Principal is who a request is made by
+type Principal struct {
+	UserID int
+	Role   Role
+}
+
+// Device is the principal of requests without an access token
+var Device = Principal{Role: RoleDevice}
+
+// ValidRole reports whether role can be given to a business user
+func ValidRole(role Role) bool {
+	return role == RoleAdmin || role == RoleBusiness
+}
+
+// Is reports whether the principal has one of the roles
+func (p Principal) Is(roles ...Role) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the request, requests no
+// principal was attached to are treated as anonymous devices
+func PrincipalFromContext(ctx context.Context) Principal {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	if !ok {
+		return Device
+	}
+	return principal
+}
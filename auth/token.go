@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// header is the fixed JWT header of every token we issue. Tokens are only
+// ever verified by this service, so HS256 is the only algorithm accepted.
+const header = `{"alg":"HS256","typ":"JWT"}`
+
+var (
+	encoding      = base64.RawURLEncoding
+	encodedHeader = encoding.EncodeToString([]byte(header))
+
+	// ErrInvalidToken is returned for malformed tokens and bad signatures
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned for well formed tokens past their expiry
+	ErrExpiredToken = errors.New("token expired")
+)
+
+type (
+	// Config holds the token settings
+	Config struct {
+		// Secret signs and verifies access tokens, it must be kept private
+		Secret string `json:"secret"`
+		// TokenTTLMinutes is how long an access token stays valid
+		TokenTTLMinutes int `json:"tokenTtlMinutes"`
+	}
+
+	// Claims is the payload of an access token
+	Claims struct {
+		UserID    int   `json:"sub"`
+		Role      Role  `json:"role"`
+		IssuedAt  int64 `json:"iat"`
+		ExpiresAt int64 `json:"exp"`
+	}
+
+	// TokenIssuer issues and verifies signed access tokens for business users
+	TokenIssuer interface {
+		Issue(principal Principal) (string, Claims, error)
+		Verify(token string) (Claims, error)
+	}
+
+	tokenIssuer struct {
+		secret []byte
+		ttl    time.Duration
+		now    func() time.Time
+	}
+)
+
+// DefaultConfig returns the token settings used when nothing is overridden.
+// There is no default secret.
+func DefaultConfig() Config {
+	return Config{TokenTTLMinutes: 24 * 60}
+}
+
+// NewTokenIssuer returns a TokenIssuer signing HS256 JWTs with cfg.Secret
+func NewTokenIssuer(cfg Config) TokenIssuer {
+	return &tokenIssuer{
+		secret: []byte(cfg.Secret),
+		ttl:    time.Duration(cfg.TokenTTLMinutes) * time.Minute,
+		now:    time.Now,
+	}
+}
+
+func (t *tokenIssuer) Issue(principal Principal) (string, Claims, error) {
+	if principal.UserID == 0 || !ValidRole(principal.Role) {
+		return "", Claims{}, errors.Errorf("cannot issue a token to %+v", principal)
+	}
+
+	now := t.now()
+	claims := Claims{
+		UserID:    principal.UserID,
+		Role:      principal.Role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(t.ttl).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", Claims{}, errors.Wrap(err, "could not encode token claims")
+	}
+
+	unsigned := encodedHeader + "." + encoding.EncodeToString(payload)
+	return unsigned + "." + encoding.EncodeToString(t.sign(unsigned)), claims, nil
+}
+
+func (t *tokenIssuer) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != encodedHeader {
+		return Claims{}, ErrInvalidToken
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, t.sign(parts[0]+"."+parts[1])) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.UserID == 0 || !ValidRole(claims.Role) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if t.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
+// Principal returns who the token was issued to
+func (c Claims) Principal() Principal {
+	return Principal{UserID: c.UserID, Role: c.Role}
+}
+
+func (t *tokenIssuer) sign(unsigned string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIssuer(secret string, now time.Time) *tokenIssuer {
+	issuer := NewTokenIssuer(Config{Secret: secret, TokenTTLMinutes: 60}).(*tokenIssuer)
+	issuer.now = func() time.Time { return now }
+	return issuer
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	now := time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC)
+	issuer := newTestIssuer("test-secret", now)
+
+	token, claims, err := issuer.Issue(Principal{UserID: 42, Role: RoleBusiness})
+	require.NoError(t, err)
+	require.Equal(t, Principal{UserID: 42, Role: RoleBusiness}, claims.Principal())
+	require.Equal(t, now.Add(time.Hour).Unix(), claims.ExpiresAt)
+
+	verified, err := issuer.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, claims, verified)
+
+	// devices are anonymous, they never get a token
+	_, _, err = issuer.Issue(Device)
+	require.Error(t, err)
+}
+
+func TestVerifyRejectsTamperedTokens(t *testing.T) {
+	now := time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC)
+	issuer := newTestIssuer("test-secret", now)
+
+	token, _, err := issuer.Issue(Principal{UserID: 42, Role: RoleBusiness})
+	require.NoError(t, err)
+
+	// signed with another secret
+	_, err = newTestIssuer("other-secret", now).Verify(token)
+	require.Equal(t, ErrInvalidToken, err)
+
+	// payload swapped for another user's
+	other, _, err := issuer.Issue(Principal{UserID: 7, Role: RoleAdmin})
+	require.NoError(t, err)
+	parts, otherParts := strings.Split(token, "."), strings.Split(other, ".")
+	_, err = issuer.Verify(parts[0] + "." + otherParts[1] + "." + parts[2])
+	require.Equal(t, ErrInvalidToken, err)
+
+	_, err = issuer.Verify("not-a-token")
+	require.Equal(t, ErrInvalidToken, err)
+}
+
+func TestVerifyRejectsExpiredTokens(t *testing.T) {
+	now := time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC)
+	token, _, err := newTestIssuer("test-secret", now).Issue(Principal{UserID: 42, Role: RoleBusiness})
+	require.NoError(t, err)
+
+	_, err = newTestIssuer("test-secret", now.Add(2*time.Hour)).Verify(token)
+	require.Equal(t, ErrExpiredToken, err)
+}
@@ -9,8 +9,21 @@ import (
 )
 
 type (
+	// Config holds the upload endpoint and the unsigned upload preset
+	Config struct {
+		BaseURL      string `json:"baseUrl"`
+		UploadPreset string `json:"uploadPreset"`
+	}
+
 	client struct {
 		logger zerolog.Logger
+		cfg    Config
+	}
+
+	// File is an image to upload that is not read from disk
+	File struct {
+		Name string
+		io.Reader
 	}
 
 	Client interface {
@@ -42,6 +55,6 @@ type (
 )
 
 // NewCloudinaryClient returns a new cloudinary client
-func NewCloudinaryClient(logger zerolog.Logger) Client {
-	return &client{logger}
+func NewCloudinaryClient(logger zerolog.Logger, cfg Config) Client {
+	return &client{logger, cfg}
 }
@@ -3,7 +3,7 @@ package cloudinary
 import (
 	"fmt"
 	"io"
-	"strings"
+	"os"
 	"testing"
 
 	"github.com/phassans/banana/shared"
@@ -11,13 +11,19 @@ import (
 )
 
 func TestClient_Upload(t *testing.T) {
-	cloudinaryClient := NewCloudinaryClient(shared.GetLogger())
+	cfg := Config{
+		BaseURL:      os.Getenv("BANANA_CLOUDINARY_URL"),
+		UploadPreset: os.Getenv("BANANA_CLOUDINARY_UPLOAD_PRESET"),
+	}
+	if cfg.BaseURL == "" || cfg.UploadPreset == "" {
+		t.Skip("BANANA_CLOUDINARY_URL and BANANA_CLOUDINARY_UPLOAD_PRESET not set")
+	}
+	cloudinaryClient := NewCloudinaryClient(shared.GetLogger(), cfg)
 	f, err := cloudinaryClient.MustOpen("../../upload_images/6c073578-cd61-4347-8bf7-e6a8a56e5edf_IMG_9615.JPG")
 	require.NoError(t, err)
 	//prepare the reader instances to encode
 	values := map[string]io.Reader{
-		"file":          f, // lets assume its this file
-		"upload_preset": strings.NewReader(UPLOAD_PRESET),
+		"file": f, // lets assume its this file
 	}
 	resp, err := cloudinaryClient.Upload(values)
 	require.NoError(t, err)
@@ -9,16 +9,18 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
-)
-
-const (
-	BASE_URL      = "https://api.cloudinary.com/v1_1/itshungryhour/image/upload"
-	UPLOAD_PRESET = "ouvsftoz"
+	"strings"
 )
 
 func (c *client) Upload(values map[string]io.Reader) (Response, error) {
 	// Prepare a form that you will submit to that URL.
 	logger := c.logger
+
+	// the configured preset is used unless the caller sends one
+	if _, ok := values["upload_preset"]; !ok && c.cfg.UploadPreset != "" {
+		values["upload_preset"] = strings.NewReader(c.cfg.UploadPreset)
+	}
+
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
 	for key, r := range values {
@@ -32,6 +34,10 @@ func (c *client) Upload(values map[string]io.Reader) (Response, error) {
 			if fw, err = w.CreateFormFile(key, x.Name()); err != nil {
 				return Response{}, err
 			}
+		} else if x, ok := r.(File); ok {
+			if fw, err = w.CreateFormFile(key, x.Name); err != nil {
+				return Response{}, err
+			}
 		} else {
 			// Add other fields
 			if fw, err = w.CreateFormField(key); err != nil {
@@ -48,7 +54,7 @@ func (c *client) Upload(values map[string]io.Reader) (Response, error) {
 	w.Close()
 
 	// Now that you have a form, you can submit it to your handler.
-	req, err := http.NewRequest("POST", BASE_URL, &b)
+	req, err := http.NewRequest("POST", c.cfg.BaseURL, &b)
 	if err != nil {
 		return Response{}, err
 	}
@@ -68,7 +74,7 @@ func (c *client) Upload(values map[string]io.Reader) (Response, error) {
 		fmt.Println("err", err)
 		return Response{}, err
 	}
-	logger = logger.With().Str("url", BASE_URL).Str("status", resp.Status).Logger()
+	logger = logger.With().Str("url", c.cfg.BaseURL).Str("status", resp.Status).Logger()
 
 	if resp.StatusCode != 200 {
 		logger = logger.With().Str("body", string(body)).Logger()
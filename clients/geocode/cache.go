@@ -0,0 +1,306 @@
+package geocode
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/geohash"
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+// unknownLocation is stored for the addresses that resolve to nothing, an
+// empty place for the locations in none
+var unknownLocation = shared.GeoLocation{Latitude: -1, Longitude: -1}
+
+// placePrecision is the length of the geohash locations are cached by when
+// reverse geocoding, cells of about 1.2km by 0.6km
+const placePrecision = 6
+
+// the answers that are not stored are kept in memory for a while only: the
+// approximate ones of the fallback geocoder until the provider is likely to
+// answer again, the addresses and locations that resolve to nothing in case
+// the provider learns about them
+const (
+	approximateTTL = 10 * time.Minute
+	unknownTTL     = time.Hour
+)
+
+type (
+	// Store keeps resolved addresses and places across restarts
+	Store interface {
+		// Load returns the location stored for address, ok is false when
+		// there is none
+		Load(address string) (location shared.GeoLocation, ok bool, err error)
+		Save(address string, location shared.GeoLocation) error
+
+		// LoadPlace returns the place stored for a geohash, ok is false
+		// when there is none
+		LoadPlace(hash string) (place shared.Place, ok bool, err error)
+		SavePlace(hash string, place shared.Place) error
+	}
+
+	sqlStore struct {
+		sql db.Querier
+	}
+
+	// Cache resolves addresses and locations through a geocoder once,
+	// keeping them in a store and for a while in memory. It is safe for
+	// concurrent use. Addresses and locations that resolve to nothing, and
+	// the approximate answers of a fallback geocoder, are only kept in
+	// memory and for a shorter while.
+	Cache struct {
+		logger   zerolog.Logger
+		geocoder Geocoder
+		store    Store
+		ttl      time.Duration
+		size     int
+
+		mu      sync.RWMutex
+		entries map[string]cacheEntry
+		places  map[string]cacheEntry
+	}
+
+	cacheEntry struct {
+		location shared.GeoLocation
+		place    shared.Place
+		expires  time.Time
+	}
+)
+
+// NewSQLStore returns a Store backed by the address_to_geo and geo_to_place
+// tables
+func NewSQLStore(psql db.Querier) Store {
+	return &sqlStore{psql}
+}
+
+func (s *sqlStore) Load(address string) (shared.GeoLocation, bool, error) {
+	var location shared.GeoLocation
+	err := s.sql.QueryRow("SELECT latitude,longitude FROM address_to_geo WHERE address = $1;", address).
+		Scan(&location.Latitude, &location.Longitude)
+	if err == sql.ErrNoRows {
+		return shared.GeoLocation{}, false, nil
+	}
+	if err != nil {
+		return shared.GeoLocation{}, false, err
+	}
+	return location, true, nil
+}
+
+func (s *sqlStore) Save(address string, location shared.GeoLocation) error {
+	_, err := s.sql.Exec("INSERT INTO address_to_geo(address,latitude,longitude) VALUES($1,$2,$3) "+
+		"ON CONFLICT (address) DO UPDATE SET latitude = excluded.latitude, longitude = excluded.longitude;",
+		address, location.Latitude, location.Longitude)
+	return err
+}
+
+func (s *sqlStore) LoadPlace(hash string) (shared.Place, bool, error) {
+	var place shared.Place
+	err := s.sql.QueryRow("SELECT neighborhood,city,state,country FROM geo_to_place WHERE geohash = $1;", hash).
+		Scan(&place.Neighborhood, &place.City, &place.State, &place.Country)
+	if err == sql.ErrNoRows {
+		return shared.Place{}, false, nil
+	}
+	if err != nil {
+		return shared.Place{}, false, err
+	}
+	if place.Neighborhood == "" && place.City == "" {
+		return shared.Place{}, true, nil
+	}
+	place, err = newPlace(place.Neighborhood, place.City, place.State, place.Country)
+	return place, true, err
+}
+
+func (s *sqlStore) SavePlace(hash string, place shared.Place) error {
+	_, err := s.sql.Exec("INSERT INTO geo_to_place(geohash,neighborhood,city,state,country) VALUES($1,$2,$3,$4,$5) "+
+		"ON CONFLICT (geohash) DO UPDATE SET neighborhood = excluded.neighborhood, city = excluded.city, "+
+		"state = excluded.state, country = excluded.country;",
+		hash, place.Neighborhood, place.City, place.State, place.Country)
+	return err
+}
+
+// NewCache returns a Cache in front of geocoder keeping up to size addresses,
+// and as many places, in memory for ttl
+func NewCache(logger zerolog.Logger, geocoder Geocoder, store Store, ttl time.Duration, size int) *Cache {
+	return &Cache{
+		logger:   logger,
+		geocoder: geocoder,
+		store:    store,
+		ttl:      ttl,
+		size:     size,
+		entries:  make(map[string]cacheEntry),
+		places:   make(map[string]cacheEntry),
+	}
+}
+
+// Geocode returns the location of address from memory, the store or else
+// the geocoder. Addresses differing in case and spacing only are the same.
+// Addresses stored as they were given, before keys were normalized, are
+// stored again under their normalized key when first found.
+func (c *Cache) Geocode(address string) (shared.GeoLocation, error) {
+	key := normalize(address)
+
+	if entry, ok := c.get(c.entries, key); ok {
+		return found(entry.location)
+	}
+
+	location, ok, err := c.store.Load(key)
+	if err != nil {
+		return shared.GeoLocation{}, err
+	}
+	if !ok && address != key {
+		location, ok, err = c.store.Load(address)
+		if err != nil {
+			return shared.GeoLocation{}, err
+		}
+		if ok && location != unknownLocation {
+			if err := c.store.Save(key, location); err != nil {
+				c.logger.Error().Msgf("could not store the location of %q: %s", key, err)
+			}
+		}
+	}
+	// addresses stored as resolving to nothing before they expired are
+	// asked for again
+	if ok && location != unknownLocation {
+		c.put(c.entries, key, cacheEntry{location: location}, c.ttl)
+		return found(location)
+	}
+
+	location, approximate, err := c.geocode(address)
+	if err == ErrNotFound {
+		c.put(c.entries, key, cacheEntry{location: unknownLocation}, c.shorter(unknownTTL))
+		return found(unknownLocation)
+	} else if err != nil {
+		return shared.GeoLocation{}, err
+	}
+	if approximate {
+		c.put(c.entries, key, cacheEntry{location: location}, c.shorter(approximateTTL))
+		return location, nil
+	}
+
+	if err := c.store.Save(key, location); err != nil {
+		c.logger.Error().Msgf("could not store the location of %q: %s", key, err)
+	}
+	c.put(c.entries, key, cacheEntry{location: location}, c.ttl)
+	return location, nil
+}
+
+// ReverseGeocode returns the place location is in from memory, the store or
+// else the geocoder. Locations in the same geohash cell are in the same
+// place.
+func (c *Cache) ReverseGeocode(location shared.GeoLocation) (shared.Place, error) {
+	key := geohash.Encode(location.Latitude, location.Longitude, placePrecision)
+
+	if entry, ok := c.get(c.places, key); ok {
+		return foundPlace(entry.place)
+	}
+
+	place, ok, err := c.store.LoadPlace(key)
+	if err != nil {
+		return shared.Place{}, err
+	}
+	if ok && place.Name != "" {
+		c.put(c.places, key, cacheEntry{place: place}, c.ttl)
+		return foundPlace(place)
+	}
+
+	place, approximate, err := c.reverseGeocode(location)
+	if err == ErrNotFound {
+		c.put(c.places, key, cacheEntry{}, c.shorter(unknownTTL))
+		return foundPlace(shared.Place{})
+	} else if err != nil {
+		return shared.Place{}, err
+	}
+	if approximate {
+		c.put(c.places, key, cacheEntry{place: place}, c.shorter(approximateTTL))
+		return place, nil
+	}
+
+	if err := c.store.SavePlace(key, place); err != nil {
+		c.logger.Error().Msgf("could not store the place of %s: %s", key, err)
+	}
+	c.put(c.places, key, cacheEntry{place: place}, c.ttl)
+	return place, nil
+}
+
+// geocode asks the geocoder for the location of address, approximate being
+// true when a fallback geocoder answered
+func (c *Cache) geocode(address string) (shared.GeoLocation, bool, error) {
+	if f, ok := c.geocoder.(*fallbackGeocoder); ok {
+		return f.geocode(address)
+	}
+	location, err := c.geocoder.Geocode(address)
+	return location, false, err
+}
+
+// reverseGeocode asks the geocoder for the place location is in,
+// approximate being true when a fallback geocoder answered
+func (c *Cache) reverseGeocode(location shared.GeoLocation) (shared.Place, bool, error) {
+	if f, ok := c.geocoder.(*fallbackGeocoder); ok {
+		return f.reverseGeocode(location)
+	}
+	place, err := c.geocoder.ReverseGeocode(location)
+	return place, false, err
+}
+
+// shorter returns ttl, unless the ttl of the cache is shorter still
+func (c *Cache) shorter(ttl time.Duration) time.Duration {
+	if c.ttl < ttl {
+		return c.ttl
+	}
+	return ttl
+}
+
+func (c *Cache) get(entries map[string]cacheEntry, key string) (cacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put keeps entry in memory for ttl, making room first when entries are full
+func (c *Cache) put(entries map[string]cacheEntry, key string, entry cacheEntry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(entries) >= c.size {
+		now := time.Now()
+		for k, e := range entries {
+			if now.After(e.expires) {
+				delete(entries, k)
+			}
+		}
+		// still full, drop entries at random
+		for k := range entries {
+			if len(entries) < c.size {
+				break
+			}
+			delete(entries, k)
+		}
+	}
+	entry.expires = time.Now().Add(ttl)
+	entries[key] = entry
+}
+
+// found turns the location stored for unknown addresses into ErrNotFound
+func found(location shared.GeoLocation) (shared.GeoLocation, error) {
+	if location == unknownLocation {
+		return shared.GeoLocation{}, ErrNotFound
+	}
+	return location, nil
+}
+
+// foundPlace turns the empty place stored for unknown locations into
+// ErrNotFound
+func foundPlace(place shared.Place) (shared.Place, error) {
+	if place.Name == "" {
+		return shared.Place{}, ErrNotFound
+	}
+	return place, nil
+}
@@ -0,0 +1,185 @@
+package geocode
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/phassans/banana/shared"
+	"github.com/umahmood/haversine"
+)
+
+type (
+	gazetteer struct {
+		places map[string]shared.GeoLocation
+		cities []city
+	}
+
+	city struct {
+		place    shared.Place
+		location shared.GeoLocation
+	}
+)
+
+// maxCityMiles is how far from the center of a city a location may be to be
+// in it
+const maxCityMiles = 10
+
+// postalCode finds the US postal codes in an address
+var postalCode = regexp.MustCompile(`\b\d{5}\b`)
+
+// knownCities are the approximate centers of the cities the service covers,
+// all in California
+var knownCities = map[string]shared.GeoLocation{
+	"sunnyvale":     {Latitude: 37.3688, Longitude: -122.0363},
+	"santa clara":   {Latitude: 37.3541, Longitude: -121.9552},
+	"san jose":      {Latitude: 37.3382, Longitude: -121.8863},
+	"mountain view": {Latitude: 37.3861, Longitude: -122.0839},
+	"palo alto":     {Latitude: 37.4419, Longitude: -122.1430},
+	"cupertino":     {Latitude: 37.3230, Longitude: -122.0322},
+	"los altos":     {Latitude: 37.3852, Longitude: -122.1141},
+	"campbell":      {Latitude: 37.2872, Longitude: -121.9500},
+	"saratoga":      {Latitude: 37.2638, Longitude: -122.0230},
+	"los gatos":     {Latitude: 37.2358, Longitude: -121.9624},
+	"milpitas":      {Latitude: 37.4323, Longitude: -121.8996},
+	"fremont":       {Latitude: 37.5485, Longitude: -121.9886},
+	"menlo park":    {Latitude: 37.4530, Longitude: -122.1817},
+	"redwood city":  {Latitude: 37.4852, Longitude: -122.2364},
+	"san mateo":     {Latitude: 37.5630, Longitude: -122.3255},
+	"san francisco": {Latitude: 37.7749, Longitude: -122.4194},
+	"oakland":       {Latitude: 37.8044, Longitude: -122.2712},
+	"berkeley":      {Latitude: 37.8715, Longitude: -122.2730},
+}
+
+// knownPostalCodes are the approximate centers of the postal codes of the
+// cities around sunnyvale
+var knownPostalCodes = map[string]shared.GeoLocation{
+	"94085": {Latitude: 37.3886, Longitude: -122.0176},
+	"94086": {Latitude: 37.3717, Longitude: -122.0230},
+	"94087": {Latitude: 37.3500, Longitude: -122.0350},
+	"94089": {Latitude: 37.4120, Longitude: -122.0150},
+	"95050": {Latitude: 37.3500, Longitude: -121.9530},
+	"95051": {Latitude: 37.3480, Longitude: -121.9840},
+	"94040": {Latitude: 37.3800, Longitude: -122.0850},
+	"94041": {Latitude: 37.3890, Longitude: -122.0780},
+	"94043": {Latitude: 37.4190, Longitude: -122.0710},
+	"95014": {Latitude: 37.3180, Longitude: -122.0450},
+}
+
+// NewGazetteer returns a Geocoder resolving postal codes and city names
+// offline, to the center of the place. The places in the CSV at path, one
+// place,latitude,longitude row each, are known besides the cities the service
+// covers. path may be empty.
+func NewGazetteer(path string) (Geocoder, error) {
+	g := &gazetteer{places: make(map[string]shared.GeoLocation)}
+	for name, location := range knownCities {
+		g.places[name] = location
+		g.places[name+", ca"] = location
+		g.cities = append(g.cities, city{shared.Place{Name: strings.Title(name), City: strings.Title(name), State: "CA", Country: "US"}, location})
+	}
+	for code, location := range knownPostalCodes {
+		g.places[code] = location
+	}
+
+	if path == "" {
+		return g, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open gazetteer %s: %s", path, err)
+	}
+	defer f.Close()
+
+	if err := g.read(f); err != nil {
+		return nil, fmt.Errorf("could not read gazetteer %s: %s", path, err)
+	}
+	return g, nil
+}
+
+// read adds the places of a CSV, its first row may be a header
+func (g *gazetteer) read(r io.Reader) error {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		if len(row) != 3 {
+			return fmt.Errorf("line %d: expected place,latitude,longitude", i+1)
+		}
+		latitude, latErr := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		longitude, lonErr := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		if latErr != nil || lonErr != nil {
+			if i == 0 {
+				continue
+			}
+			return fmt.Errorf("line %d: invalid coordinates", i+1)
+		}
+		location := shared.GeoLocation{Latitude: latitude, Longitude: longitude}
+		g.places[normalize(row[0])] = location
+
+		// "city, state" rows name the cities locations can be in
+		parts := strings.Split(row[0], ",")
+		if len(parts) == 2 && !postalCode.MatchString(row[0]) {
+			name, state := strings.TrimSpace(parts[0]), strings.ToUpper(strings.TrimSpace(parts[1]))
+			g.cities = append(g.cities, city{shared.Place{Name: name, City: name, State: state}, location})
+		}
+	}
+	return nil
+}
+
+// Geocode returns the location of the postal code in address, or else of its
+// city
+func (g *gazetteer) Geocode(address string) (shared.GeoLocation, error) {
+	address = normalize(address)
+	if location, ok := g.places[address]; ok {
+		return location, nil
+	}
+
+	for _, code := range postalCode.FindAllString(address, -1) {
+		if location, ok := g.places[code]; ok {
+			return location, nil
+		}
+	}
+
+	// "street, city, state", the city with its state first
+	parts := strings.Split(address, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	for i := 0; i+1 < len(parts); i++ {
+		if location, ok := g.places[parts[i]+", "+parts[i+1]]; ok {
+			return location, nil
+		}
+	}
+	for i := len(parts) - 1; i >= 0; i-- {
+		if location, ok := g.places[parts[i]]; ok {
+			return location, nil
+		}
+	}
+	return shared.GeoLocation{}, ErrNotFound
+}
+
+// ReverseGeocode returns the city whose center is nearest to location, when
+// it is near enough
+func (g *gazetteer) ReverseGeocode(location shared.GeoLocation) (shared.Place, error) {
+	nearest, nearestMiles := -1, float64(maxCityMiles)
+	for i, c := range g.cities {
+		miles, _ := haversine.Distance(
+			haversine.Coord{Lat: location.Latitude, Lon: location.Longitude},
+			haversine.Coord{Lat: c.location.Latitude, Lon: c.location.Longitude},
+		)
+		if miles <= nearestMiles {
+			nearest, nearestMiles = i, miles
+		}
+	}
+	if nearest < 0 {
+		return shared.Place{}, ErrNotFound
+	}
+	return g.cities[nearest].place, nil
+}
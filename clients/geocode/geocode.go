@@ -0,0 +1,227 @@
+package geocode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+// providers addresses can be resolved with
+const (
+	ProviderGoogle    = "google"
+	ProviderNominatim = "nominatim"
+	ProviderGazetteer = "gazetteer"
+)
+
+// DefaultNominatimURL is the public OpenStreetMap nominatim server
+const DefaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+type (
+	// Config picks the geocoder addresses are resolved with and how long
+	// resolved addresses are kept in memory
+	Config struct {
+		// Provider is one of google, nominatim or gazetteer
+		Provider     string `json:"provider"`
+		NominatimURL string `json:"nominatimUrl"`
+		// UserAgent identifies the service to nominatim, its usage policy
+		// requires one
+		UserAgent string `json:"userAgent"`
+		// GazetteerPath is an optional CSV of place,latitude,longitude rows
+		// added to the places the gazetteer knows
+		GazetteerPath   string `json:"gazetteerPath"`
+		TimeoutMS       int    `json:"timeoutMs"`
+		CacheTTLMinutes int    `json:"cacheTtlMinutes"`
+		CacheSize       int    `json:"cacheSize"`
+	}
+
+	// Geocoder resolves addresses to coordinates and coordinates to the
+	// places they are in
+	Geocoder interface {
+		// Geocode returns the location of address, ErrNotFound when there
+		// is none
+		Geocode(address string) (shared.GeoLocation, error)
+
+		// ReverseGeocode returns the neighborhood and city of location,
+		// ErrNotFound when it is in none
+		ReverseGeocode(location shared.GeoLocation) (shared.Place, error)
+	}
+
+	fallbackGeocoder struct {
+		logger   zerolog.Logger
+		geocoder Geocoder
+		fallback Geocoder
+	}
+
+	// Fake resolves the addresses and locations it is given and nothing else
+	Fake struct {
+		mu        sync.Mutex
+		locations map[string]shared.GeoLocation
+		places    map[shared.GeoLocation]shared.Place
+		calls     int
+		// Err, when set, is returned by Geocode and ReverseGeocode
+		Err error
+	}
+)
+
+// ErrNotFound is returned when an address does not resolve to any location,
+// or a location is in no known place
+var ErrNotFound = errors.New("address not found")
+
+// DefaultConfig returns the geocoder settings used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		Provider:        ProviderGoogle,
+		NominatimURL:    DefaultNominatimURL,
+		UserAgent:       "banana",
+		TimeoutMS:       5000,
+		CacheTTLMinutes: 24 * 60,
+		CacheSize:       10000,
+	}
+}
+
+// New returns the geocoder of cfg.Provider. Unless it is the gazetteer
+// already, the gazetteer answers whenever the provider fails, so that known
+// places still resolve when the provider cannot be reached.
+func New(logger zerolog.Logger, cfg Config, google GoogleConfig) (Geocoder, error) {
+	gazetteer, err := NewGazetteer(cfg.GazetteerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: time.Duration(cfg.TimeoutMS) * time.Millisecond}
+	switch cfg.Provider {
+	case ProviderGoogle:
+		return WithFallback(logger, NewGoogleGeocoder(logger, google, client), gazetteer), nil
+	case ProviderNominatim:
+		return WithFallback(logger, NewNominatimGeocoder(logger, cfg, client), gazetteer), nil
+	case ProviderGazetteer:
+		return gazetteer, nil
+	}
+	return nil, fmt.Errorf("unknown geocoder provider %q", cfg.Provider)
+}
+
+// WithFallback returns a Geocoder asking fallback for the addresses geocoder
+// fails to resolve. When neither resolves an address, the error of geocoder
+// is returned.
+func WithFallback(logger zerolog.Logger, geocoder Geocoder, fallback Geocoder) Geocoder {
+	return &fallbackGeocoder{logger, geocoder, fallback}
+}
+
+func (f *fallbackGeocoder) Geocode(address string) (shared.GeoLocation, error) {
+	location, _, err := f.geocode(address)
+	return location, err
+}
+
+// geocode is Geocode telling whether the location came from the fallback,
+// which is only approximate
+func (f *fallbackGeocoder) geocode(address string) (shared.GeoLocation, bool, error) {
+	location, err := f.geocoder.Geocode(address)
+	if err == nil {
+		return location, false, nil
+	}
+	if err != ErrNotFound {
+		f.logger.Error().Msgf("geocoding %q failed, falling back: %s", address, err)
+	}
+
+	if location, fallbackErr := f.fallback.Geocode(address); fallbackErr == nil {
+		return location, true, nil
+	}
+	return shared.GeoLocation{}, false, err
+}
+
+func (f *fallbackGeocoder) ReverseGeocode(location shared.GeoLocation) (shared.Place, error) {
+	place, _, err := f.reverseGeocode(location)
+	return place, err
+}
+
+// reverseGeocode is ReverseGeocode telling whether the place came from the
+// fallback, which is only approximate
+func (f *fallbackGeocoder) reverseGeocode(location shared.GeoLocation) (shared.Place, bool, error) {
+	place, err := f.geocoder.ReverseGeocode(location)
+	if err == nil {
+		return place, false, nil
+	}
+	if err != ErrNotFound {
+		f.logger.Error().Msgf("reverse geocoding %v failed, falling back: %s", location, err)
+	}
+
+	if place, fallbackErr := f.fallback.ReverseGeocode(location); fallbackErr == nil {
+		return place, true, nil
+	}
+	return shared.Place{}, false, err
+}
+
+// NewFake returns a Fake resolving the given addresses and locations
+func NewFake(locations map[string]shared.GeoLocation, places map[shared.GeoLocation]shared.Place) *Fake {
+	return &Fake{locations: locations, places: places}
+}
+
+// Geocode returns the location given for address
+func (f *Fake) Geocode(address string) (shared.GeoLocation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.Err != nil {
+		return shared.GeoLocation{}, f.Err
+	}
+	location, ok := f.locations[address]
+	if !ok {
+		return shared.GeoLocation{}, ErrNotFound
+	}
+	return location, nil
+}
+
+// ReverseGeocode returns the place given for location
+func (f *Fake) ReverseGeocode(location shared.GeoLocation) (shared.Place, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.Err != nil {
+		return shared.Place{}, f.Err
+	}
+	place, ok := f.places[location]
+	if !ok {
+		return shared.Place{}, ErrNotFound
+	}
+	return place, nil
+}
+
+// Calls returns how many addresses and locations were asked for so far
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// newPlace returns the place named by its neighborhood, or else its city,
+// ErrNotFound when it has neither
+func newPlace(neighborhood string, city string, state string, country string) (shared.Place, error) {
+	place := shared.Place{
+		Name:         neighborhood,
+		Neighborhood: neighborhood,
+		City:         city,
+		State:        state,
+		Country:      country,
+	}
+	if place.Name == "" {
+		place.Name = city
+	}
+	if place.Name == "" {
+		return shared.Place{}, ErrNotFound
+	}
+	return place, nil
+}
+
+// normalize lower cases an address and collapses its spaces
+func normalize(address string) string {
+	return strings.Join(strings.Fields(strings.ToLower(address)), " ")
+}
@@ -0,0 +1,225 @@
+package geocode
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mu        sync.Mutex
+	locations map[string]shared.GeoLocation
+	places    map[string]shared.Place
+}
+
+func (m *memoryStore) Load(address string) (shared.GeoLocation, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	location, ok := m.locations[address]
+	return location, ok, nil
+}
+
+func (m *memoryStore) Save(address string, location shared.GeoLocation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.locations[address] = location
+	return nil
+}
+
+func (m *memoryStore) LoadPlace(hash string) (shared.Place, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	place, ok := m.places[hash]
+	return place, ok, nil
+}
+
+func (m *memoryStore) SavePlace(hash string, place shared.Place) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.places[hash] = place
+	return nil
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{locations: make(map[string]shared.GeoLocation), places: make(map[string]shared.Place)}
+}
+
+func TestCache(t *testing.T) {
+	sunnyvale := shared.GeoLocation{Latitude: 37.3688, Longitude: -122.0363}
+	fake := NewFake(map[string]shared.GeoLocation{"Sunnyvale, CA": sunnyvale}, nil)
+	store := newMemoryStore()
+	cache := NewCache(shared.GetLogger(), fake, store, time.Hour, 10)
+
+	// concurrent lookups are safe and resolve to the same place
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			location, err := cache.Geocode("Sunnyvale, CA")
+			require.NoError(t, err)
+			require.Equal(t, sunnyvale, location)
+		}()
+	}
+	wg.Wait()
+
+	calls := fake.Calls()
+	location, err := cache.Geocode("  sunnyvale,   ca ")
+	require.NoError(t, err)
+	require.Equal(t, sunnyvale, location)
+	require.Equal(t, calls, fake.Calls())
+	require.Equal(t, sunnyvale, store.locations["sunnyvale, ca"])
+
+	// unknown addresses are cached too
+	_, err = cache.Geocode("nowhere")
+	require.Equal(t, ErrNotFound, err)
+	_, err = cache.Geocode("nowhere")
+	require.Equal(t, ErrNotFound, err)
+	require.Equal(t, calls+1, fake.Calls())
+
+	// failures are not
+	fake.Err = errors.New("unreachable")
+	_, err = cache.Geocode("somewhere")
+	require.Equal(t, fake.Err, err)
+	_, hasSomewhere := store.locations["somewhere"]
+	require.False(t, hasSomewhere)
+
+	// addresses stored before keys were normalized are still found
+	oakland := shared.GeoLocation{Latitude: 37.8044, Longitude: -122.2712}
+	store.locations["Oakland, CA"] = oakland
+	calls = fake.Calls()
+	location, err = cache.Geocode("Oakland, CA")
+	require.NoError(t, err)
+	require.Equal(t, oakland, location)
+	require.Equal(t, oakland, store.locations["oakland, ca"])
+	require.Equal(t, calls, fake.Calls())
+
+	// expired addresses are loaded from the store again
+	expired := NewCache(shared.GetLogger(), fake, store, -time.Second, 10)
+	location, err = expired.Geocode("Sunnyvale, CA")
+	require.NoError(t, err)
+	require.Equal(t, sunnyvale, location)
+
+	// the cache never outgrows its size
+	small := NewCache(shared.GetLogger(), fake, store, time.Hour, 2)
+	for _, address := range []string{"sunnyvale, ca", "nowhere", "sunnyvale, ca ", "other"} {
+		small.Geocode(address)
+	}
+	require.True(t, len(small.entries) <= 2)
+}
+
+func TestCacheReverseGeocode(t *testing.T) {
+	mission := shared.GeoLocation{Latitude: 37.7599, Longitude: -122.4148}
+	place := shared.Place{Name: "Mission District", Neighborhood: "Mission District", City: "San Francisco", State: "CA", Country: "US"}
+	fake := NewFake(nil, map[shared.GeoLocation]shared.Place{mission: place})
+	store := newMemoryStore()
+	cache := NewCache(shared.GetLogger(), fake, store, time.Hour, 10)
+
+	found, err := cache.ReverseGeocode(mission)
+	require.NoError(t, err)
+	require.Equal(t, place, found)
+
+	// a location a few meters away is in the same place, without asking again
+	calls := fake.Calls()
+	found, err = cache.ReverseGeocode(shared.GeoLocation{Latitude: 37.7600, Longitude: -122.4149})
+	require.NoError(t, err)
+	require.Equal(t, place, found)
+	require.Equal(t, calls, fake.Calls())
+	require.Equal(t, place, store.places["9q8yy3"])
+
+	// locations in no place are cached too
+	nowhere := shared.GeoLocation{Latitude: 0, Longitude: 0}
+	_, err = cache.ReverseGeocode(nowhere)
+	require.Equal(t, ErrNotFound, err)
+	_, err = cache.ReverseGeocode(nowhere)
+	require.Equal(t, ErrNotFound, err)
+	require.Equal(t, calls+1, fake.Calls())
+}
+
+func TestGazetteer(t *testing.T) {
+	places, err := NewGazetteer("")
+	require.NoError(t, err)
+
+	for _, address := range []string{"Sunnyvale", "sunnyvale, CA", "747 Calla Dr, Apt 1, Sunnyvale, CA", "94086"} {
+		location, err := places.Geocode(address)
+		require.NoError(t, err, address)
+		require.InDelta(t, 37.37, location.Latitude, 0.02, address)
+	}
+	_, err = places.Geocode("Springfield")
+	require.Equal(t, ErrNotFound, err)
+
+	g := places.(*gazetteer)
+	require.NoError(t, g.read(strings.NewReader("place,latitude,longitude\n\"Portland, OR\",45.5152,-122.6784\n")))
+	location, err := places.Geocode("1 Main St, Portland, OR")
+	require.NoError(t, err)
+	require.Equal(t, shared.GeoLocation{Latitude: 45.5152, Longitude: -122.6784}, location)
+	require.Error(t, g.read(strings.NewReader("a,b,c\nportland,x,y\n")))
+
+	// reverse geocoding finds the nearest city
+	place, err := places.ReverseGeocode(shared.GeoLocation{Latitude: 37.3717, Longitude: -122.0230})
+	require.NoError(t, err)
+	require.Equal(t, shared.Place{Name: "Sunnyvale", City: "Sunnyvale", State: "CA", Country: "US"}, place)
+	place, err = places.ReverseGeocode(shared.GeoLocation{Latitude: 45.52, Longitude: -122.68})
+	require.NoError(t, err)
+	require.Equal(t, "Portland", place.City)
+	require.Equal(t, "OR", place.State)
+	_, err = places.ReverseGeocode(shared.GeoLocation{Latitude: 40.7128, Longitude: -74.0060})
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestWithFallback(t *testing.T) {
+	sunnyvale := shared.GeoLocation{Latitude: 37.3688, Longitude: -122.0363}
+	primary := NewFake(nil, nil)
+	primary.Err = errors.New("unreachable")
+	fallback := NewFake(map[string]shared.GeoLocation{"sunnyvale": sunnyvale}, nil)
+	geocoder := WithFallback(shared.GetLogger(), primary, fallback)
+
+	location, err := geocoder.Geocode("sunnyvale")
+	require.NoError(t, err)
+	require.Equal(t, sunnyvale, location)
+
+	// when neither resolves it, the error of the primary geocoder is kept
+	_, err = geocoder.Geocode("nowhere")
+	require.Equal(t, primary.Err, err)
+}
+
+func TestCacheDoesNotStoreApproximateAnswers(t *testing.T) {
+	sunnyvale := shared.GeoLocation{Latitude: 37.3688, Longitude: -122.0363}
+	centroid := shared.GeoLocation{Latitude: 37.37, Longitude: -122.04}
+	primary := NewFake(map[string]shared.GeoLocation{"747 calla dr, sunnyvale": sunnyvale}, nil)
+	primary.Err = errors.New("unreachable")
+	fallback := NewFake(map[string]shared.GeoLocation{"747 calla dr, sunnyvale": centroid}, nil)
+	store := newMemoryStore()
+	cache := NewCache(shared.GetLogger(), WithFallback(shared.GetLogger(), primary, fallback), store, time.Hour, 10)
+
+	// the fallback answers while the provider is down, for a while only
+	location, err := cache.Geocode("747 calla dr, sunnyvale")
+	require.NoError(t, err)
+	require.Equal(t, centroid, location)
+	require.Empty(t, store.locations)
+
+	cache.entries["747 calla dr, sunnyvale"] = cacheEntry{location: centroid, expires: time.Now().Add(-time.Second)}
+	primary.Err = nil
+	location, err = cache.Geocode("747 calla dr, sunnyvale")
+	require.NoError(t, err)
+	require.Equal(t, sunnyvale, location)
+	require.Equal(t, sunnyvale, store.locations["747 calla dr, sunnyvale"])
+
+	// addresses stored as resolving to nothing are asked for again
+	store.locations["nowhere"] = unknownLocation
+	calls := primary.Calls()
+	_, err = cache.Geocode("nowhere")
+	require.Equal(t, ErrNotFound, err)
+	require.Equal(t, calls+1, primary.Calls())
+	require.Equal(t, unknownLocation, cache.entries["nowhere"].location)
+	require.True(t, cache.entries["nowhere"].expires.Before(time.Now().Add(unknownTTL+time.Second)))
+}
@@ -0,0 +1,162 @@
+package geocode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+const googleGeocodeURL = "https://maps.googleapis.com/maps/api/geocode/json"
+
+type (
+	// GoogleConfig holds the settings for the google geocoding api
+	GoogleConfig struct {
+		APIKey string `json:"apiKey"`
+	}
+
+	googleGeocoder struct {
+		logger zerolog.Logger
+		apiKey string
+		url    string
+		client *http.Client
+	}
+)
+
+// NewGoogleGeocoder returns a Geocoder backed by the google geocoding api
+func NewGoogleGeocoder(logger zerolog.Logger, cfg GoogleConfig, client *http.Client) Geocoder {
+	return &googleGeocoder{logger, cfg.APIKey, googleGeocodeURL, client}
+}
+
+// GoogleResponse holds response from google
+type GoogleResponse struct {
+	Results []struct {
+		AddressComponents []struct {
+			LongName  string   `json:"long_name"`
+			ShortName string   `json:"short_name"`
+			Types     []string `json:"types"`
+		} `json:"address_components"`
+		FormattedAddress string `json:"formatted_address"`
+		Geometry         struct {
+			Bounds struct {
+				Northeast struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"northeast"`
+				Southwest struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"southwest"`
+			} `json:"bounds"`
+			Location struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			} `json:"location"`
+			LocationType string `json:"location_type"`
+			Viewport     struct {
+				Northeast struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"northeast"`
+				Southwest struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"southwest"`
+			} `json:"viewport"`
+		} `json:"geometry"`
+		PlaceID string   `json:"place_id"`
+		Types   []string `json:"types"`
+	} `json:"results"`
+	Status string `json:"status"`
+}
+
+// Geocode returns the location of address
+func (g *googleGeocoder) Geocode(address string) (shared.GeoLocation, error) {
+	g.logger.Info().Msgf("google geocode address: %s", address)
+
+	googleResponse, err := g.get("address", address)
+	if err != nil {
+		return shared.GeoLocation{}, err
+	}
+	if len(googleResponse.Results) == 0 {
+		return shared.GeoLocation{}, ErrNotFound
+	}
+
+	location := googleResponse.Results[0].Geometry.Location
+	return shared.GeoLocation{Latitude: location.Lat, Longitude: location.Lng}, nil
+}
+
+// ReverseGeocode returns the neighborhood and city of location
+func (g *googleGeocoder) ReverseGeocode(location shared.GeoLocation) (shared.Place, error) {
+	latlng := fmt.Sprintf("%f,%f", location.Latitude, location.Longitude)
+	g.logger.Info().Msgf("google reverse geocode location: %s", latlng)
+
+	googleResponse, err := g.get("latlng", latlng)
+	if err != nil {
+		return shared.Place{}, err
+	}
+
+	// results go from the street address to the country, the first one
+	// naming a component wins
+	components := make(map[string]string)
+	for _, result := range googleResponse.Results {
+		for _, component := range result.AddressComponents {
+			for _, componentType := range component.Types {
+				if _, ok := components[componentType]; ok {
+					continue
+				}
+				name := component.LongName
+				if componentType == "administrative_area_level_1" || componentType == "country" {
+					name = component.ShortName
+				}
+				components[componentType] = name
+			}
+		}
+	}
+
+	neighborhood := components["neighborhood"]
+	if neighborhood == "" {
+		neighborhood = components["sublocality"]
+	}
+	return newPlace(neighborhood, components["locality"], components["administrative_area_level_1"], components["country"])
+}
+
+// get asks the geocoding api for the results of a query, ZERO_RESULTS
+// being no results
+func (g *googleGeocoder) get(param string, value string) (GoogleResponse, error) {
+	req, err := http.NewRequest("GET", g.url, nil)
+	if err != nil {
+		return GoogleResponse{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	q := req.URL.Query()
+	q.Add(param, value)
+	q.Add("key", g.apiKey)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return GoogleResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return GoogleResponse{}, fmt.Errorf("error geocoding %s: %s. status: %d", param, value, resp.StatusCode)
+	}
+
+	googleResponse := GoogleResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&googleResponse); err != nil {
+		return GoogleResponse{}, fmt.Errorf("invalid google geocode response for %s: %s. %s", param, value, err)
+	}
+
+	switch googleResponse.Status {
+	case "OK":
+		return googleResponse, nil
+	case "ZERO_RESULTS":
+		return GoogleResponse{}, nil
+	}
+	return GoogleResponse{}, fmt.Errorf("error geocoding %s: %s. status:%s", param, value, googleResponse.Status)
+}
@@ -0,0 +1,96 @@
+package geocode
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+	"github.com/umahmood/haversine"
+)
+
+func newTestGoogleClient(t *testing.T) Geocoder {
+	apiKey := os.Getenv("BANANA_GOOGLE_API_KEY")
+	if apiKey == "" {
+		t.Skip("BANANA_GOOGLE_API_KEY not set")
+	}
+	return NewGoogleGeocoder(shared.GetLogger(), GoogleConfig{APIKey: apiKey}, http.DefaultClient)
+}
+
+func TestGetLatLong(t *testing.T) {
+	line1 := "747 Calla Dr"
+	line2 := "Apt 1"
+	city := "Sunnyvale"
+	state := "CA"
+	geoAddress := fmt.Sprintf("%s,%s,%s,%s", line1, line2, city, state)
+	newTestGoogleClient(t).Geocode(url.QueryEscape(geoAddress))
+}
+
+func TestGetLatLongInvalid(t *testing.T) {
+	line1 := "foobar"
+	line2 := "Apt 1"
+	city := "Sunnyvale"
+	state := "CA"
+	geoAddress := fmt.Sprintf("%s,%s,%s,%s", line1, line2, city, state)
+	newTestGoogleClient(t).Geocode(url.QueryEscape(geoAddress))
+}
+
+func TestHaversineDistance(t *testing.T) {
+	oxford := haversine.Coord{Lat: 51.45, Lon: 1.15} // Oxford, UK
+	turin := haversine.Coord{Lat: 45.04, Lon: 7.42}  // Turin, Italy
+	mi, km := haversine.Distance(oxford, turin)
+	require.InDelta(t, 528.0, mi, 0.5)
+	require.InDelta(t, 849.8, km, 0.5)
+}
+
+func TestGoogleGeocoderResponses(t *testing.T) {
+	responses := map[string]string{
+		"sunnyvale": `{"status":"OK","results":[{"geometry":{"location":{"lat":37.3688,"lng":-122.0363}}}]}`,
+		"foobar":    `{"status":"ZERO_RESULTS","results":[]}`,
+		"denied":    `{"status":"REQUEST_DENIED","results":[]}`,
+		"garbled":   `{"status":`,
+		"37.759900,-122.414800": `{"status":"OK","results":[
+			{"address_components":[{"long_name":"Mission District","short_name":"Mission District","types":["neighborhood","political"]}]},
+			{"address_components":[
+				{"long_name":"San Francisco","short_name":"SF","types":["locality","political"]},
+				{"long_name":"California","short_name":"CA","types":["administrative_area_level_1","political"]},
+				{"long_name":"United States","short_name":"US","types":["country","political"]}]}]}`,
+		"0.000000,0.000000": `{"status":"ZERO_RESULTS","results":[]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if latlng := r.URL.Query().Get("latlng"); latlng != "" {
+			fmt.Fprint(w, responses[latlng])
+			return
+		}
+		fmt.Fprint(w, responses[r.URL.Query().Get("address")])
+	}))
+	defer server.Close()
+
+	g := &googleGeocoder{shared.GetLogger(), "key", server.URL, server.Client()}
+
+	location, err := g.Geocode("sunnyvale")
+	require.NoError(t, err)
+	require.Equal(t, shared.GeoLocation{Latitude: 37.3688, Longitude: -122.0363}, location)
+
+	_, err = g.Geocode("foobar")
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = g.Geocode("denied")
+	require.Error(t, err)
+	require.NotEqual(t, ErrNotFound, err)
+
+	// a bad response is an error rather than a panic
+	_, err = g.Geocode("garbled")
+	require.Error(t, err)
+
+	place, err := g.ReverseGeocode(shared.GeoLocation{Latitude: 37.7599, Longitude: -122.4148})
+	require.NoError(t, err)
+	require.Equal(t, shared.Place{Name: "Mission District", Neighborhood: "Mission District", City: "San Francisco", State: "CA", Country: "US"}, place)
+
+	_, err = g.ReverseGeocode(shared.GeoLocation{})
+	require.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,129 @@
+package geocode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+type (
+	nominatimGeocoder struct {
+		logger    zerolog.Logger
+		url       string
+		userAgent string
+		client    *http.Client
+	}
+
+	nominatimPlace struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+
+	nominatimReverse struct {
+		Error   string `json:"error"`
+		Address struct {
+			Neighbourhood string `json:"neighbourhood"`
+			Quarter       string `json:"quarter"`
+			Suburb        string `json:"suburb"`
+			City          string `json:"city"`
+			Town          string `json:"town"`
+			Village       string `json:"village"`
+			State         string `json:"state"`
+			CountryCode   string `json:"country_code"`
+		} `json:"address"`
+	}
+)
+
+// NewNominatimGeocoder returns a Geocoder backed by a nominatim compatible
+// search api, such as OpenStreetMap's
+func NewNominatimGeocoder(logger zerolog.Logger, cfg Config, client *http.Client) Geocoder {
+	return &nominatimGeocoder{logger, strings.TrimRight(cfg.NominatimURL, "/"), cfg.UserAgent, client}
+}
+
+// Geocode returns the location of address
+func (n *nominatimGeocoder) Geocode(address string) (shared.GeoLocation, error) {
+	n.logger.Info().Msgf("nominatim geocode address: %s", address)
+
+	var places []nominatimPlace
+	if err := n.get("/search", url.Values{"q": {address}, "format": {"json"}, "limit": {"1"}}, &places); err != nil {
+		return shared.GeoLocation{}, err
+	}
+	if len(places) == 0 {
+		return shared.GeoLocation{}, ErrNotFound
+	}
+
+	// nominatim returns coordinates as strings
+	latitude, err := strconv.ParseFloat(places[0].Lat, 64)
+	if err != nil {
+		return shared.GeoLocation{}, fmt.Errorf("invalid nominatim latitude %q for address: %s", places[0].Lat, address)
+	}
+	longitude, err := strconv.ParseFloat(places[0].Lon, 64)
+	if err != nil {
+		return shared.GeoLocation{}, fmt.Errorf("invalid nominatim longitude %q for address: %s", places[0].Lon, address)
+	}
+	return shared.GeoLocation{Latitude: latitude, Longitude: longitude}, nil
+}
+
+// ReverseGeocode returns the neighborhood and city of location
+func (n *nominatimGeocoder) ReverseGeocode(location shared.GeoLocation) (shared.Place, error) {
+	n.logger.Info().Msgf("nominatim reverse geocode location: %f,%f", location.Latitude, location.Longitude)
+
+	var reverse nominatimReverse
+	if err := n.get("/reverse", url.Values{
+		"lat":    {strconv.FormatFloat(location.Latitude, 'f', -1, 64)},
+		"lon":    {strconv.FormatFloat(location.Longitude, 'f', -1, 64)},
+		"format": {"json"},
+		"zoom":   {"16"},
+	}, &reverse); err != nil {
+		return shared.Place{}, err
+	}
+	if reverse.Error != "" {
+		return shared.Place{}, ErrNotFound
+	}
+
+	address := reverse.Address
+	neighborhood := firstOf(address.Neighbourhood, address.Quarter, address.Suburb)
+	city := firstOf(address.City, address.Town, address.Village)
+	return newPlace(neighborhood, city, address.State, strings.ToUpper(address.CountryCode))
+}
+
+// get decodes the json answer of nominatim to a query into v
+func (n *nominatimGeocoder) get(path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequest("GET", n.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", n.userAgent)
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error querying nominatim %s?%s. status: %d", path, req.URL.RawQuery, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid nominatim response to %s?%s. %s", path, req.URL.RawQuery, err)
+	}
+	return nil
+}
+
+// firstOf returns the first of values that is not empty
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
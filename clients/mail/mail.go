@@ -0,0 +1,148 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type (
+	// Config holds the smtp server emails are sent through. Without a Host
+	// emails are only logged.
+	Config struct {
+		Host     string `json:"host"`
+		Port     string `json:"port"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		// From is the address emails are sent from
+		From      string `json:"from"`
+		TimeoutMS int    `json:"timeoutMs"`
+	}
+
+	// Message is a plain text email to a single recipient
+	Message struct {
+		To      string
+		Subject string
+		Body    string
+	}
+
+	// Sender delivers emails
+	Sender interface {
+		Send(msg Message) error
+	}
+
+	smtpSender struct {
+		logger zerolog.Logger
+		cfg    Config
+	}
+
+	logSender struct {
+		logger zerolog.Logger
+	}
+)
+
+// DefaultConfig returns the defaults of the smtp settings, with no server
+func DefaultConfig() Config {
+	return Config{Port: "587", TimeoutMS: 10000}
+}
+
+// New returns the Sender cfg asks for, one that only logs emails when no
+// smtp server is set
+func New(logger zerolog.Logger, cfg Config) Sender {
+	if cfg.Host == "" {
+		return logSender{logger}
+	}
+	return &smtpSender{logger, cfg}
+}
+
+// Send sends msg through the smtp server, upgrading the connection to tls
+// when the server offers it
+func (s *smtpSender) Send(msg Message) error {
+	data, err := compose(s.cfg.From, msg)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(s.cfg.TimeoutMS) * time.Millisecond
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(s.cfg.Host, s.cfg.Port), timeout)
+	if err != nil {
+		return errors.Wrap(err, "could not reach the smtp server")
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return errors.Wrap(err, "could not greet the smtp server")
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return errors.Wrap(err, "could not start tls")
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return errors.Wrap(err, "could not authenticate with the smtp server")
+		}
+	}
+
+	if err := client.Mail(s.cfg.From); err != nil {
+		return errors.Wrap(err, "smtp server refused the sender")
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return errors.Wrap(err, "smtp server refused the recipient")
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "smtp server refused the email")
+	}
+
+	s.logger.Info().Msgf("sent email %q to %s", msg.Subject, msg.To)
+	return client.Quit()
+}
+
+// Send logs msg
+func (l logSender) Send(msg Message) error {
+	l.logger.Info().Msgf("no smtp server, not sending email %q to %s", msg.Subject, msg.To)
+	return nil
+}
+
+// compose returns msg from as an email. Addresses and the subject cannot
+// span lines, so that they cannot add headers.
+func compose(from string, msg Message) ([]byte, error) {
+	for _, field := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(field, "\r\n") {
+			return nil, errors.New("email addresses and subject must be on a single line")
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.Replace(strings.Replace(msg.Body, "\r\n", "\n", -1), "\n", "\r\n", -1))
+	b.WriteString("\r\n")
+	return b.Bytes(), nil
+}
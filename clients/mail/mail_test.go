@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompose(t *testing.T) {
+	data, err := compose("hello@itshungryhour.com", Message{
+		To:      "jane@example.com",
+		Subject: "Your happy hour at Café Luna is live",
+		Body:    "Thanks!\nSee you there.",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "From: hello@itshungryhour.com\r\n"+
+		"To: jane@example.com\r\n"+
+		"Subject: =?utf-8?q?Your_happy_hour_at_Caf=C3=A9_Luna_is_live?=\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"Content-Transfer-Encoding: 8bit\r\n"+
+		"\r\n"+
+		"Thanks!\r\nSee you there.\r\n", string(data))
+
+	// submitted addresses cannot add headers
+	_, err = compose("hello@itshungryhour.com", Message{To: "jane@example.com\r\nBcc: all@example.com", Subject: "hi"})
+	require.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/phassans/banana/clients/cloudinary"
+)
+
+// cloudinaryStore uploads images with an unsigned upload preset
+type cloudinaryStore struct {
+	client cloudinary.Client
+}
+
+// errCloudinaryDelete is returned by Delete, unsigned uploads can only be
+// removed from the cloudinary console
+var errCloudinaryDelete = errors.New("cloudinary images uploaded unsigned cannot be deleted")
+
+// NewCloudinaryStore returns an ImageStore uploading through client
+func NewCloudinaryStore(client cloudinary.Client) ImageStore {
+	return &cloudinaryStore{client}
+}
+
+func (s *cloudinaryStore) Put(key string, contentType string, data []byte) (string, error) {
+	// the key without its extension names the image, so that uploading the
+	// same image again does not add a copy
+	publicID := strings.TrimSuffix(key, path.Ext(key))
+	response, err := s.client.Upload(map[string]io.Reader{
+		"file":      cloudinary.File{Name: key, Reader: bytes.NewReader(data)},
+		"public_id": strings.NewReader(publicID),
+	})
+	if err != nil {
+		return "", err
+	}
+	if response.SecureURL != "" {
+		return response.SecureURL, nil
+	}
+	return response.URL, nil
+}
+
+func (s *cloudinaryStore) Delete(key string) error {
+	return errCloudinaryDelete
+}
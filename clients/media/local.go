@@ -0,0 +1,82 @@
+package media
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// localStore keeps images in a directory, for development and single host
+// deployments. The server serves the directory at LocalPath.
+type localStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStore returns an ImageStore writing to dir, creating it when
+// missing, whose images are served at baseURL
+func NewLocalStore(dir string, baseURL string) (ImageStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &localStore{dir, strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *localStore) Put(key string, contentType string, data []byte) (string, error) {
+	// write to a temporary file first so that a failed write never leaves
+	// half an image behind
+	tmp, err := ioutil.TempFile(s.dir, ".upload")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *localStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *localStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.Base(key))
+}
+
+// LocalHandler serves the images a local store keeps in dir. Directories are
+// not listed and the temporary files of uploads in progress are not served.
+func LocalHandler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// images are kept side by side, at the top of dir
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" || strings.Contains(name, "/") || strings.HasPrefix(name, ".") {
+			http.NotFound(w, r)
+			return
+		}
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,76 @@
+// Package media checks, cleans and stores the images uploaded with
+// listings and happy hour submissions.
+package media
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/phassans/banana/clients/cloudinary"
+	"github.com/rs/zerolog"
+)
+
+// providers images can be stored with
+const (
+	ProviderCloudinary = "cloudinary"
+	ProviderS3         = "s3"
+	ProviderLocal      = "local"
+)
+
+// LocalPath is where the server serves the images of the local store from
+const LocalPath = "/images"
+
+type (
+	// Config picks the store images are kept in and the images accepted
+	Config struct {
+		// Provider is one of cloudinary, s3 or local
+		Provider string `json:"provider"`
+		// MaxBytes, MaxWidth and MaxHeight bound every uploaded image
+		MaxBytes  int `json:"maxBytes"`
+		MaxWidth  int `json:"maxWidth"`
+		MaxHeight int `json:"maxHeight"`
+		TimeoutMS int `json:"timeoutMs"`
+		// LocalDir is the directory the local store writes to and LocalURL
+		// the url it is served at
+		LocalDir string   `json:"localDir"`
+		LocalURL string   `json:"localUrl"`
+		S3       S3Config `json:"s3"`
+	}
+
+	// ImageStore keeps the bytes of images under a key
+	ImageStore interface {
+		// Put stores data under key and returns the url it is served at
+		Put(key string, contentType string, data []byte) (string, error)
+
+		// Delete removes the image stored under key
+		Delete(key string) error
+	}
+)
+
+// DefaultConfig returns the image settings used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		Provider:  ProviderCloudinary,
+		MaxBytes:  10 << 20,
+		MaxWidth:  6000,
+		MaxHeight: 6000,
+		TimeoutMS: 30000,
+		LocalDir:  "upload_images",
+		LocalURL:  "http://localhost:8080" + LocalPath,
+	}
+}
+
+// New returns the ImageStore of cfg.Provider
+func New(logger zerolog.Logger, cfg Config, cloudinaryClient cloudinary.Client) (ImageStore, error) {
+	switch cfg.Provider {
+	case ProviderCloudinary:
+		return NewCloudinaryStore(cloudinaryClient), nil
+	case ProviderS3:
+		client := &http.Client{Timeout: time.Duration(cfg.TimeoutMS) * time.Millisecond}
+		return NewS3Store(logger, cfg.S3, client), nil
+	case ProviderLocal:
+		return NewLocalStore(cfg.LocalDir, cfg.LocalURL)
+	}
+	return nil, fmt.Errorf("unknown image provider %q", cfg.Provider)
+}
@@ -0,0 +1,227 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders of the image types accepted
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+	"github.com/rs/zerolog"
+)
+
+// extensions are the image types accepted and the extension they are
+// stored with
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+type (
+	// Image is an image kept in the ImageStore
+	Image struct {
+		URL         string
+		Key         string
+		Hash        string
+		ContentType string
+		Width       int
+		Height      int
+		Bytes       int
+		// New is false when the same image had been uploaded before
+		New bool
+	}
+
+	// Index remembers the images stored by the hash of their content
+	Index interface {
+		// Load returns the image stored with hash, ok is false when there
+		// is none
+		Load(hash string) (img Image, ok bool, err error)
+		Save(img Image) error
+		Delete(hash string) error
+	}
+
+	sqlIndex struct {
+		sql db.Querier
+	}
+
+	// Pipeline checks uploaded images, strips their metadata and stores
+	// each distinct image once. It is safe for concurrent use.
+	Pipeline struct {
+		logger zerolog.Logger
+		store  ImageStore
+		index  Index
+		cfg    Config
+	}
+)
+
+// NewSQLIndex returns an Index backed by the image table
+func NewSQLIndex(psql db.Querier) Index {
+	return &sqlIndex{psql}
+}
+
+func (s *sqlIndex) Load(hash string) (Image, bool, error) {
+	img := Image{Hash: hash}
+	err := s.sql.QueryRow("SELECT url,store_key,content_type,width,height,bytes FROM image WHERE hash = $1;", hash).
+		Scan(&img.URL, &img.Key, &img.ContentType, &img.Width, &img.Height, &img.Bytes)
+	if err == sql.ErrNoRows {
+		return Image{}, false, nil
+	}
+	if err != nil {
+		return Image{}, false, err
+	}
+	return img, true, nil
+}
+
+func (s *sqlIndex) Save(img Image) error {
+	_, err := s.sql.Exec("INSERT INTO image(hash,url,store_key,content_type,width,height,bytes) VALUES($1,$2,$3,$4,$5,$6,$7) "+
+		"ON CONFLICT (hash) DO UPDATE SET url = excluded.url, store_key = excluded.store_key;",
+		img.Hash, img.URL, img.Key, img.ContentType, img.Width, img.Height, img.Bytes)
+	return err
+}
+
+func (s *sqlIndex) Delete(hash string) error {
+	_, err := s.sql.Exec("DELETE FROM image WHERE hash = $1;", hash)
+	return err
+}
+
+// NewPipeline returns a Pipeline storing images accepted by the limits of
+// cfg in store
+func NewPipeline(logger zerolog.Logger, cfg Config, store ImageStore, index Index) *Pipeline {
+	return &Pipeline{logger, store, index, cfg}
+}
+
+// Save reads an image from r and stores it, unless the same image is stored
+// already. Images that are too large, or are no jpeg, png or gif, are
+// refused with a helper.ValidationError.
+func (p *Pipeline) Save(r io.Reader) (Image, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(p.cfg.MaxBytes)+1))
+	if err != nil {
+		return Image{}, err
+	}
+	if len(data) > p.cfg.MaxBytes {
+		return Image{}, helper.ValidationError{Message: fmt.Sprintf("image upload failed, images may be at most %d KB", p.cfg.MaxBytes>>10)}
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := extensions[contentType]
+	if !ok {
+		return Image{}, helper.ValidationError{Message: fmt.Sprintf("image upload failed, unsupported type %s", contentType)}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, helper.ValidationError{Message: fmt.Sprintf("image upload failed, unreadable %s", contentType)}
+	}
+	if config.Width > p.cfg.MaxWidth || config.Height > p.cfg.MaxHeight {
+		return Image{}, helper.ValidationError{Message: fmt.Sprintf("image upload failed, images may be at most %dx%d pixels",
+			p.cfg.MaxWidth, p.cfg.MaxHeight)}
+	}
+
+	data, err = strip(contentType, data)
+	if err != nil {
+		return Image{}, helper.ValidationError{Message: fmt.Sprintf("image upload failed, unreadable %s", contentType)}
+	}
+
+	// images are shown the way their exif orientation says, sideways
+	// photos taller than wide
+	width, height := config.Width, config.Height
+	orientation := orientation(contentType, data)
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	img, ok, err := p.index.Load(hash)
+	if err != nil {
+		return Image{}, helper.DatabaseError{DBError: err.Error()}
+	}
+	if ok {
+		return img, nil
+	}
+
+	img = Image{
+		Key:         hash + extension,
+		Hash:        hash,
+		ContentType: contentType,
+		Width:       width,
+		Height:      height,
+		Bytes:       len(data),
+		New:         true,
+	}
+	if img.URL, err = p.store.Put(img.Key, contentType, data); err != nil {
+		return Image{}, err
+	}
+	if err := p.saveRenditions(img, data, orientation); err != nil {
+		p.discard(img)
+		return Image{}, err
+	}
+	if err := p.index.Save(img); err != nil {
+		p.discard(img)
+		return Image{}, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	p.logger.Info().Msgf("stored image %s of %d bytes", img.Key, img.Bytes)
+	return img, nil
+}
+
+// Discard removes the images that were new to the store again, for when
+// what they were uploaded with could not be saved
+func (p *Pipeline) Discard(images []Image) {
+	for _, img := range images {
+		if img.New {
+			p.discard(img)
+		}
+	}
+}
+
+// saveRenditions stores the renditions of img, turned the right way up for
+// orientation, unless the store renders them itself
+func (p *Pipeline) saveRenditions(img Image, data []byte, orientation uint16) error {
+	if p.cfg.Provider == ProviderCloudinary {
+		return nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return helper.ValidationError{Message: fmt.Sprintf("image upload failed, unreadable %s", img.ContentType)}
+	}
+	decoded = orient(decoded, orientation)
+	for _, r := range Renditions {
+		rendition, contentType, err := render(decoded, img.Key, r)
+		if err != nil {
+			return err
+		}
+		if _, err := p.store.Put(VariantKey(img.Key, r), contentType, rendition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) discard(img Image) {
+	keys := []string{img.Key}
+	if p.cfg.Provider != ProviderCloudinary {
+		for _, r := range Renditions {
+			keys = append(keys, VariantKey(img.Key, r))
+		}
+	}
+	for _, key := range keys {
+		if err := p.store.Delete(key); err != nil {
+			p.logger.Error().Msgf("error deleting image %s: %s", key, err)
+		}
+	}
+	if err := p.index.Delete(img.Hash); err != nil {
+		p.logger.Error().Msgf("error deleting image %s from the index: %s", img.Key, err)
+	}
+}
@@ -0,0 +1,138 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"path"
+)
+
+// jpegQuality is the quality renditions are encoded with
+const jpegQuality = 80
+
+// render returns the rendition r of img, the image stored under key, and
+// its content type
+func render(img image.Image, key string, r Rendition) ([]byte, string, error) {
+	var b bytes.Buffer
+	if path.Ext(VariantKey(key, r)) == ".png" {
+		if err := png.Encode(&b, resize(img, r)); err != nil {
+			return nil, "", err
+		}
+		return b.Bytes(), "image/png", nil
+	}
+
+	if err := jpeg.Encode(&b, resize(img, r), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, "", err
+	}
+	return b.Bytes(), "image/jpeg", nil
+}
+
+// resize scales src to the size of rendition r, cropping the center of src
+// to the proportions of r first when it crops. Each pixel is the average of
+// the pixels of src it covers. The area used is converted to RGBA once, which
+// image/draw does without going through the colors of single pixels for the
+// jpeg, png and gif images accepted, and averaged from its bytes.
+func resize(src image.Image, r Rendition) image.Image {
+	bounds := src.Bounds()
+	width, height := r.Size(bounds.Dx(), bounds.Dy())
+
+	area := bounds
+	if r.Crop {
+		if bounds.Dx()*height > bounds.Dy()*width {
+			w := bounds.Dy() * width / height
+			x := bounds.Min.X + (bounds.Dx()-w)/2
+			area = image.Rect(x, bounds.Min.Y, x+w, bounds.Max.Y)
+		} else {
+			h := bounds.Dx() * height / width
+			y := bounds.Min.Y + (bounds.Dy()-h)/2
+			area = image.Rect(bounds.Min.X, y, bounds.Max.X, y+h)
+		}
+	}
+
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(area)
+		draw.Draw(rgba, area, src, area.Min, draw.Src)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := span(area.Min.Y, area.Dy(), y, height)
+		for x := 0; x < width; x++ {
+			x0, x1 := span(area.Min.X, area.Dx(), x, width)
+
+			var red, green, blue, alpha uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[rgba.PixOffset(x0, sy):rgba.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					red, green, blue, alpha = red+uint64(row[i]), green+uint64(row[i+1]), blue+uint64(row[i+2]), alpha+uint64(row[i+3])
+				}
+			}
+
+			n := uint64((x1 - x0) * (y1 - y0))
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(red / n)
+			dst.Pix[i+1] = uint8(green / n)
+			dst.Pix[i+2] = uint8(blue / n)
+			dst.Pix[i+3] = uint8(alpha / n)
+		}
+	}
+	return dst
+}
+
+// orient turns src the right way up for its exif orientation: mirrored for
+// 2 and 4, rotated half a turn for 3, and a quarter turn with 5 to 8, which
+// swap the width and the height
+func orient(src image.Image, orientation uint16) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	w, h := bounds.Dx(), bounds.Dy()
+
+	size := image.Rect(0, 0, w, h)
+	if orientation >= 5 {
+		size = image.Rect(0, 0, h, w)
+	}
+	dst := image.NewRGBA(size)
+	for y := 0; y < size.Dy(); y++ {
+		for x := 0; x < size.Dx(); x++ {
+			// the pixel of src shown at x, y
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], rgba.Pix[rgba.PixOffset(sx, sy):rgba.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// span returns the source pixels destination pixel i of size covers, at
+// least one
+func span(min int, length int, i int, size int) (int, int) {
+	from := min + i*length/size
+	to := min + (i+1)*length/size
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
@@ -0,0 +1,146 @@
+package media
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type (
+	// S3Config locates a bucket of an S3 compatible object store, AWS S3,
+	// minio, DigitalOcean spaces or the like
+	S3Config struct {
+		// Endpoint is the url of the object store, e.g.
+		// https://s3.us-west-1.amazonaws.com
+		Endpoint        string `json:"endpoint"`
+		Region          string `json:"region"`
+		Bucket          string `json:"bucket"`
+		AccessKeyID     string `json:"accessKeyId"`
+		SecretAccessKey string `json:"secretAccessKey"`
+		// PublicURL is the url the bucket is served at, a CDN in front of
+		// it for instance. The bucket url is used when empty.
+		PublicURL string `json:"publicUrl"`
+	}
+
+	// s3Store puts objects with path style requests signed with AWS
+	// signature version 4
+	s3Store struct {
+		logger zerolog.Logger
+		cfg    S3Config
+		client *http.Client
+	}
+)
+
+// NewS3Store returns an ImageStore writing to the bucket of cfg
+func NewS3Store(logger zerolog.Logger, cfg S3Config, client *http.Client) ImageStore {
+	return &s3Store{logger, cfg, client}
+}
+
+func (s *s3Store) Put(key string, contentType string, data []byte) (string, error) {
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if err := s.do(req, data); err != nil {
+		return "", err
+	}
+
+	if s.cfg.PublicURL != "" {
+		return strings.TrimSuffix(s.cfg.PublicURL, "/") + "/" + key, nil
+	}
+	return s.objectURL(key), nil
+}
+
+func (s *s3Store) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil)
+}
+
+func (s *s3Store) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.cfg.Endpoint, "/"), s.cfg.Bucket, key)
+}
+
+func (s *s3Store) do(req *http.Request, payload []byte) error {
+	sign(req, payload, s.cfg, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		s.logger.Error().Str("url", req.URL.String()).Str("status", resp.Status).Str("body", string(body)).
+			Msgf("object store non 2xx response")
+		return fmt.Errorf("%s %s returned with errorCode: %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	return nil
+}
+
+// sign adds the AWS signature version 4 of req, with the headers it has at
+// t, to its Authorization header
+func sign(req *http.Request, payload []byte, cfg S3Config, t time.Time) {
+	payloadHash := sha256.Sum256(payload)
+	amzDate := t.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
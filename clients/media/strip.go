@@ -0,0 +1,187 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	// pngMetadata are the png chunks carrying exif data and text, the time
+	// a picture was taken or the camera it was taken with
+	pngMetadata = map[string]bool{"eXIf": true, "tEXt": true, "iTXt": true, "zTXt": true, "tIME": true}
+
+	errMalformed = errors.New("malformed image")
+
+	exifHeader = []byte("Exif\x00\x00")
+)
+
+// orientationTag is the exif tag telling which way up a picture was taken
+const orientationTag = 0x0112
+
+// strip removes the metadata, gps coordinates included, phones write into
+// the images they take. Images of other types are returned as they are.
+func strip(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	}
+	return data, nil
+}
+
+// stripJPEG drops the APP1 segments holding exif and xmp data and the APP13
+// segment holding iptc data. The orientation recorded in exif is kept, in an
+// exif segment of its own, so that pictures are still shown the right way up.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	oriented := false
+	for i := 2; ; {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, errMalformed
+		}
+		marker := data[i+1]
+		// markers may be padded with any number of 0xFF
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		// the entropy coded image follows the start of scan, metadata
+		// segments all come before it
+		if marker == 0xDA {
+			out.Write(data[i:])
+			return out.Bytes(), nil
+		}
+
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) {
+			return nil, errMalformed
+		}
+		if marker != 0xE1 && marker != 0xED {
+			out.Write(data[i:end])
+		} else if orientation, ok := exifOrientation(data[i+4 : end]); ok && !oriented {
+			out.Write(orientationSegment(orientation))
+			oriented = true
+		}
+		i = end
+	}
+}
+
+// exifOrientation returns the orientation recorded in the first image file
+// directory of an exif segment, ok is false when there is none
+func exifOrientation(segment []byte) (uint16, bool) {
+	if !bytes.HasPrefix(segment, exifHeader) {
+		return 0, false
+	}
+	tiff := segment[len(exifHeader):]
+	if len(tiff) < 8 {
+		return 0, false
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0, false
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for e := 0; e < entries; e++ {
+		// tag, type, count and value, short values in the first two bytes
+		entry := ifd + 2 + 12*e
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:entry+2]) != orientationTag {
+			continue
+		}
+		orientation := order.Uint16(tiff[entry+8 : entry+10])
+		if orientation < 1 || orientation > 8 {
+			return 0, false
+		}
+		return orientation, true
+	}
+	return 0, false
+}
+
+// orientation returns the exif orientation of an image, 1, the right way up,
+// when it records none
+func orientation(contentType string, data []byte) uint16 {
+	if contentType != "image/jpeg" || len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if marker == 0xDA || end > len(data) {
+			break
+		}
+		if marker == 0xE1 {
+			if o, ok := exifOrientation(data[i+4 : end]); ok {
+				return o
+			}
+		}
+		i = end
+	}
+	return 1
+}
+
+// orientationSegment returns an APP1 segment whose exif data is orientation
+// alone
+func orientationSegment(orientation uint16) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian, the directory follows
+		0, 1, // one entry
+		byte(orientationTag >> 8), byte(orientationTag & 0xFF), 0, 3, 0, 0, 0, 1, // a short
+		byte(orientation >> 8), byte(orientation), 0, 0,
+		0, 0, 0, 0, // no next directory
+	}
+	length := 2 + len(exifHeader) + len(tiff)
+	segment := []byte{0xFF, 0xE1, byte(length >> 8), byte(length)}
+	segment = append(segment, exifHeader...)
+	return append(segment, tiff...)
+}
+
+// stripPNG drops the exif, text and time chunks
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		// length, type, data and crc
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:i+4]))
+		if end > len(data) || end < i {
+			return nil, errMalformed
+		}
+		if !pngMetadata[string(data[i+4:i+8])] {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	return out.Bytes(), nil
+}
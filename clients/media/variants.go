@@ -0,0 +1,155 @@
+package media
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	// Rendition is a size images are shown at, filling Width by Height and
+	// cropping what overflows when Crop, or else fitting within it
+	Rendition struct {
+		Name   string
+		Width  int
+		Height int
+		Crop   bool
+	}
+
+	// Variants names the renditions of images: cloudinary and google drive
+	// images are resized by their provider, images of the s3 and local
+	// stores have had their renditions generated when uploaded
+	Variants struct {
+		storeURL string
+	}
+)
+
+// renditions of every image
+var (
+	Thumbnail = Rendition{Name: "thumbnail", Width: 150, Height: 150, Crop: true}
+	Card      = Rendition{Name: "card", Width: 600, Height: 338, Crop: true}
+	Detail    = Rendition{Name: "detail", Width: 1200, Height: 1200}
+
+	Renditions = []Rendition{Thumbnail, Card, Detail}
+)
+
+var (
+	// storedKey is the key of images stored by the pipeline
+	storedKey = regexp.MustCompile(`^[0-9a-f]{64}\.(jpg|png|gif)$`)
+
+	// driveFile finds the id of google drive files shared as
+	// drive.google.com/file/d/<id>/view
+	driveFile = regexp.MustCompile(`/file/d/([^/]+)`)
+)
+
+// NewVariants returns the Variants of the images stored as cfg says
+func NewVariants(cfg Config) Variants {
+	switch cfg.Provider {
+	case ProviderLocal:
+		return Variants{strings.TrimSuffix(cfg.LocalURL, "/")}
+	case ProviderS3:
+		if cfg.S3.PublicURL != "" {
+			return Variants{strings.TrimSuffix(cfg.S3.PublicURL, "/")}
+		}
+		return Variants{fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.S3.Endpoint, "/"), cfg.S3.Bucket)}
+	}
+	return Variants{}
+}
+
+// Size returns the size of the rendition of an image of width by height,
+// which is unknown when they are 0. Images are never enlarged to fit.
+func (r Rendition) Size(width int, height int) (int, int) {
+	if r.Crop {
+		return r.Width, r.Height
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0
+	}
+
+	scale := math.Min(1, math.Min(float64(r.Width)/float64(width), float64(r.Height)/float64(height)))
+	return int(math.Max(1, float64(width)*scale+0.5)), int(math.Max(1, float64(height)*scale+0.5))
+}
+
+// Of returns the renditions of the image at link, of width by height when
+// known. Images of unknown origin are shown as they are.
+func (v Variants) Of(link string, width int, height int) shared.ImageVariants {
+	variant := func(r Rendition) shared.ImageVariant {
+		return shared.ImageVariant{URL: link, Width: width, Height: height}
+	}
+
+	switch {
+	case link == "":
+		return shared.ImageVariants{}
+	case strings.Contains(link, "res.cloudinary.com") && strings.Contains(link, "/upload/"):
+		variant = func(r Rendition) shared.ImageVariant {
+			w, h := r.Size(width, height)
+			return shared.ImageVariant{URL: cloudinaryURL(link, r), Width: w, Height: h}
+		}
+	case strings.Contains(link, "drive.google.com"):
+		if id := driveID(link); id != "" {
+			variant = func(r Rendition) shared.ImageVariant {
+				// drive fits thumbnails within their size, it does not crop
+				fit := Rendition{Width: r.Width, Height: r.Height}
+				w, h := fit.Size(width, height)
+				return shared.ImageVariant{
+					URL:   fmt.Sprintf("https://drive.google.com/thumbnail?id=%s&sz=w%d-h%d", url.QueryEscape(id), r.Width, r.Height),
+					Width: w, Height: h,
+				}
+			}
+		}
+	case v.storeURL != "" && strings.HasPrefix(link, v.storeURL+"/") && storedKey.MatchString(path.Base(link)):
+		variant = func(r Rendition) shared.ImageVariant {
+			w, h := r.Size(width, height)
+			return shared.ImageVariant{URL: v.storeURL + "/" + VariantKey(path.Base(link), r), Width: w, Height: h}
+		}
+	}
+
+	return shared.ImageVariants{
+		Thumbnail: variant(Thumbnail),
+		Card:      variant(Card),
+		Detail:    variant(Detail),
+	}
+}
+
+// VariantKey returns the key the rendition of the image stored under key is
+// stored under. Renditions of png and gif images are png, to keep their
+// transparency, the others jpeg.
+func VariantKey(key string, r Rendition) string {
+	extension := path.Ext(key)
+	if extension != ".png" && extension != ".gif" {
+		return strings.TrimSuffix(key, extension) + "_" + r.Name + ".jpg"
+	}
+	return strings.TrimSuffix(key, extension) + "_" + r.Name + ".png"
+}
+
+// cloudinaryURL asks cloudinary for the rendition of the image at link, in
+// the best format and quality the browser or app accepts, webp or avif
+// included
+func cloudinaryURL(link string, r Rendition) string {
+	parts := strings.SplitN(link, "/upload/", 2)
+	crop := "c_limit"
+	if r.Crop {
+		crop = "c_fill"
+	}
+	return fmt.Sprintf("%s/upload/%s,w_%d,h_%d,q_auto,f_auto/%s", parts[0], crop, r.Width, r.Height, parts[1])
+}
+
+// driveID returns the id of the google drive file at link
+func driveID(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if id := u.Query().Get("id"); id != "" {
+		return id
+	}
+	if m := driveFile.FindStringSubmatch(u.Path); m != nil {
+		return m[1]
+	}
+	return ""
+}
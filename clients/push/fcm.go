@@ -0,0 +1,103 @@
+package push
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type (
+	// Config holds the Firebase Cloud Messaging settings and how often
+	// saved notifications are pushed
+	Config struct {
+		// FCMServerKey is the legacy server key of the firebase project
+		FCMServerKey string `json:"fcmServerKey"`
+		FCMURL       string `json:"fcmUrl"`
+		// DispatchIntervalMinutes is how often saved notifications are
+		// evaluated, 0 turns pushing off
+		DispatchIntervalMinutes int `json:"dispatchIntervalMinutes"`
+	}
+
+	fcmProvider struct {
+		logger zerolog.Logger
+		cfg    Config
+		client *http.Client
+	}
+
+	fcmRequest struct {
+		To           string            `json:"to"`
+		Notification fcmNotification   `json:"notification"`
+		Data         map[string]string `json:"data,omitempty"`
+	}
+
+	fcmNotification struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+
+	fcmResponse struct {
+		Success int `json:"success"`
+		Failure int `json:"failure"`
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+)
+
+// DefaultFCMURL is the legacy FCM HTTP endpoint
+const DefaultFCMURL = "https://fcm.googleapis.com/fcm/send"
+
+// NewFCMProvider returns a Provider sending through Firebase Cloud Messaging
+func NewFCMProvider(logger zerolog.Logger, cfg Config) Provider {
+	return &fcmProvider{logger, cfg, &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (f *fcmProvider) Send(msg Message) error {
+	body, err := json.Marshal(fcmRequest{
+		To:           msg.Token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, f.cfg.FCMURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+f.cfg.FCMServerKey)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "could not reach fcm")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fcm returned status %d", resp.StatusCode)
+	}
+
+	var result fcmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return errors.Wrap(err, "could not decode fcm response")
+	}
+
+	if result.Failure > 0 && len(result.Results) > 0 {
+		switch reason := result.Results[0].Error; reason {
+		case "NotRegistered", "InvalidRegistration":
+			return ErrUnregistered
+		default:
+			return fmt.Errorf("fcm could not deliver: %s", reason)
+		}
+	}
+
+	f.logger.Info().Msgf("push sent: %s", msg.Title)
+	return nil
+}
@@ -0,0 +1,24 @@
+package push
+
+import "errors"
+
+type (
+	// Message is a push notification to a single device
+	Message struct {
+		// Token is the registration token the device got from its push service
+		Token string
+		Title string
+		Body  string
+		// Data is delivered to the app alongside the notification
+		Data map[string]string
+	}
+
+	// Provider delivers push notifications
+	Provider interface {
+		Send(msg Message) error
+	}
+)
+
+// ErrUnregistered is returned when the push service no longer knows the
+// registration token, e.g. because the app was uninstalled
+var ErrUnregistered = errors.New("registration token is not registered")
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goware/emailx"
+	"github.com/phassans/banana/auth"
+	"github.com/phassans/banana/clients/cloudinary"
+	"github.com/phassans/banana/clients/geocode"
+	"github.com/phassans/banana/clients/mail"
+	"github.com/phassans/banana/clients/media"
+	"github.com/phassans/banana/clients/push"
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/model/common"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Config holds everything that differs between deployments. It is
+	// loaded from an optional json file and then overridden from the
+	// environment, see Load.
+	Config struct {
+		Server       Server                   `json:"server"`
+		Hystrix      Hystrix                  `json:"hystrix"`
+		Database     db.Config                `json:"database"`
+		Google       geocode.GoogleConfig     `json:"google"`
+		Geocoder     geocode.Config           `json:"geocoder"`
+		Cloudinary   cloudinary.Config        `json:"cloudinary"`
+		Images       media.Config             `json:"images"`
+		Search       common.SearchConfig      `json:"search"`
+		ListingDates common.ListingDateConfig `json:"listingDates"`
+		SearchLog    common.SearchLogConfig   `json:"searchLog"`
+		Auth         auth.Config              `json:"auth"`
+		Push         push.Config              `json:"push"`
+		Mail         mail.Config              `json:"mail"`
+	}
+
+	// Server holds the settings of the http server
+	Server struct {
+		Port string `json:"port"`
+		// AutoMigrate applies pending schema migrations at startup
+		AutoMigrate bool `json:"autoMigrate"`
+	}
+
+	// Hystrix holds the circuit breaker defaults applied to every endpoint
+	Hystrix struct {
+		TimeoutMS     int `json:"timeoutMs"`
+		MaxConcurrent int `json:"maxConcurrent"`
+	}
+
+	// envVar maps an environment variable onto a field of Config
+	envVar struct {
+		name string
+		set  func(cfg *Config, value string) error
+	}
+)
+
+// minSecretLength is the shortest token signing secret we accept
+const minSecretLength = 32
+
+// envVars lists every environment override, applied after the config file.
+var envVars = []envVar{
+	stringVar("BANANA_PORT", func(c *Config) *string { return &c.Server.Port }),
+	boolVar("BANANA_AUTO_MIGRATE", func(c *Config) *bool { return &c.Server.AutoMigrate }),
+
+	intVar("BANANA_HYSTRIX_TIMEOUT_MS", func(c *Config) *int { return &c.Hystrix.TimeoutMS }),
+	intVar("BANANA_HYSTRIX_MAX_CONCURRENT", func(c *Config) *int { return &c.Hystrix.MaxConcurrent }),
+
+	stringVar("BANANA_DB_HOST", func(c *Config) *string { return &c.Database.Host }),
+	stringVar("BANANA_DB_PORT", func(c *Config) *string { return &c.Database.Port }),
+	stringVar("BANANA_DB_USER", func(c *Config) *string { return &c.Database.User }),
+	stringVar("BANANA_DB_PASSWORD", func(c *Config) *string { return &c.Database.Password }),
+	stringVar("BANANA_DB_NAME", func(c *Config) *string { return &c.Database.Database }),
+
+	stringVar("BANANA_GOOGLE_API_KEY", func(c *Config) *string { return &c.Google.APIKey }),
+
+	stringVar("BANANA_GEOCODER_PROVIDER", func(c *Config) *string { return &c.Geocoder.Provider }),
+	stringVar("BANANA_GEOCODER_NOMINATIM_URL", func(c *Config) *string { return &c.Geocoder.NominatimURL }),
+	stringVar("BANANA_GEOCODER_USER_AGENT", func(c *Config) *string { return &c.Geocoder.UserAgent }),
+	stringVar("BANANA_GEOCODER_GAZETTEER_PATH", func(c *Config) *string { return &c.Geocoder.GazetteerPath }),
+	intVar("BANANA_GEOCODER_TIMEOUT_MS", func(c *Config) *int { return &c.Geocoder.TimeoutMS }),
+	intVar("BANANA_GEOCODER_CACHE_TTL_MINUTES", func(c *Config) *int { return &c.Geocoder.CacheTTLMinutes }),
+	intVar("BANANA_GEOCODER_CACHE_SIZE", func(c *Config) *int { return &c.Geocoder.CacheSize }),
+
+	stringVar("BANANA_CLOUDINARY_URL", func(c *Config) *string { return &c.Cloudinary.BaseURL }),
+	stringVar("BANANA_CLOUDINARY_UPLOAD_PRESET", func(c *Config) *string { return &c.Cloudinary.UploadPreset }),
+
+	stringVar("BANANA_IMAGES_PROVIDER", func(c *Config) *string { return &c.Images.Provider }),
+	intVar("BANANA_IMAGES_MAX_BYTES", func(c *Config) *int { return &c.Images.MaxBytes }),
+	intVar("BANANA_IMAGES_MAX_WIDTH", func(c *Config) *int { return &c.Images.MaxWidth }),
+	intVar("BANANA_IMAGES_MAX_HEIGHT", func(c *Config) *int { return &c.Images.MaxHeight }),
+	intVar("BANANA_IMAGES_TIMEOUT_MS", func(c *Config) *int { return &c.Images.TimeoutMS }),
+	stringVar("BANANA_IMAGES_LOCAL_DIR", func(c *Config) *string { return &c.Images.LocalDir }),
+	stringVar("BANANA_IMAGES_LOCAL_URL", func(c *Config) *string { return &c.Images.LocalURL }),
+	stringVar("BANANA_IMAGES_S3_ENDPOINT", func(c *Config) *string { return &c.Images.S3.Endpoint }),
+	stringVar("BANANA_IMAGES_S3_REGION", func(c *Config) *string { return &c.Images.S3.Region }),
+	stringVar("BANANA_IMAGES_S3_BUCKET", func(c *Config) *string { return &c.Images.S3.Bucket }),
+	stringVar("BANANA_IMAGES_S3_ACCESS_KEY_ID", func(c *Config) *string { return &c.Images.S3.AccessKeyID }),
+	stringVar("BANANA_IMAGES_S3_SECRET_ACCESS_KEY", func(c *Config) *string { return &c.Images.S3.SecretAccessKey }),
+	stringVar("BANANA_IMAGES_S3_PUBLIC_URL", func(c *Config) *string { return &c.Images.S3.PublicURL }),
+
+	floatVar("BANANA_SEARCH_MAX_DISTANCE_TODAY", func(c *Config) *float64 { return &c.Search.MaxDistanceForTodaysDeals }),
+	floatVar("BANANA_SEARCH_MAX_DISTANCE_FUTURE", func(c *Config) *float64 { return &c.Search.MaxDistanceForFutureDeals }),
+	floatVar("BANANA_SEARCH_MAX_DISTANCE_GROUP_NOW", func(c *Config) *float64 { return &c.Search.MaxDistanceToGroupNow }),
+	floatVar("BANANA_SEARCH_MAX_FILTER_DISTANCE", func(c *Config) *float64 { return &c.Search.MaxFilterDistance }),
+	intVar("BANANA_SEARCH_MAX_FUTURE_DAYS", func(c *Config) *int { return &c.Search.MaxFutureDays }),
+
+	intVar("BANANA_LISTING_DATES_WINDOW_WEEKS", func(c *Config) *int { return &c.ListingDates.WindowWeeks }),
+	intVar("BANANA_LISTING_DATES_REFRESH_INTERVAL_MINUTES", func(c *Config) *int { return &c.ListingDates.RefreshIntervalMinutes }),
+
+	intVar("BANANA_SEARCH_LOG_QUEUE_SIZE", func(c *Config) *int { return &c.SearchLog.QueueSize }),
+	intVar("BANANA_SEARCH_LOG_BATCH_SIZE", func(c *Config) *int { return &c.SearchLog.BatchSize }),
+	intVar("BANANA_SEARCH_LOG_FLUSH_INTERVAL_MS", func(c *Config) *int { return &c.SearchLog.FlushIntervalMS }),
+
+	stringVar("BANANA_AUTH_SECRET", func(c *Config) *string { return &c.Auth.Secret }),
+	intVar("BANANA_AUTH_TOKEN_TTL_MINUTES", func(c *Config) *int { return &c.Auth.TokenTTLMinutes }),
+
+	stringVar("BANANA_PUSH_FCM_SERVER_KEY", func(c *Config) *string { return &c.Push.FCMServerKey }),
+	stringVar("BANANA_PUSH_FCM_URL", func(c *Config) *string { return &c.Push.FCMURL }),
+	intVar("BANANA_PUSH_DISPATCH_INTERVAL_MINUTES", func(c *Config) *int { return &c.Push.DispatchIntervalMinutes }),
+
+	stringVar("BANANA_MAIL_HOST", func(c *Config) *string { return &c.Mail.Host }),
+	stringVar("BANANA_MAIL_PORT", func(c *Config) *string { return &c.Mail.Port }),
+	stringVar("BANANA_MAIL_USERNAME", func(c *Config) *string { return &c.Mail.Username }),
+	stringVar("BANANA_MAIL_PASSWORD", func(c *Config) *string { return &c.Mail.Password }),
+	stringVar("BANANA_MAIL_FROM", func(c *Config) *string { return &c.Mail.From }),
+	intVar("BANANA_MAIL_TIMEOUT_MS", func(c *Config) *int { return &c.Mail.TimeoutMS }),
+}
+
+// Default returns the configuration used when nothing is overridden.
+// Credentials and api keys have no defaults and must be supplied.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port: "8080",
+		},
+		Hystrix: Hystrix{
+			TimeoutMS:     30000,
+			MaxConcurrent: 3000,
+		},
+		Database: db.Config{
+			Host:     "localhost",
+			Port:     "5432",
+			Database: "banana",
+		},
+		Geocoder: geocode.DefaultConfig(),
+		Cloudinary: cloudinary.Config{
+			BaseURL: "https://api.cloudinary.com/v1_1/itshungryhour/image/upload",
+		},
+		Images:       media.DefaultConfig(),
+		Search:       common.DefaultSearchConfig(),
+		ListingDates: common.DefaultListingDateConfig(),
+		SearchLog:    common.DefaultSearchLogConfig(),
+		Auth:         auth.DefaultConfig(),
+		Push: push.Config{
+			FCMURL: push.DefaultFCMURL,
+		},
+		Mail: mail.DefaultConfig(),
+	}
+}
+
+// Load builds the configuration from the defaults, the json file at path
+// (skipped when path is empty) and the BANANA_* environment variables, in
+// that order, and validates the result.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every missing or out of range setting at once.
+func (c Config) Validate() error {
+	var problems []string
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port <= 0 {
+		problems = append(problems, fmt.Sprintf("server.port %q is not a valid port", c.Server.Port))
+	}
+
+	if c.Hystrix.TimeoutMS <= 0 {
+		problems = append(problems, "hystrix.timeoutMs must be positive")
+	}
+	if c.Hystrix.MaxConcurrent <= 0 {
+		problems = append(problems, "hystrix.maxConcurrent must be positive")
+	}
+
+	required := map[string]string{
+		"database.host":     c.Database.Host,
+		"database.port":     c.Database.Port,
+		"database.user":     c.Database.User,
+		"database.password": c.Database.Password,
+		"database.database": c.Database.Database,
+		"auth.secret":       c.Auth.Secret,
+	}
+	switch c.Geocoder.Provider {
+	case geocode.ProviderGoogle:
+		required["google.apiKey"] = c.Google.APIKey
+	case geocode.ProviderNominatim:
+		required["geocoder.nominatimUrl"] = c.Geocoder.NominatimURL
+		required["geocoder.userAgent"] = c.Geocoder.UserAgent
+	case geocode.ProviderGazetteer:
+	default:
+		problems = append(problems, fmt.Sprintf("geocoder.provider %q must be google, nominatim or gazetteer", c.Geocoder.Provider))
+	}
+	switch c.Images.Provider {
+	case media.ProviderCloudinary:
+		required["cloudinary.baseUrl"] = c.Cloudinary.BaseURL
+		required["cloudinary.uploadPreset"] = c.Cloudinary.UploadPreset
+	case media.ProviderS3:
+		required["images.s3.endpoint"] = c.Images.S3.Endpoint
+		required["images.s3.region"] = c.Images.S3.Region
+		required["images.s3.bucket"] = c.Images.S3.Bucket
+		required["images.s3.accessKeyId"] = c.Images.S3.AccessKeyID
+		required["images.s3.secretAccessKey"] = c.Images.S3.SecretAccessKey
+	case media.ProviderLocal:
+		required["images.localDir"] = c.Images.LocalDir
+		required["images.localUrl"] = c.Images.LocalURL
+	default:
+		problems = append(problems, fmt.Sprintf("images.provider %q must be cloudinary, s3 or local", c.Images.Provider))
+	}
+	var missing []string
+	for name, value := range required {
+		if strings.TrimSpace(value) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		problems = append(problems, fmt.Sprintf("missing %s", strings.Join(missing, ", ")))
+	}
+
+	if c.Geocoder.TimeoutMS <= 0 || c.Geocoder.CacheTTLMinutes <= 0 || c.Geocoder.CacheSize <= 0 {
+		problems = append(problems, "geocoder timeout and cache settings must be positive")
+	}
+
+	if c.Images.MaxBytes <= 0 || c.Images.MaxWidth <= 0 || c.Images.MaxHeight <= 0 || c.Images.TimeoutMS <= 0 {
+		problems = append(problems, "images limits and timeout must be positive")
+	}
+
+	if c.Auth.Secret != "" && len(c.Auth.Secret) < minSecretLength {
+		problems = append(problems, fmt.Sprintf("auth.secret must be at least %d characters", minSecretLength))
+	}
+	if c.Auth.TokenTTLMinutes <= 0 {
+		problems = append(problems, "auth.tokenTtlMinutes must be positive")
+	}
+
+	if c.Push.DispatchIntervalMinutes < 0 {
+		problems = append(problems, "push.dispatchIntervalMinutes must not be negative")
+	}
+	if c.Push.DispatchIntervalMinutes > 0 && strings.TrimSpace(c.Push.FCMServerKey) == "" {
+		problems = append(problems, "push.fcmServerKey is required to push notifications")
+	}
+
+	if c.Mail.Host != "" && emailx.ValidateFast(c.Mail.From) != nil {
+		problems = append(problems, "mail.from must be an email address to send emails")
+	}
+	if c.Mail.TimeoutMS <= 0 {
+		problems = append(problems, "mail.timeoutMs must be positive")
+	}
+
+	search := c.Search
+	if search.MaxDistanceForTodaysDeals <= 0 || search.MaxDistanceForFutureDeals <= 0 ||
+		search.MaxDistanceToGroupNow <= 0 || search.MaxFilterDistance <= 0 {
+		problems = append(problems, "search distances must be positive")
+	}
+	if search.MaxFutureDays <= 0 {
+		problems = append(problems, "search.maxFutureDays must be positive")
+	}
+	for city, override := range search.Cities {
+		if city != strings.ToLower(strings.TrimSpace(city)) {
+			problems = append(problems, fmt.Sprintf("search.cities key %q must be a lower case city name", city))
+		}
+		if override.MaxDistanceForTodaysDeals < 0 || override.MaxDistanceForFutureDeals < 0 ||
+			override.MaxDistanceToGroupNow < 0 || override.MaxFilterDistance < 0 {
+			problems = append(problems, fmt.Sprintf("search.cities %q distances must not be negative", city))
+		}
+	}
+
+	if c.ListingDates.WindowWeeks <= 0 {
+		problems = append(problems, "listingDates.windowWeeks must be positive")
+	}
+	if c.ListingDates.RefreshIntervalMinutes < 0 {
+		problems = append(problems, "listingDates.refreshIntervalMinutes must not be negative")
+	}
+
+	if c.SearchLog.QueueSize <= 0 || c.SearchLog.BatchSize <= 0 || c.SearchLog.FlushIntervalMS <= 0 {
+		problems = append(problems, "searchLog sizes and interval must be positive")
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "could not open config file")
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(c); err != nil {
+		return errors.Wrapf(err, "could not parse config file %s", path)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, v := range envVars {
+		value, ok := lookup(v.name)
+		if !ok {
+			continue
+		}
+		if err := v.set(c, value); err != nil {
+			return errors.Wrapf(err, "invalid value for %s", v.name)
+		}
+	}
+	return nil
+}
+
+func stringVar(name string, field func(*Config) *string) envVar {
+	return envVar{name, func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func intVar(name string, field func(*Config) *int) envVar {
+	return envVar{name, func(c *Config, value string) error {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = i
+		return nil
+	}}
+}
+
+func floatVar(name string, field func(*Config) *float64) envVar {
+	return envVar{name, func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = f
+		return nil
+	}}
+}
+
+func boolVar(name string, field func(*Config) *bool) envVar {
+	return envVar{name, func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}}
+}
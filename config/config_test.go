@@ -0,0 +1,133 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() Config {
+	cfg := Default()
+	cfg.Database.User = "banana"
+	cfg.Database.Password = "secret"
+	cfg.Google.APIKey = "key"
+	cfg.Cloudinary.UploadPreset = "preset"
+	cfg.Auth.Secret = "0123456789abcdef0123456789abcdef"
+	return cfg
+}
+
+func TestValidateDefaultsRequireSecrets(t *testing.T) {
+	err := Default().Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "database.password")
+	require.Contains(t, err.Error(), "google.apiKey")
+	require.Contains(t, err.Error(), "auth.secret")
+
+	require.NoError(t, validConfig().Validate())
+}
+
+func TestValidateRejectsBadValues(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Port = "http"
+	cfg.Search.MaxDistanceForTodaysDeals = 0
+	cfg.Auth.Secret = "short"
+	cfg.Push.DispatchIntervalMinutes = 5
+	cfg.ListingDates.WindowWeeks = 0
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "server.port")
+	require.Contains(t, err.Error(), "search distances")
+	require.Contains(t, err.Error(), "auth.secret must be at least")
+	require.Contains(t, err.Error(), "push.fcmServerKey")
+	require.Contains(t, err.Error(), "listingDates.windowWeeks")
+}
+
+func TestValidateGeocoderProvider(t *testing.T) {
+	cfg := validConfig()
+	cfg.Google.APIKey = ""
+	cfg.Geocoder.Provider = "gazetteer"
+	require.NoError(t, cfg.Validate())
+
+	cfg.Geocoder.Provider = "nominatim"
+	cfg.Geocoder.UserAgent = ""
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "geocoder.userAgent")
+
+	cfg.Geocoder.Provider = "bing"
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "geocoder.provider")
+}
+
+func TestValidateImagesProvider(t *testing.T) {
+	cfg := validConfig()
+	cfg.Cloudinary.UploadPreset = ""
+	cfg.Images.Provider = "local"
+	require.NoError(t, cfg.Validate())
+
+	cfg.Images.Provider = "s3"
+	cfg.Images.S3.Endpoint = "https://s3.us-west-1.amazonaws.com"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "images.s3.bucket")
+	require.NotContains(t, err.Error(), "images.s3.endpoint")
+
+	cfg.Images.Provider = "ftp"
+	cfg.Images.MaxBytes = 0
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "images.provider")
+	require.Contains(t, err.Error(), "images limits")
+}
+
+func TestValidateMail(t *testing.T) {
+	cfg := validConfig()
+	cfg.Mail.Host = "smtp.example.com"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "mail.from")
+
+	cfg.Mail.From = "hello@itshungryhour.com"
+	require.NoError(t, cfg.Validate())
+}
+
+func TestApplyEnvOverridesFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "banana-config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{"server": {"port": "9090"}, "search": {"maxDistanceToGroupNow": 3}}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	cfg := validConfig()
+	require.NoError(t, cfg.readFile(f.Name()))
+	require.Equal(t, "9090", cfg.Server.Port)
+	require.Equal(t, 3.0, cfg.Search.MaxDistanceToGroupNow)
+	// fields missing from the file keep their defaults
+	require.Equal(t, 25.0, cfg.Search.MaxDistanceForFutureDeals)
+
+	env := map[string]string{
+		"BANANA_PORT":                      "7070",
+		"BANANA_DB_PASSWORD":               "from-env",
+		"BANANA_SEARCH_MAX_DISTANCE_TODAY": "12.5",
+		"BANANA_AUTO_MIGRATE":              "true",
+		"BANANA_AUTH_TOKEN_TTL_MINUTES":    "60",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	require.NoError(t, cfg.applyEnv(lookup))
+	require.Equal(t, "7070", cfg.Server.Port)
+	require.Equal(t, "from-env", cfg.Database.Password)
+	require.Equal(t, 12.5, cfg.Search.MaxDistanceForTodaysDeals)
+	require.True(t, cfg.Server.AutoMigrate)
+	require.Equal(t, 60, cfg.Auth.TokenTTLMinutes)
+
+	env["BANANA_HYSTRIX_TIMEOUT_MS"] = "soon"
+	require.Error(t, cfg.applyEnv(lookup))
+}
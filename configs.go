@@ -1,10 +1,13 @@
 package main
 
 import (
+	"flag"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/afex/hystrix-go/hystrix"
+	"github.com/phassans/banana/config"
 	"github.com/phassans/banana/shared"
 )
 
@@ -15,19 +18,35 @@ var (
 
 // defaults
 var (
-	hystrixHTTPTimeout = 30 * time.Second
-	maxHTTPConcurrency = 3000
-	serverPort         = "8080"
-	serverErrChannel   = make(chan error)
+	serverErrChannel = make(chan error)
+	configPath       string
+	autoMigrate      bool
 )
 
-func config() {
+// setup parses the command line, initializes the logger and loads the
+// configuration. The config file can also be given with BANANA_CONFIG.
+func setup() (config.Config, error) {
+	// parse command line flags
+	flag.StringVar(&configPath, "config", os.Getenv("BANANA_CONFIG"), "path to a json config file")
+	flag.BoolVar(&autoMigrate, "migrate", false, "apply pending schema migrations before starting the server")
+	flag.Parse()
+
 	// record server start time
 	serverStartTime = time.Now()
 
+	shared.InitLogger()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return cfg, err
+	}
+	if autoMigrate {
+		cfg.Server.AutoMigrate = true
+	}
+
 	// Configure hystrix.
-	hystrix.DefaultTimeout = int(hystrixHTTPTimeout / time.Millisecond)
-	hystrix.DefaultMaxConcurrent = maxHTTPConcurrency
+	hystrix.DefaultTimeout = cfg.Hystrix.TimeoutMS
+	hystrix.DefaultMaxConcurrent = cfg.Hystrix.MaxConcurrent
 
-	shared.InitLogger()
+	return cfg, nil
 }
@@ -54,6 +54,10 @@ func GetErrorStatus(err error) int {
 		return http.StatusBadRequest
 	case helper.BusinessError:
 		return http.StatusBadRequest
+	case helper.AuthenticationError:
+		return http.StatusUnauthorized
+	case helper.AuthorizationError:
+		return http.StatusForbidden
 	case *APIError:
 		return err.Code
 	default:
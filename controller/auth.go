@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/phassans/banana/auth"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+)
+
+const bearerPrefix = "Bearer "
+
+// Identify attaches the principal of every request to its context. Requests
+// carrying an access token act as its user, requests without one as an
+// anonymous app device. An invalid or expired token is rejected rather than
+// downgraded, so clients notice they have to log in again.
+func Identify(tokens auth.TokenIssuer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Device)))
+				return
+			}
+
+			if !strings.HasPrefix(header, bearerPrefix) {
+				reject(w, r, helper.AuthenticationError{Message: "malformed authorization header"})
+				return
+			}
+
+			claims, err := tokens.Verify(strings.TrimPrefix(header, bearerPrefix))
+			if err != nil {
+				reject(w, r, helper.AuthenticationError{Message: err.Error()})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), claims.Principal())))
+		})
+	}
+}
+
+// RequireRole only lets principals with one of the roles through. It must
+// run after Identify.
+func RequireRole(roles ...auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.PrincipalFromContext(r.Context())
+			if principal.Is(roles...) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// anonymous devices are told to log in, users that are logged in
+			// are told they are not allowed
+			if principal.Is(auth.RoleDevice) {
+				reject(w, r, helper.AuthenticationError{Message: "missing access token"})
+				return
+			}
+			reject(w, r, helper.AuthorizationError{Message: fmt.Sprintf("role %s is not allowed", principal.Role)})
+		})
+	}
+}
+
+func reject(w http.ResponseWriter, r *http.Request, err error) {
+	logger := shared.GetLogger()
+	logger.Info().Msgf("rejected %s: %s", r.URL.Path, err)
+	NewAPIError(err).Send(w) // nolint: errcheck
+}
+
+// authenticatedUser returns the business user or admin making the request
+func authenticatedUser(ctx context.Context) (auth.Principal, error) {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal.UserID == 0 {
+		return auth.Principal{}, helper.AuthenticationError{Message: "missing access token"}
+	}
+	return principal, nil
+}
+
+// authorizeUser allows users to act on their own account only
+func authorizeUser(ctx context.Context, userID int) error {
+	principal, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.UserID != userID && !principal.Is(auth.RoleAdmin) {
+		return helper.AuthorizationError{Message: fmt.Sprintf("not allowed to act on user %d", userID)}
+	}
+	return nil
+}
+
+// authorizeBusiness allows users to act on businesses they are associated
+// with only, admins may act on any business
+func (rtr *router) authorizeBusiness(ctx context.Context, businessID int) error {
+	principal, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.Is(auth.RoleAdmin) {
+		return nil
+	}
+
+	owns, err := rtr.engines.UserOwnsBusiness(principal.UserID, businessID)
+	if err != nil {
+		return err
+	}
+	if !owns {
+		return helper.AuthorizationError{Message: fmt.Sprintf("not allowed to act on business %d", businessID)}
+	}
+	return nil
+}
+
+// authorizeListing allows users to act on listings of their own businesses
+// only, admins may act on any listing
+func (rtr *router) authorizeListing(ctx context.Context, listingID int) error {
+	principal, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.Is(auth.RoleAdmin) {
+		return nil
+	}
+
+	owns, err := rtr.engines.UserOwnsListing(principal.UserID, listingID)
+	if err != nil {
+		return err
+	}
+	if !owns {
+		return helper.AuthorizationError{Message: fmt.Sprintf("not allowed to act on listing %d", listingID)}
+	}
+	return nil
+}
@@ -51,6 +51,13 @@ func (r createBusinessEndpoint) Execute(ctx context.Context, rtr *router, reques
 		return nil, err
 	}
 
+	// the new business is associated with the authenticated user
+	principal, err := authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request.UserID = principal.UserID
+
 	var hoursInfo []shared.Hours
 	for _, day := range request.Hours {
 		h := shared.Hours{
@@ -16,6 +16,10 @@ func (r allBusinessEndpoint) Do(ctx context.Context, rtr *router, values url.Val
 		return nil, err
 	}
 
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	return rtr.engines.GetAllBusiness(userID)
 }
 
@@ -30,6 +30,14 @@ func (r deleteBusinessEndpoint) Execute(ctx context.Context, rtr *router, reques
 		return nil, err
 	}
 
+	if err := rtr.authorizeBusiness(ctx, request.BusinessID); err != nil {
+		return nil, err
+	}
+
+	// only the authenticated user's association is removed
+	principal, _ := authenticatedUser(ctx)
+	request.UserID = principal.UserID
+
 	err := rtr.engines.BusinessDelete(request.BusinessID, request.UserID)
 	result := deleteBusinessResponse{deleteBusinessRequest: request, Error: NewAPIError(err)}
 	return result, err
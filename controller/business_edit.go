@@ -51,6 +51,10 @@ func (r businessEditEndpoint) Execute(ctx context.Context, rtr *router, requestI
 		return nil, err
 	}
 
+	if err := rtr.authorizeBusiness(ctx, request.BusinessID); err != nil {
+		return nil, err
+	}
+
 	var hoursInfo []shared.Hours
 	for _, day := range request.Hours {
 		h := shared.Hours{
@@ -16,11 +16,14 @@ type (
 		Latitude  float64 `json:"latitude,omitempty"`
 		Longitude float64 `json:"longitude,omitempty"`
 		Location  string  `json:"location,omitempty"`
+		Cursor    string  `json:"cursor,omitempty"`
+		Limit     int     `json:"limit,omitempty"`
 	}
 
 	favoritesViewResult struct {
-		Result []shared.SearchListingResult
-		Error  *APIError `json:"error,omitempty"`
+		Result     []shared.SearchListingResult
+		NextCursor string    `json:"nextCursor,omitempty"`
+		Error      *APIError `json:"error,omitempty"`
 	}
 
 	favoritesViewEndpoint struct{}
@@ -35,14 +38,15 @@ func (r favoritesViewEndpoint) Execute(ctx context.Context, rtr *router, request
 		return nil, err
 	}
 
-	result, err := rtr.engines.GetAllFavorites(
+	result, nextCursor, err := rtr.engines.GetAllFavorites(
 		request.PhoneID,
 		request.SortBy,
 		request.Latitude,
 		request.Longitude,
 		request.Location,
+		shared.Page{Cursor: request.Cursor, Limit: request.Limit},
 	)
-	return favoritesViewResult{Result: result, Error: NewAPIError(err)}, err
+	return favoritesViewResult{Result: result, NextCursor: nextCursor, Error: NewAPIError(err)}, err
 }
 
 func (r favoritesViewEndpoint) Validate(request interface{}) error {
@@ -59,6 +63,10 @@ func (r favoritesViewEndpoint) Validate(request interface{}) error {
 		return helper.ValidationError{Message: fmt.Sprint("favorite all failed, invalid 'sortBy'")}
 	}
 
+	if req.Limit < 0 {
+		return helper.ValidationError{Message: fmt.Sprint("favorite all failed, invalid 'limit'")}
+	}
+
 	return nil
 }
 
@@ -4,10 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"path"
 
 	"github.com/phassans/banana/shared"
 )
 
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
 func (rtr *router) newGetHandler(endpoint getEndPoint) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var err error
@@ -35,11 +41,18 @@ func (rtr *router) newGetHandler(endpoint getEndPoint) http.HandlerFunc {
 			Int("errorStatus", GetErrorStatus(err)).Logger()
 
 		if err != nil {
-			logger.Error().Msgf(err.Error())
+			logger.Error().Msg(err.Error())
 			return
 		}
 		logger.Info().Msgf("GET success")
 
+		if csv, ok := endpoint.(csvEndpoint); ok && vals.Get("format") == formatCSV {
+			w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+			w.Header().Set("Content-Disposition", "attachment; filename="+path.Base(endpoint.GetPath())+".csv")
+			err = csv.WriteCSV(w, result)
+			return
+		}
+
 		err = json.NewEncoder(w).Encode(result)
 	}
 }
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	happyHourAllResult struct {
+		Result     []shared.HappyHourSubmission
+		NextCursor string    `json:"nextCursor,omitempty"`
+		Error      *APIError `json:"error,omitempty"`
+	}
+
+	happyHourAllEndpoint struct{}
+)
+
+var happyHourAll getEndPoint = happyHourAllEndpoint{}
+
+func (r happyHourAllEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (interface{}, error) {
+	// the submissions waiting for moderation by default
+	status := values.Get("status")
+	if status == "" {
+		status = shared.HappyHourPending
+	}
+
+	var limit int
+	if values.Get("limit") != "" {
+		var err error
+		limit, err = strconv.Atoi(values.Get("limit"))
+		if err != nil || limit < 0 {
+			return nil, helper.ValidationError{Message: fmt.Sprint("happy hour all failed, invalid 'limit'")}
+		}
+	}
+
+	result, nextCursor, err := rtr.engines.GetHappyHourSubmissions(status, shared.Page{Cursor: values.Get("cursor"), Limit: limit})
+	return happyHourAllResult{Result: result, NextCursor: nextCursor, Error: NewAPIError(err)}, err
+}
+
+func (r happyHourAllEndpoint) GetPath() string {
+	return "/happyhours"
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	happyHourApproveRequest struct {
+		HappyHourID int `json:"hhId"`
+		// the business and listings the submission is imported as, in the
+		// format of the webhook
+		webhookRequest
+		BusinessID int `json:"businessId,omitempty"`
+	}
+
+	happyHourApproveResult struct {
+		happyHourApproveRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	happyHourApproveEndpoint struct{}
+)
+
+var happyHourApprove postEndpoint = happyHourApproveEndpoint{}
+
+func (r happyHourApproveEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(happyHourApproveRequest)
+
+	if err := r.Validate(requestI); err != nil {
+		return nil, err
+	}
+
+	logger := shared.GetLogger()
+	logger = logger.With().
+		Str("endpoint", r.GetPath()).
+		Int("hhId", request.HappyHourID).Logger()
+	logger.Info().Msgf("happy hour approve request")
+
+	submission, err := rtr.engines.GetHappyHourSubmission(request.HappyHourID)
+	if err != nil {
+		return nil, err
+	}
+	if submission.Status != shared.HappyHourPending {
+		return nil, helper.ValidationError{Message: fmt.Sprintf("happy hour %d was already %s", request.HappyHourID, submission.Status)}
+	}
+
+	// listings without images of their own show the photos submitted
+	for i := range request.Listings {
+		if len(request.Listings[i].ImageLink) == 0 {
+			request.Listings[i].ImageLink = submission.Images
+		}
+	}
+
+	// the business is imported the way the webhook imports it, together with
+	// the moderation of the submission
+	err = rtr.engines.Transact(func(engines model.Engine) error {
+		businessID, err := webhookEndpoint{}.importBusiness(engines, &request.webhookRequest, logger)
+		if err != nil {
+			return err
+		}
+		request.BusinessID = businessID
+		return engines.ModerateHappyHour(request.HappyHourID, shared.HappyHourApproved, "", businessID)
+	})
+	if err != nil {
+		return happyHourApproveResult{happyHourApproveRequest: request, Error: NewAPIError(err)}, err
+	}
+
+	rtr.notifySubmitter(request.HappyHourID)
+	return happyHourApproveResult{happyHourApproveRequest: request, Error: NewAPIError(nil)}, nil
+}
+
+func (r happyHourApproveEndpoint) Validate(request interface{}) error {
+	req := request.(happyHourApproveRequest)
+
+	if req.HappyHourID == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("happy hour approve failed, please provide 'hhId'")}
+	}
+	if req.Name == "" {
+		return helper.ValidationError{Message: fmt.Sprint("happy hour approve failed, please provide the business 'name'")}
+	}
+	if len(req.Listings) == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("happy hour approve failed, please provide at least one listing")}
+	}
+	return nil
+}
+
+func (r happyHourApproveEndpoint) GetPath() string {
+	return "/happyhour/approve"
+}
+
+func (r happyHourApproveEndpoint) HTTPRequest() interface{} {
+	return happyHourApproveRequest{}
+}
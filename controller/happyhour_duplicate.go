@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	happyHourDuplicateRequest struct {
+		HappyHourID int    `json:"hhId"`
+		BusinessID  int    `json:"businessId"`
+		Reason      string `json:"reason,omitempty"`
+	}
+
+	happyHourDuplicateResult struct {
+		happyHourDuplicateRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	happyHourDuplicateEndpoint struct{}
+)
+
+var happyHourDuplicate postEndpoint = happyHourDuplicateEndpoint{}
+
+func (r happyHourDuplicateEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(happyHourDuplicateRequest)
+
+	if err := r.Validate(requestI); err != nil {
+		return nil, err
+	}
+
+	if _, err := rtr.engines.GetBusinessFromID(request.BusinessID); err != nil {
+		if dbErr, ok := err.(helper.DatabaseError); ok && dbErr.DBError == sql.ErrNoRows.Error() {
+			err = helper.ValidationError{Message: fmt.Sprintf("happy hour duplicate failed, no business %d", request.BusinessID)}
+		}
+		return nil, err
+	}
+
+	err := rtr.engines.ModerateHappyHour(request.HappyHourID, shared.HappyHourDuplicate, strings.TrimSpace(request.Reason), request.BusinessID)
+	if err == nil {
+		rtr.notifySubmitter(request.HappyHourID)
+	}
+	result := happyHourDuplicateResult{happyHourDuplicateRequest: request, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r happyHourDuplicateEndpoint) Validate(request interface{}) error {
+	req := request.(happyHourDuplicateRequest)
+
+	if req.HappyHourID == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("happy hour duplicate failed, please provide 'hhId'")}
+	}
+	if req.BusinessID == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("happy hour duplicate failed, please provide the 'businessId' it duplicates")}
+	}
+	return nil
+}
+
+func (r happyHourDuplicateEndpoint) GetPath() string {
+	return "/happyhour/duplicate"
+}
+
+func (r happyHourDuplicateEndpoint) HTTPRequest() interface{} {
+	return happyHourDuplicateRequest{}
+}
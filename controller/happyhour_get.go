@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/phassans/banana/helper"
+)
+
+type (
+	happyHourGetEndpoint struct{}
+)
+
+var happyHourGet getEndPoint = happyHourGetEndpoint{}
+
+func (r happyHourGetEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (interface{}, error) {
+	if values.Get("hhId") == "" {
+		return nil, helper.ValidationError{Message: fmt.Sprint("happy hour get failed, missing hhId")}
+	}
+
+	happyHourID, err := strconv.Atoi(values.Get("hhId"))
+	if err != nil {
+		return nil, helper.ValidationError{Message: fmt.Sprint("happy hour get failed, invalid hhId")}
+	}
+
+	return rtr.engines.GetHappyHourSubmission(happyHourID)
+}
+
+func (r happyHourGetEndpoint) GetPath() string {
+	return "/happyhour"
+}
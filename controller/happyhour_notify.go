@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goware/emailx"
+	"github.com/phassans/banana/clients/mail"
+	"github.com/phassans/banana/shared"
+)
+
+// notifySubmitter emails the submitter of the moderated happy hour
+// happyHourID the outcome, when they left a valid email. The email is sent in
+// the background, a failure to send it is only logged.
+func (rtr *router) notifySubmitter(happyHourID int) {
+	logger := shared.GetLogger()
+
+	submission, err := rtr.engines.GetHappyHourSubmission(happyHourID)
+	if err != nil {
+		logger.Error().Msgf("could not notify the submitter of happy hour %d: %s", happyHourID, err)
+		return
+	}
+	if emailx.ValidateFast(submission.Email) != nil {
+		return
+	}
+
+	var business string
+	if submission.BusinessID != 0 {
+		if b, err := rtr.engines.GetBusinessFromID(submission.BusinessID); err == nil {
+			business = b.Name
+		}
+	}
+
+	msg, ok := moderationMessage(submission, business)
+	if !ok {
+		return
+	}
+	go func() {
+		if err := rtr.mailer.Send(msg); err != nil {
+			logger.Error().Msgf("could not email the submitter of happy hour %d: %s", happyHourID, err)
+		}
+	}()
+}
+
+// moderationMessage returns the email telling the submitter of submission the
+// outcome of its moderation, business being the name of the business it was
+// added as or duplicates, and false while it is pending
+func moderationMessage(submission shared.HappyHourSubmission, business string) (mail.Message, bool) {
+	if business == "" {
+		business = submission.Restaurant
+	}
+	// names go in the subject, which holds a single line
+	business = strings.Join(strings.Fields(business), " ")
+
+	greeting := "Hi,"
+	if submission.Name != "" {
+		greeting = fmt.Sprintf("Hi %s,", submission.Name)
+	}
+
+	var subject, body string
+	switch submission.Status {
+	case shared.HappyHourApproved:
+		subject = fmt.Sprintf("%s is now on Hungry Hour", business)
+		body = fmt.Sprintf("Thanks for telling us about the happy hour of %s, it is now listed for everyone to find.", business)
+	case shared.HappyHourDuplicate:
+		subject = fmt.Sprintf("%s is already on Hungry Hour", business)
+		body = fmt.Sprintf("Thanks for telling us about the happy hour of %s, it turns out we already list it.", business)
+		if submission.Reason != "" {
+			body += "\n\n" + submission.Reason
+		}
+	case shared.HappyHourRejected:
+		subject = fmt.Sprintf("About your happy hour submission for %s", business)
+		body = fmt.Sprintf("Thanks for telling us about the happy hour of %s, unfortunately we could not list it:\n\n%s",
+			business, submission.Reason)
+	default:
+		return mail.Message{}, false
+	}
+
+	return mail.Message{
+		To:      submission.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("%s\n\n%s\n\nThe Hungry Hour team\n", greeting, body),
+	}, true
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	happyHourRejectRequest struct {
+		HappyHourID int    `json:"hhId"`
+		Reason      string `json:"reason"`
+	}
+
+	happyHourRejectResult struct {
+		happyHourRejectRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	happyHourRejectEndpoint struct{}
+)
+
+var happyHourReject postEndpoint = happyHourRejectEndpoint{}
+
+func (r happyHourRejectEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(happyHourRejectRequest)
+
+	if err := r.Validate(requestI); err != nil {
+		return nil, err
+	}
+
+	err := rtr.engines.ModerateHappyHour(request.HappyHourID, shared.HappyHourRejected, strings.TrimSpace(request.Reason), 0)
+	if err == nil {
+		rtr.notifySubmitter(request.HappyHourID)
+	}
+	result := happyHourRejectResult{happyHourRejectRequest: request, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r happyHourRejectEndpoint) Validate(request interface{}) error {
+	req := request.(happyHourRejectRequest)
+
+	if req.HappyHourID == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("happy hour reject failed, please provide 'hhId'")}
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return helper.ValidationError{Message: fmt.Sprint("happy hour reject failed, please provide a 'reason'")}
+	}
+	return nil
+}
+
+func (r happyHourRejectEndpoint) GetPath() string {
+	return "/happyhour/reject"
+}
+
+func (r happyHourRejectEndpoint) HTTPRequest() interface{} {
+	return happyHourRejectRequest{}
+}
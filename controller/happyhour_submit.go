@@ -3,24 +3,15 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
-	"github.com/google/uuid"
-
-	"github.com/phassans/banana/clients/cloudinary"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/shared"
 )
 
-const (
-	IMAGE_FOLDER_PATH = "upload_images/"
-)
-
 type (
 	hresp struct {
 		Message string    `json:"message,omitempty"`
@@ -36,6 +27,8 @@ func (rtr *router) newImageHandler(endpoint postEndpoint) http.HandlerFunc {
 			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
 			return
 		}
+		// remove the temporary files of large uploads
+		defer r.MultipartForm.RemoveAll()
 
 		m := r.MultipartForm
 		images := m.File["images"]
@@ -72,72 +65,28 @@ func (rtr *router) newImageHandler(endpoint postEndpoint) http.HandlerFunc {
 			return
 		}
 
-		cloudinaryClient := cloudinary.NewCloudinaryClient(logger)
-		fileNames := make([]string, len(images))
-		for i, _ := range images {
-
-			uuid, err := uuid.NewRandom()
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			//for each fileheader, get a handle to the actual file
-			file, err := images[i].Open()
-			defer file.Close()
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			fileName := fmt.Sprintf("upload_images/%s_%s", uuid, images[i].Filename)
-			fileNames[i] = fileName
-			//create destination file making sure the path is writeable.
-			dst, err := os.Create(fileName)
-			defer dst.Close()
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
-				return
-			}
-			//copy the uploaded file to the destination file
-			if _, err := io.Copy(dst, file); err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
-				return
-			}
+		saved, err := rtr.saveImages(images)
+		if err != nil {
+			w.WriteHeader(GetErrorStatus(err))
+			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+			return
 		}
 
 		hhID, err := rtr.engines.SubmitHappyHour(phoneID, name, email, businessOwnerBool, restaurant, city, description)
 		if err != nil {
+			rtr.images.Discard(saved)
 			w.WriteHeader(http.StatusBadRequest)
 			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+			return
 		}
 
-		for i, _ := range images {
-			go func(i int) {
-				f, err := cloudinaryClient.MustOpen(fileNames[i])
-				if err != nil {
-					logger.Error().Msgf("error opening file: %s", err)
-					return
-				}
-
-				values := map[string]io.Reader{
-					"file":          f,
-					"upload_preset": strings.NewReader(cloudinary.UPLOAD_PRESET),
-				}
-				cloudinaryResponse, err := cloudinaryClient.Upload(values)
-				if err != nil {
-					logger.Error().Msgf("error uploading file to cloudinary: %s", err)
-					return
-				}
-
-				_, err = rtr.engines.SubmitHappyHourImages(hhID, cloudinaryResponse.URL)
-				if err != nil {
-					logger.Error().Msgf("error updating DB: %s", err)
-					return
-				}
-			}(i)
+		for _, img := range saved {
+			if _, err := rtr.engines.SubmitHappyHourImages(hhID, img.URL); err != nil {
+				logger.Error().Msgf("error adding image %s to happy hour %d: %s", img.URL, hhID, err)
+				w.WriteHeader(GetErrorStatus(err))
+				err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+				return
+			}
 		}
 
 		w.WriteHeader(http.StatusOK)
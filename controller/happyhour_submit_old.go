@@ -12,14 +12,14 @@ import (
 
 type (
 	happyHourSubmitRequest struct {
-		PhoneID       string                  `json:"phoneId"`
-		Name          string                  `json:"name,omitempty"`
-		Email         string                  `json:"email,omitempty"`
-		BusinessOwner bool                    `json:"businessOwner"`
-		Restaurant    string                  `json:"restaurant,omitempty"`
-		City          string                  `json:"city,omitempty"`
-		Description   string                  `json:"description,omitempty"`
-		images        []*multipart.FileHeader `json:"images,omitempty"`
+		PhoneID       string `json:"phoneId"`
+		Name          string `json:"name,omitempty"`
+		Email         string `json:"email,omitempty"`
+		BusinessOwner bool   `json:"businessOwner"`
+		Restaurant    string `json:"restaurant,omitempty"`
+		City          string `json:"city,omitempty"`
+		Description   string `json:"description,omitempty"`
+		images        []*multipart.FileHeader
 	}
 
 	happyHourSubmitResponse struct {
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+
+	"github.com/phassans/banana/clients/media"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+)
+
+// saveImages stores every uploaded image, or none of them: when one cannot
+// be stored the images stored before it are discarded again
+func (rtr *router) saveImages(files []*multipart.FileHeader) ([]media.Image, error) {
+	saved := make([]media.Image, 0, len(files))
+	for _, file := range files {
+		img, err := rtr.saveImage(file)
+		if err != nil {
+			rtr.images.Discard(saved)
+			if e, ok := err.(helper.ValidationError); ok {
+				return nil, helper.ValidationError{Message: fmt.Sprintf("%s: %s", e.Message, file.Filename)}
+			}
+			return nil, err
+		}
+		saved = append(saved, img)
+	}
+	return saved, nil
+}
+
+func (rtr *router) saveImage(file *multipart.FileHeader) (media.Image, error) {
+	f, err := file.Open()
+	if err != nil {
+		return media.Image{}, err
+	}
+	defer f.Close()
+	return rtr.images.Save(f)
+}
+
+// galleryOf returns the gallery of the saved images, captioned by the form
+// fields captions and altTexts given in the order of the images. The image
+// at index primaryImage, the first one by default, becomes the primary image.
+func galleryOf(saved []media.Image, form url.Values) ([]shared.GalleryImage, error) {
+	primary := 0
+	if value := form.Get("primaryImage"); value != "" {
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(saved) {
+			return nil, helper.ValidationError{Message: fmt.Sprintf("listing images failed, invalid primaryImage: %s", value)}
+		}
+		primary = i
+	}
+
+	captions, altTexts := form["captions"], form["altTexts"]
+	gallery := make([]shared.GalleryImage, 0, len(saved))
+	for i, img := range saved {
+		image := shared.GalleryImage{URL: img.URL, Primary: i == primary}
+		if i < len(captions) {
+			image.Caption = captions[i]
+		}
+		if i < len(altTexts) {
+			image.AltText = altTexts[i]
+		}
+		gallery = append(gallery, image)
+	}
+	return gallery, nil
+}
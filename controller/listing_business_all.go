@@ -10,13 +10,16 @@ import (
 
 type (
 	listingAllRequest struct {
-		BusinessID int `json:"businessId"`
+		BusinessID int    `json:"businessId"`
+		Cursor     string `json:"cursor,omitempty"`
+		Limit      int    `json:"limit,omitempty"`
 		//Status     string `json:"status"`
 	}
 
 	listingAllResult struct {
-		Result []shared.Listing
-		Error  *APIError `json:"error,omitempty"`
+		Result     []shared.Listing
+		NextCursor string    `json:"nextCursor,omitempty"`
+		Error      *APIError `json:"error,omitempty"`
 	}
 
 	listingAllEndpoint struct{}
@@ -31,8 +34,8 @@ func (r listingAllEndpoint) Execute(ctx context.Context, rtr *router, requestI i
 		return nil, err
 	}
 
-	result, err := rtr.engines.GetListingsByBusinessID(request.BusinessID, "")
-	return listingAllResult{Result: result, Error: NewAPIError(err)}, err
+	result, nextCursor, err := rtr.engines.GetListingsByBusinessID(request.BusinessID, "", shared.Page{Cursor: request.Cursor, Limit: request.Limit})
+	return listingAllResult{Result: result, NextCursor: nextCursor, Error: NewAPIError(err)}, err
 }
 
 func (r listingAllEndpoint) Validate(request interface{}) error {
@@ -49,6 +52,10 @@ func (r listingAllEndpoint) Validate(request interface{}) error {
 		return helper.ValidationError{Message: fmt.Sprint("listing all failed, missing businessId")}
 	}
 
+	if input.Limit < 0 {
+		return helper.ValidationError{Message: fmt.Sprint("listing all failed, invalid 'limit'")}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"context"
+	"net/url"
+)
+
+type (
+	listingDatesStatusEndpoint struct{}
+)
+
+var listingDatesStatus getEndPoint = listingDatesStatusEndpoint{}
+
+func (r listingDatesStatusEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (interface{}, error) {
+	return rtr.dateScheduler.Status(), nil
+}
+
+func (r listingDatesStatusEndpoint) GetPath() string {
+	return "/listing/dates/status"
+}
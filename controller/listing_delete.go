@@ -29,6 +29,10 @@ func (r deleteListingEndpoint) Execute(ctx context.Context, rtr *router, request
 		return nil, err
 	}
 
+	if err := rtr.authorizeListing(ctx, request.ListingID); err != nil {
+		return nil, err
+	}
+
 	err := rtr.engines.DeleteListing(request.ListingID)
 	result := deleteListingResponse{deleteListingRequest: request, Error: NewAPIError(err)}
 	return result, err
@@ -11,25 +11,28 @@ import (
 
 type (
 	listingEditRequest struct {
-		ListingID           int      `json:"listingId"`
-		BusinessID          int      `json:"businessId"`
-		Title               string   `json:"title"`
-		OldPrice            float64  `json:"oldPrice,omitempty"`
-		NewPrice            float64  `json:"newPrice"`
-		Discount            float64  `json:"discount,omitempty"`
-		DiscountDescription string   `json:"discountDescription,omitempty"`
-		DietaryRestriction  []string `json:"dietaryRestriction,omitempty"`
-		Description         string   `json:"description"`
-		StartDate           string   `json:"startDate"`
-		StartTime           string   `json:"startTime"`
-		EndTime             string   `json:"endTime,omitempty"`
-		MultipleDays        bool     `json:"multipleDays"`
-		EndDate             string   `json:"endDate,omitempty"`
-		Recurring           bool     `json:"recurring"`
-		RecurringDays       []string `json:"recurringDays,omitempty"`
-		RecurringEndDate    string   `json:"recurringEndDate,omitempty"`
-		ListingType         string   `json:"listingType"`
-		ImageLink           string   `json:"imageLink,omitempty"`
+		ListingID           int                   `json:"listingId"`
+		BusinessID          int                   `json:"businessId"`
+		Title               string                `json:"title"`
+		OldPrice            float64               `json:"oldPrice,omitempty"`
+		NewPrice            float64               `json:"newPrice"`
+		Discount            float64               `json:"discount,omitempty"`
+		DiscountDescription string                `json:"discountDescription,omitempty"`
+		DietaryRestriction  []string              `json:"dietaryRestriction,omitempty"`
+		Description         string                `json:"description"`
+		StartDate           string                `json:"startDate"`
+		StartTime           string                `json:"startTime"`
+		EndTime             string                `json:"endTime,omitempty"`
+		MultipleDays        bool                  `json:"multipleDays"`
+		EndDate             string                `json:"endDate,omitempty"`
+		Recurring           bool                  `json:"recurring"`
+		RecurringDays       []string              `json:"recurringDays,omitempty"`
+		RecurringEndDate    string                `json:"recurringEndDate,omitempty"`
+		RecurrenceRule      string                `json:"recurrenceRule,omitempty"`
+		TimeWindows         []shared.TimeWindow   `json:"timeWindows,omitempty"`
+		ListingType         string                `json:"listingType"`
+		ImageLink           string                `json:"imageLink,omitempty"`
+		Gallery             []shared.GalleryImage `json:"gallery,omitempty"`
 	}
 
 	listingEditResult struct {
@@ -49,6 +52,15 @@ func (r editListingEndpoint) Execute(ctx context.Context, rtr *router, requestI
 		return nil, err
 	}
 
+	if err := rtr.authorizeListing(ctx, request.ListingID); err != nil {
+		return nil, err
+	}
+
+	// a listing may only be moved to another business of the same user
+	if err := rtr.authorizeBusiness(ctx, request.BusinessID); err != nil {
+		return nil, err
+	}
+
 	l := shared.Listing{
 		Title:               request.Title,
 		OldPrice:            request.OldPrice,
@@ -66,9 +78,12 @@ func (r editListingEndpoint) Execute(ctx context.Context, rtr *router, requestI
 		Recurring:           request.Recurring,
 		RecurringDays:       request.RecurringDays,
 		RecurringEndDate:    request.RecurringEndDate,
+		RecurrenceRule:      request.RecurrenceRule,
+		TimeWindows:         request.TimeWindows,
 		Type:                request.ListingType,
 		ListingID:           request.ListingID,
 		ImageLink:           request.ImageLink,
+		Gallery:             request.Gallery,
 	}
 
 	err := rtr.engines.ListingEdit(&l)
@@ -83,7 +98,11 @@ func (r editListingEndpoint) Validate(request interface{}) error {
 		return helper.ValidationError{Message: fmt.Sprint("listing edit failed, invalid 'listingType'")}
 	}
 
-	var businessFields = []string{input.Title, input.Description, input.StartDate, input.StartTime, input.EndTime}
+	var businessFields = []string{input.Title, input.Description, input.StartDate}
+	// recurring listings may give their times per day instead
+	if len(input.TimeWindows) == 0 {
+		businessFields = append(businessFields, input.StartTime, input.EndTime)
+	}
 	for _, field := range businessFields {
 		if strings.TrimSpace(field) == "" {
 			return helper.ValidationError{Message: fmt.Sprint("listing edit failed, missing mandatory fields")}
@@ -102,10 +121,9 @@ func (r editListingEndpoint) Validate(request interface{}) error {
 		return helper.ValidationError{Message: fmt.Sprint("listing edit failed, please provide 'endDate' for multiple days lising")}
 	}
 
-	if input.Recurring && input.RecurringEndDate == "" {
-		return helper.ValidationError{Message: fmt.Sprint("listing edit failed, please provide 'recurringEndDate' for recurring listing")}
-	} else if input.Recurring && len(input.RecurringDays) == 0 {
-		return helper.ValidationError{Message: fmt.Sprint("listing edit failed, please provide 'recurringDays' for recurring listing")}
+	// recurring listings without a recurringEndDate recur until they are edited
+	if input.Recurring && len(input.RecurringDays) == 0 && input.RecurrenceRule == "" && len(input.TimeWindows) == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("listing edit failed, please provide 'recurringDays' or 'recurrenceRule' for recurring listing")}
 	}
 
 	if input.ListingID == 0 {
@@ -3,17 +3,11 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
-	"strings"
-	"sync"
 
-	"github.com/google/uuid"
-	"github.com/phassans/banana/clients/cloudinary"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/shared"
 )
@@ -26,6 +20,8 @@ func (rtr *router) newListingEditImageHandler(endpoint postEndpoint) http.Handle
 			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
 			return
 		}
+		// remove the temporary files of large uploads
+		defer r.MultipartForm.RemoveAll()
 
 		l := shared.Listing{}
 		for field, values := range r.Form {
@@ -56,6 +52,14 @@ func (rtr *router) newListingEditImageHandler(endpoint postEndpoint) http.Handle
 					l.StartDate = value
 				} else if field == "recurringEndDate" {
 					l.RecurringEndDate = value
+				} else if field == "recurrenceRule" {
+					l.RecurrenceRule = value
+				} else if field == "timeWindows" {
+					if err := json.Unmarshal([]byte(value), &l.TimeWindows); err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(helper.ValidationError{Message: "invalid timeWindows"})})
+						return
+					}
 				} else if field == "recurringDays" {
 					l.RecurringDays = values
 					break
@@ -89,77 +93,45 @@ func (rtr *router) newListingEditImageHandler(endpoint postEndpoint) http.Handle
 			return
 		}
 
-		if len(images) > 0 {
-			cloudinaryClient := cloudinary.NewCloudinaryClient(logger)
-			fileNames := make([]string, len(images))
-			for i, _ := range images {
-
-				uuid, err := uuid.NewRandom()
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-
-				//for each fileheader, get a handle to the actual file
-				file, err := images[i].Open()
-				defer file.Close()
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-
-				fileName := fmt.Sprintf("upload_images/%s_%s", uuid, images[i].Filename)
-				fileNames[i] = fileName
-				//create destination file making sure the path is writeable.
-				dst, err := os.Create(fileName)
-				defer dst.Close()
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
-					return
-				}
-				//copy the uploaded file to the destination file
-				if _, err := io.Copy(dst, file); err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
-					return
-				}
-			}
-
-			var wg sync.WaitGroup
-			wg.Add(len(images))
-			for i, _ := range images {
-				go func(i int, wg *sync.WaitGroup, l *shared.Listing) {
-					defer wg.Done()
+		if err := rtr.authorizeListing(r.Context(), l.ListingID); err != nil {
+			w.WriteHeader(GetErrorStatus(err))
+			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+			return
+		}
 
-					f, err := cloudinaryClient.MustOpen(fileNames[i])
-					if err != nil {
-						logger.Error().Msgf("error opening file: %s", err)
-						return
-					}
+		// a listing may only be moved to another business of the same user
+		if err := rtr.authorizeBusiness(r.Context(), l.BusinessID); err != nil {
+			w.WriteHeader(GetErrorStatus(err))
+			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+			return
+		}
 
-					values := map[string]io.Reader{
-						"file":          f,
-						"upload_preset": strings.NewReader(cloudinary.UPLOAD_PRESET),
-					}
-					cloudinaryResponse, err := cloudinaryClient.Upload(values)
-					if err != nil {
-						logger.Error().Msgf("error uploading file to cloudinary: %s", err)
-						return
-					}
-					l.ImageLink = cloudinaryResponse.URL
-				}(i, &wg, &l)
-			}
-			wg.Wait()
+		saved, err := rtr.saveImages(images)
+		if err != nil {
+			w.WriteHeader(GetErrorStatus(err))
+			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+			return
+		}
+		// the uploaded images are added to the gallery of the listing, which
+		// keeps its images when none is uploaded
+		gallery, err := galleryOf(saved, r.Form)
+		if err != nil {
+			rtr.images.Discard(saved)
+			w.WriteHeader(GetErrorStatus(err))
+			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+			return
 		}
+		l.Gallery = gallery
 
 		// make it always recurring
 		l.Recurring = true
 
 		err = rtr.engines.ListingEdit(&l)
 		if err != nil {
+			rtr.images.Discard(saved)
 			w.WriteHeader(http.StatusBadRequest)
 			err = json.NewEncoder(w).Encode(hresp{Error: NewAPIError(err)})
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -169,7 +141,11 @@ func (rtr *router) newListingEditImageHandler(endpoint postEndpoint) http.Handle
 }
 
 func ValidateEditFields(images []*multipart.FileHeader, r url.Values) error {
-	fields := []string{"businessId", "title", "discountDescription", "startDate", "recurringEndDate", "recurringDays", "startTime", "endTime"}
+	fields := []string{"businessId", "title", "discountDescription", "startDate"}
+	// recurring listings may give their times per day instead
+	if r.Get("timeWindows") == "" {
+		fields = append(fields, "startTime", "endTime")
+	}
 
 	for _, field := range fields {
 		if r.Get(field) == "" {
@@ -177,5 +153,9 @@ func ValidateEditFields(images []*multipart.FileHeader, r url.Values) error {
 		}
 	}
 
+	if r.Get("recurringDays") == "" && r.Get("recurrenceRule") == "" && r.Get("timeWindows") == "" {
+		return helper.ValidationError{Message: fmt.Sprint("listing add failed, missing recurringDays, recurrenceRule or timeWindows")}
+	}
+
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/locale"
 	"github.com/phassans/banana/shared"
 )
 
@@ -54,7 +55,7 @@ func (r listingEndpoint) Do(ctx context.Context, rtr *router, values url.Values)
 		Str("phoneID", phoneID).Logger()
 	logger.Info().Msgf("listing get request")
 
-	listingInfo, err := rtr.engines.GetListingInfo(listingID, phoneID, latitude, longitude, location)
+	listingInfo, err := rtr.engines.GetListingInfo(listingID, phoneID, latitude, longitude, location, locale.LanguageFromContext(ctx))
 	if err != nil {
 		return nil, err
 	}
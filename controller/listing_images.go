@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	listingImagesRequest struct {
+		ListingID int                   `json:"listingId"`
+		Images    []shared.GalleryImage `json:"images"`
+	}
+
+	listingImagesResponse struct {
+		listingImagesRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	listingImagesEndpoint struct{}
+)
+
+var listingImages postEndpoint = listingImagesEndpoint{}
+
+// Execute orders the images of a listing as they are given, by their
+// imageId, and removes the images left out. The response holds the gallery
+// of the listing as it is now.
+func (r listingImagesEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(listingImagesRequest)
+
+	if err := r.Validate(requestI); err != nil {
+		return nil, err
+	}
+
+	if err := rtr.authorizeListing(ctx, request.ListingID); err != nil {
+		return nil, err
+	}
+
+	err := rtr.engines.SetListingImages(request.ListingID, request.Images)
+	if err == nil {
+		request.Images, err = rtr.engines.GetListingImages(request.ListingID)
+	}
+	result := listingImagesResponse{listingImagesRequest: request, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r listingImagesEndpoint) Validate(request interface{}) error {
+	input := request.(listingImagesRequest)
+	if input.ListingID == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("listing images failed, missing listingId")}
+	}
+	if len(input.Images) == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("listing images failed, missing images")}
+	}
+	for _, img := range input.Images {
+		if img.ImageID == 0 {
+			return helper.ValidationError{Message: fmt.Sprint("listing images failed, an image is missing its 'imageId'")}
+		}
+	}
+
+	return nil
+}
+
+func (r listingImagesEndpoint) GetPath() string {
+	return "/listing/images"
+}
+
+func (r listingImagesEndpoint) HTTPRequest() interface{} {
+	return listingImagesRequest{}
+}
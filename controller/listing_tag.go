@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+)
+
+type (
+	listingTagRequest struct{}
+
+	listingTagResult struct {
+		Tagged int       `json:"tagged"`
+		Error  *APIError `json:"error,omitempty"`
+	}
+
+	listingTagEndpoint struct{}
+)
+
+var listingTag postEndpoint = listingTagEndpoint{}
+
+// Execute derives the category of every listing again, after the keywords of
+// the categories changed
+func (r listingTagEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	tagged, err := rtr.engines.TagListings()
+	result := listingTagResult{Tagged: tagged, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r listingTagEndpoint) Validate(request interface{}) error {
+	return nil
+}
+
+func (r listingTagEndpoint) GetPath() string {
+	return "/listing/tag"
+}
+
+func (r listingTagEndpoint) HTTPRequest() interface{} {
+	return listingTagRequest{}
+}
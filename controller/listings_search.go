@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/locale"
 	"github.com/phassans/banana/shared"
 )
 
@@ -23,14 +24,21 @@ type (
 		Keywords       string   `json:"keywords,omitempty"`
 		SortBy         string   `json:"sortBy,omitempty"`
 		SearchDay      string   `json:"searchDay,omitempty"`
+		Cursor         string   `json:"cursor,omitempty"`
+		Limit          int      `json:"limit,omitempty"`
 
 		PhoneID string `json:"phoneId"`
 	}
 
 	listingsSearchResult struct {
-		Result  []shared.SearchListingResult
-		Message string    `json:"message,omitempty"`
-		Error   *APIError `json:"error,omitempty"`
+		Result     []shared.SearchListingResult
+		NextCursor string `json:"nextCursor,omitempty"`
+		// Place names where the search was made, e.g. "Mission District"
+		Place *shared.Place `json:"place,omitempty"`
+		// ComingSoon is set for searches outside every region served
+		ComingSoon bool      `json:"comingSoon,omitempty"`
+		Message    string    `json:"message,omitempty"`
+		Error      *APIError `json:"error,omitempty"`
 	}
 
 	listingsSearchEndpoint struct{}
@@ -55,7 +63,9 @@ func (r listingsSearchEndpoint) Execute(ctx context.Context, rtr *router, reques
 		Str("distanceFilter", request.DistanceFilter).
 		Str("keywords", request.Keywords).
 		Str("searchDay", request.SearchDay).
-		Str("sortBy", request.SortBy).Logger()
+		Str("sortBy", request.SortBy).
+		Str("cursor", request.Cursor).
+		Int("limit", request.Limit).Logger()
 	logger.Info().Msgf("search request")
 
 	if request.SortBy == "dateadded" {
@@ -86,13 +96,20 @@ func (r listingsSearchEndpoint) Execute(ctx context.Context, rtr *router, reques
 		SortBy:         request.SortBy,
 		PhoneID:        request.PhoneID,
 		Search:         request.Search,
+		Page:           shared.Page{Cursor: request.Cursor, Limit: request.Limit},
+		Language:       locale.LanguageFromContext(ctx),
 	}
 
-	result, err := rtr.engines.SearchListings(searchRequest)
-	return listingsSearchResult{Result: result, Error: NewAPIError(err), Message: populateSearchMessage(len(result), request.Keywords)}, err
+	page, err := rtr.engines.SearchListingsPage(searchRequest)
+	return listingsSearchResult{Result: page.Results, NextCursor: page.NextCursor, Place: page.Place, ComingSoon: page.ComingSoon,
+		Error: NewAPIError(err), Message: populateSearchMessage(len(page.Results), request.Keywords, page.ComingSoon)}, err
 }
 
-func populateSearchMessage(numberOfResults int, keywords string) string {
+func populateSearchMessage(numberOfResults int, keywords string, comingSoon bool) string {
+	if comingSoon {
+		return "coming soon to your city"
+	}
+
 	if numberOfResults > 0 {
 		return ""
 	}
@@ -136,7 +153,8 @@ func (r listingsSearchEndpoint) Validate(request interface{}) error {
 		strings.ToLower(req.SortBy) != shared.SortByTimeLeft &&
 		strings.ToLower(req.SortBy) != shared.SortByPrice &&
 		req.SortBy != shared.SortByDateAdded &&
-		strings.ToLower(req.SortBy) != shared.SortByMostPopular {
+		strings.ToLower(req.SortBy) != shared.SortByMostPopular &&
+		strings.ToLower(req.SortBy) != shared.SortByRelevance {
 		return helper.ValidationError{Message: fmt.Sprint("listing search failed, invalid 'sortBy'")}
 	}
 
@@ -150,6 +168,10 @@ func (r listingsSearchEndpoint) Validate(request interface{}) error {
 		}
 	}
 
+	if req.Limit < 0 {
+		return helper.ValidationError{Message: fmt.Sprint("listings search failed, invalid 'limit'")}
+	}
+
 	return nil
 }
 
@@ -41,7 +41,7 @@ func (rtr *router) newPostHandler(endpoint postEndpoint) http.HandlerFunc {
 			//Str("query", fmt.Sprintf("%#v", request.Elem().Interface())).
 			Int("status", GetErrorStatus(err)).Logger()
 		if err != nil {
-			logger.Error().Msgf(err.Error())
+			logger.Error().Msg(err.Error())
 
 			// TBD: HACK, will fix later
 			/*if endpoint.GetPath() == "/business/add" {
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/phassans/banana/model/region"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	regionAddRequest struct {
+		shared.Region
+	}
+
+	regionAddResult struct {
+		regionAddRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	regionAddEndpoint struct{}
+)
+
+var regionAdd postEndpoint = regionAddEndpoint{}
+
+func (r regionAddEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(regionAddRequest)
+	request.Region = region.Normalize(request.Region)
+
+	if err := r.Validate(request); err != nil {
+		return nil, err
+	}
+
+	regionID, err := rtr.engines.AddRegion(request.Region)
+	request.RegionID = regionID
+	result := regionAddResult{regionAddRequest: request, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r regionAddEndpoint) Validate(request interface{}) error {
+	req := request.(regionAddRequest)
+	return region.Validate(region.Normalize(req.Region))
+}
+
+func (r regionAddEndpoint) GetPath() string {
+	return "/region/add"
+}
+
+func (r regionAddEndpoint) HTTPRequest() interface{} {
+	return regionAddRequest{}
+}
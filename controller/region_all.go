@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"context"
+	"net/url"
+)
+
+type (
+	allRegionEndpoint struct{}
+)
+
+var regionAll getEndPoint = allRegionEndpoint{}
+
+func (r allRegionEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (interface{}, error) {
+	return rtr.engines.GetRegions()
+}
+
+func (r allRegionEndpoint) GetPath() string {
+	return "/regions"
+}
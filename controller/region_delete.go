@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phassans/banana/helper"
+)
+
+type (
+	regionDeleteRequest struct {
+		RegionID int `json:"regionId"`
+	}
+
+	regionDeleteResult struct {
+		regionDeleteRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	regionDeleteEndpoint struct{}
+)
+
+var regionDelete postEndpoint = regionDeleteEndpoint{}
+
+func (r regionDeleteEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(regionDeleteRequest)
+
+	if err := r.Validate(requestI); err != nil {
+		return nil, err
+	}
+
+	err := rtr.engines.DeleteRegion(request.RegionID)
+	result := regionDeleteResult{regionDeleteRequest: request, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r regionDeleteEndpoint) Validate(request interface{}) error {
+	req := request.(regionDeleteRequest)
+
+	if req.RegionID == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("region delete failed, please provide 'regionId'")}
+	}
+	return nil
+}
+
+func (r regionDeleteEndpoint) GetPath() string {
+	return "/region/delete"
+}
+
+func (r regionDeleteEndpoint) HTTPRequest() interface{} {
+	return regionDeleteRequest{}
+}
@@ -2,13 +2,19 @@ package controller
 
 import (
 	"context"
+	"expvar"
+	"io"
 	"net/http"
 	"net/url"
 
 	"github.com/afex/hystrix-go/hystrix"
 	"github.com/go-chi/chi"
+	"github.com/phassans/banana/auth"
+	"github.com/phassans/banana/clients/mail"
+	"github.com/phassans/banana/clients/media"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/model"
+	"github.com/phassans/banana/model/listing"
 	"github.com/rs/cors"
 )
 
@@ -17,7 +23,11 @@ var (
 )
 
 type router struct {
-	engines model.Engine
+	engines       model.Engine
+	images        *media.Pipeline
+	mailer        mail.Sender
+	tokens        auth.TokenIssuer
+	dateScheduler *listing.DateScheduler
 	chi.Router
 }
 
@@ -31,6 +41,13 @@ type (
 		Do(context.Context, *router, url.Values) (interface{}, error)
 	}
 
+	// csvEndpoint is a GET endpoint whose result can be exported as CSV
+	// with format=csv
+	csvEndpoint interface {
+		getEndPoint
+		WriteCSV(io.Writer, interface{}) error
+	}
+
 	postEndpoint interface {
 		endpoint
 		HTTPRequest() interface{}
@@ -40,32 +57,22 @@ type (
 )
 
 var (
-	// getEndpoints lists all the GET endpoints.
+	// getEndpoints lists the GET endpoints open to everyone, including
+	// anonymous app devices.
 	getEndpoints = []getEndPoint{
-		businessAll,
 		listingInfo,
 		businessInfo,
-		userGet,
-		getStats,
-		listingUpdateDate,
-		listingAdminInfo,
+		searchSuggest,
+		regionAll,
 	}
 
 	// createEndpoints lists POST endpoints that create records.
 	addEndpoints = []postEndpoint{
 		userAdd,
 		userVerify,
-		userEdit,
 
-		businessAdd,
-		businessEdit,
-		businessDelete,
-
-		listingAdd,
 		listingsSearch,
-		listingDelete,
 		listingAll,
-		listingEdit,
 
 		notificationAdd,
 		notificationDelete,
@@ -92,15 +99,68 @@ var (
 
 		fetchUser,
 		fetchUserChannels,
+	}
+
+	// authGetEndpoints lists GET endpoints serving the data of business
+	// owners, to themselves and admins only.
+	authGetEndpoints = []getEndPoint{
+		userGet,
+		businessAll,
+	}
+
+	// authEndpoints lists POST endpoints for business owners, see
+	// authorizeBusiness for what each of them may change.
+	authEndpoints = []postEndpoint{
+		userEdit,
+
+		businessAdd,
+		businessEdit,
+		businessDelete,
+
+		listingAdd,
+		listingDelete,
+		listingEdit,
+		listingImages,
+	}
+
+	// adminGetEndpoints and adminPostEndpoints are mounted under /admin and
+	// are only served to admins.
+	adminGetEndpoints = []getEndPoint{
+		getStats,
+		listingUpdateDate,
+		listingDatesStatus,
+		listingAdminInfo,
+		searchAnalytics,
+		stockImageAll,
+		happyHourAll,
+		happyHourGet,
+	}
 
+	adminPostEndpoints = []postEndpoint{
 		webhook,
+
+		regionAdd,
+		regionDelete,
+
+		stockImageAdd,
+		stockImageDelete,
+		listingTag,
+
+		happyHourApprove,
+		happyHourReject,
+		happyHourDuplicate,
 	}
 )
 
 // NewRESTRouter construct a Router interface for Restful API.
-func NewRESTRouter(engines model.Engine) http.Handler {
+func NewRESTRouter(engines model.Engine, images *media.Pipeline, mailer mail.Sender, tokens auth.TokenIssuer,
+	dateScheduler *listing.DateScheduler) http.Handler {
 	rtr := &router{
 		engines,
+		images,
+		mailer,
+		tokens,
+		dateScheduler,
 		chi.NewRouter(),
 	}
 
@@ -119,9 +179,12 @@ func NewRESTRouter(engines model.Engine) http.Handler {
 	rtr.Use(
 		helper.SetJSONContentResponse,
 		helper.SetFieldsInLogger,
+		helper.SetLanguage,
 	)
 
 	rtr.Route(apiVersion, func(r chi.Router) {
+		r.Use(Identify(tokens))
+
 		r.Group(func(r chi.Router) {
 			r.Get("/openapp/{devicetype}/{id}", rtr.LinkHandler())
 		})
@@ -133,36 +196,51 @@ func NewRESTRouter(engines model.Engine) http.Handler {
 		}
 
 		for _, endpoint := range addEndpoints {
-			if endpoint.GetPath() == "/listing/add" {
-				r.Group(func(r chi.Router) {
-					r.Post(endpoint.GetPath(), rtr.newListingImageHandler(endpoint))
-				})
-				continue
-			}
+			r.Group(func(r chi.Router) {
+				r.Post(endpoint.GetPath(), rtr.postHandler(endpoint))
+			})
+		}
 
-			if endpoint.GetPath() == "/listing/edit" {
-				r.Group(func(r chi.Router) {
-					r.Post(endpoint.GetPath(), rtr.newListingEditImageHandler(endpoint))
-				})
-				continue
+		r.Group(func(r chi.Router) {
+			r.Use(RequireRole(auth.RoleBusiness, auth.RoleAdmin))
+			for _, endpoint := range authGetEndpoints {
+				r.Get(endpoint.GetPath(), rtr.newGetHandler(endpoint))
 			}
-
-			if endpoint.GetPath() == "/hhsubmit" {
-				r.Group(func(r chi.Router) {
-					r.Post(endpoint.GetPath(), rtr.newImageHandler(endpoint))
-				})
-				continue
+			for _, endpoint := range authEndpoints {
+				r.Post(endpoint.GetPath(), rtr.postHandler(endpoint))
 			}
+		})
 
-			r.Group(func(r chi.Router) {
-				r.Post(endpoint.GetPath(), rtr.newPostHandler(endpoint))
-			})
-		}
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(RequireRole(auth.RoleAdmin))
+			r.Get("/metrics", expvar.Handler().ServeHTTP)
+			for _, endpoint := range adminGetEndpoints {
+				r.Get(endpoint.GetPath(), rtr.newGetHandler(endpoint))
+			}
+			for _, endpoint := range adminPostEndpoints {
+				r.Post(endpoint.GetPath(), rtr.postHandler(endpoint))
+			}
+		})
 	})
 
 	return rtr
 }
 
+// postHandler picks the handler for a POST endpoint, endpoints taking
+// images are posted as multipart forms.
+func (rtr *router) postHandler(endpoint postEndpoint) http.HandlerFunc {
+	switch endpoint.GetPath() {
+	case "/listing/add":
+		return rtr.newListingImageHandler(endpoint)
+	case "/listing/edit":
+		return rtr.newListingEditImageHandler(endpoint)
+	case "/hhsubmit":
+		return rtr.newImageHandler(endpoint)
+	default:
+		return rtr.newPostHandler(endpoint)
+	}
+}
+
 func (rtr *router) cleanup(e *error, w http.ResponseWriter) {
 	err := *e
 	if err != nil {
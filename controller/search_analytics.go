@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/analytics"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	searchAnalyticsResult struct {
+		Result []shared.SearchStats
+		Error  *APIError `json:"error,omitempty"`
+	}
+
+	searchAnalyticsEndpoint struct{}
+)
+
+var searchAnalytics getEndPoint = searchAnalyticsEndpoint{}
+
+func (r searchAnalyticsEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (interface{}, error) {
+	if err := r.Validate(values); err != nil {
+		return nil, err
+	}
+
+	// the last week by default
+	to := time.Now()
+	if values.Get("to") != "" {
+		to, _ = time.Parse(shared.DateFormatSQL, values.Get("to"))
+	}
+	from := to.AddDate(0, 0, -6)
+	if values.Get("from") != "" {
+		from, _ = time.Parse(shared.DateFormatSQL, values.Get("from"))
+	}
+	if from.After(to) {
+		return nil, helper.ValidationError{Message: fmt.Sprint("search analytics failed, 'from' is after 'to'")}
+	}
+	if to.Sub(from) >= analytics.MaxSearchStatsDays*24*time.Hour {
+		return nil, helper.ValidationError{Message: fmt.Sprintf("search analytics failed, at most %d days at once", analytics.MaxSearchStatsDays)}
+	}
+
+	var top int
+	if values.Get("top") != "" {
+		var err error
+		top, err = strconv.Atoi(values.Get("top"))
+		if err != nil || top < 0 || top > analytics.MaxTop {
+			return nil, helper.ValidationError{Message: fmt.Sprintf("search analytics failed, 'top' must be between 0 and %d", analytics.MaxTop)}
+		}
+	}
+
+	logger := shared.GetLogger()
+	logger = logger.With().
+		Str("endpoint", r.GetPath()).
+		Str("from", from.Format(shared.DateFormatSQL)).
+		Str("to", to.Format(shared.DateFormatSQL)).
+		Int("top", top).Logger()
+	logger.Info().Msgf("search analytics request")
+
+	result, err := rtr.engines.SearchStats(from, to, top)
+	return searchAnalyticsResult{Result: result, Error: NewAPIError(err)}, err
+}
+
+func (r searchAnalyticsEndpoint) GetPath() string {
+	return "/analytics/search"
+}
+
+func (r searchAnalyticsEndpoint) Validate(values url.Values) error {
+	for _, param := range []string{"from", "to"} {
+		if values.Get(param) == "" {
+			continue
+		}
+		if _, err := time.Parse(shared.DateFormatSQL, values.Get(param)); err != nil {
+			return helper.ValidationError{Message: fmt.Sprintf("search analytics failed, invalid '%s', expected %s", param, shared.DateFormatSQL)}
+		}
+	}
+
+	switch values.Get("format") {
+	case "", formatJSON, formatCSV:
+	default:
+		return helper.ValidationError{Message: fmt.Sprint("search analytics failed, 'format' must be json or csv")}
+	}
+
+	return nil
+}
+
+func (r searchAnalyticsEndpoint) WriteCSV(w io.Writer, result interface{}) error {
+	return analytics.WriteSearchStatsCSV(w, result.(searchAnalyticsResult).Result)
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/listing"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	searchSuggestResult struct {
+		Result []shared.Suggestion
+		Error  *APIError `json:"error,omitempty"`
+	}
+
+	searchSuggestEndpoint struct{}
+)
+
+var searchSuggest getEndPoint = searchSuggestEndpoint{}
+
+func (r searchSuggestEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (interface{}, error) {
+	if err := r.Validate(values); err != nil {
+		return nil, err
+	}
+
+	var err error
+	var latitude float64
+	if values.Get("latitude") != "" {
+		latitude, err = strconv.ParseFloat(values.Get("latitude"), 64)
+		if err != nil {
+			return nil, helper.ValidationError{Message: fmt.Sprint("search suggest failed, invalid 'latitude'")}
+		}
+	}
+
+	var longitude float64
+	if values.Get("longitude") != "" {
+		longitude, err = strconv.ParseFloat(values.Get("longitude"), 64)
+		if err != nil {
+			return nil, helper.ValidationError{Message: fmt.Sprint("search suggest failed, invalid 'longitude'")}
+		}
+	}
+
+	var limit int
+	if values.Get("limit") != "" {
+		limit, err = strconv.Atoi(values.Get("limit"))
+		if err != nil || limit < 0 || limit > listing.MaxSuggestions {
+			return nil, helper.ValidationError{Message: fmt.Sprintf("search suggest failed, 'limit' must be between 0 and %d", listing.MaxSuggestions)}
+		}
+	}
+
+	logger := shared.GetLogger()
+	logger = logger.With().
+		Str("endpoint", r.GetPath()).
+		Str("q", values.Get("q")).
+		Float64("latitude", latitude).
+		Float64("longitude", longitude).
+		Str("location", values.Get("location")).Logger()
+	logger.Info().Msgf("search suggest request")
+
+	result, err := rtr.engines.SuggestSearches(values.Get("q"), latitude, longitude, values.Get("location"), limit)
+	return searchSuggestResult{Result: result, Error: NewAPIError(err)}, err
+}
+
+func (r searchSuggestEndpoint) GetPath() string {
+	return "/search/suggest"
+}
+
+func (r searchSuggestEndpoint) Validate(values url.Values) error {
+	if strings.TrimSpace(values.Get("q")) == "" {
+		return helper.ValidationError{Message: fmt.Sprint("search suggest failed, missing 'q'")}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/phassans/banana/model/stockimage"
+	"github.com/phassans/banana/shared"
+)
+
+type (
+	stockImageAddRequest struct {
+		shared.StockImage
+	}
+
+	stockImageAddResult struct {
+		stockImageAddRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	stockImageAddEndpoint struct{}
+)
+
+var stockImageAdd postEndpoint = stockImageAddEndpoint{}
+
+func (r stockImageAddEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(stockImageAddRequest)
+
+	if err := r.Validate(request); err != nil {
+		return nil, err
+	}
+
+	stockImageID, err := rtr.engines.AddStockImage(request.StockImage)
+	request.StockImageID = stockImageID
+	result := stockImageAddResult{stockImageAddRequest: request, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r stockImageAddEndpoint) Validate(request interface{}) error {
+	req := request.(stockImageAddRequest)
+	return stockimage.Validate(req.StockImage)
+}
+
+func (r stockImageAddEndpoint) GetPath() string {
+	return "/stockimage/add"
+}
+
+func (r stockImageAddEndpoint) HTTPRequest() interface{} {
+	return stockImageAddRequest{}
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"context"
+	"net/url"
+)
+
+type (
+	allStockImageEndpoint struct{}
+)
+
+var stockImageAll getEndPoint = allStockImageEndpoint{}
+
+func (r allStockImageEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (interface{}, error) {
+	return rtr.engines.GetStockImages()
+}
+
+func (r allStockImageEndpoint) GetPath() string {
+	return "/stockimages"
+}
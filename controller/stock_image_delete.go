@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phassans/banana/helper"
+)
+
+type (
+	stockImageDeleteRequest struct {
+		StockImageID int `json:"stockImageId"`
+	}
+
+	stockImageDeleteResult struct {
+		stockImageDeleteRequest
+		Error *APIError `json:"error,omitempty"`
+	}
+
+	stockImageDeleteEndpoint struct{}
+)
+
+var stockImageDelete postEndpoint = stockImageDeleteEndpoint{}
+
+func (r stockImageDeleteEndpoint) Execute(ctx context.Context, rtr *router, requestI interface{}) (interface{}, error) {
+	request := requestI.(stockImageDeleteRequest)
+
+	if err := r.Validate(requestI); err != nil {
+		return nil, err
+	}
+
+	err := rtr.engines.DeleteStockImage(request.StockImageID)
+	result := stockImageDeleteResult{stockImageDeleteRequest: request, Error: NewAPIError(err)}
+	return result, err
+}
+
+func (r stockImageDeleteEndpoint) Validate(request interface{}) error {
+	req := request.(stockImageDeleteRequest)
+
+	if req.StockImageID == 0 {
+		return helper.ValidationError{Message: fmt.Sprint("stock image delete failed, please provide 'stockImageId'")}
+	}
+	return nil
+}
+
+func (r stockImageDeleteEndpoint) GetPath() string {
+	return "/stockimage/delete"
+}
+
+func (r stockImageDeleteEndpoint) HTTPRequest() interface{} {
+	return stockImageDeleteRequest{}
+}
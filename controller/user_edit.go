@@ -35,6 +35,10 @@ func (r editUserEndpoint) Execute(ctx context.Context, rtr *router, requestI int
 		return nil, err
 	}
 
+	if err := authorizeUser(ctx, request.UserID); err != nil {
+		return nil, err
+	}
+
 	err := rtr.engines.UserEdit(request.UserID, request.Name, request.Email, request.Password, request.Phone)
 	result := editUserResponse{editUserRequest: request, Error: NewAPIError(err)}
 	return result, err
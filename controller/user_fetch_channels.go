@@ -36,9 +36,6 @@ func (r fetchUserChannelsEndpoint) Execute(ctx context.Context, rtr *router, req
 
 	chans, _ := rtr.engines.FetchUserChannels(request.UserID)
 	return fetchUserChannelsResponse{fetchUserChannelsRequest: request, Error: NewAPIError(nil), Channels: chans}, nil
-
-	result := fetchUserChannelsResponse{fetchUserChannelsRequest: request, Error: NewAPIError(nil)}
-	return result, nil
 }
 
 func (r fetchUserChannelsEndpoint) Validate(request interface{}) error {
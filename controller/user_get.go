@@ -26,6 +26,10 @@ func (r userEndpoint) Do(ctx context.Context, rtr *router, values url.Values) (i
 		return nil, err
 	}
 
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	userInfo, err := rtr.engines.UserGet(userID)
 	if err != nil {
 		return nil, err
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/goware/emailx"
+	"github.com/phassans/banana/auth"
 	"github.com/phassans/banana/helper"
 )
 
@@ -14,13 +15,17 @@ type (
 		Email    string `json:"email"`
 		Password string `json:"password,omitempty"`
 		FullName string `json:"fullName"`
+		Role     string `json:"role,omitempty"`
 
 		UserID int `json:"userId,omitempty"`
 	}
 
 	verifyUserResponse struct {
 		verifyUserRequest
-		Error *APIError `json:"error,omitempty"`
+		// AccessToken authenticates the user on the endpoints in authEndpoints
+		AccessToken string    `json:"accessToken,omitempty"`
+		ExpiresAt   int64     `json:"expiresAt,omitempty"`
+		Error       *APIError `json:"error,omitempty"`
 	}
 
 	verifyUserEndpoint struct{}
@@ -41,8 +46,17 @@ func (r verifyUserEndpoint) Execute(ctx context.Context, rtr *router, requestI i
 	request.Password = ""
 	request.FullName = userInfo.Name
 	request.UserID = userInfo.UserID
+	request.Role = userInfo.Role
+
+	result := verifyUserResponse{verifyUserRequest: request}
+	if err == nil {
+		var claims auth.Claims
+		principal := auth.Principal{UserID: userInfo.UserID, Role: auth.Role(userInfo.Role)}
+		result.AccessToken, claims, err = rtr.tokens.Issue(principal)
+		result.ExpiresAt = claims.ExpiresAt
+	}
 
-	result := verifyUserResponse{verifyUserRequest: request, Error: NewAPIError(err)}
+	result.Error = NewAPIError(err)
 	return result, err
 }
 
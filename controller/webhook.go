@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/phassans/banana/model"
 	"github.com/phassans/banana/shared"
 	"github.com/rs/zerolog"
 )
@@ -30,16 +31,17 @@ type (
 		// Listing
 
 		Listings []struct {
-			Title               string   `json:"title"`
-			Description         string   `json:"description"`
-			DiscountDescription string   `json:"discountDescription,omitempty"`
-			StartDate           string   `json:"startDate"`
-			RecurringEndDate    string   `json:"recurringEndDate,omitempty"`
-			RecurringDays       []string `json:"recurringDays"`
-			StartTime           string   `json:"startTime"`
-			EndTime             string   `json:"endTime"`
-			ListingID           int      `json:"listingId,omitempty"`
-			ImageLink           []string `json:"imageLink,omitempty"`
+			Title               string              `json:"title"`
+			Description         string              `json:"description"`
+			DiscountDescription string              `json:"discountDescription,omitempty"`
+			StartDate           string              `json:"startDate"`
+			RecurringEndDate    string              `json:"recurringEndDate,omitempty"`
+			RecurringDays       []string            `json:"recurringDays"`
+			TimeWindows         []shared.TimeWindow `json:"timeWindows,omitempty"`
+			StartTime           string              `json:"startTime"`
+			EndTime             string              `json:"endTime"`
+			ListingID           int                 `json:"listingId,omitempty"`
+			ImageLink           []string            `json:"imageLink,omitempty"`
 		} `json:"listings"`
 	}
 
@@ -63,10 +65,36 @@ func (r webhookEndpoint) Execute(ctx context.Context, rtr *router, requestI inte
 		return nil, err
 	}
 
-	hoursInfo := r.getBusinessHours(request)
+	// the business and all its listings are imported together or not at all
+	var businessErr error
+	err := rtr.engines.Transact(func(engines model.Engine) error {
+		businessID, err := r.importBusiness(engines, &request, log)
+		if businessID == 0 {
+			businessErr = err
+		}
+		return err
+	})
+	if businessErr != nil {
+		result := webhookResult{webhookRequest: request, Error: NewAPIError(businessErr)}
+		return result, nil
+	}
+	if err != nil {
+		result := webhookResult{webhookRequest: request, Error: NewAPIError(err)}
+		return result, err
+	}
+
+	result := webhookResult{webhookRequest: request, Error: NewAPIError(nil)}
+	return result, nil
+}
+
+// importBusiness adds the business of request and its listings with engines,
+// recording the ids of the listings in request, and returns the id of the
+// business, 0 when the business could not be added.
+func (r webhookEndpoint) importBusiness(engines model.Engine, request *webhookRequest, log zerolog.Logger) (int, error) {
+	hoursInfo := r.getBusinessHours(*request)
 	log.Info().Msgf("hoursInfo %v", hoursInfo)
 
-	businessID, _, err := rtr.engines.AddBusiness(
+	businessID, _, err := engines.AddBusiness(
 		request.Name,
 		request.Phone,
 		request.Website,
@@ -79,9 +107,7 @@ func (r webhookEndpoint) Execute(ctx context.Context, rtr *router, requestI inte
 		1,
 	)
 	if err != nil {
-
-		result := webhookResult{webhookRequest: request, Error: NewAPIError(err)}
-		return result, nil
+		return 0, err
 	}
 
 	for i, listing := range request.Listings {
@@ -104,21 +130,19 @@ func (r webhookEndpoint) Execute(ctx context.Context, rtr *router, requestI inte
 			Recurring:           true,
 			RecurringDays:       listing.RecurringDays,
 			RecurringEndDate:    listing.RecurringEndDate,
+			TimeWindows:         listing.TimeWindows,
 			Type:                "happyhour",
-			ImageLink:           listing.ImageLink[0],
+			Gallery:             webhookGallery(listing.ImageLink),
 		}
 		log.Info().Msgf("listing %d %v", i, l)
 
-		listingID, err := rtr.engines.AddListing(&l)
-		listing.ListingID = listingID
+		listingID, err := engines.AddListing(&l)
 		if err != nil {
-			result := webhookResult{webhookRequest: request, Error: NewAPIError(err)}
-			return result, err
+			return businessID, err
 		}
+		request.Listings[i].ListingID = listingID
 	}
-
-	result := webhookResult{webhookRequest: request, Error: NewAPIError(nil)}
-	return result, nil
+	return businessID, nil
 }
 
 func (r webhookEndpoint) Validate(request interface{}) error {
@@ -203,3 +227,16 @@ func (r webhookEndpoint) getHours(businessDay string) shared.Hours {
 
 	return res
 }
+
+// webhookGallery returns the gallery of the image links of a listing, in the
+// order they are given, the first one being the primary image
+func webhookGallery(links []string) []shared.GalleryImage {
+	gallery := make([]shared.GalleryImage, 0, len(links))
+	for i, link := range links {
+		if link == "" {
+			continue
+		}
+		gallery = append(gallery, shared.GalleryImage{URL: link, Primary: i == 0})
+	}
+	return gallery
+}
@@ -30,15 +30,15 @@ type Roach struct {
 // Config holds the configuration used for instantiating a new Roach.
 type Config struct {
 	// Address that locates our postgres instance
-	Host string
+	Host string `json:"host"`
 	// Port to connect to
-	Port string
+	Port string `json:"port"`
 	// User that has access to the database
-	User string
+	User string `json:"user"`
 	// Password so that the user can login
-	Password string
+	Password string `json:"password"`
 	// Database to connect to (must have been created priorly)
-	Database string
+	Database string `json:"database"`
 }
 
 // New returns a Roach with the sql.DB set with the postgres
@@ -91,7 +91,7 @@ func (r *Roach) Close() (err error) {
 
 	if err = r.Db.Close(); err != nil {
 		err = errors.Wrapf(err,
-			"Errored closing database connection",
+			"Errored closing database connection (%s)",
 			spew.Sdump(r.cfg))
 	}
 
@@ -0,0 +1,286 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// migrationLockID is the key of the postgres advisory lock held while
+// migrations run, so that two instances starting together do not race.
+const migrationLockID = 4242
+
+const createMigrationsTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations
+(
+  version    INT       NOT NULL,
+  name       TEXT      NOT NULL,
+  applied_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (version)
+);`
+
+type (
+	// Migration is a single versioned change to the schema. Up moves the
+	// schema forward to Version, Down reverts it to the previous version.
+	Migration struct {
+		Version int
+		Name    string
+		Up      string
+		Down    string
+	}
+
+	// MigrationStatus reports whether a migration has been applied.
+	MigrationStatus struct {
+		Version   int       `json:"version"`
+		Name      string    `json:"name"`
+		Applied   bool      `json:"applied"`
+		AppliedAt time.Time `json:"appliedAt,omitempty"`
+	}
+
+	// SchemaBehindError is returned when the database has not been migrated
+	// to the latest version known to the binary.
+	SchemaBehindError struct {
+		Current int
+		Latest  int
+	}
+
+	// Migrator applies ordered migrations and records them in the
+	// schema_migrations table.
+	Migrator struct {
+		db         *sql.DB
+		logger     zerolog.Logger
+		migrations []Migration
+	}
+)
+
+func (e SchemaBehindError) Error() string {
+	return fmt.Sprintf("database schema is at version %d, latest is %d. run `banana migrate up`", e.Current, e.Latest)
+}
+
+// NewMigrator returns a Migrator for the given migrations, sorted by version.
+func NewMigrator(db *sql.DB, logger zerolog.Logger, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return &Migrator{db: db, logger: logger, migrations: sorted}
+}
+
+// LatestVersion returns the highest version known to the migrator.
+func (m *Migrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// CurrentVersion returns the highest version applied to the database.
+func (m *Migrator) CurrentVersion() (int, error) {
+	if err := m.ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	err := m.db.QueryRow("SELECT MAX(version) FROM schema_migrations;").Scan(&version)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not read schema version")
+	}
+	return int(version.Int64), nil
+}
+
+// Check returns a SchemaBehindError if there are migrations left to apply.
+func (m *Migrator) Check() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	current, err := m.CurrentVersion()
+	if err != nil {
+		return err
+	}
+	return SchemaBehindError{Current: current, Latest: m.LatestVersion()}
+}
+
+// Pending returns the migrations that have not been applied yet, in order.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Status returns every known migration along with when it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Up applies all pending migrations in version order. Each migration runs
+// in its own transaction together with its schema_migrations row.
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(func() error {
+		pending, err := m.Pending()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			if err := m.apply(migration); err != nil {
+				return err
+			}
+			m.logger.Info().Msgf("applied migration %d_%s", migration.Version, migration.Name)
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last `steps` applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(func() error {
+		applied, err := m.appliedVersions()
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(migration); err != nil {
+				return err
+			}
+			m.logger.Info().Msgf("reverted migration %d_%s", migration.Version, migration.Name)
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (m *Migrator) apply(migration Migration) error {
+	return m.inTransaction(migration, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO schema_migrations(version,name,applied_at) VALUES($1,$2,$3);",
+			migration.Version, migration.Name, time.Now())
+		return err
+	})
+}
+
+func (m *Migrator) revert(migration Migration) error {
+	return m.inTransaction(migration, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1;", migration.Version)
+		return err
+	})
+}
+
+func (m *Migrator) inTransaction(migration Migration, f func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin migration transaction")
+	}
+
+	if err := f(tx); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return errors.Wrapf(err, "migration %d_%s failed", migration.Version, migration.Name)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "could not commit migration %d_%s", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+func (m *Migrator) withLock(f func() error) error {
+	if err := m.ensureMigrationsTable(); err != nil {
+		return err
+	}
+
+	// advisory locks are held per session, so pin a single connection
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "could not acquire connection for migrations")
+	}
+	defer conn.Close() // nolint: errcheck
+
+	if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_lock($1);", migrationLockID); err != nil {
+		return errors.Wrap(err, "could not acquire migration lock")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1);", migrationLockID) // nolint: errcheck
+
+	return f()
+}
+
+func (m *Migrator) ensureMigrationsTable() error {
+	if _, err := m.db.Exec(createMigrationsTableSQL); err != nil {
+		return errors.Wrap(err, "could not create schema_migrations table")
+	}
+	return nil
+}
+
+func (m *Migrator) appliedVersions() (map[int]time.Time, error) {
+	if err := m.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read applied migrations")
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, errors.Wrap(err, "could not read applied migrations")
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not read applied migrations")
+	}
+	return applied, nil
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, migration := range Migrations {
+		require.Equal(t, i+1, migration.Version, "migration versions must be sequential")
+		require.NotEmpty(t, migration.Name)
+		require.NotEmpty(t, migration.Up)
+		require.NotEmpty(t, migration.Down)
+	}
+}
+
+func TestNewMigratorSortsByVersion(t *testing.T) {
+	migrator := NewMigrator(nil, shared.GetLogger(), []Migration{{Version: 3}, {Version: 1}, {Version: 2}})
+	require.Equal(t, 3, migrator.LatestVersion())
+	require.Equal(t, 1, migrator.migrations[0].Version)
+}
@@ -0,0 +1,855 @@
+package db
+
+// Migrations is the ordered list of schema changes for the banana database.
+// New changes are appended with the next version number; applied migrations
+// must never be edited.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up: `
+DO $$
+BEGIN
+  EXECUTE format('ALTER DATABASE %I SET timezone TO %L', current_database(), 'US/Pacific');
+END
+$$;
+
+DO $$
+BEGIN
+  CREATE TYPE days_of_month AS ENUM (
+    'monday',
+    'tuesday',
+    'wednesday',
+    'thursday',
+    'friday',
+    'saturday',
+    'sunday'
+  );
+EXCEPTION
+  WHEN duplicate_object THEN NULL;
+END
+$$;
+
+DO $$
+BEGIN
+  CREATE TYPE dietary_restrictions AS ENUM (
+    'gluten free',
+    'vegan',
+    'vegetarian',
+    'spicy'
+  );
+EXCEPTION
+  WHEN duplicate_object THEN NULL;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS business
+(
+  business_id SERIAL,
+  name        TEXT NOT NULL,
+  phone       TEXT NOT NULL,
+  website     TEXT,
+  PRIMARY KEY (business_id)
+);
+
+CREATE TABLE IF NOT EXISTS business_hours
+(
+  business_id INT           NOT NULL,
+  day         DAYS_OF_MONTH NOT NULL,
+  open_time   TIME          NOT NULL,
+  close_time  TIME          NOT NULL,
+  FOREIGN KEY (business_id) REFERENCES business (business_id)
+);
+
+CREATE TABLE IF NOT EXISTS business_cuisine
+(
+  business_id INT  NOT NULL,
+  cuisine     TEXT NOT NULL,
+  FOREIGN KEY (business_id) REFERENCES business (business_id)
+);
+
+CREATE TABLE IF NOT EXISTS business_user
+(
+  user_id  SERIAL,
+  name     TEXT NOT NULL,
+  email    TEXT NOT NULL,
+  password TEXT NOT NULL,
+  phone    TEXT,
+  PRIMARY KEY (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_to_business
+(
+  user_id     INT NOT NULL,
+  business_id INT NOT NULL,
+  PRIMARY KEY (user_id, business_id),
+  FOREIGN KEY (user_id) REFERENCES business_user (user_id),
+  FOREIGN KEY (business_id) REFERENCES business (business_id)
+);
+
+CREATE TABLE IF NOT EXISTS business_country
+(
+  country_id SERIAL,
+  NAME       TEXT NOT NULL,
+  PRIMARY KEY (country_id)
+);
+
+CREATE TABLE IF NOT EXISTS business_address
+(
+  address_id  SERIAL UNIQUE,
+  business_id INT     NOT NULL,
+  street      TEXT    NOT NULL,
+  city        TEXT    NOT NULL,
+  postal_code INT     NOT NULL,
+  state       TEXT    NOT NULL,
+  country_id  INT     NOT NULL,
+  latitude    NUMERIC NOT NULL,
+  longitude   NUMERIC NOT NULL,
+  PRIMARY KEY (address_id, business_id),
+  FOREIGN KEY (country_id) REFERENCES business_country (country_id)
+);
+
+CREATE TABLE IF NOT EXISTS listing
+(
+  listing_id           SERIAL UNIQUE,
+  business_id          INT       NOT NULL,
+  title                TEXT      NOT NULL,
+  old_price            DECIMAL,
+  new_price            DECIMAL,
+  discount             DECIMAL,
+  discount_description TEXT,
+  description          TEXT,
+  start_date           DATE      NOT NULL,
+  start_time           TIME      NOT NULL,
+  end_time             TIME      NOT NULL,
+  multiple_days        BOOLEAN   NOT NULL,
+  end_date             DATE,
+  recurring            BOOLEAN   NOT NULL,
+  recurring_end_date   DATE,
+  listing_type         TEXT      NOT NULL,
+  listing_create_date  TIMESTAMP NOT NULL,
+  PRIMARY KEY (listing_id),
+  FOREIGN KEY (business_id) REFERENCES business (business_id)
+);
+
+CREATE TABLE IF NOT EXISTS listing_date
+(
+  listing_date_id SERIAL UNIQUE,
+  listing_id      INT  NOT NULL,
+  listing_date    DATE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS listing_recurring
+(
+  listing_id INT           NOT NULL,
+  day        days_of_month NOT NULL,
+  PRIMARY KEY (listing_id, day),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+
+CREATE TABLE IF NOT EXISTS listing_image
+(
+  image_id   SERIAL,
+  listing_id INT  NOT NULL,
+  path       TEXT NOT NULL,
+  PRIMARY KEY (image_id, listing_id),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+
+CREATE TABLE IF NOT EXISTS listing_dietary_restrictions
+(
+  listing_id  INT                  NOT NULL,
+  restriction DIETARY_RESTRICTIONS NOT NULL,
+  PRIMARY KEY (listing_id, restriction),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+
+CREATE TABLE IF NOT EXISTS favorites
+(
+  favorite_id SERIAL,
+  phone_id    TEXT NOT NULL,
+  listing_id  INT  NOT NULL,
+  listing_date_id INT,
+  favorite_add_date TIMESTAMP NOT NULL,
+  PRIMARY KEY (favorite_id, phone_id),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications
+(
+  notification_id SERIAL UNIQUE,
+  notification_name TEXT,
+  phone_id        TEXT NOT NULL,
+  latitude        NUMERIC,
+  longitude       NUMERIC,
+  location        TEXT,
+  price_filter    TEXT,
+  distance_filter TEXT,
+  keywords        TEXT,
+  PRIMARY KEY (notification_id, phone_id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications_dietary_restrictions
+(
+  notification_id INT                  NOT NULL,
+  restriction     DIETARY_RESTRICTIONS NOT NULL,
+  PRIMARY KEY (notification_id, restriction),
+  FOREIGN KEY (notification_id) REFERENCES notifications (notification_id)
+);
+
+CREATE TABLE IF NOT EXISTS register_phone
+(
+  registration_id SERIAL UNIQUE,
+  registration_token TEXT UNIQUE NOT NULL,
+  phone_id           TEXT UNIQUE NOT NULL,
+  phone_model        TEXT,
+  register_date      TIMESTAMP NULL,
+  update_date        TIMESTAMP NULL,
+  PRIMARY KEY (registration_id)
+);
+
+CREATE TABLE IF NOT EXISTS preferences
+(
+  prefernce_id   SERIAL UNIQUE,
+  phone_id       TEXT UNIQUE NOT NULL,
+  cuisine        TEXT,
+  PRIMARY KEY (phone_id,cuisine)
+);
+
+CREATE TABLE IF NOT EXISTS contact_us
+(
+  contact_us_id   SERIAL UNIQUE,
+  phone_id        TEXT  NOT NULL,
+  uname           TEXT,
+  email           TEXT,
+  subject         TEXT,
+  comments        TEXT,
+  contact_date    TIMESTAMP NOT NULL,
+  PRIMARY KEY (contact_us_id)
+);
+
+CREATE TABLE IF NOT EXISTS category_to_keyword
+(
+  category        TEXT  NOT NULL,
+  keyword         TEXT,
+  PRIMARY KEY (category,keyword)
+);
+
+CREATE TABLE IF NOT EXISTS address_to_geo
+(
+  address TEXT,
+  latitude        NUMERIC,
+  longitude       NUMERIC,
+  PRIMARY KEY (address)
+);
+
+CREATE TABLE IF NOT EXISTS search
+(
+  search_id       SERIAL UNIQUE,
+  search_request  TEXT  NOT NULL,
+  search_date     TIMESTAMP NOT NULL,
+  PRIMARY KEY (search_id)
+);
+
+CREATE TABLE IF NOT EXISTS upvotes
+(
+  upvote_id SERIAL,
+  phone_id    TEXT NOT NULL,
+  listing_id  INT  NOT NULL,
+  upvote_date TIMESTAMP NOT NULL,
+  PRIMARY KEY (upvote_id, phone_id),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+
+CREATE TABLE IF NOT EXISTS happyhour
+(
+  hh_id   SERIAL UNIQUE,
+  phone_id        TEXT  NOT NULL,
+  name            TEXT,
+  email           TEXT,
+  business_owner  TEXT,
+  restaurant      TEXT,
+  city            TEXT,
+  description     TEXT,
+  submission_date TIMESTAMP NOT NULL,
+  PRIMARY KEY (hh_id)
+);
+
+CREATE TABLE IF NOT EXISTS happyhour_images
+(
+  hh_image_id     SERIAL UNIQUE,
+  hh_id           INT  NOT NULL,
+  image_name      TEXT,
+  submission_date TIMESTAMP NOT NULL,
+  PRIMARY KEY (hh_image_id),
+  FOREIGN KEY (hh_id) REFERENCES happyhour (hh_id)
+);
+
+CREATE TABLE IF NOT EXISTS report_inaccurate
+(
+  report_inaccurate_id SERIAL,
+  phone_id    TEXT NOT NULL,
+  listing_id  INT  NOT NULL,
+  report_inaccurate_add_date TIMESTAMP NOT NULL,
+  PRIMARY KEY (report_inaccurate_id),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+
+INSERT INTO business_country (name)
+SELECT 'USA' WHERE NOT EXISTS (SELECT 1 FROM business_country WHERE name = 'USA');
+INSERT INTO business_country (name)
+SELECT 'INDIA' WHERE NOT EXISTS (SELECT 1 FROM business_country WHERE name = 'INDIA');
+`,
+		Down: `
+DROP TABLE IF EXISTS user_to_business;
+DROP TABLE IF EXISTS business_user;
+DROP TABLE IF EXISTS business_hours;
+DROP TABLE IF EXISTS business_cuisine;
+DROP TABLE IF EXISTS business_address;
+DROP TABLE IF EXISTS business_country;
+
+DROP TABLE IF EXISTS listing_date;
+DROP TABLE IF EXISTS listing_recurring;
+DROP TABLE IF EXISTS listing_image;
+DROP TABLE IF EXISTS listing_dietary_restrictions;
+
+DROP TABLE IF EXISTS favorites;
+DROP TABLE IF EXISTS report_inaccurate;
+DROP TABLE IF EXISTS upvotes;
+
+DROP TABLE IF EXISTS listing;
+DROP TABLE IF EXISTS business;
+
+DROP TABLE IF EXISTS notifications_dietary_restrictions;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS register_phone;
+
+DROP TYPE IF EXISTS dietary_restrictions;
+DROP TYPE IF EXISTS days_of_month;
+
+DROP TABLE IF EXISTS preferences;
+DROP TABLE IF EXISTS contact_us;
+DROP TABLE IF EXISTS category_to_keyword;
+DROP TABLE IF EXISTS address_to_geo;
+DROP TABLE IF EXISTS search;
+
+DROP TABLE IF EXISTS happyhour_images;
+DROP TABLE IF EXISTS happyhour;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "listing_time_windows",
+		Up: `
+ALTER TABLE listing_date
+  ADD COLUMN IF NOT EXISTS start_time TIME,
+  ADD COLUMN IF NOT EXISTS end_time   TIME;
+
+ALTER TABLE listing_recurring
+  ADD COLUMN IF NOT EXISTS start_time TIME,
+  ADD COLUMN IF NOT EXISTS end_time   TIME;
+`,
+		Down: `
+ALTER TABLE listing_recurring
+  DROP COLUMN IF EXISTS start_time,
+  DROP COLUMN IF EXISTS end_time;
+
+ALTER TABLE listing_date
+  DROP COLUMN IF EXISTS start_time,
+  DROP COLUMN IF EXISTS end_time;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "business_user_role",
+		Up: `
+ALTER TABLE business_user
+  ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'business';
+
+DO $$
+BEGIN
+  ALTER TABLE business_user
+    ADD CONSTRAINT business_user_role_check CHECK (role IN ('admin', 'business'));
+EXCEPTION
+  WHEN duplicate_object THEN NULL;
+END
+$$;
+`,
+		Down: `
+ALTER TABLE business_user
+  DROP CONSTRAINT IF EXISTS business_user_role_check,
+  DROP COLUMN IF EXISTS role;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "notification_sent",
+		Up: `
+CREATE TABLE IF NOT EXISTS notification_sent
+(
+  notification_id INT       NOT NULL,
+  listing_id      INT       NOT NULL,
+  sent_date       TIMESTAMP NOT NULL,
+  PRIMARY KEY (notification_id, listing_id),
+  FOREIGN KEY (notification_id) REFERENCES notifications (notification_id),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS notification_sent;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "listing_recurrence_rule",
+		Up: `
+ALTER TABLE listing
+  ADD COLUMN IF NOT EXISTS recurrence_rule TEXT;
+`,
+		Down: `
+ALTER TABLE listing
+  DROP COLUMN IF EXISTS recurrence_rule;
+`,
+	},
+	{
+		Version: 6,
+		Name:    "listing_recurring_windows",
+		Up: `
+ALTER TABLE listing_recurring
+  DROP CONSTRAINT IF EXISTS listing_recurring_pkey;
+
+ALTER TABLE listing_recurring
+  ADD COLUMN IF NOT EXISTS listing_recurring_id SERIAL PRIMARY KEY;
+
+CREATE UNIQUE INDEX IF NOT EXISTS listing_recurring_window
+  ON listing_recurring (listing_id, day, start_time);
+`,
+		Down: `
+DROP INDEX IF EXISTS listing_recurring_window;
+
+DELETE FROM listing_recurring a
+  USING listing_recurring b
+  WHERE a.listing_id = b.listing_id AND a.day = b.day
+    AND a.listing_recurring_id > b.listing_recurring_id;
+
+ALTER TABLE listing_recurring
+  DROP COLUMN IF EXISTS listing_recurring_id;
+
+ALTER TABLE listing_recurring
+  ADD PRIMARY KEY (listing_id, day);
+`,
+	},
+	{
+		Version: 7,
+		Name:    "business_address_geohash",
+		Up: `
+CREATE OR REPLACE FUNCTION geohash_encode(lat DOUBLE PRECISION, lon DOUBLE PRECISION, len INT)
+  RETURNS TEXT AS $$
+DECLARE
+  base32  TEXT := '0123456789bcdefghjkmnpqrstuvwxyz';
+  min_lat DOUBLE PRECISION := -90;
+  max_lat DOUBLE PRECISION := 90;
+  min_lon DOUBLE PRECISION := -180;
+  max_lon DOUBLE PRECISION := 180;
+  mid     DOUBLE PRECISION;
+  hash    TEXT := '';
+  bits    INT := 0;
+  ch      INT := 0;
+  even    BOOLEAN := true;
+BEGIN
+  WHILE length(hash) < len LOOP
+    IF even THEN
+      mid := (min_lon + max_lon) / 2;
+      IF lon >= mid THEN
+        ch := ch * 2 + 1;
+        min_lon := mid;
+      ELSE
+        ch := ch * 2;
+        max_lon := mid;
+      END IF;
+    ELSE
+      mid := (min_lat + max_lat) / 2;
+      IF lat >= mid THEN
+        ch := ch * 2 + 1;
+        min_lat := mid;
+      ELSE
+        ch := ch * 2;
+        max_lat := mid;
+      END IF;
+    END IF;
+    even := NOT even;
+
+    bits := bits + 1;
+    IF bits = 5 THEN
+      hash := hash || substr(base32, ch + 1, 1);
+      bits := 0;
+      ch := 0;
+    END IF;
+  END LOOP;
+  RETURN hash;
+END
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION business_address_set_geohash()
+  RETURNS TRIGGER AS $$
+BEGIN
+  NEW.geohash := geohash_encode(NEW.latitude, NEW.longitude, 7);
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE business_address
+  ADD COLUMN IF NOT EXISTS geohash TEXT;
+
+UPDATE business_address
+  SET geohash = geohash_encode(latitude, longitude, 7);
+
+DROP TRIGGER IF EXISTS business_address_geohash ON business_address;
+CREATE TRIGGER business_address_geohash
+  BEFORE INSERT OR UPDATE OF latitude, longitude ON business_address
+  FOR EACH ROW EXECUTE PROCEDURE business_address_set_geohash();
+
+CREATE INDEX IF NOT EXISTS business_address_geohash_idx
+  ON business_address (geohash text_pattern_ops);
+`,
+		Down: `
+DROP INDEX IF EXISTS business_address_geohash_idx;
+DROP TRIGGER IF EXISTS business_address_geohash ON business_address;
+
+ALTER TABLE business_address
+  DROP COLUMN IF EXISTS geohash;
+
+DROP FUNCTION IF EXISTS business_address_set_geohash();
+DROP FUNCTION IF EXISTS geohash_encode(DOUBLE PRECISION, DOUBLE PRECISION, INT);
+`,
+	},
+	{
+		Version: 8,
+		Name:    "listing_search",
+		Up: `
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS listing_search
+(
+  listing_id INT      NOT NULL,
+  document   TSVECTOR NOT NULL,
+  words      TEXT     NOT NULL,
+  PRIMARY KEY (listing_id),
+  FOREIGN KEY (listing_id) REFERENCES listing (listing_id)
+);
+
+CREATE INDEX IF NOT EXISTS listing_search_document
+  ON listing_search USING GIN (document);
+
+CREATE INDEX IF NOT EXISTS listing_search_words
+  ON listing_search USING GIN (words gin_trgm_ops);
+
+INSERT INTO listing_search(listing_id, document, words)
+SELECT listing.listing_id,
+  setweight(to_tsvector('english', listing.title), 'A') ||
+  setweight(to_tsvector('english', business.name), 'A') ||
+  setweight(to_tsvector('english', coalesce(cuisines.cuisine, '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(listing.discount_description, '')), 'C') ||
+  setweight(to_tsvector('english', coalesce(listing.description, '')), 'D'),
+  concat_ws(' ', listing.title, business.name, cuisines.cuisine, listing.discount_description, listing.description)
+FROM listing
+  INNER JOIN business ON listing.business_id = business.business_id
+  LEFT JOIN (SELECT business_id, string_agg(cuisine, ' ') as cuisine FROM business_cuisine GROUP BY business_id) cuisines
+    ON listing.business_id = cuisines.business_id
+WHERE TRUE
+ON CONFLICT (listing_id) DO NOTHING;
+`,
+		Down: `
+DROP TABLE IF EXISTS listing_search;
+`,
+	},
+	{
+		Version: 9,
+		Name:    "search_date_index",
+		Up: `
+CREATE INDEX IF NOT EXISTS search_search_date_idx
+  ON search (search_date);
+ALTER TABLE search ADD COLUMN IF NOT EXISTS results INT;
+`,
+		Down: `
+ALTER TABLE search DROP COLUMN IF EXISTS results;
+DROP INDEX IF EXISTS search_search_date_idx;
+`,
+	},
+	{
+		Version: 10,
+		Name:    "search_outcome",
+		Up: `
+ALTER TABLE search
+  ADD COLUMN IF NOT EXISTS search_filters TEXT,
+  ADD COLUMN IF NOT EXISTS latitude       DOUBLE PRECISION,
+  ADD COLUMN IF NOT EXISTS longitude      DOUBLE PRECISION,
+  ADD COLUMN IF NOT EXISTS duration_ms    INT,
+  ADD COLUMN IF NOT EXISTS error_category TEXT;
+`,
+		Down: `
+ALTER TABLE search
+  DROP COLUMN IF EXISTS search_filters,
+  DROP COLUMN IF EXISTS latitude,
+  DROP COLUMN IF EXISTS longitude,
+  DROP COLUMN IF EXISTS duration_ms,
+  DROP COLUMN IF EXISTS error_category;
+`,
+	},
+	{
+		Version: 11,
+		Name:    "geo_to_place",
+		Up: `
+CREATE TABLE IF NOT EXISTS geo_to_place
+(
+  geohash      TEXT NOT NULL,
+  neighborhood TEXT NOT NULL,
+  city         TEXT NOT NULL,
+  state        TEXT NOT NULL,
+  country      TEXT NOT NULL,
+  PRIMARY KEY (geohash)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS geo_to_place;
+`,
+	},
+	{
+		Version: 12,
+		Name:    "region",
+		Up: `
+CREATE TABLE IF NOT EXISTS region
+(
+  region_id           SERIAL UNIQUE,
+  name                TEXT             NOT NULL,
+  latitude            DOUBLE PRECISION NOT NULL,
+  longitude           DOUBLE PRECISION NOT NULL,
+  radius_miles        DOUBLE PRECISION NOT NULL DEFAULT 0,
+  polygon             TEXT,
+  time_zone           TEXT             NOT NULL,
+  currency            TEXT             NOT NULL,
+  distance_unit       TEXT             NOT NULL,
+  search_radius_miles DOUBLE PRECISION NOT NULL,
+  PRIMARY KEY (region_id),
+  UNIQUE (name)
+);
+
+INSERT INTO region(name, latitude, longitude, radius_miles, time_zone, currency, distance_unit, search_radius_miles)
+VALUES ('Bay Area', 37.36883, -122.0363496, 100, 'America/Los_Angeles', 'USD', 'mi', 15)
+ON CONFLICT (name) DO NOTHING;
+`,
+		Down: `
+DROP TABLE IF EXISTS region;
+`,
+	},
+	{
+		Version: 13,
+		Name:    "image",
+		Up: `
+CREATE TABLE IF NOT EXISTS image
+(
+  hash         TEXT      NOT NULL,
+  url          TEXT      NOT NULL,
+  store_key    TEXT      NOT NULL,
+  content_type TEXT      NOT NULL,
+  width        INT       NOT NULL,
+  height       INT       NOT NULL,
+  bytes        INT       NOT NULL,
+  created_at   TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (hash)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS image;
+`,
+	},
+	{
+		Version: 14,
+		Name:    "listing_image_gallery",
+		Up: `
+ALTER TABLE listing_image
+  ADD COLUMN IF NOT EXISTS position   INT     NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS caption    TEXT    NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS alt_text   TEXT    NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS is_primary BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- number the images of every listing in the order they were added, the
+-- first being the primary one
+UPDATE listing_image
+SET position = ordered.position, is_primary = ordered.position = 0
+FROM (SELECT image_id, ROW_NUMBER() OVER (PARTITION BY listing_id ORDER BY image_id) - 1 AS position
+      FROM listing_image) ordered
+WHERE listing_image.image_id = ordered.image_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS listing_image_primary ON listing_image (listing_id) WHERE is_primary;
+`,
+		Down: `
+DROP INDEX IF EXISTS listing_image_primary;
+
+ALTER TABLE listing_image
+  DROP COLUMN IF EXISTS position,
+  DROP COLUMN IF EXISTS caption,
+  DROP COLUMN IF EXISTS alt_text,
+  DROP COLUMN IF EXISTS is_primary;
+`,
+	},
+	{
+		Version: 15,
+		Name:    "stock_image",
+		Up: `
+CREATE TABLE IF NOT EXISTS stock_image
+(
+  stock_image_id SERIAL    UNIQUE,
+  category       TEXT      NOT NULL,
+  url            TEXT      NOT NULL,
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (stock_image_id),
+  UNIQUE (category, url)
+);
+
+ALTER TABLE listing ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+-- the categories the stock library was started with, tagged by the words
+-- their listings are described with
+INSERT INTO category_to_keyword(category, keyword)
+VALUES
+  ('Asian Appetizers', 'asian appetizers'),
+  ('Asian Appetizers', 'egg rolls'),
+  ('Asian Appetizers', 'spring rolls'),
+  ('Asian Appetizers', 'dumplings'),
+  ('Asian Appetizers', 'gyoza'),
+  ('Asian Appetizers', 'edamame'),
+  ('BBQ', 'bbq'),
+  ('BBQ', 'barbecue'),
+  ('BBQ', 'brisket'),
+  ('BBQ', 'ribs'),
+  ('BBQ', 'pulled pork'),
+  ('Bar snacks', 'bar snacks'),
+  ('Bar snacks', 'fries'),
+  ('Bar snacks', 'nachos'),
+  ('Bar snacks', 'sliders'),
+  ('Bar snacks', 'pretzel'),
+  ('Bar snacks', 'onion rings'),
+  ('Beer', 'beer'),
+  ('Beer', 'beers'),
+  ('Beer', 'pint'),
+  ('Beer', 'pints'),
+  ('Beer', 'draft'),
+  ('Beer', 'ipa'),
+  ('Beer', 'lager'),
+  ('Beer', 'pitcher'),
+  ('Burger', 'burger'),
+  ('Burger', 'burgers'),
+  ('Burger', 'cheeseburger'),
+  ('Chinese Food', 'chinese'),
+  ('Chinese Food', 'dim sum'),
+  ('Chinese Food', 'chow mein'),
+  ('Chinese Food', 'fried rice'),
+  ('Cocktail', 'cocktail'),
+  ('Cocktail', 'cocktails'),
+  ('Cocktail', 'margarita'),
+  ('Cocktail', 'margaritas'),
+  ('Cocktail', 'martini'),
+  ('Cocktail', 'mojito'),
+  ('Cocktail', 'well drinks'),
+  ('Coffee', 'coffee'),
+  ('Coffee', 'espresso'),
+  ('Coffee', 'latte'),
+  ('Coffee', 'cappuccino'),
+  ('Curry', 'curry'),
+  ('Curry', 'tikka masala'),
+  ('Curry', 'indian'),
+  ('Ice cream', 'ice cream'),
+  ('Ice cream', 'gelato'),
+  ('Ice cream', 'frozen yogurt'),
+  ('Italian', 'italian'),
+  ('Italian', 'pasta'),
+  ('Italian', 'risotto'),
+  ('Italian', 'bruschetta'),
+  ('Mediterranean', 'mediterranean'),
+  ('Mediterranean', 'falafel'),
+  ('Mediterranean', 'hummus'),
+  ('Mediterranean', 'gyro'),
+  ('Mediterranean', 'shawarma'),
+  ('Mediterranean', 'kebab'),
+  ('Mexican', 'mexican'),
+  ('Mexican', 'burrito'),
+  ('Mexican', 'burritos'),
+  ('Mexican', 'quesadilla'),
+  ('Mexican', 'enchilada'),
+  ('Mexican', 'guacamole'),
+  ('Milk Tea Boba', 'milk tea'),
+  ('Milk Tea Boba', 'boba'),
+  ('Milk Tea Boba', 'bubble tea'),
+  ('Oysters', 'oyster'),
+  ('Oysters', 'oysters'),
+  ('Pizza', 'pizza'),
+  ('Pizza', 'pizzas'),
+  ('Pizza', 'slice'),
+  ('Poke', 'poke'),
+  ('Ramen', 'ramen'),
+  ('Ramen', 'noodle soup'),
+  ('Skewers', 'skewers'),
+  ('Skewers', 'yakitori'),
+  ('Skewers', 'kebabs'),
+  ('Special Drinks', 'special drinks'),
+  ('Special Drinks', 'sangria'),
+  ('Special Drinks', 'mimosa'),
+  ('Special Drinks', 'mimosas'),
+  ('Special Drinks', 'sake'),
+  ('Sushi', 'sushi'),
+  ('Sushi', 'sashimi'),
+  ('Sushi', 'nigiri'),
+  ('Tacos', 'taco'),
+  ('Tacos', 'tacos'),
+  ('Thai', 'thai'),
+  ('Thai', 'pad thai'),
+  ('Wine', 'wine'),
+  ('Wine', 'wines'),
+  ('Wine', 'glass of wine'),
+  ('Wine', 'prosecco'),
+  ('Wings', 'wings'),
+  ('Wings', 'chicken wings'),
+  ('Wings', 'hot wings')
+ON CONFLICT (category, keyword) DO NOTHING;
+`,
+		Down: `
+ALTER TABLE listing DROP COLUMN IF EXISTS category;
+
+DROP TABLE IF EXISTS stock_image;
+`,
+	},
+	{
+		Version: 16,
+		Name:    "happyhour_moderation",
+		Up: `
+ALTER TABLE happyhour
+  ADD COLUMN IF NOT EXISTS status         TEXT NOT NULL DEFAULT 'pending',
+  ADD COLUMN IF NOT EXISTS reason         TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS business_id    INT REFERENCES business (business_id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS moderated_date TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS happyhour_status ON happyhour (status, hh_id);
+`,
+		Down: `
+DROP INDEX IF EXISTS happyhour_status;
+
+ALTER TABLE happyhour
+  DROP COLUMN IF EXISTS status,
+  DROP COLUMN IF EXISTS reason,
+  DROP COLUMN IF EXISTS business_id,
+  DROP COLUMN IF EXISTS moderated_date;
+`,
+	},
+	{
+		Version: 17,
+		Name:    "image_url_index",
+		Up: `
+-- listing images are joined to the image table by url
+CREATE INDEX IF NOT EXISTS image_url_idx ON image (url);
+`,
+		Down: `
+DROP INDEX IF EXISTS image_url_idx;
+`,
+	},
+}
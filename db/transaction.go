@@ -0,0 +1,56 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Querier is the subset of *sql.DB and *sql.Tx the engines use, so the
+	// same engine code runs either directly on the pool or inside a
+	// transaction.
+	Querier interface {
+		Exec(query string, args ...interface{}) (sql.Result, error)
+		Query(query string, args ...interface{}) (*sql.Rows, error)
+		QueryRow(query string, args ...interface{}) *sql.Row
+	}
+
+	txBeginner interface {
+		Begin() (*sql.Tx, error)
+	}
+)
+
+// Transact runs f as a single unit of work. When q is a connection pool a
+// new transaction is started, committed if f succeeds and rolled back if it
+// returns an error or panics. When q is already a transaction f joins it, and
+// the outermost caller decides whether to commit.
+func Transact(q Querier, f func(tx Querier) error) (err error) {
+	beginner, ok := q.(txBeginner)
+	if !ok {
+		return f(q)
+	}
+
+	tx, err := beginner.Begin()
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback() // nolint: errcheck
+			panic(p)
+		}
+	}()
+
+	if err = f(tx); err != nil {
+		// the error from f is what callers map to a response, keep it as is
+		tx.Rollback() // nolint: errcheck
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+	return nil
+}
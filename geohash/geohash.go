@@ -0,0 +1,178 @@
+// Package geohash encodes locations as geohashes and finds the geohash cells
+// covering a search radius, so that nearby locations can be looked up by
+// prefix on an indexed column.
+package geohash
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+	// Precision is the length of the geohashes stored in
+	// business_address.geohash, cells are about 150m wide
+	Precision = 7
+
+	milesPerDegree = 69.09
+)
+
+// Encode returns the geohash of the given precision containing lat, lon.
+func Encode(lat float64, lon float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	hash := make([]byte, 0, precision)
+	bits, ch := 0, 0
+	even := true
+	for len(hash) < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				minLon = mid
+			} else {
+				ch = ch << 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch = ch << 1
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		bits++
+		if bits == 5 {
+			hash = append(hash, base32[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// Center returns the location at the center of the cell hash, it is
+// zero when hash is not a geohash.
+func Center(hash string) (float64, float64) {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	even := true
+	for i := 0; i < len(hash); i++ {
+		ch := strings.IndexByte(base32, hash[i])
+		if ch < 0 {
+			return 0, 0
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := ch>>uint(bit)&1 == 1
+			if even {
+				mid := (minLon + maxLon) / 2
+				if set {
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if set {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return (minLat + maxLat) / 2, (minLon + maxLon) / 2
+}
+
+// cellSize returns the height and width in degrees of the cells of the given
+// precision
+func cellSize(precision int) (float64, float64) {
+	lonBits := (5*precision + 1) / 2
+	latBits := 5 * precision / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+// Cover returns the geohash cells that together contain every location within
+// miles of lat, lon. It picks the longest cells that are still larger than the
+// radius, so that only a handful are returned. It returns nil when the radius
+// is too large to be narrowed down by cells.
+func Cover(lat float64, lon float64, miles float64) []string {
+	minLat, maxLat, minLon, maxLon, ok := BoundingBox(lat, lon, miles)
+	if !ok {
+		return nil
+	}
+
+	precision := 0
+	for p := Precision; p > 0; p-- {
+		height, width := cellSize(p)
+		if height >= maxLat-minLat && width >= maxLon-minLon {
+			precision = p
+			break
+		}
+	}
+	if precision == 0 {
+		return nil
+	}
+
+	// stepping by at most a cell hits every cell the box overlaps
+	height, width := cellSize(precision)
+	seen := make(map[string]bool)
+	var cells []string
+	for _, y := range steps(minLat, maxLat, height) {
+		for _, x := range steps(minLon, maxLon, width) {
+			cell := Encode(y, wrap(x), precision)
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+		}
+	}
+	return cells
+}
+
+// BoundingBox returns the latitudes and longitudes bounding every location
+// within miles of lat, lon. Longitudes may run past the antimeridian. ok is
+// false when the box would reach a pole or wrap around the earth.
+func BoundingBox(lat float64, lon float64, miles float64) (minLat float64, maxLat float64, minLon float64, maxLon float64, ok bool) {
+	latDelta := miles / milesPerDegree
+	minLat, maxLat = math.Max(lat-latDelta, -90), math.Min(lat+latDelta, 90)
+
+	// a degree of longitude shrinks towards the poles
+	widest := math.Max(math.Abs(minLat), math.Abs(maxLat))
+	if widest >= 89 {
+		return 0, 0, 0, 0, false
+	}
+	lonDelta := latDelta / math.Cos(widest*math.Pi/180)
+	if lonDelta >= 180 {
+		return 0, 0, 0, 0, false
+	}
+	return minLat, maxLat, lon - lonDelta, lon + lonDelta, true
+}
+
+// steps returns the points from min to max, both included, at most step apart
+func steps(min float64, max float64, step float64) []float64 {
+	var points []float64
+	for p := min; p < max; p += step {
+		points = append(points, p)
+	}
+	return append(points, max)
+}
+
+// wrap brings a longitude past the antimeridian back into -180..180
+func wrap(lon float64) float64 {
+	switch {
+	case lon < -180:
+		return lon + 360
+	case lon >= 180:
+		return lon - 360
+	}
+	return lon
+}
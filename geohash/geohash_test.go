@@ -0,0 +1,59 @@
+package geohash
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	require.Equal(t, "u4pruydqqvj", Encode(57.64911, 10.40744, 11))
+	require.Equal(t, "9q8yyk8", Encode(37.7749, -122.4194, Precision))
+	require.Equal(t, "9q9", Encode(37.3688, -122.0363, 3))
+}
+
+func TestCenter(t *testing.T) {
+	lat, lon := Center("9q8yyk8")
+	require.Equal(t, "9q8yyk8", Encode(lat, lon, Precision))
+	require.InDelta(t, 37.7749, lat, 0.001)
+	require.InDelta(t, -122.4194, lon, 0.001)
+
+	lat, lon = Center("not a geohash")
+	require.Zero(t, lat)
+	require.Zero(t, lon)
+}
+
+func TestCover(t *testing.T) {
+	for _, center := range []struct {
+		lat, lon, miles float64
+	}{
+		{37.3688, -122.0363, 15}, // sunnyvale
+		{37.3688, -122.0363, 0.5},
+		{12.9716, 77.5946, 25}, // bangalore
+		{-33.8688, 151.2093, 10},
+		{64.1466, -21.9426, 25},
+		{0.001, 179.99, 5}, // across the antimeridian
+	} {
+		cells := Cover(center.lat, center.lon, center.miles)
+		require.NotEmpty(t, cells)
+		require.True(t, len(cells) <= 9, "%v", cells)
+
+		// every point on the radius falls in one of the cells
+		for angle := 0.0; angle < 2*math.Pi; angle += math.Pi / 16 {
+			lat := center.lat + center.miles/milesPerDegree*math.Sin(angle)
+			lon := center.lon + center.miles/milesPerDegree/math.Cos(lat*math.Pi/180)*math.Cos(angle)
+			hash := Encode(lat, wrap(lon), Precision)
+
+			covered := false
+			for _, cell := range cells {
+				covered = covered || strings.HasPrefix(hash, cell)
+			}
+			require.True(t, covered, "%f,%f (%s) not in %v", lat, lon, hash, cells)
+		}
+	}
+
+	require.Nil(t, Cover(37.3688, -122.0363, 5000))
+	require.Nil(t, Cover(89.5, 0, 10))
+}
@@ -92,3 +92,23 @@ func (l LocationError) Error() string {
 	b, _ := json.Marshal(l)
 	return fmt.Sprintf("location error: %s", string(b))
 }
+
+// AuthenticationError ...
+type AuthenticationError struct {
+	Message string `json:"message,omitempty"`
+}
+
+func (a AuthenticationError) Error() string {
+	b, _ := json.Marshal(a)
+	return fmt.Sprintf("authentication error: %s", string(b))
+}
+
+// AuthorizationError ...
+type AuthorizationError struct {
+	Message string `json:"message,omitempty"`
+}
+
+func (a AuthorizationError) Error() string {
+	b, _ := json.Marshal(a)
+	return fmt.Sprintf("authorization error: %s", string(b))
+}
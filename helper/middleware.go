@@ -4,6 +4,7 @@ import (
 	"net"
 	"net/http"
 
+	"github.com/phassans/banana/locale"
 	"github.com/phassans/banana/shared"
 )
 
@@ -29,3 +30,12 @@ func SetFieldsInLogger(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// SetLanguage attaches the language the response is to be in, from the
+// Accept-Language header, to the context of the request
+func SetLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language := locale.Language(r.Header.Get("Accept-Language"))
+		next.ServeHTTP(w, r.WithContext(locale.WithLanguage(r.Context(), language)))
+	})
+}
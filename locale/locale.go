@@ -0,0 +1,218 @@
+// Package locale formats prices, distances, dates and the fixed strings of
+// listings for the language of a user and the region they are in.
+package locale
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/shared"
+)
+
+// languages listings can be shown in
+const (
+	English = "en"
+	French  = "fr"
+)
+
+// kilometersPerMile converts the miles distances are computed in
+const kilometersPerMile = 1.609344
+
+type (
+	// Locale formats for a language and a region. The zero Locale formats
+	// in English, in dollars and miles.
+	Locale struct {
+		language string
+		region   shared.Region
+	}
+
+	currency struct {
+		symbol   string
+		decimals int
+	}
+
+	contextKey struct{}
+)
+
+// currencies are the symbols prices are shown with and their decimals, other
+// currencies are shown with their code and 2 decimals
+var currencies = map[string]currency{
+	"USD": {"$", 2},
+	"CAD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"INR": {"₹", 2},
+	"JPY": {"¥", 0},
+}
+
+// New returns the Locale of region for the best of the languages an
+// Accept-Language header asks for, English when none is known
+func New(region shared.Region, acceptLanguage string) Locale {
+	return Locale{language: Language(acceptLanguage), region: region}
+}
+
+// Language returns the known language an Accept-Language header, e.g.
+// "fr-CA,fr;q=0.9,en;q=0.8", prefers, English when none is known
+func Language(acceptLanguage string) string {
+	type preference struct {
+		language string
+		quality  float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		language := strings.SplitN(tag, "-", 2)[0]
+		if _, ok := translations[language]; !ok && language != English {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			preferences = append(preferences, preference{language, quality})
+		}
+	}
+
+	if len(preferences) == 0 {
+		return English
+	}
+	sort.SliceStable(preferences, func(i, j int) bool { return preferences[i].quality > preferences[j].quality })
+	return preferences[0].language
+}
+
+// WithLanguage returns a copy of ctx carrying the language of the request
+func WithLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, contextKey{}, language)
+}
+
+// LanguageFromContext returns the language of the request, English when
+// none was attached
+func LanguageFromContext(ctx context.Context) string {
+	language, ok := ctx.Value(contextKey{}).(string)
+	if !ok || language == "" {
+		return English
+	}
+	return language
+}
+
+// Language returns the language of the locale
+func (l Locale) Language() string {
+	if l.language == "" {
+		return English
+	}
+	return l.language
+}
+
+// Price returns price in the currency of the region, "$ 4.50" in English and
+// "4,50 $" in French. There is no price to show when it is 0.
+func (l Locale) Price(price float64) string {
+	if price == 0.0 {
+		return ""
+	}
+
+	c := currency{"$", 2}
+	if l.region.Currency != "" {
+		c = currency{l.region.Currency, 2}
+		if known, ok := currencies[l.region.Currency]; ok {
+			c = known
+		}
+	}
+
+	amount := l.number(price, c.decimals)
+	if l.Language() == French {
+		return amount + " " + c.symbol
+	}
+	return c.symbol + " " + amount
+}
+
+// Distance returns a distance in miles in the unit of the region, "1.61km"
+// in English and "1,61 km" in French
+func (l Locale) Distance(miles float64) string {
+	unit, distance := shared.DistanceUnitMiles, miles
+	if l.region.DistanceUnit == shared.DistanceUnitKilometers {
+		unit, distance = shared.DistanceUnitKilometers, miles*kilometersPerMile
+	}
+
+	if l.Language() == French {
+		return l.number(distance, 2) + " " + unit
+	}
+	return l.number(distance, 2) + unit
+}
+
+// Sprintf formats the English format, translated to the language of the
+// locale when there is a translation
+func (l Locale) Sprintf(format string, args ...interface{}) string {
+	if translated, ok := translations[l.Language()][format]; ok {
+		format = translated
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Date returns the month and day of t, "Sep 5" in English and "5 sept." in
+// French
+func (l Locale) Date(t time.Time) string {
+	if names, ok := months[l.Language()]; ok {
+		return fmt.Sprintf("%d %s", t.Day(), names[t.Month()-1])
+	}
+	return fmt.Sprintf("%s %d", t.Month().String()[0:3], t.Day())
+}
+
+// Weekday returns the name of day, abbreviated when short
+func (l Locale) Weekday(day time.Weekday, short bool) string {
+	names, ok := weekdays[l.Language()]
+	if !ok {
+		if short {
+			return day.String()[0:3]
+		}
+		return day.String()
+	}
+
+	if short {
+		return names[day][0:3] + "."
+	}
+	return names[day]
+}
+
+// Time returns a time of day given as 15:04:05, "5pm" or "5:30pm" in
+// English and "17h" or "17h30" in French
+func (l Locale) Time(clock string) (string, error) {
+	if l.Language() != French {
+		return shared.GetTimeIn12HourFormat(clock)
+	}
+	if clock == "" {
+		return "", nil
+	}
+
+	// times of day are read from the DB with a date
+	parts := strings.Split(clock, "T")
+	t, err := time.Parse(shared.TimeLayout24Hour, strings.TrimSuffix(parts[len(parts)-1], "Z"))
+	if err != nil {
+		return "", err
+	}
+	if t.Minute() == 0 {
+		return fmt.Sprintf("%dh", t.Hour()), nil
+	}
+	return fmt.Sprintf("%dh%02d", t.Hour(), t.Minute()), nil
+}
+
+// number formats n with decimals and the decimal separator of the language
+func (l Locale) number(n float64, decimals int) string {
+	s := strconv.FormatFloat(n, 'f', decimals, 64)
+	if l.Language() == French {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package locale
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanguage(t *testing.T) {
+	require.Equal(t, English, Language(""))
+	require.Equal(t, English, Language("de-DE,de;q=0.9"))
+	require.Equal(t, French, Language("fr-CA,fr;q=0.9,en;q=0.8"))
+	require.Equal(t, English, Language("fr;q=0.5, en-US"))
+	require.Equal(t, French, Language("de, fr;q=0.4"))
+	require.Equal(t, English, Language("fr;q=0"))
+
+	require.Equal(t, English, LanguageFromContext(context.Background()))
+	require.Equal(t, French, LanguageFromContext(WithLanguage(context.Background(), French)))
+}
+
+func TestLocale(t *testing.T) {
+	toronto := shared.Region{Currency: "CAD", DistanceUnit: shared.DistanceUnitKilometers}
+	date := time.Date(2018, time.September, 5, 0, 0, 0, 0, time.UTC)
+
+	english := New(toronto, "en-CA")
+	require.Equal(t, "$ 4.50", english.Price(4.5))
+	require.Equal(t, "", english.Price(0))
+	require.Equal(t, "1.61km", english.Distance(1))
+	require.Equal(t, "Sep 5", english.Date(date))
+	require.Equal(t, "Wed", english.Weekday(date.Weekday(), true))
+	require.Equal(t, "Today", english.Sprintf("Today"))
+	require.Equal(t, "2 hours left", english.Sprintf("%d hours left", 2))
+	clock, err := english.Time("17:30:00")
+	require.NoError(t, err)
+	require.Equal(t, "5:30pm", clock)
+
+	french := New(toronto, "fr-CA,fr;q=0.9")
+	require.Equal(t, "4,50 $", french.Price(4.5))
+	require.Equal(t, "1,61 km", french.Distance(1))
+	require.Equal(t, "5 sept.", french.Date(date))
+	require.Equal(t, "mercredi", french.Weekday(date.Weekday(), false))
+	require.Equal(t, "mer.", french.Weekday(date.Weekday(), true))
+	require.Equal(t, "Aujourd'hui", french.Sprintf("Today"))
+	require.Equal(t, "2 heures restantes", french.Sprintf("%d hours left", 2))
+	clock, err = french.Time("0000-01-01T17:00:00Z")
+	require.NoError(t, err)
+	require.Equal(t, "17h", clock)
+
+	// the zero locale shows dollars and miles in english
+	require.Equal(t, "$ 4.50", Locale{}.Price(4.5))
+	require.Equal(t, "1.00mi", Locale{}.Distance(1))
+	require.Equal(t, "CHF 4.50", New(shared.Region{Currency: "CHF"}, "").Price(4.5))
+	require.Equal(t, "¥ 450", New(shared.Region{Currency: "JPY"}, "").Price(450))
+}
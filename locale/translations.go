@@ -0,0 +1,33 @@
+package locale
+
+import "time"
+
+// translations map the English formats of the fixed strings of listings to
+// their translation, per language
+var translations = map[string]map[string]string{
+	French: {
+		"Today":         "Aujourd'hui",
+		"%d mins left":  "%d min restantes",
+		"%d hour left":  "%d heure restante",
+		"%d hours left": "%d heures restantes",
+	},
+}
+
+// weekdays are the names of the days, per language other than English
+var weekdays = map[string][7]string{
+	French: {
+		time.Sunday:    "dimanche",
+		time.Monday:    "lundi",
+		time.Tuesday:   "mardi",
+		time.Wednesday: "mercredi",
+		time.Thursday:  "jeudi",
+		time.Friday:    "vendredi",
+		time.Saturday:  "samedi",
+	},
+}
+
+// months are the abbreviated names of the months, per language other than
+// English
+var months = map[string][12]string{
+	French: {"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+}
@@ -1,69 +1,188 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/phassans/banana/model/donforgetto"
 
+	"github.com/phassans/banana/auth"
+	"github.com/phassans/banana/clients/cloudinary"
+	"github.com/phassans/banana/clients/geocode"
+	"github.com/phassans/banana/clients/mail"
+	"github.com/phassans/banana/clients/media"
+	"github.com/phassans/banana/clients/push"
+	"github.com/phassans/banana/config"
 	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/model"
+	"github.com/phassans/banana/model/analytics"
 	"github.com/phassans/banana/model/business"
 	"github.com/phassans/banana/model/favourite"
 	"github.com/phassans/banana/model/listing"
 	"github.com/phassans/banana/model/notification"
 	"github.com/phassans/banana/model/prefernce"
+	"github.com/phassans/banana/model/region"
+	"github.com/phassans/banana/model/stockimage"
 	"github.com/phassans/banana/model/upvote"
 	"github.com/phassans/banana/model/user"
 	"github.com/phassans/banana/route"
 	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
 )
 
+// shutdownTimeout is how long requests in flight get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// set up defaults and configs
-	config()
+	cfg, err := setup()
 	logger := shared.GetLogger()
+	if err != nil {
+		logger.Fatal().Msgf("could not load config: %s", err)
+	}
+
 	// set up DB
-	roach, err := db.New(db.Config{Host: "localhost", Port: "5432", User: "pshashidhara", Password: "banana123", Database: "banana"})
+	roach, err := db.New(cfg.Database)
 	if err != nil {
 		logger.Fatal().Msgf("could not connect to db. errpr %s", err)
 	}
 
 	logger.Info().Msg("successfully connected to db")
 
+	// `banana migrate ...` manages the schema and exits
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(roach, logger, args[1:]); err != nil {
+			logger.Error().Msgf("migrate failed: %s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// refuse to serve on a schema that is behind
+	if err := ensureSchema(roach, logger, cfg.Server.AutoMigrate); err != nil {
+		logger.Fatal().Msgf("schema check failed: %s", err)
+	}
+
+	// create clients
+	geocoder, err := geocode.New(logger, cfg.Geocoder, cfg.Google)
+	if err != nil {
+		logger.Fatal().Msgf("geocoder setup failed: %s", err)
+	}
+	geoCache := geocode.NewCache(logger, geocoder, geocode.NewSQLStore(roach.Db),
+		time.Duration(cfg.Geocoder.CacheTTLMinutes)*time.Minute, cfg.Geocoder.CacheSize)
+	imageStore, err := media.New(logger, cfg.Images, cloudinary.NewCloudinaryClient(logger, cfg.Cloudinary))
+	if err != nil {
+		logger.Fatal().Msgf("image store setup failed: %s", err)
+	}
+	images := media.NewPipeline(logger, cfg.Images, imageStore, media.NewSQLIndex(roach.Db))
+	tokens := auth.NewTokenIssuer(cfg.Auth)
+
 	// createEngines
-	userEngine := user.NewUserEngine(roach.Db, logger)
-	businessEngine := business.NewBusinessEngine(roach.Db, logger, userEngine)
-	listingEngine := listing.NewListingEngine(roach.Db, logger, businessEngine)
-	favouriteEngine := favourite.NewFavoriteEngine(roach.Db, logger, businessEngine, listingEngine)
-	notificationEngine := notification.NewNotificationEngine(roach.Db, logger, businessEngine)
-	prefernceEngine := prefernce.NewPreferenceEngine(roach.Db, logger)
-	upvoteEngine := upvote.NewUpvoteEngine(roach.Db, logger, listingEngine)
-	donforgettoEngine := donforgetto.NewDonforgettoEngine(roach.Db, logger)
-
-	engines := model.NewGenericEngine(
-		businessEngine,
-		userEngine,
-		listingEngine,
-		favouriteEngine,
-		notificationEngine,
-		prefernceEngine,
-		upvoteEngine,
-		donforgettoEngine,
-	)
+	searchLog := listing.NewSearchLogWriter(roach.Db, logger, cfg.SearchLog)
+	engines := newEngines(roach.Db, logger, cfg, geoCache, searchLog)
+
+	// write the search log in the background
+	stop := make(chan struct{})
+	logged := make(chan struct{})
+	go func() {
+		searchLog.Run(stop)
+		close(logged)
+	}()
+
+	// push saved notifications in the background
+	if cfg.Push.DispatchIntervalMinutes > 0 {
+		provider := push.NewFCMProvider(logger, cfg.Push)
+		interval := time.Duration(cfg.Push.DispatchIntervalMinutes) * time.Minute
+		go notification.NewDispatcher(logger, engines, engines, provider, interval).Run(stop)
+	}
+
+	// tag the listings in the background, the ones added before listings
+	// had a category included
+	go func() {
+		if _, err := engines.TagListings(); err != nil {
+			logger.Error().Msgf("tagging listings failed: %s", err)
+		}
+	}()
+
+	// keep the dates of recurring listings materialized in the background
+	dateScheduler := listing.NewDateScheduler(logger, engines, time.Duration(cfg.ListingDates.RefreshIntervalMinutes)*time.Minute)
+	if cfg.ListingDates.RefreshIntervalMinutes > 0 {
+		go dateScheduler.Run(stop)
+	}
 
 	// start the server
-	server = http.Server{Addr: net.JoinHostPort("", serverPort), Handler: route.APIServerHandler(engines)}
+	server = http.Server{Addr: net.JoinHostPort("", cfg.Server.Port), Handler: route.APIServerHandler(engines, images, cfg.Images, mail.New(logger, cfg.Mail), tokens, dateScheduler)}
 	go func() { serverErrChannel <- server.ListenAndServe() }()
 
 	// log server start time
 	logger.Info().Msgf("API server started at %s. time:%s", server.Addr, serverStartTime)
 
-	// wait for any server error
+	// wait for any server error or a signal to stop
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	exitCode := 0
 	select {
 	case err := <-serverErrChannel:
-		logger.Fatal().Msgf("service stopped due to error %v with uptime %v", err, time.Since(serverStartTime))
-		roach.Close() // nolint: errcheck
+		logger.Error().Msgf("service stopped due to error %v with uptime %v", err, time.Since(serverStartTime))
+		exitCode = 1
+	case sig := <-signals:
+		logger.Info().Msgf("received %s, shutting down with uptime %v", sig, time.Since(serverStartTime))
 	}
+
+	// finish the requests in flight, then write the searches still queued
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error().Msgf("server shutdown failed: %s", err)
+	}
+	cancel()
+	close(stop)
+	<-logged
+
+	roach.Close() // nolint: errcheck
+	os.Exit(exitCode)
+}
+
+// newEngines wires every engine on top of q. The engines rebuild themselves
+// through it when a transaction is started, see model.Engine.Transact.
+func newEngines(q db.Querier, logger zerolog.Logger, cfg config.Config, geocoder geocode.Geocoder,
+	searchLog listing.SearchLogger) model.Engine {
+	userEngine := user.NewUserEngine(q, logger)
+	businessEngine := business.NewBusinessEngine(q, logger, userEngine, geocoder)
+	regionEngine := region.NewRegionEngine(q, logger)
+	stockImageEngine := stockimage.NewStockImageEngine(q, logger)
+	listingEngine := listing.NewListingEngine(q, logger, businessEngine, regionEngine, stockImageEngine, geocoder,
+		media.NewVariants(cfg.Images), cfg.Search, cfg.ListingDates, searchLog)
+	favouriteEngine := favourite.NewFavoriteEngine(q, logger, businessEngine, listingEngine, cfg.Search)
+	notificationEngine := notification.NewNotificationEngine(q, logger, businessEngine, geocoder)
+	prefernceEngine := prefernce.NewPreferenceEngine(q, logger)
+	upvoteEngine := upvote.NewUpvoteEngine(q, logger, listingEngine)
+	donforgettoEngine := donforgetto.NewDonforgettoEngine(q, logger)
+	analyticsEngine := analytics.NewAnalyticsEngine(q, logger)
+
+	build := func(tx db.Querier) model.Engine {
+		return newEngines(tx, logger, cfg, geocoder, searchLog)
+	}
+
+	return model.NewGenericEngine(
+		q,
+		build,
+		businessEngine,
+		userEngine,
+		listingEngine,
+		favouriteEngine,
+		notificationEngine,
+		prefernceEngine,
+		upvoteEngine,
+		donforgettoEngine,
+		analyticsEngine,
+		regionEngine,
+		stockImageEngine,
+	)
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/phassans/banana/db"
+	"github.com/rs/zerolog"
+)
+
+const migrateUsage = `usage: banana migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down [n]  revert the last n applied migrations (default 1)
+  status    list migrations and whether they are applied`
+
+// runMigrate implements the `banana migrate` subcommand.
+func runMigrate(roach db.Roach, logger zerolog.Logger, args []string) error {
+	migrator := db.NewMigrator(roach.Db, logger, db.Migrations)
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s), schema at version %d\n", len(applied), migrator.LatestVersion())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d_%s\n", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-30s %s\n", status.Version, status.Name, applied)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// ensureSchema refuses to start the server on an out of date schema unless
+// autoMigrate is set, in which case pending migrations are applied first.
+func ensureSchema(roach db.Roach, logger zerolog.Logger, autoMigrate bool) error {
+	migrator := db.NewMigrator(roach.Db, logger, db.Migrations)
+
+	if autoMigrate {
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		logger.Info().Msgf("applied %d pending migration(s) at startup", len(applied))
+		return nil
+	}
+
+	return migrator.Check()
+}
@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// MaxSearchStatsDays caps the days the search stats are asked for at once
+	MaxSearchStatsDays = 92
+
+	// MaxTop caps the values listed per dimension of the search stats
+	MaxTop = 100
+
+	// DefaultTop is the number of values listed per dimension by default
+	DefaultTop = 20
+)
+
+type (
+	analyticsEngine struct {
+		sql    db.Querier
+		logger zerolog.Logger
+	}
+
+	// AnalyticsEngine reports on the searches logged by the listing engine
+	AnalyticsEngine interface {
+		SearchStats(from time.Time, to time.Time, top int) ([]shared.SearchStats, error)
+	}
+)
+
+// NewAnalyticsEngine returns an instance of analyticsEngine
+func NewAnalyticsEngine(psql db.Querier, logger zerolog.Logger) AnalyticsEngine {
+	return &analyticsEngine{psql, logger}
+}
+
+// SearchStats returns the stats of the searches made from the day of from to
+// the day of to, both included, one entry per day with searches. Every
+// dimension lists its top values only.
+func (a *analyticsEngine) SearchStats(from time.Time, to time.Time, top int) ([]shared.SearchStats, error) {
+	if top <= 0 || top > MaxTop {
+		top = DefaultTop
+	}
+
+	from = day(from)
+	to = day(to).AddDate(0, 0, 1)
+
+	rows, err := a.sql.Query("SELECT search_request, search_date, search_filters, latitude, longitude, "+
+		"results, error_category FROM search "+
+		"WHERE search_date >= $1 AND search_date < $2 ORDER BY search_date;", from, to)
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	defer rows.Close()
+
+	stats := newSearchAggregator()
+	for rows.Next() {
+		var row SearchLogRow
+		if err := rows.Scan(&row.Request, &row.Date, &row.Filters, &row.Latitude, &row.Longitude,
+			&row.Results, &row.ErrorCategory); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+
+		entry, err := ParseSearchLog(row)
+		if err != nil {
+			a.logger.Warn().Msgf("skipping search logged on %s: %s", row.Date, err)
+			continue
+		}
+		stats.add(entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	return stats.stats(top), nil
+}
+
+// day returns the start of the day of t
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,147 @@
+package analytics
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/geohash"
+	"github.com/phassans/banana/shared"
+)
+
+const (
+	// AreaPrecision is the length of the geohash cells searches are grouped
+	// in, cells are about 5km wide, roughly a neighborhood
+	AreaPrecision = 5
+
+	// defaultSortBy stands for the searches leaving the order to the service
+	defaultSortBy = "default"
+)
+
+type (
+	// SearchLogRow is a row of the search table. Searches logged before
+	// their outcome was recorded only have a request and a date.
+	SearchLogRow struct {
+		Request       string
+		Date          time.Time
+		Filters       sql.NullString
+		Latitude      sql.NullFloat64
+		Longitude     sql.NullFloat64
+		Results       sql.NullInt64
+		ErrorCategory sql.NullString
+	}
+
+	// SearchLogEntry is a logged search broken down into the dimensions it is
+	// reported on
+	SearchLogEntry struct {
+		Date      string
+		Geohash   string
+		Location  string
+		Keywords  string
+		Filters   []string
+		SortBy    string
+		SearchDay string
+		Failed    bool
+
+		// Results is the number of listings found, nil when the search was
+		// logged without its outcome
+		Results *int
+	}
+)
+
+// ParseSearchLog breaks down a logged search. The filters and location the
+// search was run with are preferred over the ones asked for. Text is lower
+// cased and trimmed, so that the same search typed differently is counted
+// once.
+func ParseSearchLog(row SearchLogRow) (SearchLogEntry, error) {
+	var request shared.SearchRequest
+	if err := json.Unmarshal([]byte(row.Request), &request); err != nil {
+		return SearchLogEntry{}, fmt.Errorf("invalid search request: %s", err)
+	}
+
+	applied := shared.SearchFilters{
+		Keywords:       request.Keywords,
+		ListingTypes:   request.ListingTypes,
+		DietaryFilters: request.DietaryFilters,
+		PriceFilter:    request.PriceFilter,
+		SortBy:         request.SortBy,
+		SearchDay:      request.SearchDay,
+	}
+	if row.Filters.Valid {
+		applied = shared.SearchFilters{}
+		if err := json.Unmarshal([]byte(row.Filters.String), &applied); err != nil {
+			return SearchLogEntry{}, fmt.Errorf("invalid search filters: %s", err)
+		}
+	}
+
+	latitude, longitude := request.Latitude, request.Longitude
+	if row.Latitude.Valid && row.Longitude.Valid {
+		latitude, longitude = row.Latitude.Float64, row.Longitude.Float64
+	}
+
+	entry := SearchLogEntry{
+		Date:      row.Date.Format(shared.DateFormatSQL),
+		Location:  normalize(request.Location),
+		Keywords:  normalize(applied.Keywords),
+		Filters:   filters(applied, request.DistanceFilter),
+		SortBy:    normalize(applied.SortBy),
+		SearchDay: normalize(applied.SearchDay),
+		Failed:    row.ErrorCategory.Valid && row.ErrorCategory.String != "",
+	}
+	if latitude != 0 || longitude != 0 {
+		entry.Geohash = geohash.Encode(latitude, longitude, AreaPrecision)
+	}
+	if entry.SortBy == "" {
+		entry.SortBy = defaultSortBy
+	}
+	if entry.SearchDay == "" {
+		entry.SearchDay = shared.SearchToday
+	}
+	if row.Results.Valid {
+		results := int(row.Results.Int64)
+		entry.Results = &results
+	}
+	return entry, nil
+}
+
+// filters returns the filters of a search as "name:value", sorted. The
+// distance asked for is kept rather than the radius searched, which every
+// search has.
+func filters(applied shared.SearchFilters, distanceFilter string) []string {
+	seen := make(map[string]bool)
+	var filters []string
+	add := func(name string, value string) {
+		value = normalize(value)
+		if value == "" {
+			return
+		}
+		filter := name + ":" + value
+		if !seen[filter] {
+			seen[filter] = true
+			filters = append(filters, filter)
+		}
+	}
+
+	for _, listingType := range applied.ListingTypes {
+		add("listingType", listingType)
+	}
+	for _, dietary := range applied.DietaryFilters {
+		add("dietary", dietary)
+	}
+	if applied.PriceFilter > 0 {
+		add("price", strconv.FormatFloat(applied.PriceFilter, 'f', -1, 64))
+	}
+	add("distance", distanceFilter)
+
+	sort.Strings(filters)
+	return filters
+}
+
+// normalize lower cases text and collapses its spaces
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
@@ -0,0 +1,240 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/phassans/banana/geohash"
+	"github.com/phassans/banana/shared"
+)
+
+// SearchStatsCSVHeader names the columns of WriteSearchStatsCSV
+var SearchStatsCSVHeader = []string{"date", "dimension", "value", "searches", "zero_results", "latitude", "longitude"}
+
+type (
+	// counter counts the searches per value of a dimension
+	counter map[string]*shared.SearchCount
+
+	// dayAggregate holds the counts of the searches of a day
+	dayAggregate struct {
+		stats      shared.SearchStats
+		queries    counter
+		areas      map[string]*shared.AreaDemand
+		sortBy     counter
+		searchDays counter
+		filters    counter
+	}
+
+	// searchAggregator sums logged searches up day by day, so that they can
+	// be streamed from the search log
+	searchAggregator struct {
+		days  map[string]*dayAggregate
+		dates []string
+	}
+)
+
+func newSearchAggregator() *searchAggregator {
+	return &searchAggregator{days: make(map[string]*dayAggregate)}
+}
+
+func (c counter) add(value string, zeroResults bool) {
+	count, ok := c[value]
+	if !ok {
+		count = &shared.SearchCount{Value: value}
+		c[value] = count
+	}
+	count.Searches++
+	if zeroResults {
+		count.ZeroResults++
+	}
+}
+
+// top returns the n values searched the most, by zero results when
+// zeroResults is set, leaving out the ones that never found nothing
+func (c counter) top(n int, zeroResults bool) []shared.SearchCount {
+	var counts = make([]shared.SearchCount, 0)
+	for _, count := range c {
+		if zeroResults && count.ZeroResults == 0 {
+			continue
+		}
+		counts = append(counts, *count)
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if zeroResults && counts[i].ZeroResults != counts[j].ZeroResults {
+			return counts[i].ZeroResults > counts[j].ZeroResults
+		}
+		if counts[i].Searches != counts[j].Searches {
+			return counts[i].Searches > counts[j].Searches
+		}
+		return counts[i].Value < counts[j].Value
+	})
+
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+func (s *searchAggregator) add(entry SearchLogEntry) {
+	day, ok := s.days[entry.Date]
+	if !ok {
+		day = &dayAggregate{
+			stats:      shared.SearchStats{Date: entry.Date},
+			queries:    make(counter),
+			areas:      make(map[string]*shared.AreaDemand),
+			sortBy:     make(counter),
+			searchDays: make(counter),
+			filters:    make(counter),
+		}
+		s.days[entry.Date] = day
+		s.dates = append(s.dates, entry.Date)
+	}
+
+	zeroResults := entry.Results != nil && *entry.Results == 0
+	day.stats.Searches++
+	if zeroResults {
+		day.stats.ZeroResultSearches++
+	}
+	if entry.Failed {
+		day.stats.FailedSearches++
+	}
+
+	if entry.Keywords != "" {
+		day.stats.KeywordSearches++
+		day.queries.add(entry.Keywords, zeroResults)
+	}
+	day.sortBy.add(entry.SortBy, zeroResults)
+	day.searchDays.add(entry.SearchDay, zeroResults)
+	for _, filter := range entry.Filters {
+		day.filters.add(filter, zeroResults)
+	}
+
+	// searches without coordinates are placed by the place they name
+	key := entry.Geohash
+	if key == "" {
+		key = "location:" + entry.Location
+	}
+	if entry.Geohash != "" || entry.Location != "" {
+		area, ok := day.areas[key]
+		if !ok {
+			area = &shared.AreaDemand{Geohash: entry.Geohash}
+			if entry.Geohash != "" {
+				area.Latitude, area.Longitude = geohash.Center(entry.Geohash)
+			} else {
+				area.Location = entry.Location
+			}
+			day.areas[key] = area
+		}
+		area.Searches++
+		if zeroResults {
+			area.ZeroResults++
+		}
+	}
+}
+
+// stats returns the stats of every day, in order, with the top n values of
+// every dimension
+func (s *searchAggregator) stats(n int) []shared.SearchStats {
+	sort.Strings(s.dates)
+
+	var stats = make([]shared.SearchStats, 0, len(s.dates))
+	for _, date := range s.dates {
+		day := s.days[date]
+		day.stats.TopQueries = day.queries.top(n, false)
+		day.stats.ZeroResultQueries = day.queries.top(n, true)
+		day.stats.SortBy = day.sortBy.top(n, false)
+		day.stats.SearchDays = day.searchDays.top(n, false)
+		day.stats.Filters = day.filters.top(n, false)
+		day.stats.Areas = topAreas(day.areas, n)
+		stats = append(stats, day.stats)
+	}
+	return stats
+}
+
+// topAreas returns the n areas searched the most
+func topAreas(areas map[string]*shared.AreaDemand, n int) []shared.AreaDemand {
+	var demand = make([]shared.AreaDemand, 0, len(areas))
+	for _, area := range areas {
+		demand = append(demand, *area)
+	}
+
+	sort.Slice(demand, func(i, j int) bool {
+		if demand[i].Searches != demand[j].Searches {
+			return demand[i].Searches > demand[j].Searches
+		}
+		if demand[i].Geohash != demand[j].Geohash {
+			return demand[i].Geohash < demand[j].Geohash
+		}
+		return demand[i].Location < demand[j].Location
+	})
+
+	if len(demand) > n {
+		demand = demand[:n]
+	}
+	return demand
+}
+
+// WriteSearchStatsCSV writes stats as CSV, one row per value of every
+// dimension, so that they can be opened in a spreadsheet
+func WriteSearchStatsCSV(w io.Writer, stats []shared.SearchStats) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(SearchStatsCSVHeader); err != nil {
+		return err
+	}
+
+	for _, day := range stats {
+		rows := [][]string{
+			countRow(day.Date, "total", shared.SearchCount{Searches: day.Searches, ZeroResults: day.ZeroResultSearches}),
+			countRow(day.Date, "keyword_searches", shared.SearchCount{Searches: day.KeywordSearches}),
+			countRow(day.Date, "failed_searches", shared.SearchCount{Searches: day.FailedSearches}),
+		}
+		for _, dimension := range []struct {
+			name   string
+			counts []shared.SearchCount
+		}{
+			{"query", day.TopQueries},
+			{"zero_result_query", day.ZeroResultQueries},
+			{"sort_by", day.SortBy},
+			{"search_day", day.SearchDays},
+			{"filter", day.Filters},
+		} {
+			for _, count := range dimension.counts {
+				rows = append(rows, countRow(day.Date, dimension.name, count))
+			}
+		}
+		for _, area := range day.Areas {
+			row := []string{day.Date, "area", csvCell(area.Location),
+				strconv.Itoa(area.Searches), strconv.Itoa(area.ZeroResults), "", ""}
+			if area.Geohash != "" {
+				row[2] = csvCell(area.Geohash)
+				row[5] = strconv.FormatFloat(area.Latitude, 'f', 6, 64)
+				row[6] = strconv.FormatFloat(area.Longitude, 'f', 6, 64)
+			}
+			rows = append(rows, row)
+		}
+
+		if err := out.WriteAll(rows); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+func countRow(date string, dimension string, count shared.SearchCount) []string {
+	return []string{date, dimension, csvCell(count.Value), strconv.Itoa(count.Searches), strconv.Itoa(count.ZeroResults), "", ""}
+}
+
+// csvCell keeps text typed by users from being run as a formula when the CSV
+// is opened in a spreadsheet
+func csvCell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchAggregator(t *testing.T) {
+	monday := time.Date(2018, 9, 3, 18, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	results := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	logged := []SearchLogRow{
+		{Request: `{"keywords":"Oysters ","latitude":37.3688,"longitude":-122.0363,"sortBy":"price","listingTypes":["happyhour"]}`,
+			Date: monday, Results: results(3)},
+		{Request: `{"keywords":"oysters","latitude":37.3700,"longitude":-122.0400,"dietaryFilters":["Vegan","vegan"]}`,
+			Date: monday, Results: results(0)},
+		// the filters and location a search was run with win over the request
+		{Request: `{"keywords":"Tacos!","location":"San  Jose"}`, Date: monday, Results: results(0),
+			Filters: sql.NullString{String: `{"keywords":"tacos","sortBy":"","searchDay":"today"}`, Valid: true}},
+		{Request: `{"location":"Sunnyvale"}`, Date: monday, ErrorCategory: sql.NullString{String: "location", Valid: true}},
+		{Request: `{"latitude":37.3688,"longitude":-122.0363,"searchDay":"tomorrow"}`, Date: tuesday},
+		{Request: `{"keywords":"=1+2","searchDay":"tomorrow"}`, Date: tuesday},
+	}
+
+	stats := newSearchAggregator()
+	for _, row := range logged {
+		entry, err := ParseSearchLog(row)
+		require.NoError(t, err)
+		stats.add(entry)
+	}
+	_, err := ParseSearchLog(SearchLogRow{Request: "not json", Date: monday})
+	require.Error(t, err)
+
+	days := stats.stats(DefaultTop)
+	require.Len(t, days, 2)
+
+	day := days[0]
+	require.Equal(t, "2018-09-03", day.Date)
+	require.Equal(t, 4, day.Searches)
+	require.Equal(t, 3, day.KeywordSearches)
+	require.Equal(t, 2, day.ZeroResultSearches)
+	require.Equal(t, 1, day.FailedSearches)
+	require.Equal(t, []shared.SearchCount{
+		{Value: "oysters", Searches: 2, ZeroResults: 1},
+		{Value: "tacos", Searches: 1, ZeroResults: 1},
+	}, day.TopQueries)
+	require.Equal(t, []shared.SearchCount{
+		{Value: "oysters", Searches: 2, ZeroResults: 1},
+		{Value: "tacos", Searches: 1, ZeroResults: 1},
+	}, day.ZeroResultQueries)
+	require.Equal(t, []shared.SearchCount{
+		{Value: "dietary:vegan", Searches: 1, ZeroResults: 1},
+		{Value: "listingType:happyhour", Searches: 1},
+	}, day.Filters)
+	require.Equal(t, []shared.SearchCount{
+		{Value: defaultSortBy, Searches: 3, ZeroResults: 2},
+		{Value: shared.SortByPrice, Searches: 1},
+	}, day.SortBy)
+
+	// nearby searches share a cell, places without coordinates stand apart
+	require.Len(t, day.Areas, 3)
+	require.Equal(t, "9q9hw", day.Areas[0].Geohash)
+	require.Equal(t, 2, day.Areas[0].Searches)
+	require.InDelta(t, 37.37, day.Areas[0].Latitude, 0.05)
+	require.Equal(t, "san jose", day.Areas[1].Location)
+	require.Equal(t, "sunnyvale", day.Areas[2].Location)
+
+	// searches logged without their outcome are never counted as zero results
+	require.Equal(t, 0, days[1].ZeroResultSearches)
+	require.Empty(t, days[1].ZeroResultQueries)
+	require.Equal(t, []shared.SearchCount{{Value: shared.SearchTomorrow, Searches: 2}}, days[1].SearchDays)
+
+	var csv bytes.Buffer
+	require.NoError(t, WriteSearchStatsCSV(&csv, days))
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	require.Equal(t, strings.Join(SearchStatsCSVHeader, ","), lines[0])
+	require.Equal(t, "2018-09-03,total,,4,2,,", lines[1])
+	require.Contains(t, lines, "2018-09-03,query,oysters,2,1,,")
+	require.Contains(t, lines, "2018-09-03,area,san jose,1,1,,")
+	// queries that look like formulas are written as text
+	require.Contains(t, lines, "2018-09-04,query,'=1+2,1,0,,")
+}
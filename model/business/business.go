@@ -6,16 +6,20 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/phassans/banana/clients/geocode"
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
 	"github.com/phassans/banana/model/user"
 	"github.com/phassans/banana/shared"
 	"github.com/rs/zerolog"
 )
 
 type businessEngine struct {
-	sql        *sql.DB
+	sql        db.Querier
 	logger     zerolog.Logger
 	userEngine user.UserEngine
+	geocoder   geocode.Geocoder
 }
 
 // BusinessEngine an interface for business operations
@@ -61,17 +65,48 @@ type BusinessEngine interface {
 	SubmitHappyHour(PhoneID string, Name string, Email string, BusinessOwner bool, Restaurant string, City string, Description string) (int, error)
 	SubmitHappyHourImages(happyHourID int, imageName string) (int, error)
 
+	// GetHappyHourSubmissions returns the submissions of a status, oldest first
+	GetHappyHourSubmissions(status string, page shared.Page) ([]shared.HappyHourSubmission, string, error)
+
+	// GetHappyHourSubmission returns a submission with its images
+	GetHappyHourSubmission(happyHourID int) (shared.HappyHourSubmission, error)
+
+	// ModerateHappyHour approves, rejects or marks a pending submission as a
+	// duplicate
+	ModerateHappyHour(happyHourID int, status string, reason string, businessID int) error
+
 	BusinessDelete(businessID int, userID int) error
 }
 
 // NewBusinessEngine returns an instance of businessEngine
-func NewBusinessEngine(psql *sql.DB, logger zerolog.Logger, userEngine user.UserEngine) BusinessEngine {
-	return &businessEngine{psql, logger, userEngine}
+func NewBusinessEngine(psql db.Querier, logger zerolog.Logger, userEngine user.UserEngine, geocoder geocode.Geocoder) BusinessEngine {
+	return &businessEngine{psql, logger, userEngine, geocoder}
+}
+
+// withTx returns a copy of the engine that runs its queries on tx
+func (b *businessEngine) withTx(tx db.Querier) *businessEngine {
+	engine := *b
+	engine.sql = tx
+	return &engine
+}
+
+// locate returns the location of a business address, addresses that do not
+// resolve are refused rather than stored without a location
+func (b *businessEngine) locate(address string) (shared.GeoLocation, error) {
+	geo, err := b.geocoder.Geocode(address)
+	if err == geocode.ErrNotFound {
+		return geo, helper.LocationError{Message: fmt.Sprintf("could not locate address: %s", address)}
+	}
+	return geo, err
 }
 
 func (b *businessEngine) GetAllBusiness(userID int) ([]shared.BusinessD, error) {
-	qry := fmt.Sprintf("SELECT business.business_id, name, phone, website,street,city,postal_code FROM business INNER JOIN business_address on business.business_id=business_address.business_id INNER JOIN user_to_business on business.business_id=user_to_business.business_id where user_id = %d;", userID)
-	rows, err := b.sql.Query(qry)
+	qry, args := common.Select("business.business_id", "name", "phone", "website", "street", "city", "postal_code").
+		From("business INNER JOIN business_address on business.business_id=business_address.business_id "+
+			"INNER JOIN user_to_business on business.business_id=user_to_business.business_id").
+		Where("user_id = ?", userID).
+		Build()
+	rows, err := b.sql.Query(qry, args...)
 	if err != nil {
 		return nil, helper.DatabaseError{DBError: err.Error()}
 	}
@@ -3,7 +3,7 @@ package business
 import (
 	"fmt"
 
-	"github.com/phassans/banana/clients"
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/shared"
 )
@@ -50,6 +50,33 @@ func (b *businessEngine) AddBusiness(
 		return businessID, 0, helper.DuplicateEntity{Name: businessName, ID: businessID, Message: fmt.Sprintf("Business with name %s already exists", businessName)}
 	}
 
+	var lastInsertBusinessID, addressID int
+	err = db.Transact(b.sql, func(tx db.Querier) error {
+		var err error
+		lastInsertBusinessID, addressID, err = b.withTx(tx).addBusiness(
+			businessName, phone, website, street, city, postalCode, state, hoursInfo, cuisine, userID)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	b.logger.Info().Msgf("business added successfully with id: %d", lastInsertBusinessID)
+	return lastInsertBusinessID, addressID, nil
+}
+
+func (b *businessEngine) addBusiness(
+	businessName string,
+	phone string,
+	website string,
+	street string,
+	city string,
+	postalCode string,
+	state string,
+	hoursInfo []shared.Hours,
+	cuisine []string,
+	userID int,
+) (int, int, error) {
 	lastInsertBusinessID, err := b.addBusinessInfo(businessName, phone, website)
 	if err != nil {
 		return 0, 0, err
@@ -83,7 +110,6 @@ func (b *businessEngine) AddBusiness(
 		b.logger.Info().Msgf("associate user to business success with businessID: %d", lastInsertBusinessID)
 	}
 
-	b.logger.Info().Msgf("business added successfully with id: %d", lastInsertBusinessID)
 	return lastInsertBusinessID, addressID, nil
 }
 
@@ -107,7 +133,7 @@ func (b *businessEngine) addBusinessAddress(
 
 	geoAddress := fmt.Sprintf("%s,%s,%s", street, city, state)
 	//geoAddress := fmt.Sprintf("%s", street)
-	resp, err := clients.GetLatLong(geoAddress)
+	geo, err := b.locate(geoAddress)
 	if err != nil {
 		return 0, err
 	}
@@ -121,8 +147,8 @@ func (b *businessEngine) addBusinessAddress(
 		postalCode,
 		state,
 		shared.CountryID,
-		resp.Lat,
-		resp.Lon,
+		geo.Latitude,
+		geo.Longitude,
 	).Scan(&addressID)
 	if err != nil {
 		return 0, helper.DatabaseError{DBError: err.Error()}
@@ -164,11 +190,10 @@ func (b *businessEngine) addHours(day string, openTime string, closeTime string,
 	addBusinessHoursSQL := "INSERT INTO business_hours(business_id,day,open_time,close_time) " +
 		"VALUES($1,$2,$3,$4);"
 
-	rows, err := b.sql.Query(addBusinessHoursSQL, businessID, day, openTime, closeTime)
+	_, err := b.sql.Exec(addBusinessHoursSQL, businessID, day, openTime, closeTime)
 	if err != nil {
 		return helper.DatabaseError{DBError: err.Error()}
 	}
-	defer rows.Close()
 
 	b.logger.Info().Msgf("add hours succesfull for businessID:%d", businessID)
 	return nil
@@ -197,11 +222,10 @@ func (b *businessEngine) addCuisine(businessID int, cuisine string) error {
 	addBusinessCuisineSQL := "INSERT INTO business_cuisine(business_id,cuisine) " +
 		"VALUES($1,$2);"
 
-	rows, err := b.sql.Query(addBusinessCuisineSQL, businessID, cuisine)
+	_, err := b.sql.Exec(addBusinessCuisineSQL, businessID, cuisine)
 	if err != nil {
 		return helper.DatabaseError{DBError: err.Error()}
 	}
-	defer rows.Close()
 
 	b.logger.Info().Msgf("add cuisine successful for businessID:%d", businessID)
 	return nil
@@ -211,11 +235,10 @@ func (b *businessEngine) associateUserToBusiness(businessID int, userID int) err
 	associateUserToBusinessSQL := "INSERT INTO user_to_business(business_id,user_id) " +
 		"VALUES($1,$2);"
 
-	rows, err := b.sql.Query(associateUserToBusinessSQL, businessID, userID)
+	_, err := b.sql.Exec(associateUserToBusinessSQL, businessID, userID)
 	if err != nil {
 		return helper.DatabaseError{DBError: err.Error()}
 	}
-	defer rows.Close()
 
 	b.logger.Info().Msgf("associateUserToBusiness successful for businessID:%d", businessID)
 	return nil
@@ -1,17 +1,22 @@
 package business
 
 import (
-	"bytes"
 	"database/sql"
 	"fmt"
 
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/model/common"
 	"github.com/phassans/banana/shared"
 )
 
 func (b *businessEngine) BusinessDelete(businessID int, userID int) error {
+	return db.Transact(b.sql, func(tx db.Querier) error {
+		return b.withTx(tx).businessDelete(businessID, userID)
+	})
+}
 
+func (b *businessEngine) businessDelete(businessID int, userID int) error {
 	listings, err := b.GetListingsByBusinessID(businessID)
 	if err != nil {
 		return err
@@ -22,7 +27,7 @@ func (b *businessEngine) BusinessDelete(businessID int, userID int) error {
 		for _, l := range listings {
 			lists += l.Title + ","
 		}
-		return helper.BusinessError{fmt.Sprintf("cannot delete business. Followings listings are tied - %s", lists[:len(lists)-1])}
+		return helper.BusinessError{Message: fmt.Sprintf("cannot delete business. Followings listings are tied - %s", lists[:len(lists)-1])}
 	}
 
 	if err := b.deleteBusinessHoursFromID(businessID); err != nil {
@@ -97,15 +102,12 @@ func (b *businessEngine) deleteBusinessHoursFromID(businessID int) error {
 }
 
 func (b *businessEngine) GetListingsByBusinessID(businessID int) ([]shared.Listing, error) {
-	selectFields := fmt.Sprintf("%s, %s", common.ListingFields, common.ListingBusinessFields)
-
-	var whereClause bytes.Buffer
-	whereClause.WriteString(fmt.Sprintf(" WHERE listing.business_id = %d", businessID))
-	query := fmt.Sprintf("%s %s %s;", selectFields, common.FromClauseBusinessListing, whereClause.String())
-
-	//fmt.Println("GetListingsByBusinessID ", query)
+	query, args := common.Select(common.ListingFields, common.ListingBusinessFields).
+		From(common.FromClauseBusinessListing).
+		Where("listing.business_id = ?", businessID).
+		Build()
 
-	rows, err := b.sql.Query(query)
+	rows, err := b.sql.Query(query, args...)
 	if err != nil {
 		return []shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
 	}
@@ -3,8 +3,9 @@ package business
 import (
 	"fmt"
 
-	"github.com/phassans/banana/clients"
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
 	"github.com/phassans/banana/shared"
 )
 
@@ -32,31 +33,56 @@ func (b *businessEngine) BusinessEdit(
 		return 0, helper.BusinessDoesNotExist{BusinessName: businessName}
 	}
 
+	err = db.Transact(b.sql, func(tx db.Querier) error {
+		return b.withTx(tx).businessEdit(businessName, phone, website, street, city, postalCode, state, hoursInfo, cuisine, businessID, addressID)
+	})
+	return 0, err
+}
+
+func (b *businessEngine) businessEdit(
+	businessName string,
+	phone string,
+	website string,
+	street string,
+	city string,
+	postalCode string,
+	state string,
+	hoursInfo []shared.Hours,
+	cuisine []string,
+	businessID int,
+	addressID int,
+) error {
 	// edit business
 	if err := b.editBusinessInfo(businessName, phone, website, businessID); err != nil {
-		return 0, err
+		return err
 	}
 	b.logger.Info().Msgf("editBusinessInfo success with businessID: %d", businessID)
 
 	// edit business address
 	if err := b.editBusinessAddress(street, city, postalCode, state, businessID, addressID); err != nil {
-		return 0, err
+		return err
 	}
 	b.logger.Info().Msgf("editBusinessAddress success with businessID: %d", businessID)
 
 	// edit business cuisine
 	if err := b.editBusinessCuisine(cuisine, businessID); err != nil {
-		return 0, err
+		return err
 	}
 	b.logger.Info().Msgf("editBusinessCuisine success with businessID: %d", businessID)
 
 	// edit business hours
 	if err := b.editBusinessHours(hoursInfo, businessID); err != nil {
-		return 0, err
+		return err
 	}
 	b.logger.Info().Msgf("editBusinessHours success with businessID: %d", businessID)
 
-	return 0, nil
+	// the name and cuisines are part of the search documents of the listings
+	if err := b.refreshSearchDocuments(businessID); err != nil {
+		return err
+	}
+	b.logger.Info().Msgf("refreshSearchDocuments success with businessID: %d", businessID)
+
+	return nil
 }
 
 func (b *businessEngine) editBusinessInfo(businessName string, phone string, website string, businessID int) error {
@@ -78,7 +104,7 @@ func (b *businessEngine) editBusinessAddress(street string, city string, postalC
 
 	// edit lat, long to database
 	geoAddress := fmt.Sprintf("%s,%s,%s", street, city, state)
-	resp, err := clients.GetLatLong(geoAddress)
+	geo, err := b.locate(geoAddress)
 	if err != nil {
 		return err
 	}
@@ -88,7 +114,7 @@ func (b *businessEngine) editBusinessAddress(street string, city string, postalC
 	SET street = $1, city = $2, postal_code = $3, state =$4, latitude=$5, longitude=$6
 	WHERE business_id = $7 AND address_id = $8;`
 
-	_, err = b.sql.Exec(updateBusinessAddressSQL, street, city, postalCode, state, resp.Lat, resp.Lon, businessID, addressID)
+	_, err = b.sql.Exec(updateBusinessAddressSQL, street, city, postalCode, state, geo.Latitude, geo.Longitude, businessID, addressID)
 	if err != nil {
 		return err
 	}
@@ -125,3 +151,11 @@ func (b *businessEngine) editBusinessCuisine(cuisines []string, businessID int)
 
 	return nil
 }
+
+func (b *businessEngine) refreshSearchDocuments(businessID int) error {
+	_, err := b.sql.Exec(fmt.Sprintf(common.UpsertListingSearchSQL, "listing.business_id = $1"), businessID)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	return nil
+}
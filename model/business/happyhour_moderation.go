@@ -0,0 +1,200 @@
+package business
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/shared"
+)
+
+const happyHourFields = "hh_id, phone_id, COALESCE(name, ''), COALESCE(email, ''), COALESCE(business_owner, ''), " +
+	"COALESCE(restaurant, ''), COALESCE(city, ''), COALESCE(description, ''), submission_date, status, reason, " +
+	"COALESCE(business_id, 0), moderated_date"
+
+// GetHappyHourSubmissions returns the submissions of status, oldest first,
+// with their images. Submissions are paged by id in the database.
+func (b *businessEngine) GetHappyHourSubmissions(status string, page shared.Page) ([]shared.HappyHourSubmission, string, error) {
+	if err := validateHappyHourStatus(status); err != nil {
+		return nil, "", err
+	}
+
+	q := common.Select(happyHourFields).
+		From("happyhour").
+		Where("status = ?", status).
+		OrderBy("hh_id")
+
+	last, ok, err := shared.Seek(page, status)
+	if err != nil {
+		return nil, "", helper.ValidationError{Message: err.Error()}
+	}
+	if ok {
+		q.Where("hh_id > ?", last.ID)
+	}
+	if page.Limit > 0 {
+		q.Limit(page.Limit + 1)
+	}
+	query, args := q.Build()
+	submissions, err := b.queryHappyHours(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys := make([]shared.PageKey, len(submissions))
+	for i, submission := range submissions {
+		keys[i] = shared.PageKey{ID: submission.HappyHourID}
+	}
+	n, next, err := shared.SeekNext(keys, status, page)
+	if err != nil {
+		return nil, "", helper.ValidationError{Message: err.Error()}
+	}
+	submissions = submissions[:n]
+
+	if err := b.setHappyHourImages(submissions); err != nil {
+		return nil, "", err
+	}
+	return submissions, next, nil
+}
+
+// GetHappyHourSubmission returns the submission happyHourID with its images
+func (b *businessEngine) GetHappyHourSubmission(happyHourID int) (shared.HappyHourSubmission, error) {
+	query, args := common.Select(happyHourFields).
+		From("happyhour").
+		Where("hh_id = ?", happyHourID).
+		Build()
+	submissions, err := b.queryHappyHours(query, args...)
+	if err != nil {
+		return shared.HappyHourSubmission{}, err
+	}
+	if len(submissions) == 0 {
+		return shared.HappyHourSubmission{}, helper.ValidationError{Message: fmt.Sprintf("happy hour %d does not exist", happyHourID)}
+	}
+
+	if err := b.setHappyHourImages(submissions); err != nil {
+		return shared.HappyHourSubmission{}, err
+	}
+	return submissions[0], nil
+}
+
+// ModerateHappyHour moves the pending submission happyHourID to status, for
+// reason, recording the business it was imported as or duplicates. A
+// submission is moderated once.
+func (b *businessEngine) ModerateHappyHour(happyHourID int, status string, reason string, businessID int) error {
+	if err := validateHappyHourStatus(status); err != nil {
+		return err
+	}
+	if status == shared.HappyHourPending {
+		return helper.ValidationError{Message: "happy hour moderation failed, a submission cannot be moved back to pending"}
+	}
+
+	result, err := b.sql.Exec("UPDATE happyhour SET status = $1, reason = $2, business_id = $3, moderated_date = $4 "+
+		"WHERE hh_id = $5 AND status = $6;", status, reason, shared.NewNullInt(businessID), time.Now(), happyHourID, shared.HappyHourPending)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	if moderated, err := result.RowsAffected(); err != nil || moderated == 1 {
+		return err
+	}
+
+	submission, err := b.GetHappyHourSubmission(happyHourID)
+	if err != nil {
+		return err
+	}
+	return helper.ValidationError{Message: fmt.Sprintf("happy hour %d was already %s", happyHourID, submission.Status)}
+}
+
+func (b *businessEngine) queryHappyHours(query string, args ...interface{}) ([]shared.HappyHourSubmission, error) {
+	rows, err := b.sql.Query(query, args...)
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	submissions := make([]shared.HappyHourSubmission, 0)
+	for rows.Next() {
+		var submission shared.HappyHourSubmission
+		var businessOwner string
+		var submissionDate time.Time
+		var moderatedDate pq.NullTime
+		err := rows.Scan(
+			&submission.HappyHourID,
+			&submission.PhoneID,
+			&submission.Name,
+			&submission.Email,
+			&businessOwner,
+			&submission.Restaurant,
+			&submission.City,
+			&submission.Description,
+			&submissionDate,
+			&submission.Status,
+			&submission.Reason,
+			&submission.BusinessID,
+			&moderatedDate,
+		)
+		if err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		submission.BusinessOwner, _ = strconv.ParseBool(businessOwner)
+		submission.SubmissionDate = submissionDate.Format(time.RFC3339)
+		if moderatedDate.Valid {
+			submission.ModeratedDate = moderatedDate.Time.Format(time.RFC3339)
+		}
+		submissions = append(submissions, submission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	return submissions, nil
+}
+
+// setHappyHourImages sets the images of submissions, in the order they were
+// submitted
+func (b *businessEngine) setHappyHourImages(submissions []shared.HappyHourSubmission) error {
+	ids := make([]interface{}, len(submissions))
+	index := make(map[int]int, len(submissions))
+	for i, submission := range submissions {
+		ids[i] = submission.HappyHourID
+		index[submission.HappyHourID] = i
+		submissions[i].Images = []string{}
+	}
+
+	query, args := common.Select("hh_id", "image_name").
+		From("happyhour_images").
+		WhereIn("hh_id", ids...).
+		OrderBy("hh_id, hh_image_id").
+		Build()
+	rows, err := b.sql.Query(query, args...)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var happyHourID int
+		var image sql.NullString
+		if err := rows.Scan(&happyHourID, &image); err != nil {
+			return helper.DatabaseError{DBError: err.Error()}
+		}
+		if image.String != "" {
+			submissions[index[happyHourID]].Images = append(submissions[index[happyHourID]].Images, image.String)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	return nil
+}
+
+func validateHappyHourStatus(status string) error {
+	switch status {
+	case shared.HappyHourPending, shared.HappyHourApproved, shared.HappyHourRejected, shared.HappyHourDuplicate:
+		return nil
+	}
+	return helper.ValidationError{Message: fmt.Sprintf("invalid happy hour status %q", status)}
+}
@@ -1,61 +1,82 @@
 package common
 
 const (
-	ListingFields = "SELECT listing.title as title, listing.old_price as old_price, listing.new_price as new_price, " +
+	ListingFields = "listing.title as title, listing.old_price as old_price, listing.new_price as new_price, " +
 		"listing.discount as discount, listing.discount_description as discount_description, listing.description as description, listing.start_date as start_date, " +
 		"listing.end_date as end_date, listing.start_time as start_time, listing.end_time as end_time, " +
 		"listing.multiple_days as multiple_days, " +
 		"listing.recurring as recurring, listing.recurring_end_date as recurring_date, listing.listing_type as listing_type, " +
 		"listing.business_id as business_id, listing.listing_id as listing_id, listing.listing_create_date as listing_create_date "
 
-	ListingFieldsDebug = "SELECT listing.title as title "
+	ListingFieldsDebug = "listing.title as title "
 
 	ListingDateFields = "listing_date.listing_date_id as listing_date_id, listing_date.listing_date as listing_date"
 
+	ListingDateTimeFields = "listing_date.start_time as date_start_time, listing_date.end_time as date_end_time"
+
 	ListingBusinessFields = "business.name as bname"
 
 	ListingBusinessAddressFields = "business_address.latitude as latitude, business_address.longitude as longitude "
 
-	ListingImageFields = "listing_image.path as path"
+	// ListingImageFields are the primary image of the listing, with its size
+	// when it was uploaded through the image pipeline, and the category its
+	// stock image is picked from when it has none
+	ListingImageFields = "COALESCE(listing_image.path, '') as path, COALESCE(image.width, 0) as image_width, " +
+		"COALESCE(image.height, 0) as image_height, listing.category as category"
 
-	FavoriteFields = "favorites.favorite_id as favorite_id, favorites.favorite_add_date as favorite_add_date"
+	FavoriteFields = "favorites.favorite_id as favorite_id, favorites.favorite_add_date as favorite_add_date, " +
+		"favorites.listing_date_id as listing_date_id"
 
-	FromClauseListing = "FROM listing " +
+	FromClauseListing = "listing " +
 		"INNER JOIN listing_date ON listing.listing_id = listing_date.listing_id " +
 		"INNER JOIN business ON listing.business_id = business.business_id " +
 		//"INNER JOIN business_cuisine ON listing.business_id = business_cuisine.business_id " +
-		"INNER JOIN listing_image ON listing.listing_id = listing_image.listing_id"
+		"LEFT JOIN listing_image ON listing.listing_id = listing_image.listing_id AND listing_image.is_primary " +
+		"LEFT JOIN image ON image.url = listing_image.path"
 
-	FromClauseListingWithAddress = "FROM listing " +
+	FromClauseListingWithAddress = "listing " +
 		"INNER JOIN listing_date ON listing.listing_id = listing_date.listing_id " +
 		"INNER JOIN business ON listing.business_id = business.business_id " +
 		//"INNER JOIN business_cuisine ON listing.business_id = business_cuisine.business_id " +
 		"INNER JOIN business_address ON listing.business_id = business_address.business_id " +
-		"INNER JOIN listing_image ON listing.listing_id = listing_image.listing_id"
+		"LEFT JOIN listing_image ON listing.listing_id = listing_image.listing_id AND listing_image.is_primary " +
+		"LEFT JOIN image ON image.url = listing_image.path"
 
-	FromClauseFavorites = "FROM favorites " +
+	FromClauseFavorites = "favorites " +
 		"INNER JOIN listing ON listing.listing_id = favorites.listing_id " +
-		"INNER JOIN listing_image ON listing_image.listing_id = favorites.listing_id " +
+		"LEFT JOIN listing_image ON listing_image.listing_id = favorites.listing_id AND listing_image.is_primary " +
+		"LEFT JOIN image ON image.url = listing_image.path " +
 		"INNER JOIN business ON listing.business_id = business.business_id " +
 		"INNER JOIN business_address ON listing.business_id = business_address.business_id "
 
-	FromClauseListingAdmin = "FROM listing " +
+	FromClauseListingAdmin = "listing " +
 		"INNER JOIN business ON listing.business_id = business.business_id " +
 		//"INNER JOIN business_cuisine ON listing.business_id = business_cuisine.business_id " +
-		"INNER JOIN listing_image ON listing.listing_id = listing_image.listing_id"
+		"LEFT JOIN listing_image ON listing.listing_id = listing_image.listing_id AND listing_image.is_primary " +
+		"LEFT JOIN image ON image.url = listing_image.path"
 
-	FromClauseBusinessListing = "FROM listing " +
+	FromClauseBusinessListing = "listing " +
 		"INNER JOIN business ON listing.business_id = business.business_id "
 
-	MaxFilterDistance = "15"
-
-	MaxFutureDays = 3
-
-	MaxDistanceForTodaysDeals = 15.0
-
-	MaxDistanceForFutureDeals = 25.0
-
-	MaxRangeAroundSunnyvale = 100.0
-
-	MaxDistanceToGroupNow = 7.5
+	// JoinListingSearch adds the search documents of the listings to one of
+	// the FromClauseListing* joins
+	JoinListingSearch = " INNER JOIN listing_search ON listing.listing_id = listing_search.listing_id"
+
+	// UpsertListingSearchSQL rebuilds the search documents of the listings
+	// matching the condition it is formatted with. Titles and business names
+	// weigh most, then cuisines, discounts and descriptions.
+	UpsertListingSearchSQL = "INSERT INTO listing_search(listing_id, document, words) " +
+		"SELECT listing.listing_id, " +
+		"setweight(to_tsvector('english', listing.title), 'A') || " +
+		"setweight(to_tsvector('english', business.name), 'A') || " +
+		"setweight(to_tsvector('english', coalesce(cuisines.cuisine, '')), 'B') || " +
+		"setweight(to_tsvector('english', coalesce(listing.discount_description, '')), 'C') || " +
+		"setweight(to_tsvector('english', coalesce(listing.description, '')), 'D'), " +
+		"concat_ws(' ', listing.title, business.name, cuisines.cuisine, listing.discount_description, listing.description) " +
+		"FROM listing " +
+		"INNER JOIN business ON listing.business_id = business.business_id " +
+		"LEFT JOIN (SELECT business_id, string_agg(cuisine, ' ') as cuisine FROM business_cuisine GROUP BY business_id) cuisines " +
+		"ON listing.business_id = cuisines.business_id " +
+		"WHERE %s " +
+		"ON CONFLICT (listing_id) DO UPDATE SET document = EXCLUDED.document, words = EXCLUDED.words"
 )
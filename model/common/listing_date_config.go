@@ -0,0 +1,19 @@
+package common
+
+// ListingDateConfig holds how far ahead listing dates of recurring listings
+// are materialized and how often the window is rolled forward.
+type ListingDateConfig struct {
+	// WindowWeeks is how many weeks of listing_date rows are kept ahead of today
+	WindowWeeks int `json:"windowWeeks"`
+	// RefreshIntervalMinutes is how often the window is rolled forward, 0 disables it
+	RefreshIntervalMinutes int `json:"refreshIntervalMinutes"`
+}
+
+// DefaultListingDateConfig returns the listing date window used when nothing
+// is overridden.
+func DefaultListingDateConfig() ListingDateConfig {
+	return ListingDateConfig{
+		WindowWeeks:            4,
+		RefreshIntervalMinutes: 60,
+	}
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Query composes a SELECT statement whose values are always passed as bound
+// parameters. Conditions are written with `?` placeholders, which Build
+// numbers as $1, $2, ... in the order they appear in the statement. Column
+// names, joins and orderings are trusted fragments and must never be built
+// from user input.
+type Query struct {
+	fields    []string
+	fieldArgs []interface{}
+	from      string
+	args      []interface{}
+	where     []string
+	groupBy   string
+	orderBy   string
+	limit     int
+}
+
+// Select starts a query returning the given fields.
+func Select(fields ...string) *Query {
+	return &Query{fields: fields}
+}
+
+// Field adds a computed field, binding args to its `?` placeholders.
+func (q *Query) Field(field string, args ...interface{}) *Query {
+	q.fields = append(q.fields, field)
+	q.fieldArgs = append(q.fieldArgs, args...)
+	return q
+}
+
+// From sets the FROM clause, e.g. one of the FromClause* joins.
+func (q *Query) From(from string) *Query {
+	q.from = from
+	return q
+}
+
+// FromSubquery selects from sub aliased as alias. The subquery's arguments
+// are bound ahead of any added to q by Where.
+func (q *Query) FromSubquery(sub *Query, alias string) *Query {
+	sql, args := sub.raw()
+	q.from = "(" + sql + ") " + alias
+	q.args = append(append([]interface{}{}, args...), q.args...)
+	return q
+}
+
+// Where adds a condition, ANDed with the others, binding args to its `?`
+// placeholders.
+func (q *Query) Where(condition string, args ...interface{}) *Query {
+	q.where = append(q.where, condition)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// WhereIn adds `column IN (...)` with one bound parameter per value. An empty
+// list matches nothing.
+func (q *Query) WhereIn(column string, values ...interface{}) *Query {
+	if len(values) == 0 {
+		return q.Where("FALSE")
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(values)), ",")
+	return q.Where(column+" IN ("+placeholders+")", values...)
+}
+
+// GroupBy sets the GROUP BY clause.
+func (q *Query) GroupBy(groupBy string) *Query {
+	q.groupBy = groupBy
+	return q
+}
+
+// OrderBy sets the ORDER BY clause.
+func (q *Query) OrderBy(orderBy string) *Query {
+	q.orderBy = orderBy
+	return q
+}
+
+// Limit caps the number of rows returned.
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+// Build returns the statement with numbered placeholders and its arguments.
+func (q *Query) Build() (string, []interface{}) {
+	sql, args := q.raw()
+	return numberPlaceholders(sql), args
+}
+
+// raw renders the statement with `?` placeholders, so it can be embedded
+// into an outer query before numbering.
+func (q *Query) raw() (string, []interface{}) {
+	var sql bytes.Buffer
+	sql.WriteString("SELECT ")
+	sql.WriteString(strings.Join(q.fields, ", "))
+	if q.from != "" {
+		sql.WriteString(" FROM ")
+		sql.WriteString(q.from)
+	}
+	if len(q.where) > 0 {
+		sql.WriteString(" WHERE (")
+		sql.WriteString(strings.Join(q.where, ") AND ("))
+		sql.WriteString(")")
+	}
+	if q.groupBy != "" {
+		sql.WriteString(" GROUP BY ")
+		sql.WriteString(q.groupBy)
+	}
+	if q.orderBy != "" {
+		sql.WriteString(" ORDER BY ")
+		sql.WriteString(q.orderBy)
+	}
+	if q.limit > 0 {
+		sql.WriteString(" LIMIT ")
+		sql.WriteString(strconv.Itoa(q.limit))
+	}
+	return sql.String(), append(append([]interface{}{}, q.fieldArgs...), q.args...)
+}
+
+func numberPlaceholders(sql string) string {
+	var numbered bytes.Buffer
+	n := 0
+	for _, r := range sql {
+		if r == '?' {
+			n++
+			numbered.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		numbered.WriteRune(r)
+	}
+	return numbered.String()
+}
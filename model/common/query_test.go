@@ -0,0 +1,73 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryBuildNumbersPlaceholders(t *testing.T) {
+	query, args := Select("listing.title", "business.name").
+		From(FromClauseBusinessListing).
+		Where("listing.business_id = ?", 7).
+		WhereIn("listing.listing_type", "happyhour", "meal").
+		OrderBy("listing.title").
+		Build()
+
+	require.Equal(t, "SELECT listing.title, business.name FROM "+FromClauseBusinessListing+
+		" WHERE (listing.business_id = $1) AND (listing.listing_type IN ($2,$3)) ORDER BY listing.title", query)
+	require.Equal(t, []interface{}{7, "happyhour", "meal"}, args)
+}
+
+func TestQueryKeepsValuesOutOfSQL(t *testing.T) {
+	phoneID := "x'; DROP TABLE favorites; --"
+	query, args := Select("favorite_id").From("favorites").Where("phone_id = ?", phoneID).Build()
+
+	require.NotContains(t, query, phoneID)
+	require.Equal(t, []interface{}{phoneID}, args)
+}
+
+func TestQueryWhereInEmptyMatchesNothing(t *testing.T) {
+	query, args := Select("address_id").From("business_address").WhereIn("business_id").Build()
+
+	require.Equal(t, "SELECT address_id FROM business_address WHERE (FALSE)", query)
+	require.Empty(t, args)
+}
+
+func TestQueryFromSubqueryBindsInnerArgsFirst(t *testing.T) {
+	inner := Select("title", "document").From("listing").Where("listing_date = ?", "2018-07-11")
+	query, args := Select("title").
+		FromSubquery(inner, "p_search").
+		Where("p_search.document @@ to_tsquery('english', ?)", "tacos").
+		Build()
+
+	require.Equal(t, "SELECT title FROM (SELECT title, document FROM listing WHERE (listing_date = $1)) p_search "+
+		"WHERE (p_search.document @@ to_tsquery('english', $2))", query)
+	require.Equal(t, []interface{}{"2018-07-11", "tacos"}, args)
+}
+
+func TestQueryFieldArgsAreBoundFirst(t *testing.T) {
+	query, args := Select("title").
+		Field("abs(latitude - ?) as distance", 37.3).
+		From("business_address").
+		Where("latitude > ?", 30).
+		OrderBy("distance").
+		Build()
+
+	require.Equal(t, "SELECT title, abs(latitude - $1) as distance FROM business_address WHERE (latitude > $2) ORDER BY distance", query)
+	require.Equal(t, []interface{}{37.3, 30}, args)
+}
+
+func TestQueryGroupByAndLimit(t *testing.T) {
+	query, args := Select("cuisine", "count(*) as businesses").
+		From("business_cuisine").
+		Where("cuisine ILIKE ?", "mex%").
+		GroupBy("cuisine").
+		OrderBy("businesses DESC").
+		Limit(5).
+		Build()
+
+	require.Equal(t, "SELECT cuisine, count(*) as businesses FROM business_cuisine WHERE (cuisine ILIKE $1) "+
+		"GROUP BY cuisine ORDER BY businesses DESC LIMIT 5", query)
+	require.Equal(t, []interface{}{"mex%"}, args)
+}
@@ -0,0 +1,79 @@
+package common
+
+import "strings"
+
+// SearchConfig holds the search tunables that vary between deployments.
+// Distances are in miles.
+type SearchConfig struct {
+	// MaxDistanceForTodaysDeals is the search radius for deals happening today
+	MaxDistanceForTodaysDeals float64 `json:"maxDistanceForTodaysDeals"`
+	// MaxDistanceForFutureDeals is the search radius for upcoming deals
+	MaxDistanceForFutureDeals float64 `json:"maxDistanceForFutureDeals"`
+	// MaxDistanceToGroupNow is how close a live deal must be to be listed first
+	MaxDistanceToGroupNow float64 `json:"maxDistanceToGroupNow"`
+	// MaxFilterDistance is the radius used when the distance filter is "all"
+	MaxFilterDistance float64 `json:"maxFilterDistance"`
+	// MaxFutureDays is how many days ahead a future search looks
+	MaxFutureDays int `json:"maxFutureDays"`
+	// Cities overrides the distances for searches in a city, keyed by its
+	// name in lower case
+	Cities map[string]CitySearchConfig `json:"cities,omitempty"`
+}
+
+// CitySearchConfig holds the distances of a city that differ from the
+// defaults, zero keeps the default
+type CitySearchConfig struct {
+	MaxDistanceForTodaysDeals float64 `json:"maxDistanceForTodaysDeals"`
+	MaxDistanceForFutureDeals float64 `json:"maxDistanceForFutureDeals"`
+	MaxDistanceToGroupNow     float64 `json:"maxDistanceToGroupNow"`
+	MaxFilterDistance         float64 `json:"maxFilterDistance"`
+}
+
+// DefaultSearchConfig returns the search tunables for the bay area.
+func DefaultSearchConfig() SearchConfig {
+	return SearchConfig{
+		MaxDistanceForTodaysDeals: 15.0,
+		MaxDistanceForFutureDeals: 25.0,
+		MaxDistanceToGroupNow:     7.5,
+		MaxFilterDistance:         15.0,
+		MaxFutureDays:             3,
+	}
+}
+
+// ForCity returns the search tunables for searches in city
+func (c SearchConfig) ForCity(city string) SearchConfig {
+	override, ok := c.Cities[strings.ToLower(strings.TrimSpace(city))]
+	if !ok {
+		return c
+	}
+
+	if override.MaxDistanceForTodaysDeals > 0 {
+		c.MaxDistanceForTodaysDeals = override.MaxDistanceForTodaysDeals
+	}
+	if override.MaxDistanceForFutureDeals > 0 {
+		c.MaxDistanceForFutureDeals = override.MaxDistanceForFutureDeals
+	}
+	if override.MaxDistanceToGroupNow > 0 {
+		c.MaxDistanceToGroupNow = override.MaxDistanceToGroupNow
+	}
+	if override.MaxFilterDistance > 0 {
+		c.MaxFilterDistance = override.MaxFilterDistance
+	}
+	return c
+}
+
+// WithinRadius returns the search tunables capped at radius, the search
+// radius of a region. Distances that are not configured are the radius.
+// A radius of zero leaves them as they are.
+func (c SearchConfig) WithinRadius(radius float64) SearchConfig {
+	if radius <= 0 {
+		return c
+	}
+	for _, distance := range []*float64{&c.MaxDistanceForTodaysDeals, &c.MaxDistanceForFutureDeals,
+		&c.MaxDistanceToGroupNow, &c.MaxFilterDistance} {
+		if *distance <= 0 || *distance > radius {
+			*distance = radius
+		}
+	}
+	return c
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchConfigForCity(t *testing.T) {
+	cfg := DefaultSearchConfig()
+	cfg.Cities = map[string]CitySearchConfig{"san francisco": {MaxDistanceForTodaysDeals: 3, MaxFilterDistance: 5}}
+
+	sf := cfg.ForCity(" San Francisco")
+	require.Equal(t, 3.0, sf.MaxDistanceForTodaysDeals)
+	require.Equal(t, 5.0, sf.MaxFilterDistance)
+	require.Equal(t, cfg.MaxDistanceForFutureDeals, sf.MaxDistanceForFutureDeals)
+	require.Equal(t, cfg.MaxDistanceToGroupNow, sf.MaxDistanceToGroupNow)
+
+	require.Equal(t, cfg, cfg.ForCity("Sunnyvale"))
+	require.Equal(t, cfg, cfg.ForCity(""))
+}
+
+func TestSearchConfigWithinRadius(t *testing.T) {
+	cfg := DefaultSearchConfig()
+	cfg.Cities = map[string]CitySearchConfig{"san francisco": {MaxDistanceForTodaysDeals: 40, MaxFilterDistance: 5}}
+
+	// city distances past the radius of the region are capped, future
+	// searches included
+	sf := cfg.ForCity("San Francisco").WithinRadius(10)
+	require.Equal(t, 10.0, sf.MaxDistanceForTodaysDeals)
+	require.Equal(t, 10.0, sf.MaxDistanceForFutureDeals)
+	require.Equal(t, 7.5, sf.MaxDistanceToGroupNow)
+	require.Equal(t, 5.0, sf.MaxFilterDistance)
+
+	// distances that are not configured are the radius
+	require.Equal(t, 10.0, SearchConfig{}.WithinRadius(10).MaxDistanceForTodaysDeals)
+	require.Equal(t, cfg, cfg.WithinRadius(0))
+}
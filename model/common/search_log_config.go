@@ -0,0 +1,22 @@
+package common
+
+// SearchLogConfig holds how searches are queued and batched on their way to
+// the search log.
+type SearchLogConfig struct {
+	// QueueSize is how many searches may wait to be written, more are dropped
+	QueueSize int `json:"queueSize"`
+	// BatchSize is how many searches are written per insert at most
+	BatchSize int `json:"batchSize"`
+	// FlushIntervalMS is how long a search waits for its batch to fill up
+	FlushIntervalMS int `json:"flushIntervalMs"`
+}
+
+// DefaultSearchLogConfig returns the search log settings used when nothing is
+// overridden.
+func DefaultSearchLogConfig() SearchLogConfig {
+	return SearchLogConfig{
+		QueueSize:       1000,
+		BatchSize:       100,
+		FlushIntervalMS: 1000,
+	}
+}
@@ -1,15 +1,14 @@
 package donforgetto
 
 import (
-	"database/sql"
-
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/shared"
 	"github.com/rs/zerolog"
 )
 
 type (
 	donforgettoEngine struct {
-		sql    *sql.DB
+		sql    db.Querier
 		logger zerolog.Logger
 	}
 
@@ -21,7 +20,7 @@ type (
 )
 
 // NewDonforgettoEngine returns an instance of donforgettoEngine
-func NewDonforgettoEngine(psql *sql.DB, logger zerolog.Logger) DonforgettoEngine {
+func NewDonforgettoEngine(psql db.Querier, logger zerolog.Logger) DonforgettoEngine {
 	return &donforgettoEngine{psql, logger}
 }
 
@@ -1,17 +1,28 @@
 package model
 
 import (
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/model/analytics"
 	"github.com/phassans/banana/model/business"
 	"github.com/phassans/banana/model/donforgetto"
 	"github.com/phassans/banana/model/favourite"
 	"github.com/phassans/banana/model/listing"
 	"github.com/phassans/banana/model/notification"
 	"github.com/phassans/banana/model/prefernce"
+	"github.com/phassans/banana/model/region"
+	"github.com/phassans/banana/model/stockimage"
 	"github.com/phassans/banana/model/upvote"
 	"github.com/phassans/banana/model/user"
 )
 
+// EngineBuilder wires every engine on top of the given querier. It is used
+// to rebuild the engines inside a transaction.
+type EngineBuilder func(q db.Querier) Engine
+
 type genericEngine struct {
+	sql   db.Querier
+	build EngineBuilder
+
 	business.BusinessEngine
 	listing.ListingEngine
 	user.UserEngine
@@ -20,18 +31,28 @@ type genericEngine struct {
 	prefernce.PreferenceEngine
 	upvote.UpvoteEngine
 	donforgetto.DonforgettoEngine
+	analytics.AnalyticsEngine
+	region.RegionEngine
+	stockimage.StockImageEngine
 }
 
 // NewGenericEngine returns genericEngine
-func NewGenericEngine(businessEngine business.BusinessEngine,
+func NewGenericEngine(psql db.Querier,
+	build EngineBuilder,
+	businessEngine business.BusinessEngine,
 	userEngine user.UserEngine,
 	listingEngine listing.ListingEngine,
 	favouriteEngine favourite.FavoriteEngine,
 	notificationEngine notification.NotificationEngine,
 	preferenceEngine prefernce.PreferenceEngine,
 	upvoteEngine upvote.UpvoteEngine,
-	donforgettoEngine donforgetto.DonforgettoEngine) Engine {
+	donforgettoEngine donforgetto.DonforgettoEngine,
+	analyticsEngine analytics.AnalyticsEngine,
+	regionEngine region.RegionEngine,
+	stockImageEngine stockimage.StockImageEngine) Engine {
 	return &genericEngine{
+		psql,
+		build,
 		businessEngine,
 		listingEngine,
 		userEngine,
@@ -40,6 +61,9 @@ func NewGenericEngine(businessEngine business.BusinessEngine,
 		preferenceEngine,
 		upvoteEngine,
 		donforgettoEngine,
+		analyticsEngine,
+		regionEngine,
+		stockImageEngine,
 	}
 }
 
@@ -53,4 +77,18 @@ type Engine interface {
 	prefernce.PreferenceEngine
 	upvote.UpvoteEngine
 	donforgetto.DonforgettoEngine
+	analytics.AnalyticsEngine
+	region.RegionEngine
+	stockimage.StockImageEngine
+
+	// Transact runs f with engines bound to a single transaction
+	Transact(f func(engines Engine) error) error
+}
+
+// Transact runs f with engines bound to a single transaction, so work that
+// spans engines commits or rolls back as a whole.
+func (g *genericEngine) Transact(f func(engines Engine) error) error {
+	return db.Transact(g.sql, func(tx db.Querier) error {
+		return f(g.build(tx))
+	})
 }
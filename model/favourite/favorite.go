@@ -1,11 +1,10 @@
 package favourite
 
 import (
-	"bytes"
 	"database/sql"
-	"fmt"
 	"time"
 
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/model/business"
 	"github.com/phassans/banana/model/common"
@@ -16,23 +15,25 @@ import (
 
 type (
 	favoriteEngine struct {
-		sql            *sql.DB
+		sql            db.Querier
 		logger         zerolog.Logger
 		businessEngine business.BusinessEngine
 		listingEngine  listing.ListingEngine
+		searchConfig   common.SearchConfig
 	}
 
 	// FavoriteEngine interface which holds all methods
 	FavoriteEngine interface {
 		AddFavorite(phoneID string, listingID int, listingDateID int) error
 		DeleteFavorite(phoneID string, listingID int, listingDateID int) error
-		GetAllFavorites(phoneID string, sortBy string, latitude float64, longitude float64, location string) ([]shared.SearchListingResult, error)
+		GetAllFavorites(phoneID string, sortBy string, latitude float64, longitude float64, location string, page shared.Page) ([]shared.SearchListingResult, string, error)
 	}
 )
 
 // NewFavoriteEngine returns an instance of favoriteEngine
-func NewFavoriteEngine(psql *sql.DB, logger zerolog.Logger, businessEngine business.BusinessEngine, listingEngine listing.ListingEngine) FavoriteEngine {
-	return &favoriteEngine{psql, logger, businessEngine, listingEngine}
+func NewFavoriteEngine(psql db.Querier, logger zerolog.Logger, businessEngine business.BusinessEngine,
+	listingEngine listing.ListingEngine, searchConfig common.SearchConfig) FavoriteEngine {
+	return &favoriteEngine{psql, logger, businessEngine, listingEngine, searchConfig}
 }
 
 func (f *favoriteEngine) AddFavorite(phoneID string, listingID int, listingDateID int) error {
@@ -73,7 +74,7 @@ func (f *favoriteEngine) DeleteFavorite(phoneID string, listingID int, listingDa
 		return nil, err
 	}
 
-	sortEngine := listing.NewSortListingEngine(listings, sortBy, shared.CurrentLocation{Latitude: latitude, Longitude: longitude}, f.sql)
+	sortEngine := listing.NewSortListingEngine(listings, sortBy, shared.CurrentLocation{Latitude: latitude, Longitude: longitude}, f.sql, f.searchConfig)
 	listings, err = sortEngine.SortListings()
 	if err != nil {
 		return nil, err
@@ -83,7 +84,8 @@ func (f *favoriteEngine) DeleteFavorite(phoneID string, listingID int, listingDa
 	return f.listingEngine.MassageAndPopulateSearchListings(listings)
 }*/
 
-func (f *favoriteEngine) GetAllFavorites(phoneID string, sortBy string, latitude float64, longitude float64, location string) ([]shared.SearchListingResult, error) {
+func (f *favoriteEngine) GetAllFavorites(phoneID string, sortBy string, latitude float64, longitude float64,
+	location string, page shared.Page) ([]shared.SearchListingResult, string, error) {
 	/*favorites, err := f.GetAllFavoritesIDs(phoneID)
 	if err != nil {
 		return nil, err
@@ -102,23 +104,37 @@ func (f *favoriteEngine) GetAllFavorites(phoneID string, sortBy string, latitude
 
 	geoLocation, err := f.listingEngine.DetermineCurrentLocation(location, latitude, longitude)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	listings, err := f.GetListingsPhoneID(phoneID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	sortEngine := listing.NewSortListingEngine(listings, sortBy, geoLocation, f.sql)
+	sortEngine := listing.NewSortListingEngine(listings, sortBy, geoLocation, f.sql, f.searchConfig)
 	listings, err = sortEngine.SortListings(false, "", false, true)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	f.logger.Info().Msgf("done sorting the listings in favorite. listings count: %d", len(listings))
 
-	return f.listingEngine.MassageAndPopulateSearchListingsFavorites(listings, true, "")
+	results, err := f.listingEngine.MassageAndPopulateSearchListingsFavorites(listings, true, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// without a location favorites are listed as they were added
+	order := sortBy
+	if order == "" && geoLocation.Latitude == 0 && geoLocation.Longitude == 0 {
+		order = shared.SortByDateAdded
+	}
 
+	keys := make([]shared.PageKey, len(results))
+	for i := range results {
+		keys[i] = listing.PageKey(order, listings[i], false)
+	}
+	return listing.PageResults(results, keys, sortBy, page)
 }
 
 func (f *favoriteEngine) GetAllFavoritesIDs(phoneID string) ([]shared.Favorite, error) {
@@ -162,16 +178,13 @@ const (
 
 func (f *favoriteEngine) GetListingsPhoneID(phoneID string) ([]shared.Listing, error) {
 
-	var whereClause bytes.Buffer
-	whereClause.WriteString(fmt.Sprintf(" WHERE favorites.phone_id = '%s'", phoneID))
-
-	selectFields := fmt.Sprintf("%s, %s, %s, %s, %s", common.ListingFields, common.ListingBusinessFields, common.ListingBusinessAddressFields, common.ListingImageFields, common.FavoriteFields)
-	//fmt.Println("selectFields: ", selectFields)
-
-	query := fmt.Sprintf("%s %s %s order by favorites.favorite_add_date desc;", selectFields, common.FromClauseFavorites, whereClause.String())
-	rows, err := f.sql.Query(query)
+	query, args := common.Select(common.ListingFields, common.ListingBusinessFields, common.ListingBusinessAddressFields, common.ListingImageFields, common.FavoriteFields).
+		From(common.FromClauseFavorites).
+		Where("favorites.phone_id = ?", phoneID).
+		OrderBy("favorites.favorite_add_date desc").
+		Build()
 
-	//fmt.Println("GetListingsPhoneID ", query)
+	rows, err := f.sql.Query(query, args...)
 
 	if err != nil {
 		return nil, helper.DatabaseError{DBError: err.Error()}
@@ -185,6 +198,7 @@ func (f *favoriteEngine) GetListingsPhoneID(phoneID string) ([]shared.Listing, e
 		var sqlRecurringEndDate sql.NullString
 		var sqlFavoriteAddDate sql.NullString
 		var sqlCreateDate sql.NullString
+		var sqlListingDateID sql.NullInt64
 		var listing shared.Listing
 		var fid int
 		err = rows.Scan(
@@ -209,11 +223,16 @@ func (f *favoriteEngine) GetListingsPhoneID(phoneID string) ([]shared.Listing, e
 			&listing.Latitude,
 			&listing.Longitude,
 			&listing.ListingImage,
+			&listing.ImageWidth,
+			&listing.ImageHeight,
+			&listing.Category,
 			&fid,
 			&sqlFavoriteAddDate,
+			&sqlListingDateID,
 		)
 
-		listing.Favorite = &shared.Favorite{fid, listing.ListingID, listing.ListingDateID, sqlFavoriteAddDate.String}
+		listing.ListingDateID = int(sqlListingDateID.Int64)
+		listing.Favorite = &shared.Favorite{FavoriteID: fid, ListingID: listing.ListingID, ListingDateID: listing.ListingDateID, FavoriteAddDate: sqlFavoriteAddDate.String}
 		listing.StartDate = sqlEndDate.String
 		listing.RecurringEndDate = sqlRecurringEndDate.String
 		listing.ListingCreateDate = sqlCreateDate.String
@@ -0,0 +1,92 @@
+package listing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type (
+	// DateMaterializer rolls the listing date window forward
+	DateMaterializer interface {
+		MaterializeListingDates(now time.Time) (MaterializeResult, error)
+	}
+
+	// DateScheduler periodically keeps the listing dates of recurring listings
+	// materialized and remembers how its last run went
+	DateScheduler struct {
+		logger   zerolog.Logger
+		engine   DateMaterializer
+		interval time.Duration
+
+		mu     sync.Mutex
+		status DateSchedulerStatus
+	}
+
+	// DateSchedulerStatus describes the last run of a DateScheduler
+	DateSchedulerStatus struct {
+		Running    bool      `json:"running"`
+		StartedAt  time.Time `json:"startedAt"`
+		FinishedAt time.Time `json:"finishedAt"`
+		MaterializeResult
+		Error string `json:"error,omitempty"`
+	}
+)
+
+// NewDateScheduler returns a DateScheduler running every interval once started
+func NewDateScheduler(logger zerolog.Logger, engine DateMaterializer, interval time.Duration) *DateScheduler {
+	return &DateScheduler{
+		logger:   logger,
+		engine:   engine,
+		interval: interval,
+	}
+}
+
+// Run materializes once right away and then every interval until stop is
+// closed
+func (s *DateScheduler) Run(stop <-chan struct{}) {
+	s.RunOnce()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.RunOnce()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RunOnce materializes the listing date window and records the outcome
+func (s *DateScheduler) RunOnce() DateSchedulerStatus {
+	s.mu.Lock()
+	s.status = DateSchedulerStatus{Running: true, StartedAt: time.Now()}
+	s.mu.Unlock()
+
+	result, err := s.engine.MaterializeListingDates(time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Running = false
+	s.status.FinishedAt = time.Now()
+	s.status.MaterializeResult = result
+	if err != nil {
+		s.status.Error = err.Error()
+		s.logger.Error().Msgf("listing date materialization failed: %s", err)
+	} else {
+		s.logger.Info().Msgf("listing date materialization done. listings: %d, added: %d, failed: %d, pruned: %d",
+			result.Listings, result.Added, result.Failed, result.Pruned)
+	}
+	return s.status
+}
+
+// Status returns how the last run went, or the run in progress
+func (s *DateScheduler) Status() DateSchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
@@ -1,16 +1,19 @@
 package listing
 
 import (
-	"bytes"
 	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/bradfitz/latlong"
+	"github.com/phassans/banana/clients/geocode"
+	"github.com/phassans/banana/clients/media"
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/model/business"
 	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/model/region"
+	"github.com/phassans/banana/model/stockimage"
 	"github.com/phassans/banana/shared"
 	"github.com/rs/zerolog"
 	"github.com/umahmood/haversine"
@@ -18,10 +21,22 @@ import (
 
 type (
 	listingEngine struct {
-		sql            *sql.DB
+		sql            db.Querier
 		logger         zerolog.Logger
 		businessEngine business.BusinessEngine
-		geoMap         map[string]shared.GeoLocation
+		regionEngine   region.RegionEngine
+		stockImages    stockimage.StockImageEngine
+		geocoder       geocode.Geocoder
+		variants       media.Variants
+		searchConfig   common.SearchConfig
+		searchLog      SearchLogger
+
+		listingDateConfig common.ListingDateConfig
+
+		// region is the region searched in, see forLocation, and language
+		// the one listings are shown in, see withLanguage
+		region   shared.Region
+		language string
 	}
 
 	// ListingEngine interface which holds all listing methods
@@ -32,9 +47,13 @@ type (
 
 		// SearchListings is to search for listings
 		SearchListings(request shared.SearchRequest) ([]shared.SearchListingResult, error)
+		SearchListingsPage(request shared.SearchRequest) (shared.SearchPage, error)
+
+		// SuggestSearches completes a search being typed
+		SuggestSearches(prefix string, latitude float64, longitude float64, location string, limit int) ([]shared.Suggestion, error)
 
 		// GetListingsByBusinessID returns listing based on businessID
-		GetListingsByBusinessID(businessID int, businessType string) ([]shared.Listing, error)
+		GetListingsByBusinessID(businessID int, businessType string, page shared.Page) ([]shared.Listing, string, error)
 
 		// GetListingByID returns listing based on ID
 		GetListingByID(listingID int, businessID int, listingDateID int) (shared.Listing, error)
@@ -42,11 +61,14 @@ type (
 		GetListingByIDAdmin(listingID int) (shared.Listing, error)
 
 		// GetListingInfo returns listing info
-		GetListingInfo(listingID int, phoneID string, latitude float64, longitude float64, location string) (shared.Listing, error)
+		GetListingInfo(listingID int, phoneID string, latitude float64, longitude float64, location string, language string) (shared.Listing, error)
 
 		// GetListingInfo returns listing info
 		UpdateListingDate(listingID int) error
 
+		// MaterializeListingDates keeps the dates of recurring listings materialized
+		MaterializeListingDates(now time.Time) (MaterializeResult, error)
+
 		// MassageAndPopulateSearchListings to massage and populate search result
 		MassageAndPopulateSearchListings([]shared.Listing, bool, string) ([]shared.SearchListingResult, error)
 
@@ -65,73 +87,110 @@ type (
 		// GetListingImage returns image of the listing
 		GetListingImage(listingID int) (string, error)
 
-		GetGeoFromAddress(string) (shared.GeoLocation, error)
+		// GetListingImages returns the gallery of the listing in order
+		GetListingImages(listingID int) ([]shared.GalleryImage, error)
 
-		AddGeoLocation(string, shared.GeoLocation) error
+		// SetListingImages orders, captions and removes images of the listing
+		SetListingImages(listingID int, images []shared.GalleryImage) error
+
+		// TagListings derives the category of every listing again
+		TagListings() (int, error)
 
 		DetermineCurrentLocation(string, float64, float64) (shared.GeoLocation, error)
 	}
 )
 
 // NewListingEngine returns a instance of listingEngine
-func NewListingEngine(psql *sql.DB, logger zerolog.Logger, businessEngine business.BusinessEngine) ListingEngine {
-	//create geolocationmap
-	geoMap := make(map[string]shared.GeoLocation)
-	return &listingEngine{psql, logger, businessEngine, geoMap}
+func NewListingEngine(psql db.Querier, logger zerolog.Logger, businessEngine business.BusinessEngine, regionEngine region.RegionEngine,
+	stockImages stockimage.StockImageEngine, geocoder geocode.Geocoder, variants media.Variants, searchConfig common.SearchConfig,
+	listingDateConfig common.ListingDateConfig, searchLog SearchLogger) ListingEngine {
+	return &listingEngine{
+		sql:               psql,
+		logger:            logger,
+		businessEngine:    businessEngine,
+		regionEngine:      regionEngine,
+		stockImages:       stockImages,
+		geocoder:          geocoder,
+		variants:          variants,
+		searchConfig:      searchConfig,
+		searchLog:         searchLog,
+		listingDateConfig: listingDateConfig,
+	}
+}
+
+// withTx returns a copy of the engine that runs its queries on tx
+func (l *listingEngine) withTx(tx db.Querier) *listingEngine {
+	engine := *l
+	engine.sql = tx
+	return &engine
 }
 
 func (l *listingEngine) GetDietaryRestriction(listingID int) ([]string, error) {
-	rows, err := l.sql.Query("SELECT restriction FROM listing_dietary_restrictions where "+
-		"listing_id = $1;", listingID)
+	restrictions, err := l.getDietaryRestrictions([]int{listingID})
 	if err != nil {
-		return []string{}, helper.DatabaseError{DBError: err.Error()}
+		return []string{}, err
 	}
-	defer rows.Close()
+	return restrictions[listingID], nil
+}
 
-	var dietaryReqs []string
-	for rows.Next() {
-		var diet string
-		err = rows.Scan(
-			&diet,
-		)
-		if err != nil {
-			return []string{}, helper.DatabaseError{DBError: err.Error()}
-		}
-		dietaryReqs = append(dietaryReqs, diet)
+func (l *listingEngine) GetRecurringListing(listingID int) ([]string, error) {
+	days, err := l.getRecurringDays([]int{listingID})
+	if err != nil {
+		return []string{}, err
 	}
+	return days[listingID], nil
+}
 
-	if err = rows.Err(); err != nil {
-		return []string{}, helper.DatabaseError{DBError: err.Error()}
-	}
+// getDietaryRestrictions returns the dietary restrictions of listingIDs
+func (l *listingEngine) getDietaryRestrictions(listingIDs []int) (map[int][]string, error) {
+	query, args := common.Select("listing_id", "restriction").
+		From("listing_dietary_restrictions").
+		WhereIn("listing_id", listingArgs(listingIDs)...).
+		Build()
+	return l.listingStrings(query, args)
+}
 
-	return dietaryReqs, nil
+// getRecurringDays returns the days listingIDs recur on, in order
+func (l *listingEngine) getRecurringDays(listingIDs []int) (map[int][]string, error) {
+	query, args := common.Select("DISTINCT listing_id", "day").
+		From("listing_recurring").
+		WhereIn("listing_id", listingArgs(listingIDs)...).
+		OrderBy("listing_id, day").
+		Build()
+	return l.listingStrings(query, args)
 }
 
-func (l *listingEngine) GetRecurringListing(listingID int) ([]string, error) {
-	rows, err := l.sql.Query("SELECT day FROM listing_recurring where "+
-		"listing_id = $1;", listingID)
+// listingStrings runs query, which selects a listing_id and a string, and
+// groups the strings by listing
+func (l *listingEngine) listingStrings(query string, args []interface{}) (map[int][]string, error) {
+	rows, err := l.sql.Query(query, args...)
 	if err != nil {
-		return []string{}, helper.DatabaseError{DBError: err.Error()}
+		return nil, helper.DatabaseError{DBError: err.Error()}
 	}
 	defer rows.Close()
 
-	var days []string
+	values := make(map[int][]string)
 	for rows.Next() {
-		var day string
-		err = rows.Scan(
-			&day,
-		)
-		if err != nil {
-			return []string{}, helper.DatabaseError{DBError: err.Error()}
+		var listingID int
+		var value string
+		if err := rows.Scan(&listingID, &value); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
 		}
-		days = append(days, day)
+		values[listingID] = append(values[listingID], value)
 	}
 
 	if err = rows.Err(); err != nil {
-		return []string{}, helper.DatabaseError{DBError: err.Error()}
+		return nil, helper.DatabaseError{DBError: err.Error()}
 	}
+	return values, nil
+}
 
-	return days, nil
+func listingArgs(listingIDs []int) []interface{} {
+	ids := make([]interface{}, 0, len(listingIDs))
+	for _, id := range listingIDs {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 func (l *listingEngine) AddDietaryRestrictionsToListings(listings []shared.Listing) ([]shared.Listing, error) {
@@ -147,21 +206,32 @@ func (l *listingEngine) AddDietaryRestrictionsToListings(listings []shared.Listi
 	return listings, nil
 }
 
-func optimizeImage(img string) string {
-	logger := shared.GetLogger()
-	if img != "" {
-		if strings.Contains(img, "drive.google.com") {
-			return img
-		} else {
-			imgParts := strings.Split(img, "/upload/")
-			if len(imgParts) != 2 {
-				logger.Error().Msgf("image does not have two parts", img)
-				return img
-			}
-			return fmt.Sprintf("%s/upload/q_auto,f_auto,fl_lossy/%s", imgParts[0], imgParts[1])
+// imageVariants returns the renditions of the image at link, nil when there
+// is none
+func (l *listingEngine) imageVariants(link string, width int, height int) *shared.ImageVariants {
+	if link == "" {
+		return nil
+	}
+	variants := l.variants.Of(link, width, height)
+	return &variants
+}
+
+// setImageVariants sets the renditions of the image of listing, its
+// ImageLink becoming the detail rendition. Listings without a photo are
+// shown with a stock image of their category.
+func (l *listingEngine) setImageVariants(listing *shared.Listing, stock *stockLibrary) error {
+	if listing.ImageLink == "" {
+		link, err := stock.pick(listing.Category, listing.ListingID)
+		if err != nil {
+			return err
 		}
+		listing.ImageLink, listing.IsStockImage = link, true
 	}
-	return img
+	listing.ImageVariants = l.imageVariants(listing.ImageLink, listing.ImageWidth, listing.ImageHeight)
+	if listing.ImageVariants != nil {
+		listing.ImageLink = listing.ImageVariants.Detail.URL
+	}
+	return nil
 }
 
 func (l *listingEngine) GetListingsDietaryRestriction(listingID int) ([]string, error) {
@@ -189,7 +259,7 @@ func (l *listingEngine) GetListingsDietaryRestriction(listingID int) ([]string,
 	return rests, nil
 }
 
-func (l *listingEngine) GetListingInfo(listingID int, phoneID string, latitude float64, longitude float64, location string) (shared.Listing, error) {
+func (l *listingEngine) GetListingInfo(listingID int, phoneID string, latitude float64, longitude float64, location string, language string) (shared.Listing, error) {
 	//var listingInfo shared.Listing
 
 	//GetListingByID
@@ -207,6 +277,15 @@ func (l *listingEngine) GetListingInfo(listingID int, phoneID string, latitude f
 		return shared.Listing{}, err
 	}
 
+	// prices and distances are shown the way the region of the user does, in
+	// their language. The details of a deal are shown outside every region
+	// too.
+	inRegion, _, err := l.forLocation(geoLocation)
+	if err != nil {
+		return shared.Listing{}, err
+	}
+	l = inRegion.withLanguage(language)
+
 	// getUpVotes
 	upvotes, err := l.GetUpVotes(listing.ListingID)
 	if err != nil {
@@ -236,6 +315,16 @@ func (l *listingEngine) GetListingInfo(listingID int, phoneID string, latitude f
 		if err != nil {
 			return shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
 		}
+
+		listing.RecurrenceRule, err = l.getRecurrenceRule(listingID)
+		if err != nil {
+			return shared.Listing{}, err
+		}
+
+		listing.TimeWindows, err = l.getTimeWindows(listingID)
+		if err != nil {
+			return shared.Listing{}, err
+		}
 	}
 
 	//GetBusinessInfo
@@ -245,7 +334,7 @@ func (l *listingEngine) GetListingInfo(listingID int, phoneID string, latitude f
 	}
 	listing.Business = &businessInfo
 
-	dateTimeRange, err := determineDealDateTimeRangeDetailsView(listing.StartTime, listing.EndTime, listing.RecurringDays)
+	dateTimeRange, err := determineDealDateTimeRangeDetailsView(listing)
 	if err != nil {
 		return shared.Listing{}, err
 	}
@@ -257,10 +346,16 @@ func (l *listingEngine) GetListingInfo(listingID int, phoneID string, latitude f
 		fromMobile := haversine.Coord{Lat: geoLocation.Latitude, Lon: geoLocation.Longitude}
 		mi, _ := haversine.Distance(fromMobile, fromDB)
 		listing.DistanceFromLocation = mi
-		listing.DistanceFromLocationString = GetDistanceFromLocationInString(listing.DistanceFromLocation, geoLocation)
-		listing.PriceInString = GetPriceFromLocationInString(listing.NewPrice, geoLocation)
+		listing.DistanceFromLocationString = l.locale().Distance(listing.DistanceFromLocation)
+		listing.PriceInString = l.locale().Price(listing.NewPrice)
 	}
 
+	// the place of the deal, e.g. "Mission District"
+	listing.Place = l.describeLocation(shared.GeoLocation{
+		Latitude:  listing.Business.BusinessAddress.Latitude,
+		Longitude: listing.Business.BusinessAddress.Longitude,
+	})
+
 	if phoneID != "" {
 		listing.IsFavorite = l.isFavorite(phoneID, listingID)
 	}
@@ -362,21 +457,18 @@ func messageListingsDateAndTime(listing shared.Listing) (shared.Listing, error)
 }
 
 func (l *listingEngine) GetListingByID(listingID int, businessID int, listingDateID int) (shared.Listing, error) {
-	selectFields := fmt.Sprintf("%s, %s, %s, %s", common.ListingFields, common.ListingBusinessFields, common.ListingDateFields, common.ListingImageFields)
-
-	var whereClause bytes.Buffer
-	whereClause.WriteString(fmt.Sprintf(" WHERE listing.listing_id = %d", listingID))
+	q := common.Select(common.ListingFields, common.ListingBusinessFields, common.ListingDateFields, common.ListingImageFields).
+		From(common.FromClauseListing).
+		Where("listing.listing_id = ?", listingID)
 	if businessID != 0 {
-		whereClause.WriteString(fmt.Sprintf(" AND business.business_id = %d", businessID))
+		q.Where("business.business_id = ?", businessID)
 	}
 	if listingDateID != 0 {
-		whereClause.WriteString(fmt.Sprintf(" AND listing_date.listing_date_id = %d", listingDateID))
+		q.Where("listing_date.listing_date_id = ?", listingDateID)
 	}
-	query := fmt.Sprintf("%s %s %s;", selectFields, common.FromClauseListing, whereClause.String())
-
-	fmt.Println("GetListingByID ", query)
+	query, args := q.Build()
 
-	rows := l.sql.QueryRow(query)
+	rows := l.sql.QueryRow(query, args...)
 
 	var listing shared.Listing
 	var sqlEndDate sql.NullString
@@ -404,13 +496,20 @@ func (l *listingEngine) GetListingByID(listingID int, businessID int, listingDat
 		&listing.ListingDateID,
 		&listing.ListingDate,
 		&listing.ImageLink,
+		&listing.ImageWidth,
+		&listing.ImageHeight,
+		&listing.Category,
 	)
 	if err != nil {
 		return shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
 	}
 
-	listing.ImageLink = optimizeImage(listing.ImageLink)
-	listing.ListingImages = []string{optimizeImage(listing.ImageLink)}
+	if err := l.setImageVariants(&listing, l.stockLibrary()); err != nil {
+		return shared.Listing{}, err
+	}
+	if err := l.setGallery(&listing); err != nil {
+		return shared.Listing{}, err
+	}
 	listing.EndDate = sqlEndDate.String
 	listing.RecurringEndDate = sqlRecurringEndDate.String
 	listing.ListingCreateDate = sqlCreateDate.String
@@ -432,19 +531,26 @@ func (l *listingEngine) GetListingByIDAdmin(listingID int) (shared.Listing, erro
 
 	res.RecurringDays = days
 
+	res.RecurrenceRule, err = l.getRecurrenceRule(listingID)
+	if err != nil {
+		return shared.Listing{}, err
+	}
+
+	res.TimeWindows, err = l.getTimeWindows(listingID)
+	if err != nil {
+		return shared.Listing{}, err
+	}
+
 	return res, nil
 }
 
 func (l *listingEngine) doGetListingByIDAdmin(listingID int) (shared.Listing, error) {
-	selectFields := fmt.Sprintf("%s, %s, %s", common.ListingFields, common.ListingBusinessFields, common.ListingImageFields)
-
-	var whereClause bytes.Buffer
-	whereClause.WriteString(fmt.Sprintf(" WHERE listing.listing_id = %d", listingID))
-	query := fmt.Sprintf("%s %s %s;", selectFields, common.FromClauseListingAdmin, whereClause.String())
+	query, args := common.Select(common.ListingFields, common.ListingBusinessFields, common.ListingImageFields).
+		From(common.FromClauseListingAdmin).
+		Where("listing.listing_id = ?", listingID).
+		Build()
 
-	//fmt.Println("GetListingByID ", query)
-
-	rows := l.sql.QueryRow(query)
+	rows := l.sql.QueryRow(query, args...)
 
 	var listing shared.Listing
 	var sqlEndDate sql.NullString
@@ -470,13 +576,20 @@ func (l *listingEngine) doGetListingByIDAdmin(listingID int) (shared.Listing, er
 		&sqlCreateDate,
 		&listing.BusinessName,
 		&listing.ImageLink,
+		&listing.ImageWidth,
+		&listing.ImageHeight,
+		&listing.Category,
 	)
 	if err != nil {
 		return shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
 	}
 
-	listing.ImageLink = optimizeImage(listing.ImageLink)
-	listing.ListingImages = []string{optimizeImage(listing.ImageLink)}
+	if err := l.setImageVariants(&listing, l.stockLibrary()); err != nil {
+		return shared.Listing{}, err
+	}
+	if err := l.setGallery(&listing); err != nil {
+		return shared.Listing{}, err
+	}
 	listing.EndDate = sqlEndDate.String
 	listing.RecurringEndDate = sqlRecurringEndDate.String
 	listing.ListingCreateDate = sqlCreateDate.String
@@ -485,15 +598,12 @@ func (l *listingEngine) doGetListingByIDAdmin(listingID int) (shared.Listing, er
 }
 
 func (l *listingEngine) GetListingByIDForUpdate(listingID int) (shared.Listing, error) {
-	selectFields := fmt.Sprintf("%s", common.ListingFields)
-
-	var whereClause bytes.Buffer
-	whereClause.WriteString(fmt.Sprintf(" WHERE listing.listing_id = %d", listingID))
-	query := fmt.Sprintf("%s %s %s;", selectFields, "FROM listing", whereClause.String())
+	query, args := common.Select(common.ListingFields).
+		From("listing").
+		Where("listing.listing_id = ?", listingID).
+		Build()
 
-	//fmt.Println("GetListingByID ", query)
-
-	rows := l.sql.QueryRow(query)
+	rows := l.sql.QueryRow(query, args...)
 
 	var listing shared.Listing
 	var sqlEndDate sql.NullString
@@ -522,7 +632,6 @@ func (l *listingEngine) GetListingByIDForUpdate(listingID int) (shared.Listing,
 		return shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
 	}
 
-	listing.ImageLink = optimizeImage(listing.ImageLink)
 	listing.EndDate = sqlEndDate.String
 	listing.RecurringEndDate = sqlRecurringEndDate.String
 	listing.ListingCreateDate = sqlCreateDate.String
@@ -530,18 +639,31 @@ func (l *listingEngine) GetListingByIDForUpdate(listingID int) (shared.Listing,
 	return listing, nil
 }
 
-func (l *listingEngine) GetListingsByBusinessID(businessID int, status string) ([]shared.Listing, error) {
-	selectFields := fmt.Sprintf("%s, %s, %s", common.ListingFields, common.ListingBusinessFields, common.ListingImageFields)
-
-	var whereClause bytes.Buffer
-	whereClause.WriteString(fmt.Sprintf(" WHERE listing.business_id = %d", businessID))
-	query := fmt.Sprintf("%s %s %s;", selectFields, common.FromClauseListingAdmin, whereClause.String())
-
-	//fmt.Println("GetListingsByBusinessID ", query)
+// GetListingsByBusinessID returns the page of the listings of a business,
+// newest first, and the cursor of the next one. Listings are paged by id in
+// the database, so filtering by status can leave a page short.
+func (l *listingEngine) GetListingsByBusinessID(businessID int, status string, page shared.Page) ([]shared.Listing, string, error) {
+	q := common.Select(common.ListingFields, common.ListingBusinessFields, common.ListingImageFields).
+		From(common.FromClauseListingAdmin).
+		Where("listing.business_id = ?", businessID).
+		OrderBy("listing.listing_id DESC")
+
+	// keys are negated ids, so newer listings come first
+	last, ok, err := shared.Seek(page, "")
+	if err != nil {
+		return []shared.Listing{}, "", helper.ValidationError{Message: err.Error()}
+	}
+	if ok {
+		q.Where("listing.listing_id < ?", -last.ID)
+	}
+	if page.Limit > 0 {
+		q.Limit(page.Limit + 1)
+	}
+	query, args := q.Build()
 
-	rows, err := l.sql.Query(query)
+	rows, err := l.sql.Query(query, args...)
 	if err != nil {
-		return []shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
+		return []shared.Listing{}, "", helper.DatabaseError{DBError: err.Error()}
 	}
 	defer rows.Close()
 
@@ -549,6 +671,7 @@ func (l *listingEngine) GetListingsByBusinessID(businessID int, status string) (
 	var sqlEndDate sql.NullString
 	var sqlRecurringEndDate sql.NullString
 	var sqlCreateDate sql.NullString
+	stock := l.stockLibrary()
 	for rows.Next() {
 		var listing shared.Listing
 		err = rows.Scan(
@@ -571,42 +694,57 @@ func (l *listingEngine) GetListingsByBusinessID(businessID int, status string) (
 			&sqlCreateDate,
 			&listing.BusinessName,
 			&listing.ImageLink,
+			&listing.ImageWidth,
+			&listing.ImageHeight,
+			&listing.Category,
 		)
 		if err != nil {
-			return []shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
+			return []shared.Listing{}, "", helper.DatabaseError{DBError: err.Error()}
+		}
+		if err := l.setImageVariants(&listing, stock); err != nil {
+			return []shared.Listing{}, "", err
 		}
-		listing.ImageLink = optimizeImage(listing.ImageLink)
 		listing.EndDate = sqlEndDate.String
 		listing.RecurringEndDate = sqlRecurringEndDate.String
 		listing.ListingCreateDate = sqlCreateDate.String
 
-		// add dietary req's
-		var reqs []string
-		reqs, err = l.GetDietaryRestriction(listing.ListingID)
-		if err != nil {
-			return []shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
-		}
-		listing.DietaryRestrictions = reqs
-
-		// add recurring listing
-		var recurring []string
-		recurring, err = l.GetRecurringListing(listing.ListingID)
-		if err != nil {
-			return []shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
-		}
-		listing.RecurringDays = recurring
-
-		// add listing status
-		listing.ListingStatus = l.getListingStatus(listing)
-
 		listings = append(listings, listing)
 	}
 
 	if err = rows.Err(); err != nil {
-		return []shared.Listing{}, helper.DatabaseError{DBError: err.Error()}
+		return []shared.Listing{}, "", helper.DatabaseError{DBError: err.Error()}
 	}
 
-	return l.filterListingBasedOnStatus(listings, status), nil
+	keys := make([]shared.PageKey, len(listings))
+	for i, listing := range listings {
+		keys[i] = shared.PageKey{ID: -listing.ListingID}
+	}
+	n, next, err := shared.SeekNext(keys, "", page)
+	if err != nil {
+		return []shared.Listing{}, "", helper.ValidationError{Message: err.Error()}
+	}
+	listings = listings[:n]
+
+	ids := make([]int, len(listings))
+	for i, listing := range listings {
+		ids[i] = listing.ListingID
+	}
+	restrictions, err := l.getDietaryRestrictions(ids)
+	if err != nil {
+		return []shared.Listing{}, "", err
+	}
+	recurring, err := l.getRecurringDays(ids)
+	if err != nil {
+		return []shared.Listing{}, "", err
+	}
+	for i := range listings {
+		listing := &listings[i]
+		listing.DietaryRestrictions = restrictions[listing.ListingID]
+		listing.RecurringDays = recurring[listing.ListingID]
+		listing.ListingStatus = l.getListingStatus(*listing)
+	}
+
+	return l.filterListingBasedOnStatus(listings, status), next, nil
 }
 
 func (l *listingEngine) isFavorite(phoneID string, listingID int) bool {
@@ -676,7 +814,7 @@ func (l *listingEngine) getAllListingIDs() ([]int, error) {
 }
 
 func (l *listingEngine) GetListingImage(listingID int) (string, error) {
-	rows, err := l.sql.Query("SELECT path FROM listing_image where listing_id = $1;", listingID)
+	rows, err := l.sql.Query("SELECT path FROM listing_image where listing_id = $1 ORDER BY is_primary DESC, position;", listingID)
 	if err != nil {
 		return "", err
 	}
@@ -692,53 +830,17 @@ func (l *listingEngine) GetListingImage(listingID int) (string, error) {
 		return "", err
 	}
 	if imageLink == "" {
-		imageLink = "https://res.cloudinary.com/itshungryhour/image/upload/v1533011858/listing/NoPicAvailable.png"
-	}
-	return optimizeImage(imageLink), nil
-}
-
-func (l *listingEngine) GetGeoFromAddress(address string) (shared.GeoLocation, error) {
-	if _, ok := l.geoMap[address]; ok {
-		l.logger.Info().Msgf("found location memory map:%s", address)
-		return l.geoMap[address], nil
-	}
-
-	return l.GetGeoFromAddressFromDB(address)
-}
-
-func (l *listingEngine) GetGeoFromAddressFromDB(address string) (shared.GeoLocation, error) {
-	rows := l.sql.QueryRow("SELECT latitude,longitude FROM address_to_geo where address = $1;", address)
-
-	var geo shared.GeoLocation
-	err := rows.Scan(&geo.Latitude, &geo.Longitude)
-
-	if err == sql.ErrNoRows {
-		return shared.GeoLocation{}, nil
-	}
-	if err != nil {
-		return shared.GeoLocation{}, err
-	}
-
-	// update in memory map
-	l.geoMap[address] = geo
-
-	return geo, nil
-}
-
-func (l *listingEngine) AddGeoLocation(location string, geoLocation shared.GeoLocation) error {
-	addListingRecurringSQL := "INSERT INTO address_to_geo(address,latitude,longitude) " +
-		"VALUES($1,$2,$3);"
-
-	_, err := l.sql.Exec(addListingRecurringSQL, location, geoLocation.Latitude, geoLocation.Longitude)
-	if err != nil {
-		return helper.DatabaseError{DBError: err.Error()}
+		// listings without a photo are shown with a stock image
+		var category string
+		err := l.sql.QueryRow("SELECT category FROM listing WHERE listing_id = $1;", listingID).Scan(&category)
+		if err != nil && err != sql.ErrNoRows {
+			return "", err
+		}
+		if imageLink, err = l.stockLibrary().pick(category, listingID); err != nil {
+			return "", err
+		}
 	}
-
-	// update in memory map
-	l.geoMap[location] = geoLocation
-
-	l.logger.Info().Msgf("added geoLocation successful for location:%s", location)
-	return nil
+	return l.variants.Of(imageLink, 0, 0).Detail.URL, nil
 }
 
 func getCurrentTimeInTimeZone(location shared.GeoLocation) (time.Time, error) {
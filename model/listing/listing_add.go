@@ -2,10 +2,10 @@ package listing
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/shared"
 )
@@ -20,12 +20,34 @@ func (l *listingEngine) AddListing(listing *shared.Listing) (int, error) {
 		return 0, helper.BusinessError{Message: fmt.Sprintf("business with id %d does not exist", listing.BusinessID)}
 	}
 
+	// the listing and all its rows are added together or not at all
+	err = db.Transact(l.sql, func(tx db.Querier) error {
+		return l.withTx(tx).addListing(listing)
+	})
+	if err != nil {
+		listing.ListingID = 0
+		return 0, err
+	}
+
+	l.logger.Info().Msgf("successfully added a listing %s for business: %s with listingId: %d", listing.Title, business.Name, listing.ListingID)
+
+	return listing.ListingID, nil
+}
+
+func (l *listingEngine) addListing(listing *shared.Listing) error {
+	if err := setRecurrenceDays(listing); err != nil {
+		return err
+	}
+	if err := setTimeWindows(listing); err != nil {
+		return err
+	}
+
 	var listingID int
 	const insertListingSQL = "INSERT INTO listing(business_id, title, old_price, new_price, discount, discount_description, description," +
-		"start_date, start_time, end_time, multiple_days, end_date, recurring, recurring_end_date, listing_type, listing_create_date) " +
-		"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) returning listing_id"
+		"start_date, start_time, end_time, multiple_days, end_date, recurring, recurring_end_date, listing_type, listing_create_date, recurrence_rule) " +
+		"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) returning listing_id"
 
-	err = l.sql.QueryRow(insertListingSQL,
+	err := l.sql.QueryRow(insertListingSQL,
 		listing.BusinessID,
 		listing.Title,
 		listing.OldPrice,
@@ -41,47 +63,46 @@ func (l *listingEngine) AddListing(listing *shared.Listing) (int, error) {
 		listing.Recurring,
 		shared.NewNullString(listing.RecurringEndDate),
 		listing.Type,
-		time.Now()).
+		time.Now(),
+		shared.NewNullString(listing.RecurrenceRule)).
 		Scan(&listingID)
 
 	if err != nil {
-		return 0, helper.DatabaseError{DBError: err.Error()}
+		return helper.DatabaseError{DBError: err.Error()}
 	}
 	listing.ListingID = listingID
 
-	// add listing image
-	if listing.ImageLink != "" {
-		if err := l.AddListingImage(listingID, listing.ImageLink); err != nil {
-			return 0, err
+	// add listing images, its gallery or else its image link
+	if images := galleryOf(listing); len(images) > 0 {
+		if err := l.addGallery(listingID, images); err != nil {
+			return err
 		}
 	} else {
 		l.logger.Info().Msg("no image link")
 	}
 
-	//if listing.Recurring {
-	for _, day := range listing.RecurringDays {
-		if err := l.AddRecurring(listingID, day); err != nil {
-			return 0, err
-		}
+	if err := l.tagListing(listing); err != nil {
+		return err
+	}
+
+	if err := l.addRecurringDays(listing); err != nil {
+		return err
 	}
-	//}
 
 	if len(listing.DietaryRestrictions) > 0 {
 		for _, restriction := range listing.DietaryRestrictions {
 			if err := l.AddDietaryRestriction(listingID, restriction); err != nil {
-				return 0, err
+				return err
 			}
 		}
 	}
 
-	// insert into listing_date
-	if err := l.AddListingDates(listing); err != nil {
-		return 0, err
+	if err := l.refreshSearchDocument(listingID); err != nil {
+		return err
 	}
 
-	l.logger.Info().Msgf("successfully added a listing %s for business: %s with listingId: %d", listing.Title, business.Name, listingID)
-
-	return listingID, nil
+	// insert into listing_date
+	return l.AddListingDates(listing)
 }
 
 func (l *listingEngine) AddListingDates(listing *shared.Listing) error {
@@ -117,65 +138,14 @@ func (l *listingEngine) AddListingDates(listing *shared.Listing) error {
 		}
 	}
 
+	// recurring listings are only materialized up to the end of the date
+	// window, the DateScheduler rolls it forward
 	if listing.Recurring {
-
-		// check if listingDate is a recurring date
-		for _, recurringDay := range listing.RecurringDays {
-			if shared.DayMap[recurringDay] == int(listingDate.Weekday()) {
-				listDate := shared.ListingDate{ListingID: listing.ListingID, ListingDate: listing.StartDate, StartTime: listing.StartTime, EndTime: listing.EndTime}
-				listings = append(listings, listDate)
-				break
-			}
-		}
-
-		listingRecurringDate, err := time.Parse(shared.DateFormat, strings.Split(listing.RecurringEndDate, "T")[0])
+		dates, err := recurringDates(*listing, listingDate, l.windowEnd(dateOf(time.Now())))
 		if err != nil {
 			return err
 		}
-		// difference b/w days
-		days := listingRecurringDate.Sub(listingDate).Hours() / 24
-		curDate := listingDate
-		for i := 1; i < int(days); i++ {
-			var lDate shared.ListingDate
-			nextDate := curDate.Add(time.Hour * 24)
-			year, month, day := nextDate.Date()
-			for _, recurringDay := range listing.RecurringDays {
-				dayIndex := int(nextDate.Weekday())
-				if shared.DayMap[recurringDay] == dayIndex {
-					next := fmt.Sprintf("%d/%d/%d", int(month), day, year)
-
-					// check if endTimeExtendsBeyond midnight
-					endTimePieces := strings.Split(listing.EndTime, ":")
-					if len(endTimePieces) > 0 {
-						timeInHour, err := strconv.ParseInt(endTimePieces[0], 10, 64)
-						if err != nil {
-							return err
-						}
-
-						// if listing extends beyond midnight split into two
-						if timeInHour >= 0 && timeInHour <= 4 {
-							l.logger.Info().Msgf("listing extends beyond midnight")
-							lDate1 := shared.ListingDate{ListingID: listing.ListingID, ListingDate: next, StartTime: listing.StartTime, EndTime: "23:59:59"}
-							listings = append(listings, lDate1)
-
-							temp := nextDate
-							nextDateSameListing := temp.Add(time.Hour * 24)
-							year, month, day := nextDateSameListing.Date()
-							nextDay := fmt.Sprintf("%d/%d/%d", int(month), day, year)
-
-							lDate2 := shared.ListingDate{ListingID: listing.ListingID, ListingDate: nextDay, StartTime: "00:00:00", EndTime: listing.EndTime}
-							listings = append(listings, lDate2)
-
-							l.logger.Info().Msgf("lDate1: %v and lDate2: %v", lDate1, lDate2)
-						} else {
-							lDate = shared.ListingDate{ListingID: listing.ListingID, ListingDate: next, StartTime: listing.StartTime, EndTime: listing.EndTime}
-							listings = append(listings, lDate)
-						}
-					}
-				}
-			}
-			curDate = nextDate
-		}
+		listings = append(listings, dates...)
 	}
 
 	for _, listing := range listings {
@@ -202,29 +172,14 @@ func (l *listingEngine) InsertListingDate(lDate shared.ListingDate) (int, error)
 	return listingDateID, nil
 }
 
-func (l *listingEngine) AddListingImage(listingID int, imageLink string) error {
-	addListingRecurringSQL := "INSERT INTO listing_image(listing_id,path) " +
-		"VALUES($1,$2);"
-
-	rows, err := l.sql.Query(addListingRecurringSQL, listingID, imageLink)
-	if err != nil {
-		return helper.DatabaseError{DBError: err.Error()}
-	}
-	defer rows.Close()
-
-	l.logger.Info().Msgf("add AddListingImage successful for listing:%d", listingID)
-	return nil
-}
-
 func (l *listingEngine) AddRecurring(listingID int, day string) error {
 	addListingRecurringSQL := "INSERT INTO listing_recurring(listing_id,day) " +
 		"VALUES($1,$2);"
 
-	rows, err := l.sql.Query(addListingRecurringSQL, listingID, day)
+	_, err := l.sql.Exec(addListingRecurringSQL, listingID, day)
 	if err != nil {
 		return helper.DatabaseError{DBError: err.Error()}
 	}
-	defer rows.Close()
 
 	l.logger.Info().Msgf("add recurring successful for listing:%d", listingID)
 	return nil
@@ -234,11 +189,10 @@ func (l *listingEngine) AddDietaryRestriction(listingID int, restriction string)
 	addListingDietRestrictionSQL := "INSERT INTO listing_dietary_restrictions(listing_id,restriction) " +
 		"VALUES($1,$2);"
 
-	rows, err := l.sql.Query(addListingDietRestrictionSQL, listingID, restriction)
+	_, err := l.sql.Exec(addListingDietRestrictionSQL, listingID, restriction)
 	if err != nil {
 		return helper.DatabaseError{DBError: err.Error()}
 	}
-	defer rows.Close()
 
 	l.logger.Info().Msgf("add listing_dietary_restrictions successful for listing:%d", listingID)
 	return nil
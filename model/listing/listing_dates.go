@@ -0,0 +1,359 @@
+package listing
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/recurrence"
+	"github.com/phassans/banana/shared"
+)
+
+const (
+	midnight = "00:00:00"
+	endOfDay = "23:59:59"
+)
+
+// MaterializeResult describes one pass over the recurring listings
+type MaterializeResult struct {
+	Listings int `json:"listings"`
+	Added    int `json:"added"`
+	Failed   int `json:"failed"`
+	Pruned   int `json:"pruned"`
+}
+
+// MaterializeListingDates keeps the listing_date rows of every recurring
+// listing materialized from today until the end of the configured window and
+// prunes the rows that have passed. Open ended recurrences are extended as
+// the window rolls forward. A listing that fails is counted and retried on
+// the next pass, it does not stop the others.
+func (l *listingEngine) MaterializeListingDates(now time.Time) (MaterializeResult, error) {
+	var result MaterializeResult
+
+	today := dateOf(now)
+	until := l.windowEnd(today)
+
+	ids, err := l.getActiveRecurringListingIDs(today)
+	if err != nil {
+		return result, err
+	}
+
+	for _, id := range ids {
+		var added int
+		err := db.Transact(l.sql, func(tx db.Querier) error {
+			var err error
+			added, err = l.withTx(tx).materializeListing(id, today, until)
+			return err
+		})
+		if err != nil {
+			result.Failed++
+			l.logger.Error().Msgf("could not materialize dates of listing %d: %s", id, err)
+			continue
+		}
+		result.Listings++
+		result.Added += added
+	}
+
+	// yesterday is kept for deals running past midnight
+	result.Pruned, err = l.pruneListingDates(today.AddDate(0, 0, -1))
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// materializeListing inserts the dates of a recurring listing between from
+// and until that are not there yet
+func (l *listingEngine) materializeListing(listingID int, from time.Time, until time.Time) (int, error) {
+	listing, err := l.GetListingByIDForUpdate(listingID)
+	if err != nil {
+		return 0, err
+	}
+
+	listing.RecurringDays, err = l.GetRecurringListing(listingID)
+	if err != nil {
+		return 0, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	listing.RecurrenceRule, err = l.getRecurrenceRule(listingID)
+	if err != nil {
+		return 0, err
+	}
+
+	listing.TimeWindows, err = l.getTimeWindows(listingID)
+	if err != nil {
+		return 0, err
+	}
+
+	listing, err = messageListingsDateAndTime(listing)
+	if err != nil {
+		return 0, err
+	}
+
+	dates, err := recurringDates(listing, from, until)
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := l.getListingDatesFrom(listingID, from)
+	if err != nil {
+		return 0, err
+	}
+
+	var added int
+	for _, date := range dates {
+		if existing[date.ListingDate] || existing[date.ListingDate+" "+date.StartTime] {
+			continue
+		}
+		if _, err := l.InsertListingDate(date); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// windowEnd returns the last date materialized on today
+func (l *listingEngine) windowEnd(today time.Time) time.Time {
+	return today.AddDate(0, 0, 7*l.listingDateConfig.WindowWeeks)
+}
+
+// recurringDates returns the listing dates of a recurring listing between
+// from and to, both included, clipped to its start date and recurring end
+// date. An empty recurring end date recurs forever. Listings with a
+// recurrence rule occur on the dates of the rule, others on their recurring
+// days. Each date gets a row per time window of its day of the week.
+func recurringDates(listing shared.Listing, from time.Time, to time.Time) ([]shared.ListingDate, error) {
+	startDate, err := time.Parse(shared.DateFormat, strings.Split(listing.StartDate, "T")[0])
+	if err != nil {
+		return nil, err
+	}
+	from = dateOf(from)
+	if from.Before(startDate) {
+		from = startDate
+	}
+
+	to = dateOf(to)
+	if listing.RecurringEndDate != "" {
+		endDate, err := time.Parse(shared.DateFormat, strings.Split(listing.RecurringEndDate, "T")[0])
+		if err != nil {
+			return nil, err
+		}
+		if endDate.Before(to) {
+			to = endDate
+		}
+	}
+
+	days, err := occurrences(listing, startDate, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []shared.ListingDate
+	for _, day := range days {
+		for _, window := range windowsOn(listing, day.Weekday()) {
+			windowDates, err := windowDates(listing.ListingID, day, window)
+			if err != nil {
+				return nil, err
+			}
+			dates = append(dates, windowDates...)
+		}
+	}
+	return dates, nil
+}
+
+// windowDates returns the listing dates of a time window on day. Windows
+// ending past midnight are split into two dates, so that searches for the
+// next day find them too.
+func windowDates(listingID int, day time.Time, window shared.TimeWindow) ([]shared.ListingDate, error) {
+	startTime, err := shared.NormalizeTime(window.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	endTime, err := shared.NormalizeTime(window.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	date := day.Format(shared.DateFormat)
+	switch {
+	case endTime > startTime:
+		return []shared.ListingDate{{ListingID: listingID, ListingDate: date, StartTime: startTime, EndTime: endTime}}, nil
+	case endTime == midnight:
+		return []shared.ListingDate{{ListingID: listingID, ListingDate: date, StartTime: startTime, EndTime: endOfDay}}, nil
+	}
+
+	nextDay := day.AddDate(0, 0, 1).Format(shared.DateFormat)
+	return []shared.ListingDate{
+		{ListingID: listingID, ListingDate: date, StartTime: startTime, EndTime: endOfDay},
+		{ListingID: listingID, ListingDate: nextDay, StartTime: midnight, EndTime: endTime},
+	}, nil
+}
+
+// occurrences returns the days between from and to a recurring listing
+// started on start occurs on
+func occurrences(listing shared.Listing, start time.Time, from time.Time, to time.Time) ([]time.Time, error) {
+	if listing.RecurrenceRule != "" {
+		rule, err := recurrence.Parse(listing.RecurrenceRule)
+		if err != nil {
+			return nil, err
+		}
+		return rule.Between(start, from, to), nil
+	}
+
+	var days []time.Time
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if recursOn(listing.RecurringDays, day.Weekday()) {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// setRecurrenceDays fills in the recurring days of a listing given as a
+// recurrence rule, they describe the listing in search results
+func setRecurrenceDays(listing *shared.Listing) error {
+	if !listing.Recurring || listing.RecurrenceRule == "" {
+		return nil
+	}
+
+	rule, err := recurrence.Parse(listing.RecurrenceRule)
+	if err != nil {
+		return helper.ValidationError{Message: fmt.Sprintf("invalid recurrenceRule: %s", err)}
+	}
+	listing.RecurrenceRule = rule.String()
+
+	if len(listing.RecurringDays) > 0 {
+		return nil
+	}
+
+	startDate, err := time.Parse(shared.DateFormat, strings.Split(listing.StartDate, "T")[0])
+	if err != nil {
+		return err
+	}
+	for _, weekday := range rule.Weekdays(startDate) {
+		listing.RecurringDays = append(listing.RecurringDays, strings.ToLower(weekday.String()))
+	}
+	return nil
+}
+
+func recursOn(recurringDays []string, weekday time.Weekday) bool {
+	for _, recurringDay := range recurringDays {
+		if day, ok := shared.DayMap[strings.ToLower(recurringDay)]; ok && day == int(weekday) {
+			return true
+		}
+	}
+	return false
+}
+
+// dateOf drops the time of day, dates are compared as parsed from
+// shared.DateFormat
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// getActiveRecurringListingIDs returns the recurring listings that have not
+// ended before today
+func (l *listingEngine) getActiveRecurringListingIDs(today time.Time) ([]int, error) {
+	rows, err := l.sql.Query("SELECT listing_id FROM listing WHERE recurring = true "+
+		"AND (recurring_end_date IS NULL OR recurring_end_date >= $1);", today.Format(shared.DateFormatSQL))
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	defer rows.Close()
+
+	var listingIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		listingIDs = append(listingIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	return listingIDs, nil
+}
+
+// getRecurrenceRule returns the recurrence rule of a listing, empty for
+// listings recurring on their recurring days
+func (l *listingEngine) getRecurrenceRule(listingID int) (string, error) {
+	var rule sql.NullString
+	err := l.sql.QueryRow("SELECT recurrence_rule FROM listing WHERE listing_id = $1;", listingID).Scan(&rule)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", helper.DatabaseError{DBError: err.Error()}
+	}
+	return rule.String, nil
+}
+
+// getListingDatesFrom returns the dates of a listing from the given date on,
+// keyed by date and start time. Rows without a start time cover the whole
+// date and are keyed by date only.
+func (l *listingEngine) getListingDatesFrom(listingID int, from time.Time) (map[string]bool, error) {
+	rows, err := l.sql.Query("SELECT listing_date, start_time FROM listing_date "+
+		"WHERE listing_id = $1 AND listing_date >= $2;", listingID, from.Format(shared.DateFormatSQL))
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	defer rows.Close()
+
+	dates := make(map[string]bool)
+	for rows.Next() {
+		var listingDate string
+		var startTime sql.NullString
+		if err := rows.Scan(&listingDate, &startTime); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+
+		date, err := shared.ConvertDBDate(listingDate)
+		if err != nil {
+			return nil, err
+		}
+		if !startTime.Valid {
+			dates[date] = true
+			continue
+		}
+
+		start, err := shared.ConvertDBTime(startTime.String)
+		if err != nil {
+			return nil, err
+		}
+		dates[date+" "+start] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	return dates, nil
+}
+
+// pruneListingDates removes the dates of recurring listings before the given
+// date, they are regenerated from the recurrence and never looked at again
+func (l *listingEngine) pruneListingDates(before time.Time) (int, error) {
+	result, err := l.sql.Exec("DELETE FROM listing_date USING listing "+
+		"WHERE listing_date.listing_id = listing.listing_id AND listing.recurring = true "+
+		"AND listing_date.listing_date < $1;", before.Format(shared.DateFormatSQL))
+	if err != nil {
+		return 0, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		return 0, helper.DatabaseError{DBError: err.Error()}
+	}
+	return int(pruned), nil
+}
@@ -0,0 +1,91 @@
+package listing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func listingDates(dates []shared.ListingDate) []string {
+	var result []string
+	for _, date := range dates {
+		result = append(result, date.ListingDate+" "+date.StartTime+"-"+date.EndTime)
+	}
+	return result
+}
+
+func TestRecurringDatesWithinWindow(t *testing.T) {
+	listing := shared.Listing{
+		ListingID:        1,
+		StartDate:        "09/03/2018", // a monday
+		RecurringEndDate: "09/17/2018",
+		RecurringDays:    []string{"monday", "wednesday"},
+		StartTime:        "16:00:00",
+		EndTime:          "18:00:00",
+	}
+
+	// the window starts before the listing and ends after it
+	dates, err := recurringDates(listing, time.Date(2018, 9, 1, 13, 0, 0, 0, time.Local), time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"09/03/2018 16:00:00-18:00:00",
+		"09/05/2018 16:00:00-18:00:00",
+		"09/10/2018 16:00:00-18:00:00",
+		"09/12/2018 16:00:00-18:00:00",
+		"09/17/2018 16:00:00-18:00:00",
+	}, listingDates(dates))
+
+	// the window cuts the recurrence short
+	dates, err = recurringDates(listing, time.Date(2018, 9, 6, 0, 0, 0, 0, time.UTC), time.Date(2018, 9, 12, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"09/10/2018 16:00:00-18:00:00",
+		"09/12/2018 16:00:00-18:00:00",
+	}, listingDates(dates))
+}
+
+func TestRecurringDatesOpenEndedPastMidnight(t *testing.T) {
+	listing := shared.Listing{
+		ListingID:     1,
+		StartDate:     "09/01/2018",
+		RecurringDays: []string{"friday"},
+		StartTime:     "22:00:00",
+		EndTime:       "02:00:00",
+	}
+
+	dates, err := recurringDates(listing, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, 1, 14, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"01/04/2019 22:00:00-23:59:59",
+		"01/05/2019 00:00:00-02:00:00",
+		"01/11/2019 22:00:00-23:59:59",
+		"01/12/2019 00:00:00-02:00:00",
+	}, listingDates(dates))
+}
+
+func TestRecurringDatesFromRecurrenceRule(t *testing.T) {
+	listing := shared.Listing{
+		ListingID:      1,
+		Recurring:      true,
+		StartDate:      "12/01/2018",
+		RecurrenceRule: "RRULE:FREQ=WEEKLY;BYDAY=TU\nEXDATE:20181225",
+		StartTime:      "16:00:00",
+		EndTime:        "18:00:00",
+	}
+
+	dates, err := recurringDates(listing, time.Date(2018, 12, 15, 0, 0, 0, 0, time.UTC), time.Date(2019, 1, 5, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"12/18/2018 16:00:00-18:00:00",
+		"01/01/2019 16:00:00-18:00:00",
+	}, listingDates(dates))
+
+	require.NoError(t, setRecurrenceDays(&listing))
+	require.Equal(t, []string{"tuesday"}, listing.RecurringDays)
+	require.Equal(t, "RRULE:FREQ=WEEKLY;BYDAY=TU\nEXDATE:20181225", listing.RecurrenceRule)
+
+	listing.RecurrenceRule = "RRULE:FREQ=SECONDLY"
+	require.Error(t, setRecurrenceDays(&listing))
+}
@@ -1,6 +1,9 @@
 package listing
 
-import "github.com/phassans/banana/helper"
+import (
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+)
 
 func (f *listingEngine) DeleteListing(listingID int) error {
 	f.logger.Error().Msgf("DeleteListing listingID: %d", listingID)
@@ -16,28 +19,13 @@ func (f *listingEngine) DeleteListing(listingID int) error {
 		return helper.ListingDoesNotExist{ListingID: listingID}
 	}
 
-	if err := f.deleteListingImage(listingID); err != nil {
-		f.logger.Error().Msgf("deleteListingImage returned with err: %s", err)
-		// return nil do not return error
-	}
-
-	if err := f.deleteListingDietaryRestriction(listingID); err != nil {
-		f.logger.Error().Msgf("deleteListingDietaryRestriction returned with err: %s", err)
-		// return nil do not return error
-	}
-
-	if err := f.deleteListingDate(listingID); err != nil {
-		f.logger.Error().Msgf("deleteListingDate returned with err: %s", err)
-		// return nil do not return error
-	}
-
-	if err := f.deleteListingRecurring(listingID); err != nil {
-		f.logger.Error().Msgf("deleteListingRecurring returned with err: %s", err)
-		// return nil do not return error
-	}
-
-	if err := f.deleteListing(listingID); err != nil {
-		f.logger.Error().Msgf("deleteListing returned with err: %s", err)
+	// remove the listing together with every row that references it, so a
+	// failure part way through leaves nothing orphaned
+	err = db.Transact(f.sql, func(tx db.Querier) error {
+		return f.withTx(tx).deleteListingAndChildren(listingID)
+	})
+	if err != nil {
+		f.logger.Error().Msgf("DeleteListing returned with err: %s", err)
 		return err
 	}
 
@@ -45,42 +33,47 @@ func (f *listingEngine) DeleteListing(listingID int) error {
 	return nil
 }
 
-func (f *listingEngine) deleteListing(listingID int) error {
-	sqlStatement := `DELETE FROM listing WHERE listing_id = $1;`
-	f.logger.Info().Msgf("deleting listing with query: %s and listing: %d", sqlStatement, listingID)
+func (f *listingEngine) deleteListingAndChildren(listingID int) error {
+	children := []string{
+		"listing_image",
+		"listing_dietary_restrictions",
+		"listing_date",
+		"listing_recurring",
+		"favorites",
+		"upvotes",
+		"report_inaccurate",
+		"notification_sent",
+		"listing_search",
+	}
+	for _, table := range children {
+		if err := f.deleteFromListingTable(table, listingID); err != nil {
+			return err
+		}
+	}
 
-	_, err := f.sql.Exec(sqlStatement, listingID)
-	return err
+	return f.deleteFromListingTable("listing", listingID)
 }
 
 func (f *listingEngine) deleteListingDate(listingID int) error {
-	sqlStatement := `DELETE FROM listing_date WHERE listing_id = $1;`
-	f.logger.Info().Msgf("deleting listing_date with query: %s and listing: %d", sqlStatement, listingID)
-
-	_, err := f.sql.Exec(sqlStatement, listingID)
-	return err
+	return f.deleteFromListingTable("listing_date", listingID)
 }
 
 func (f *listingEngine) deleteListingRecurring(listingID int) error {
-	sqlStatement := `DELETE FROM listing_recurring WHERE listing_id = $1;`
-	f.logger.Info().Msgf("deleting listing_recurring with query: %s and listing: %d", sqlStatement, listingID)
-
-	_, err := f.sql.Exec(sqlStatement, listingID)
-	return err
+	return f.deleteFromListingTable("listing_recurring", listingID)
 }
 
 func (f *listingEngine) deleteListingDietaryRestriction(listingID int) error {
-	sqlStatement := `DELETE FROM listing_dietary_restrictions WHERE listing_id = $1;`
-	f.logger.Info().Msgf("deleting listing_dietary_restrictions with query: %s and listing: %d", sqlStatement, listingID)
-
-	_, err := f.sql.Exec(sqlStatement, listingID)
-	return err
+	return f.deleteFromListingTable("listing_dietary_restrictions", listingID)
 }
 
-func (f *listingEngine) deleteListingImage(listingID int) error {
-	sqlStatement := `DELETE FROM listing_image WHERE listing_id = $1;`
-	f.logger.Info().Msgf("deleting listing_image with query: %s and listing: %d", sqlStatement, listingID)
+// deleteFromListingTable deletes the rows of listingID from one of the
+// listing tables above. table is never user input.
+func (f *listingEngine) deleteFromListingTable(table string, listingID int) error {
+	sqlStatement := "DELETE FROM " + table + " WHERE listing_id = $1;"
+	f.logger.Info().Msgf("deleting %s with query: %s and listing: %d", table, sqlStatement, listingID)
 
-	_, err := f.sql.Exec(sqlStatement, listingID)
-	return err
+	if _, err := f.sql.Exec(sqlStatement, listingID); err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package listing
 
 import (
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/shared"
 )
@@ -17,14 +18,38 @@ func (l *listingEngine) ListingEdit(listing *shared.Listing) error {
 		return helper.ListingDoesNotExist{ListingID: listing.ListingID}
 	}
 
+	return db.Transact(l.sql, func(tx db.Querier) error {
+		return l.withTx(tx).listingEdit(listing)
+	})
+}
+
+func (l *listingEngine) listingEdit(listing *shared.Listing) error {
+	if err := setRecurrenceDays(listing); err != nil {
+		return err
+	}
+	if err := setTimeWindows(listing); err != nil {
+		return err
+	}
+
 	// edit listing info
 	if err := l.editListingInfo(listing); err != nil {
 		return err
 	}
 	l.logger.Info().Msgf("editListingInfo success for listing: %d", listing.ListingID)
 
-	// edit listing image
-	if listing.ImageLink != "" {
+	// tag the listing with the category its description mentions now
+	if err := l.tagListing(listing); err != nil {
+		return err
+	}
+
+	// edit listing images: a gallery is added to the images of the listing,
+	// an image link replaces its primary image
+	if len(listing.Gallery) > 0 {
+		if err := l.addGallery(listing.ListingID, listing.Gallery); err != nil {
+			return err
+		}
+		l.logger.Info().Msgf("addGallery success for listing: %d", listing.ListingID)
+	} else if listing.ImageLink != "" {
 		if err := l.editListingImage(listing); err != nil {
 			return err
 		}
@@ -49,7 +74,7 @@ func (l *listingEngine) ListingEdit(listing *shared.Listing) error {
 	}
 	l.logger.Info().Msgf("editListingDates success for listing: %d", listing.ListingID)
 
-	return nil
+	return l.refreshSearchDocument(listing.ListingID)
 }
 
 func (l *listingEngine) editRecurringDays(listing *shared.Listing) error {
@@ -58,11 +83,7 @@ func (l *listingEngine) editRecurringDays(listing *shared.Listing) error {
 	}
 
 	if listing.Recurring {
-		for _, day := range listing.RecurringDays {
-			if err := l.AddRecurring(listing.ListingID, day); err != nil {
-				return err
-			}
-		}
+		return l.addRecurringDays(listing)
 	}
 	return nil
 }
@@ -99,8 +120,9 @@ func (l *listingEngine) editListingInfo(listing *shared.Listing) error {
 	updateListingInfoSQL := `
 	UPDATE listing
 	SET title = $1, old_price = $2, new_price = $3, discount = $4, discount_description = $5, description = $6, start_date = $7, start_time = $8, 
-	end_time = $9, multiple_days = $10, end_date = $11, recurring = $12, recurring_end_date = $13
-	WHERE listing_id = $14 AND business_id = $15 AND listing_type = $16;`
+	end_time = $9, multiple_days = $10, end_date = $11, recurring = $12, recurring_end_date = $13,
+	recurrence_rule = $14
+	WHERE listing_id = $15 AND business_id = $16 AND listing_type = $17;`
 
 	_, err := l.sql.Exec(
 		updateListingInfoSQL,
@@ -117,6 +139,7 @@ func (l *listingEngine) editListingInfo(listing *shared.Listing) error {
 		shared.NewNullString(listing.EndDate),
 		listing.Recurring,
 		shared.NewNullString(listing.RecurringEndDate),
+		shared.NewNullString(listing.RecurrenceRule),
 		listing.ListingID,
 		listing.BusinessID,
 		listing.Type,
@@ -131,9 +154,9 @@ func (l *listingEngine) editListingImage(listing *shared.Listing) error {
 	updateListingImageSQL := `
 	UPDATE listing_image
 	SET path = $1
-	WHERE listing_id = $2`
+	WHERE listing_id = $2 AND is_primary`
 
-	_, err := l.sql.Exec(
+	result, err := l.sql.Exec(
 		updateListingImageSQL,
 		listing.ImageLink,
 		listing.ListingID,
@@ -141,5 +164,10 @@ func (l *listingEngine) editListingImage(listing *shared.Listing) error {
 	if err != nil {
 		return err
 	}
+
+	// listings added without an image get it as their first
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return l.addGallery(listing.ListingID, []shared.GalleryImage{{URL: listing.ImageLink}})
+	}
 	return nil
 }
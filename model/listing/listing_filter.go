@@ -1,9 +1,6 @@
 package listing
 
 import (
-	"strconv"
-
-	"github.com/phassans/banana/model/common"
 	"github.com/phassans/banana/shared"
 )
 
@@ -18,27 +15,6 @@ func (l *listingEngine) FilterByPrice(listings []shared.Listing, priceFilter flo
 	return listingsResult, nil
 }
 
-func (l *listingEngine) FilterByDistance(listings []shared.Listing, distanceFilter string) ([]shared.Listing, error) {
-	if distanceFilter == "all" {
-		distanceFilter = common.MaxFilterDistance
-	}
-
-	// if parse error, do not apply filter, just return
-	dFilter, err := strconv.ParseFloat(distanceFilter, 64)
-	if err != nil {
-		return listings, err
-	}
-
-	// get dietary restriction
-	var listingsResult []shared.Listing
-	for _, listing := range listings {
-		if listing.DistanceFromLocation <= dFilter {
-			listingsResult = append(listingsResult, listing)
-		}
-	}
-	return listingsResult, nil
-}
-
 func (l *listingEngine) FilterByDietaryRestrictions(listings []shared.Listing, dietaryFilters []string) ([]shared.Listing, error) {
 	// get dietary restriction
 	var listingsResult []shared.Listing
@@ -87,7 +63,8 @@ func (l *listingEngine) getListingStatus(listing shared.Listing) string {
 		if endDateTimeLeft < 0 {
 			return shared.ListingEnded
 		}
-	} else if listing.Recurring {
+	} else if listing.Recurring && listing.RecurringEndDate != "" {
+		// open ended recurrences never end
 		recurringEndDateTimeLeft, err := calculateTimeLeft(listing.RecurringEndDate, listing.EndTime, listing.CurrentLocation)
 		if err != nil {
 			return ""
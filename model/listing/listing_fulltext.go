@@ -0,0 +1,80 @@
+package listing
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
+)
+
+// snippetOptions highlights the matches of a search in the description
+const snippetOptions = "StartSel=<b>, StopSel=</b>, MaxFragments=1, MaxWords=25, MinWords=10"
+
+// refreshSearchDocument rebuilds the search document of a listing, it has to
+// follow every change to the listing
+func (l *listingEngine) refreshSearchDocument(listingID int) error {
+	_, err := l.sql.Exec(fmt.Sprintf(common.UpsertListingSearchSQL, "listing.listing_id = $1"), listingID)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	return nil
+}
+
+// addKeywordFilter narrows q to the listings matching keywords and selects
+// their relevance and a snippet of their description with the matches
+// highlighted. Words match on their stem and as prefixes, so "oyster" finds
+// "oysters" and "marg" finds "margaritas". When nothing matches that way,
+// misspelled words still match similar words in the listing. Keywords naming a
+// category also match the keywords of the category.
+func addKeywordFilter(q *common.Query, keywords string, categoryKeywords []string) {
+	query := textQuery(keywords, categoryKeywords)
+	text := strings.ToLower(strings.TrimSpace(keywords))
+
+	q.Field("ts_rank_cd(listing_search.document, to_tsquery('english', ?)) + "+
+		"word_similarity(?, listing_search.words) as relevance", query, text)
+	q.Field("ts_headline('english', listing.description, to_tsquery('english', ?), ?) as snippet",
+		query, snippetOptions)
+
+	if query == "" {
+		q.Where("? <% listing_search.words", text)
+		return
+	}
+	q.Where("listing_search.document @@ to_tsquery('english', ?) OR ? <% listing_search.words", query, text)
+}
+
+// textQuery returns the tsquery matching every word of keywords as a prefix,
+// or any of the category keywords. Punctuation is dropped so that the query
+// is always well formed.
+func textQuery(keywords string, categoryKeywords []string) string {
+	var alternatives []string
+	if all := prefixTerms(keywords); all != "" {
+		alternatives = append(alternatives, all)
+	}
+	for _, keyword := range categoryKeywords {
+		if all := prefixTerms(keyword); all != "" {
+			alternatives = append(alternatives, all)
+		}
+	}
+
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	for i, alternative := range alternatives {
+		alternatives[i] = "(" + alternative + ")"
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+func prefixTerms(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	for _, word := range words {
+		terms = append(terms, word+":*")
+	}
+	return strings.Join(terms, " & ")
+}
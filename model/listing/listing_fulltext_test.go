@@ -0,0 +1,15 @@
+package listing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextQuery(t *testing.T) {
+	require.Equal(t, "margs:*", textQuery("Margs", nil))
+	require.Equal(t, "happy:* & hour:* & oysters:*", textQuery(" happy-hour, oysters!", nil))
+	require.Equal(t, "(mexican:*) | (taco:*) | (fish:* & taco:*)", textQuery("mexican", []string{"taco", "fish taco"}))
+	require.Equal(t, "taco:*", textQuery("&|!", []string{"taco"}))
+	require.Equal(t, "", textQuery("':*", nil))
+}
@@ -0,0 +1,254 @@
+package listing
+
+import (
+	"fmt"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/shared"
+)
+
+// galleryFields are the images of listings with their size when they were
+// uploaded through the image pipeline
+const galleryFields = "listing_image.listing_id, listing_image.image_id, listing_image.path, listing_image.position, " +
+	"listing_image.caption, listing_image.alt_text, listing_image.is_primary, " +
+	"COALESCE(image.width, 0), COALESCE(image.height, 0)"
+
+const fromGallery = "listing_image LEFT JOIN image ON image.url = listing_image.path"
+
+// galleryOf returns the images to add with listing, its gallery or else its
+// ImageLink
+func galleryOf(listing *shared.Listing) []shared.GalleryImage {
+	if len(listing.Gallery) > 0 {
+		return listing.Gallery
+	}
+	if listing.ImageLink != "" {
+		return []shared.GalleryImage{{URL: listing.ImageLink}}
+	}
+	return nil
+}
+
+// validateGallery refuses images without a url and galleries with more than
+// one primary image
+func validateGallery(images []shared.GalleryImage) error {
+	primaries := 0
+	for _, img := range images {
+		if img.URL == "" {
+			return helper.ValidationError{Message: "listing images failed, an image is missing its 'url'"}
+		}
+		if img.Primary {
+			primaries++
+		}
+	}
+	if primaries > 1 {
+		return helper.ValidationError{Message: "listing images failed, only one image can be 'primary'"}
+	}
+	return nil
+}
+
+// addGallery adds images after the images listingID has. The image flagged
+// primary becomes the primary image, the first one when the listing has
+// none yet.
+func (l *listingEngine) addGallery(listingID int, images []shared.GalleryImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+	if err := validateGallery(images); err != nil {
+		return err
+	}
+
+	var next int
+	var hasPrimary bool
+	err := l.sql.QueryRow("SELECT COALESCE(MAX(position) + 1, 0), COALESCE(BOOL_OR(is_primary), FALSE) "+
+		"FROM listing_image WHERE listing_id = $1;", listingID).Scan(&next, &hasPrimary)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+
+	primary := -1
+	for i, img := range images {
+		if img.Primary {
+			primary = i
+		}
+	}
+	if primary >= 0 && hasPrimary {
+		if err := l.clearPrimaryImage(listingID); err != nil {
+			return err
+		}
+	}
+	if primary < 0 && !hasPrimary {
+		primary = 0
+	}
+
+	for i, img := range images {
+		_, err := l.sql.Exec("INSERT INTO listing_image(listing_id,path,position,caption,alt_text,is_primary) "+
+			"VALUES($1,$2,$3,$4,$5,$6);", listingID, img.URL, next+i, img.Caption, img.AltText, i == primary)
+		if err != nil {
+			return helper.DatabaseError{DBError: err.Error()}
+		}
+	}
+
+	l.logger.Info().Msgf("added %d images to listing: %d", len(images), listingID)
+	return nil
+}
+
+func (l *listingEngine) clearPrimaryImage(listingID int) error {
+	_, err := l.sql.Exec("UPDATE listing_image SET is_primary = FALSE WHERE listing_id = $1 AND is_primary;", listingID)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	return nil
+}
+
+// GetListingImages returns the gallery of listingID in order
+func (l *listingEngine) GetListingImages(listingID int) ([]shared.GalleryImage, error) {
+	galleries, err := l.getGalleries([]int{listingID})
+	if err != nil {
+		return nil, err
+	}
+	return galleries[listingID], nil
+}
+
+// getGalleries returns the galleries of listingIDs in order, with the
+// renditions of their images
+func (l *listingEngine) getGalleries(listingIDs []int) (map[int][]shared.GalleryImage, error) {
+	ids := make([]interface{}, 0, len(listingIDs))
+	for _, id := range listingIDs {
+		ids = append(ids, id)
+	}
+	query, args := common.Select(galleryFields).
+		From(fromGallery).
+		WhereIn("listing_image.listing_id", ids...).
+		OrderBy("listing_image.listing_id, listing_image.position, listing_image.image_id").
+		Build()
+
+	rows, err := l.sql.Query(query, args...)
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	galleries := make(map[int][]shared.GalleryImage)
+	for rows.Next() {
+		var listingID int
+		var img shared.GalleryImage
+		err := rows.Scan(&listingID, &img.ImageID, &img.URL, &img.Position, &img.Caption, &img.AltText, &img.Primary,
+			&img.Width, &img.Height)
+		if err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		img.Variants = l.imageVariants(img.URL, img.Width, img.Height)
+		galleries[listingID] = append(galleries[listingID], img)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	return galleries, nil
+}
+
+// galleriesOf returns the galleries of listings
+func (l *listingEngine) galleriesOf(listings []shared.Listing) (map[int][]shared.GalleryImage, error) {
+	ids := make([]int, 0, len(listings))
+	for _, listing := range listings {
+		ids = append(ids, listing.ListingID)
+	}
+	return l.getGalleries(ids)
+}
+
+// setGallery sets the gallery of listing and its ListingImages, the detail
+// renditions of the gallery in order
+func (l *listingEngine) setGallery(listing *shared.Listing) error {
+	gallery, err := l.GetListingImages(listing.ListingID)
+	if err != nil {
+		return err
+	}
+	listing.Gallery = gallery
+	listing.ListingImages = galleryLinks(gallery)
+	if len(gallery) == 0 && listing.ImageLink != "" {
+		// listings without a photo show their stock image
+		listing.ListingImages = []string{listing.ImageLink}
+	}
+	return nil
+}
+
+// galleryLinks returns the detail renditions of gallery in order
+func galleryLinks(gallery []shared.GalleryImage) []string {
+	links := make([]string, 0, len(gallery))
+	for _, img := range gallery {
+		if img.Variants != nil {
+			links = append(links, img.Variants.Detail.URL)
+		}
+	}
+	return links
+}
+
+// SetListingImages orders the gallery of listingID as images, which refer to
+// its images by id, updating their caption, alt text and primary flag. The
+// images left out are removed.
+func (l *listingEngine) SetListingImages(listingID int, images []shared.GalleryImage) error {
+	if len(images) == 0 {
+		return helper.ValidationError{Message: "listing images failed, a listing needs at least one image"}
+	}
+
+	current, err := l.GetListingImages(listingID)
+	if err != nil {
+		return err
+	}
+	if len(current) == 0 {
+		return helper.ListingDoesNotExist{ListingID: listingID}
+	}
+
+	known := make(map[int]bool, len(current))
+	for _, img := range current {
+		known[img.ImageID] = true
+	}
+	kept := make(map[int]bool, len(images))
+	primary, primaries := 0, 0
+	for i, img := range images {
+		if !known[img.ImageID] {
+			return helper.ValidationError{Message: fmt.Sprintf("listing images failed, listing %d has no image %d", listingID, img.ImageID)}
+		}
+		if kept[img.ImageID] {
+			return helper.ValidationError{Message: fmt.Sprintf("listing images failed, image %d is listed twice", img.ImageID)}
+		}
+		kept[img.ImageID] = true
+		if img.Primary {
+			primary = i
+			primaries++
+		}
+	}
+	if primaries > 1 {
+		return helper.ValidationError{Message: "listing images failed, only one image can be 'primary'"}
+	}
+
+	err = db.Transact(l.sql, func(tx db.Querier) error {
+		engine := l.withTx(tx)
+		if err := engine.clearPrimaryImage(listingID); err != nil {
+			return err
+		}
+		for _, img := range current {
+			if kept[img.ImageID] {
+				continue
+			}
+			if _, err := tx.Exec("DELETE FROM listing_image WHERE listing_id = $1 AND image_id = $2;", listingID, img.ImageID); err != nil {
+				return helper.DatabaseError{DBError: err.Error()}
+			}
+		}
+		for i, img := range images {
+			_, err := tx.Exec("UPDATE listing_image SET position = $1, caption = $2, alt_text = $3, is_primary = $4 "+
+				"WHERE listing_id = $5 AND image_id = $6;", i, img.Caption, img.AltText, i == primary, listingID, img.ImageID)
+			if err != nil {
+				return helper.DatabaseError{DBError: err.Error()}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	l.logger.Info().Msgf("set %d images of listing: %d", len(images), listingID)
+	return nil
+}
@@ -0,0 +1,34 @@
+package listing
+
+import (
+	"testing"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGallery(t *testing.T) {
+	// listings given an image link only get it as their gallery
+	require.Equal(t, []shared.GalleryImage{{URL: "https://example.com/beer.jpg"}},
+		galleryOf(&shared.Listing{ImageLink: "https://example.com/beer.jpg"}))
+	require.Nil(t, galleryOf(&shared.Listing{}))
+
+	gallery := []shared.GalleryImage{
+		{URL: "https://example.com/beer.jpg", Caption: "beer"},
+		{URL: "https://example.com/wings.jpg", Primary: true},
+	}
+	require.Equal(t, gallery, galleryOf(&shared.Listing{ImageLink: "https://example.com/other.jpg", Gallery: gallery}))
+	require.NoError(t, validateGallery(gallery))
+
+	for _, invalid := range [][]shared.GalleryImage{
+		{{URL: "https://example.com/beer.jpg", Primary: true}, {URL: "https://example.com/wings.jpg", Primary: true}},
+		{{URL: "https://example.com/beer.jpg"}, {Caption: "wings"}},
+	} {
+		require.IsType(t, helper.ValidationError{}, validateGallery(invalid))
+	}
+
+	variants := &shared.ImageVariants{Detail: shared.ImageVariant{URL: "https://example.com/beer_detail.jpg"}}
+	require.Equal(t, []string{"https://example.com/beer_detail.jpg"},
+		galleryLinks([]shared.GalleryImage{{URL: "https://example.com/beer.jpg", Variants: variants}, {}}))
+}
@@ -0,0 +1,148 @@
+package listing
+
+import (
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+)
+
+// PageKey returns the place of a listing in the ordering sortBy, as sorted by
+// the SortListingEngine. Listings that are on now are grouped first when now
+// is set. Ties are broken by distance and then by listing date, so that every
+// listing has a place of its own. Keys hold instants rather than durations,
+// the time left of a deal changes between pages, its end does not.
+func PageKey(sortBy string, listing shared.Listing, now bool) shared.PageKey {
+	group := 1.0
+	if now {
+		group = 0
+	}
+
+	var rank []float64
+	switch sortBy {
+	case shared.SortByTimeLeft:
+		var end float64
+		if listing.ListingDate != "" && listing.EndTime != "" {
+			if endsAt, err := listingEndsAt(listing.ListingDate, listing.EndTime); err == nil {
+				end = float64(endsAt.Unix())
+			}
+		}
+		rank = []float64{group, end}
+	case shared.SortByPrice:
+		// deals without a price come after the priced ones
+		unpriced := 0.0
+		if listing.NewPrice == 0 {
+			unpriced = 1
+		}
+		rank = []float64{group, unpriced, listing.NewPrice}
+	case shared.SortByDateAdded:
+		added := listing.ListingCreateDate
+		if listing.Favorite != nil {
+			added = listing.Favorite.FavoriteAddDate
+		}
+		var newest float64
+		if addedAt, err := time.Parse(shared.DateTimeFormat, added); err == nil {
+			newest = -float64(addedAt.Unix())
+		}
+		rank = []float64{group, newest}
+	case shared.SortByMostPopular:
+		rank = []float64{group, -float64(listing.UpVotes)}
+	case shared.SortByRelevance:
+		rank = []float64{group, -listing.Relevance}
+	default:
+		// searches over several days list them day by day
+		var day float64
+		if date, err := time.Parse(shared.DateFormatSQL, strings.Split(listing.ListingDate, "T")[0]); err == nil {
+			day = float64(date.Unix())
+		}
+		rank = []float64{group, day}
+	}
+
+	return shared.PageKey{Rank: append(rank, listing.DistanceFromLocation), ID: listing.ListingDateID}
+}
+
+// isLiveAt reports whether the date of a listing is on at the wall clock
+// time at
+func isLiveAt(listing shared.Listing, at time.Time) bool {
+	if listing.ListingDate == "" || !strings.Contains(listing.StartTime, "T") || !strings.Contains(listing.EndTime, "T") {
+		return false
+	}
+	start, err := time.Parse(shared.DateTimeFormat, getListingDateTime(listing.ListingDate, listing.StartTime))
+	if err != nil {
+		return false
+	}
+	end, err := listingEndsAt(listing.ListingDate, listing.EndTime)
+	if err != nil {
+		return false
+	}
+	return inTimeSpan(start, end, at)
+}
+
+// wallClock returns the time of day t shows, as if it was UTC, the way
+// listing times are compared
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// pageSearchResults orders the results of a search, results[i] being the
+// result of listings[i], and returns the page asked for with the cursor of
+// the next one. Deals on now come first when sorting by time left, and when
+// sorting by distance if they are within MaxDistanceToGroupNow. Whether a
+// deal is on is decided as of the first page, now for a first page, so that
+// deals starting or ending between pages do not move between them.
+func (l *listingEngine) pageSearchResults(request shared.SearchRequest, listings []shared.Listing,
+	results []shared.SearchListingResult, now time.Time) ([]shared.SearchListingResult, string, error) {
+
+	at := shared.PageTime(request.Page, wallClock(now))
+
+	sortBy := request.SortBy
+	if sortBy == "" && request.Future {
+		// future deals are sorted by time left, still grouping the ones on now
+		sortBy = shared.SortByTimeLeft
+	}
+
+	keys := make([]shared.PageKey, len(results))
+	for i, result := range results {
+		var now bool
+		switch request.SortBy {
+		case shared.SortByTimeLeft:
+			now = isLiveAt(listings[i], at)
+		case "", shared.SortByDistance:
+			now = isLiveAt(listings[i], at) && result.DistanceFromLocation <= l.searchConfig.MaxDistanceToGroupNow
+		}
+		keys[i] = PageKey(sortBy, listings[i], now)
+	}
+
+	return PageResultsAt(results, keys, sortBy, request.Page, at)
+}
+
+// PageResults orders results by their keys and returns the page asked for
+// with the cursor of the next one
+func PageResults(results []shared.SearchListingResult, keys []shared.PageKey, sortBy string,
+	page shared.Page) ([]shared.SearchListingResult, string, error) {
+	return PageResultsAt(results, keys, sortBy, page, time.Time{})
+}
+
+// PageResultsAt is PageResults for orderings that depend on the time, see
+// shared.PaginateAt
+func PageResultsAt(results []shared.SearchListingResult, keys []shared.PageKey, sortBy string,
+	page shared.Page, at time.Time) ([]shared.SearchListingResult, string, error) {
+
+	order := shared.OrderByPageKey(keys)
+	sortedKeys := make([]shared.PageKey, len(order))
+	for i, j := range order {
+		sortedKeys[i] = keys[j]
+	}
+
+	from, to, next, err := shared.PaginateAt(sortedKeys, sortBy, page, at)
+	if err != nil {
+		return nil, "", helper.ValidationError{Message: err.Error()}
+	}
+
+	var paged = make([]shared.SearchListingResult, 0, to-from)
+	for _, j := range order[from:to] {
+		paged = append(paged, results[j])
+	}
+	return paged, next, nil
+}
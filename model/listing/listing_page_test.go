@@ -0,0 +1,75 @@
+package listing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageSearchResultsGroupsNow(t *testing.T) {
+	l := &listingEngine{searchConfig: common.DefaultSearchConfig()}
+
+	listing := func(id int, start, end string, distance float64) shared.Listing {
+		return shared.Listing{
+			ListingDateID:        id,
+			ListingDate:          "2018-09-03T00:00:00Z",
+			StartTime:            "0000-01-01T" + start + ":00Z",
+			EndTime:              "0000-01-01T" + end + ":00Z",
+			DistanceFromLocation: distance,
+		}
+	}
+	listings := []shared.Listing{
+		listing(1, "17:30", "17:50", 1),
+		listing(2, "15:00", "19:00", 2),
+		listing(3, "16:00", "18:00", 3),
+		listing(4, "16:00", "18:00", 9),
+	}
+	var results []shared.SearchListingResult
+	for _, listing := range listings {
+		results = append(results, shared.SearchListingResult{
+			ListingDateID:        listing.ListingDateID,
+			DistanceFromLocation: listing.DistanceFromLocation,
+		})
+	}
+
+	ids := func(results []shared.SearchListingResult) []int {
+		var ids []int
+		for _, result := range results {
+			ids = append(ids, result.ListingDateID)
+		}
+		return ids
+	}
+	at := func(clock string) time.Time {
+		now, err := time.Parse(shared.DateTimeFormat, "2018-09-03T"+clock+":00Z")
+		require.NoError(t, err)
+		return now
+	}
+
+	// deals on now within MaxDistanceToGroupNow come first
+	request := shared.SearchRequest{Page: shared.Page{Limit: 3}}
+	page, next, err := l.pageSearchResults(request, listings, results, at("17:00"))
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 3, 1}, ids(page))
+
+	request.Page.Cursor = next
+	page, next, err = l.pageSearchResults(request, listings, results, at("17:00"))
+	require.NoError(t, err)
+	require.Equal(t, []int{4}, ids(page))
+	require.Empty(t, next)
+
+	// by time left every deal on now comes first
+	request = shared.SearchRequest{SortBy: shared.SortByTimeLeft, Page: shared.Page{Limit: 2}}
+	page, next, err = l.pageSearchResults(request, listings, results, at("17:00"))
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 4}, ids(page))
+
+	// a deal starting between pages keeps its place
+	request.Page.Cursor = next
+	page, next, err = l.pageSearchResults(request, listings, results, at("17:40"))
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1}, ids(page))
+	require.Empty(t, next)
+}
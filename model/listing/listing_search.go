@@ -3,75 +3,115 @@ package listing
 import (
 	"bytes"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/bradfitz/latlong"
-
-	"github.com/phassans/banana/clients"
+	"github.com/phassans/banana/clients/geocode"
+	"github.com/phassans/banana/geohash"
 	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/locale"
 	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/model/region"
 	"github.com/phassans/banana/shared"
-	"github.com/umahmood/haversine"
 )
 
 func (l *listingEngine) SearchListings(request shared.SearchRequest) ([]shared.SearchListingResult, error) {
+	page, err := l.SearchListingsPage(request)
+	return page.Results, err
+}
+
+// SearchListingsPage returns the page of search results request.Page asks
+// for, with the cursor of the next page and the place searched in. Searches
+// made through the app are logged with their outcome.
+func (l *listingEngine) SearchListingsPage(request shared.SearchRequest) (shared.SearchPage, error) {
+	record := SearchRecord{Request: request, Date: time.Now()}
+
+	page, err := l.searchListingsPage(request, &record)
+
+	if !request.Internal && l.searchLog != nil {
+		record.Duration = time.Since(record.Date)
+		record.ErrorCategory = searchErrorCategory(err)
+		l.searchLog.Log(record)
+	}
+	return page, err
+}
+
+// searchListingsPage runs a search, filling in record as it goes
+func (l *listingEngine) searchListingsPage(request shared.SearchRequest, record *SearchRecord) (shared.SearchPage, error) {
 	var listings []shared.Listing
 	var err error
 
-	// log search Request
-	go func(req shared.SearchRequest) {
-		err := l.LogSearchRequest(req)
-		l.logger.Error().Msgf("LogSearchRequest returned with error: %s", err)
-	}(request)
+	record.Filters = normalizeSearchFilters(request, 0)
 
 	// determine current location
 	currentLocation, err := l.DetermineCurrentLocation(request.Location, request.Latitude, request.Longitude)
 	if err != nil {
-		return nil, err
+		return shared.SearchPage{}, err
+	}
+	record.Location = currentLocation
+
+	// searches outside every region served find nothing yet
+	inRegion, ok, err := l.forLocation(currentLocation)
+	if err != nil {
+		return shared.SearchPage{}, err
+	}
+	if !ok {
+		l.logger.Info().Msgf("location not in any region: %v", currentLocation)
+		return shared.SearchPage{Results: []shared.SearchListingResult{}, ComingSoon: true}, nil
 	}
+	l = inRegion.withLanguage(request.Language)
+
+	// searches in a city use its distances, within the search radius of
+	// the region
+	var city string
+	place := l.describeLocation(currentLocation)
+	if place != nil {
+		city = place.City
+	}
+	l = l.forCity(city)
+
+	// the database keeps the listings within radius, nearest first
+	radius, err := l.searchRadius(request.Future, request.DistanceFilter)
+	if err != nil {
+		return shared.SearchPage{}, err
+	}
+	record.Filters.Radius = radius
 
 	// GetListings
-	listings, err = l.GetListings(request.ListingTypes, request.Keywords, request.Future, request.SearchDay, currentLocation)
+	listings, err = l.GetListings(request.ListingTypes, request.Keywords, request.SearchDay, currentLocation, radius)
 	if err != nil {
-		return nil, err
+		return shared.SearchPage{}, err
 	}
 	l.logger.Info().Msgf("total number of listing found: %d", len(listings))
 
 	// populate UpVotes
 	if err := l.populateUpVotes(request.PhoneID, listings); err != nil {
-		return nil, err
+		return shared.SearchPage{}, err
 	}
 
-	// isLocationInRange
-	/*if !isDistanceInRange(currentLocation) {
-		l.logger.Error().Msgf("location not in range: %v", currentLocation)
-		return []shared.SearchListingResult{}, helper.LocationError{Message: "location not in range"}
-	}*/
 	l.logger.Info().Msgf("search location: %v", currentLocation)
 
 	// sort Listings based on sortBy
-	sortListingEngine := NewSortListingEngine(listings, request.SortBy, currentLocation, l.sql)
+	sortListingEngine := NewSortListingEngine(listings, request.SortBy, currentLocation, l.sql, l.searchConfig)
 	listings, err = sortListingEngine.SortListings(request.Future, request.SearchDay, request.Search, false)
 	if err != nil {
-		return nil, err
+		return shared.SearchPage{}, err
 	}
 	l.logger.Info().Msgf("done sorting the listings. listings count: %d", len(listings))
 
 	// filterResults
-	listings, err = l.filterResults(listings, request.PriceFilter, request.DietaryFilters, request.DistanceFilter)
+	listings, err = l.filterResults(listings, request.PriceFilter, request.DietaryFilters)
 	if err != nil {
-		return nil, err
+		return shared.SearchPage{}, err
 	}
 	l.logger.Info().Msgf("applied filters. number of listings: %d", len(listings))
 
 	// populate favorites
 	if err := l.populateFavorites(request.PhoneID, listings); err != nil {
-		return nil, err
+		return shared.SearchPage{}, err
 	}
 
 	var searchListing = make([]shared.SearchListingResult, 0)
@@ -79,24 +119,27 @@ func (l *listingEngine) SearchListings(request shared.SearchRequest) ([]shared.S
 	if isWeekDay(request.SearchDay) {
 		searchListing, err = l.MassageAndPopulateSearchListingsWeekly(listings, false, request.SearchDay)
 		if err != nil {
-			return searchListing, err
+			return shared.SearchPage{}, err
 		}
 	} else {
 		searchListing, err = l.MassageAndPopulateSearchListings(listings, false, request.SearchDay)
 		if err != nil {
-			return searchListing, err
+			return shared.SearchPage{}, err
 		}
 	}
 
-	if request.SortBy == shared.SortByTimeLeft {
-		searchListing = groupListingsBasedOnCurrentTime(searchListing)
-		return searchListing, nil
-	} else if request.SortBy == "" || request.SortBy == shared.SortByDistance {
-		searchListing = GroupListingsOnNow(searchListing)
-		return searchListing, nil
+	record.Results = len(searchListing)
+
+	now, err := l.now(currentLocation)
+	if err != nil {
+		return shared.SearchPage{}, err
 	}
 
-	return searchListing, nil
+	results, next, err := l.pageSearchResults(request, listings, searchListing, now)
+	if err != nil {
+		return shared.SearchPage{}, err
+	}
+	return shared.SearchPage{Results: results, NextCursor: next, Place: place}, nil
 }
 
 func isWeekDay(sday string) bool {
@@ -151,84 +194,94 @@ func (l *listingEngine) populateUpVotes(phoneID string, listings []shared.Listin
 	return nil
 }
 
+// DetermineCurrentLocation returns the coordinates given, or else the
+// location of the place named
 func (l *listingEngine) DetermineCurrentLocation(location string, latitude float64, longitude float64) (shared.GeoLocation, error) {
-	var currentLocation shared.GeoLocation
-	var resp clients.LatLong
-	var err error
-	if location != "" && latitude == 0 && longitude == 0 {
-		// check DB first
-		currentLocation, err = l.GetGeoFromAddress(location)
-		if err != nil {
-			l.logger.Error().Msgf("GetGeoFromAddress returned with error: %s", err)
-			return currentLocation, err
-		}
-		if currentLocation != (shared.GeoLocation{}) {
-			l.logger.Info().Msgf("GeoLocation found in DB")
-			// if invalid location
-			if currentLocation.Latitude == -1 && currentLocation.Longitude == -1 {
-				return currentLocation, helper.LocationError{Message: "invalid location"}
-			}
-		} else {
-			// else fetch from Google API getLatLonFromLocation
-			resp, err = clients.GetLatLong(location)
-			if err != nil {
-				return currentLocation, err
-			}
-			currentLocation = shared.GeoLocation{Latitude: resp.Lat, Longitude: resp.Lon}
+	if location == "" || latitude != 0 || longitude != 0 {
+		return shared.GeoLocation{Latitude: latitude, Longitude: longitude}, nil
+	}
 
-			// cache the result in database
-			go func() {
-				err = l.AddGeoLocation(location, currentLocation)
-				if err != nil {
-					l.logger.Error().Msgf("AddGeoLocation error: %s", err)
-				}
+	currentLocation, err := l.geocoder.Geocode(location)
+	if err == geocode.ErrNotFound {
+		return currentLocation, helper.LocationError{Message: "invalid location"}
+	}
+	if err != nil {
+		l.logger.Error().Msgf("geocoding %s returned with error: %s", location, err)
+		return currentLocation, err
+	}
 
-			}()
+	l.logger.Info().Msgf("geolocation lat: %f and lon: %f", currentLocation.Latitude, currentLocation.Longitude)
+	return currentLocation, nil
+}
 
-			// if invalid location
-			if currentLocation.Latitude == -1 && currentLocation.Longitude == -1 {
-				return currentLocation, helper.LocationError{Message: "invalid location"}
-			}
+// describeLocation returns the place location is in, nil when it is unknown
+// or cannot be told now
+func (l *listingEngine) describeLocation(location shared.GeoLocation) *shared.Place {
+	if location.Latitude == 0 && location.Longitude == 0 {
+		return nil
+	}
 
-			l.logger.Info().Msgf("GeoLocation found in Google")
-		}
+	place, err := l.geocoder.ReverseGeocode(location)
+	if err == geocode.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		l.logger.Error().Msgf("reverse geocoding %v returned with error: %s", location, err)
+		return nil
+	}
+	return &place
+}
 
-		l.logger.Info().Msgf("geolocation lat: %f and lon: %f", currentLocation.Latitude, currentLocation.Longitude)
-	} else {
-		currentLocation = shared.GeoLocation{Latitude: latitude, Longitude: longitude}
+// forLocation returns a copy of the engine searching in the region location
+// is in, see forCity for its distances. ok is false when location is in no
+// region. Without a location there is no region to tell, ok is true.
+func (l *listingEngine) forLocation(location shared.GeoLocation) (*listingEngine, bool, error) {
+	if location.Latitude == 0 && location.Longitude == 0 {
+		return l, true, nil
 	}
 
-	return currentLocation, nil
+	r, ok, err := l.regionEngine.RegionFor(location)
+	if err != nil || !ok {
+		return l, false, err
+	}
+
+	engine := *l
+	engine.region = r
+	return &engine, true, nil
 }
 
-func GroupListingsOnNow(listings []shared.SearchListingResult) []shared.SearchListingResult {
-	var searchListingsLeft = make([]shared.SearchListingResult, 0)
-	var searchListingRange = make([]shared.SearchListingResult, 0)
-	for _, listing := range listings {
-		if strings.Contains(listing.DateTimeRange, "left") && listing.DistanceFromLocation <= common.MaxDistanceToGroupNow {
-			searchListingsLeft = append(searchListingsLeft, listing)
-		} else {
-			searchListingRange = append(searchListingRange, listing)
-		}
-	}
-	return append(searchListingsLeft, searchListingRange...)
+// withLanguage returns a copy of the engine showing listings in language
+func (l *listingEngine) withLanguage(language string) *listingEngine {
+	engine := *l
+	engine.language = language
+	return &engine
 }
 
-func groupListingsBasedOnCurrentTime(listings []shared.SearchListingResult) []shared.SearchListingResult {
-	var searchListingsLeft []shared.SearchListingResult
-	var searchListingRange []shared.SearchListingResult
-	for _, listing := range listings {
-		if strings.Contains(listing.DateTimeRange, "left") {
-			searchListingsLeft = append(searchListingsLeft, listing)
-		} else {
-			searchListingRange = append(searchListingRange, listing)
-		}
+// locale returns how listings are shown in the region searched, in the
+// language of the user
+func (l *listingEngine) locale() locale.Locale {
+	return locale.New(l.region, l.language)
+}
+
+// now returns the current time in the time zone of the region searched in,
+// or else of loc
+func (l *listingEngine) now(loc shared.GeoLocation) (time.Time, error) {
+	if l.region.TimeZone != "" {
+		return region.Now(l.region)
 	}
-	return append(searchListingsLeft, searchListingRange...)
+	return getCurrentTimeInTimeZone(loc)
+}
+
+// forCity returns a copy of the engine searching with the distances of city,
+// capped at the search radius of the region searched in
+func (l *listingEngine) forCity(city string) *listingEngine {
+	engine := *l
+	engine.searchConfig = l.searchConfig.ForCity(city).WithinRadius(l.region.SearchRadiusMiles)
+	return &engine
 }
 
 func (l *listingEngine) filterResults(listings []shared.Listing, priceFilter float64,
-	dietaryFilters []string, distanceFilter string) ([]shared.Listing, error) {
+	dietaryFilters []string) ([]shared.Listing, error) {
 
 	var err error
 	if priceFilter > 0.0 {
@@ -241,51 +294,28 @@ func (l *listingEngine) filterResults(listings []shared.Listing, priceFilter flo
 		l.logger.Info().Msgf("applied dietaryFilters. count listings: %d", len(listings))
 	}
 
-	if distanceFilter != "" {
-		listings, err = l.FilterByDistance(listings, distanceFilter)
-		l.logger.Info().Msgf("applied distanceFilter. count listings: %d", len(listings))
-	}
-
 	return listings, err
 }
 
-func (l *listingEngine) getListingTypeWhereClause(listingTypes []string) string {
-	var listingTypesClause bytes.Buffer
-	if len(listingTypes) > 0 {
-		listingTypesClause.WriteString("(")
-		i := 1
-		for _, listingType := range listingTypes {
-			if len(listingTypes) == i {
-				listingTypesClause.WriteString(fmt.Sprintf("'%s')", listingType))
-			} else {
-				listingTypesClause.WriteString(fmt.Sprintf("'%s',", listingType))
-			}
-			i++
-		}
-	}
-	return listingTypesClause.String()
-}
-
-func (l *listingEngine) getWhereClause(listingTypes []string, future bool, searchDay string, loc shared.GeoLocation) (string, error) {
-	var whereClause bytes.Buffer
-
-	timeInZone, err := getCurrentTimeInTimeZone(loc)
+// addSearchFilters narrows q to the listing dates covered by searchDay and to
+// listingTypes. It returns false when searchDay covers no dates, in which
+// case there is nothing to search.
+func (l *listingEngine) addSearchFilters(q *common.Query, listingTypes []string, searchDay string, loc shared.GeoLocation) (bool, error) {
+	timeInZone, err := l.now(loc)
 	if err != nil {
-		return "", err
+		return false, err
 	}
 
 	if searchDay == "today" || searchDay == "" {
 		currentDate := timeInZone.Format(shared.DateFormatSQL) //"2006-01-02"
 		currentTime := timeInZone.Format(shared.TimeLayout24Hour)
 
-		whereClause.WriteString(fmt.Sprintf("WHERE listing_date.listing_date = '%s' AND listing_date.end_time >= '%s'", currentDate, currentTime))
+		q.Where("listing_date.listing_date = ? AND listing_date.end_time >= ?", currentDate, currentTime)
 	} else if searchDay == shared.SearchTomorrow || searchDay == shared.SearchThisWeek || searchDay == shared.SearchNextWeek {
-		var dateClause bytes.Buffer
-
 		currentDate := timeInZone.Format(shared.DateFormat)
 		listingDate, err := time.Parse(shared.DateFormat, currentDate)
 		if err != nil {
-			return "", helper.DatabaseError{DBError: err.Error()}
+			return false, helper.DatabaseError{DBError: err.Error()}
 		}
 
 		startDay := 0
@@ -294,80 +324,123 @@ func (l *listingEngine) getWhereClause(listingTypes []string, future bool, searc
 		case shared.SearchTomorrow:
 			endDay = 1
 		case shared.SearchThisWeek:
-			//fmt.Println("listingDate.Weekday().String()", listingDate.Weekday().String())
 			endDay = 6 - shared.DayMap[strings.ToLower(listingDate.Weekday().String())]
 		case shared.SearchNextWeek:
 			startDay = 6 - shared.DayMap[strings.ToLower(listingDate.Weekday().String())]
 			endDay = 13 - shared.DayMap[strings.ToLower(listingDate.Weekday().String())]
 		}
 
-		if searchDay == shared.SearchTomorrow || searchDay == shared.SearchThisWeek {
-			curr := listingDate
-			dateClause.WriteString("(")
-
-			if endDay == 0 {
-				if dateClause.String() == "(" {
-					return "", nil
-				}
-				dateClause.WriteString(")")
-			}
-
-			for i := 0; i < endDay; i++ {
-				nextDate := curr.Add(time.Hour * 24)
-				if i == endDay-1 {
-					dateClause.WriteString(fmt.Sprintf("'%s')", strings.Split(nextDate.String(), " ")[0]))
-				} else {
-					dateClause.WriteString(fmt.Sprintf("'%s',", strings.Split(nextDate.String(), " ")[0]))
-				}
-				curr = nextDate
-
+		var dates []interface{}
+		curr := listingDate
+		for i := 0; i < endDay; i++ {
+			nextDate := curr.Add(time.Hour * 24)
+			if i >= startDay {
+				dates = append(dates, nextDate.Format(shared.DateFormatSQL))
 			}
-			whereClause.WriteString(fmt.Sprintf("WHERE listing_date IN %s", dateClause.String()))
-
-		} else if searchDay == shared.SearchNextWeek {
-			curr := listingDate
-			dateClause.WriteString("(")
-			for i := 0; i < endDay; i++ {
-				nextDate := curr.Add(time.Hour * 24)
-				if i >= startDay {
-					if i == endDay-1 {
-						dateClause.WriteString(fmt.Sprintf("'%s')", strings.Split(nextDate.String(), " ")[0]))
-					} else {
-						dateClause.WriteString(fmt.Sprintf("'%s',", strings.Split(nextDate.String(), " ")[0]))
-					}
-				}
-				curr = nextDate
+			curr = nextDate
+		}
 
-			}
-			whereClause.WriteString(fmt.Sprintf("WHERE listing_date IN %s", dateClause.String()))
+		if len(dates) == 0 {
+			return false, nil
 		}
-	} else if searchDay != "" {
+		q.WhereIn("listing_date.listing_date", dates...)
+	} else {
 		currentDate := timeInZone.Format(shared.DateFormat)
 		listingDate, err := time.Parse(shared.DateFormat, currentDate)
 		if err != nil {
-			return "", helper.DatabaseError{DBError: err.Error()}
+			return false, helper.DatabaseError{DBError: err.Error()}
 		}
 
+		var searchDate string
 		curr := listingDate
-		var res string
 		for i := 0; i <= 7; i++ {
 			nextDate := curr.Add(time.Hour * 24)
 			curr = nextDate
 			if strings.ToLower(nextDate.Weekday().String()) == searchDay {
-				res = nextDate.String()
+				searchDate = nextDate.Format(shared.DateFormatSQL)
 				break
 			}
 		}
 
-		whereClause.WriteString(fmt.Sprintf("WHERE listing_date.listing_date = '%s'", strings.Split(res, " ")[0]))
+		if searchDate == "" {
+			return false, nil
+		}
+		q.Where("listing_date.listing_date = ?", searchDate)
 	}
 
 	if len(listingTypes) > 0 {
-		whereClause.WriteString(fmt.Sprintf(" AND listing_type in %s", l.getListingTypeWhereClause(listingTypes)))
+		types := make([]interface{}, len(listingTypes))
+		for i, listingType := range listingTypes {
+			types[i] = listingType
+		}
+		q.WhereIn("listing.listing_type", types...)
+	}
+
+	return true, nil
+}
+
+// distanceSQL is the haversine distance in miles of a business address from
+// the bound latitude, latitude and longitude, the same as haversine.Distance
+const distanceSQL = "3958 * 2 * asin(least(1, sqrt(" +
+	"power(sin(radians(business_address.latitude - ?) / 2), 2) + " +
+	"cos(radians(?)) * cos(radians(business_address.latitude)) * " +
+	"power(sin(radians(business_address.longitude - ?) / 2), 2))))"
+
+// searchRadius returns the radius in miles to search within, the distance
+// filter narrows the default radius of the search but never widens it
+func (l *listingEngine) searchRadius(future bool, distanceFilter string) (float64, error) {
+	radius := l.searchConfig.MaxDistanceForTodaysDeals
+	if future {
+		radius = l.searchConfig.MaxDistanceForFutureDeals
+	}
+
+	if distanceFilter == "" {
+		return radius, nil
 	}
 
-	//fmt.Println(whereClause.String())
-	return whereClause.String(), nil
+	filter := l.searchConfig.MaxFilterDistance
+	if distanceFilter != "all" {
+		var err error
+		filter, err = strconv.ParseFloat(distanceFilter, 64)
+		if err != nil {
+			return 0, helper.ValidationError{Message: fmt.Sprintf("invalid distanceFilter: %s", distanceFilter)}
+		}
+	}
+	return math.Min(radius, filter), nil
+}
+
+// addDistanceFilter selects the distance in miles of each listing from loc as
+// distance and narrows q to the listings within radius. The geohash cells
+// covering the radius are looked up on the index first, the exact distance is
+// only computed for the addresses in them. Without a location every listing
+// is kept at distance 0.
+func addDistanceFilter(q *common.Query, loc shared.GeoLocation, radius float64) {
+	if loc.Latitude == 0 && loc.Longitude == 0 {
+		q.Field("0 as distance")
+		return
+	}
+
+	q.Field(distanceSQL+" as distance", loc.Latitude, loc.Latitude, loc.Longitude)
+	addRadiusFilter(q, loc, radius)
+}
+
+// addRadiusFilter narrows q, which joins business_address, to the addresses
+// within radius of loc. Without a location every address is kept.
+func addRadiusFilter(q *common.Query, loc shared.GeoLocation, radius float64) {
+	if loc.Latitude == 0 && loc.Longitude == 0 {
+		return
+	}
+
+	if cells := geohash.Cover(loc.Latitude, loc.Longitude, radius); len(cells) > 0 {
+		var conditions []string
+		var prefixes []interface{}
+		for _, cell := range cells {
+			conditions = append(conditions, "business_address.geohash LIKE ?")
+			prefixes = append(prefixes, cell+"%")
+		}
+		q.Where(strings.Join(conditions, " OR "), prefixes...)
+	}
+	q.Where(distanceSQL+" <= ?", loc.Latitude, loc.Latitude, loc.Longitude, radius)
 }
 
 func (l *listingEngine) getKeywordsFromCategory(keywords string) ([]string, error) {
@@ -394,14 +467,14 @@ func (l *listingEngine) getKeywordsFromCategory(keywords string) ([]string, erro
 	return foundKeywords, nil
 }
 
-func (l *listingEngine) GetListings(listingType []string, keywords string, future bool, searchDay string, loc shared.GeoLocation) ([]shared.Listing, error) {
+func (l *listingEngine) GetListings(listingType []string, keywords string, searchDay string, loc shared.GeoLocation, radius float64) ([]shared.Listing, error) {
 	if searchDay == shared.SearchThisWeek {
-		todaysListings, err := l.getListings(listingType, keywords, future, shared.SearchToday, loc)
+		todaysListings, err := l.getListings(listingType, keywords, shared.SearchToday, loc, radius)
 		if err != nil {
 			return nil, err
 		}
 
-		thisWeekListings, err := l.getListings(listingType, keywords, future, shared.SearchThisWeek, loc)
+		thisWeekListings, err := l.getListings(listingType, keywords, shared.SearchThisWeek, loc, radius)
 		if err != nil {
 			return nil, err
 		}
@@ -409,60 +482,47 @@ func (l *listingEngine) GetListings(listingType []string, keywords string, futur
 		todaysListings = append(todaysListings, thisWeekListings...)
 		return todaysListings, nil
 	}
-	return l.getListings(listingType, keywords, future, searchDay, loc)
+	return l.getListings(listingType, keywords, searchDay, loc, radius)
 
 }
 
-func (l *listingEngine) getListings(listingType []string, keywords string, future bool, searchDay string, loc shared.GeoLocation) ([]shared.Listing, error) {
-	// determine where clause
-	whereClause, err := l.getWhereClause(listingType, future, searchDay, loc)
-	if err != nil {
-		return nil, err
-	}
-
-	// if no where clause nothing to do here, return
-	if whereClause == "" {
-		return nil, nil
+func (l *listingEngine) getListings(listingType []string, keywords string, searchDay string, loc shared.GeoLocation, radius float64) ([]shared.Listing, error) {
+	from := common.FromClauseListingWithAddress
+	if keywords != "" {
+		from += common.JoinListingSearch
 	}
+	q := common.Select(common.ListingFields, common.ListingBusinessFields, common.ListingBusinessAddressFields,
+		common.ListingDateFields, common.ListingImageFields, common.ListingDateTimeFields).From(from)
+	addDistanceFilter(q, loc, radius)
 
-	splitKeywordsBySpace := strings.Split(keywords, " ")
-	searchKeywords := strings.Join(splitKeywordsBySpace, ",")
-
-	selectFields := fmt.Sprintf("%s, %s, %s, %s, %s", common.ListingFields, common.ListingBusinessFields, common.ListingBusinessAddressFields, common.ListingDateFields, common.ListingImageFields)
-
-	var searchQuery string
 	if keywords != "" {
-
-		foundKeys, err := l.getKeywordsFromCategory(keywords)
+		categoryKeywords, err := l.getKeywordsFromCategory(keywords)
 		if err != nil {
 			return nil, err
 		}
-
-		if len(foundKeys) > 0 {
-			var noSpaceKeys []string
-			for _, k := range foundKeys {
-				k = strings.Replace(k, " ", " | ", -1)
-				noSpaceKeys = append(noSpaceKeys, k)
-			}
-			searchKeywords = strings.Join(noSpaceKeys, " | ")
-		}
-
-		searchQuery = fmt.Sprintf("SELECT title, old_price, new_price, discount, discount_description, description, start_date, end_date, "+
-			"start_time, end_time, multiple_days, recurring, recurring_date, listing_type, business_id, listing_id, listing_create_date, bname, latitude, longitude, listing_date_id, listing_date, path "+
-			"FROM (%s, "+
-			"to_tsvector('english', business.name) || "+
-			"to_tsvector('english', listing.title) || "+
-			//"to_tsvector('english', business_cuisine.cuisine) || "+
-			"to_tsvector('english', listing.description) as document "+
-			"%s %s ) p_search "+
-			"WHERE p_search.document @@ to_tsquery('english', '%s');", selectFields, common.FromClauseListingWithAddress, whereClause, searchKeywords)
+		addKeywordFilter(q, keywords, categoryKeywords)
 	} else {
-		searchQuery = fmt.Sprintf("%s %s %s;", selectFields, common.FromClauseListingWithAddress, whereClause)
+		q.Field("0 as relevance")
+		q.Field("'' as snippet")
 	}
 
+	// determine where clause
+	ok, err := l.addSearchFilters(q, listingType, searchDay, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	// if no dates to search nothing to do here, return
+	if !ok {
+		return nil, nil
+	}
+
+	q.OrderBy("distance, listing_date_id")
+
+	searchQuery, args := q.Build()
 	l.logger.Info().Msgf("search Query: %s", searchQuery)
 
-	rows, err := l.sql.Query(searchQuery)
+	rows, err := l.sql.Query(searchQuery, args...)
 	if err != nil {
 		return nil, helper.DatabaseError{DBError: err.Error()}
 	}
@@ -470,9 +530,12 @@ func (l *listingEngine) getListings(listingType []string, keywords string, futur
 	defer rows.Close()
 
 	var listings []shared.Listing
+	var dates []dateTimes
 	var sqlEndDate sql.NullString
 	var sqlRecurringEndDate sql.NullString
 	var sqlCreateDate sql.NullString
+	var sqlDateStartTime sql.NullString
+	var sqlDateEndTime sql.NullString
 	for rows.Next() {
 		var listing shared.Listing
 		err = rows.Scan(
@@ -499,12 +562,19 @@ func (l *listingEngine) getListings(listingType []string, keywords string, futur
 			&listing.ListingDateID,
 			&listing.ListingDate,
 			&listing.ListingImage,
+			&listing.ImageWidth,
+			&listing.ImageHeight,
+			&listing.Category,
+			&sqlDateStartTime,
+			&sqlDateEndTime,
+			&listing.DistanceFromLocation,
+			&listing.Relevance,
+			&listing.Snippet,
 		)
 		if err != nil {
 			return nil, helper.DatabaseError{DBError: err.Error()}
 		}
-		listing.ListingImage = optimizeImage(listing.ListingImage)
-		listing.ListingImages = []string{optimizeImage(listing.ListingImage)}
+		dates = append(dates, dateTimes{sqlDateStartTime, sqlDateEndTime})
 		listing.EndDate = sqlEndDate.String
 		listing.RecurringEndDate = sqlRecurringEndDate.String
 		listing.ListingCreateDate = sqlCreateDate.String
@@ -516,11 +586,21 @@ func (l *listingEngine) getListings(listingType []string, keywords string, futur
 		return nil, helper.DatabaseError{DBError: err.Error()}
 	}
 
-	return listings, nil
+	// a date runs at the time window of its day
+	windows, err := l.getSplitWindows(listings, dates)
+	if err != nil {
+		return nil, err
+	}
+	return setDateTimes(listings, dates, windows), nil
 }
 
 func (l *listingEngine) MassageAndPopulateSearchListings(listings []shared.Listing, isFavorite bool, searchDay string) ([]shared.SearchListingResult, error) {
 	var listingsResult = make([]shared.SearchListingResult, 0)
+	galleries, err := l.galleriesOf(listings)
+	if err != nil {
+		return nil, err
+	}
+	stock := l.stockLibrary()
 	for _, listing := range listings {
 		timeLeft, err := l.calculateTimeLeftForSearch(listing.ListingDate, listing.StartTime, listing.EndTime, listing.CurrentLocation)
 		if err != nil {
@@ -533,6 +613,10 @@ func (l *listingEngine) MassageAndPopulateSearchListings(listings []shared.Listi
 		}
 		//l.logger.Info().Msgf("dateTimeRange: %s", dateTimeRange)
 
+		listingImage, listingImages, imageVariants, isStockImage, err := l.searchImages(listing, galleries[listing.ListingID], stock)
+		if err != nil {
+			return nil, err
+		}
 		sr := shared.SearchListingResult{
 			ListingID:                  listing.ListingID,
 			ListingType:                listing.Type,
@@ -541,69 +625,81 @@ func (l *listingEngine) MassageAndPopulateSearchListings(listings []shared.Listi
 			BusinessID:                 listing.BusinessID,
 			BusinessName:               listing.BusinessName,
 			Price:                      listing.NewPrice,
-			PriceInString:              GetPriceFromLocationInString(listing.NewPrice, listing.CurrentLocation),
+			PriceInString:              l.locale().Price(listing.NewPrice),
 			Discount:                   listing.Discount,
 			DiscountDescription:        listing.DiscountDescription,
 			DietaryRestrictions:        listing.DietaryRestrictions,
 			TimeLeft:                   0,
-			ListingImage:               listing.ListingImage,
-			ListingImages:              []string{listing.ListingImage},
+			ListingImage:               listingImage,
+			ListingImages:              listingImages,
+			ImageVariants:              imageVariants,
+			IsStockImage:               isStockImage,
 			DistanceFromLocation:       listing.DistanceFromLocation,
-			DistanceFromLocationString: GetDistanceFromLocationInString(listing.DistanceFromLocation, listing.CurrentLocation),
+			DistanceFromLocationString: l.locale().Distance(listing.DistanceFromLocation),
 			IsFavorite:                 listing.IsFavorite,
 			DateTimeRange:              dateTimeRange,
 			ListingDateID:              listing.ListingDateID,
 			Upvotes:                    listing.UpVotes,
 			IsUserVoted:                listing.IsUserVoted,
+			Snippet:                    listing.Snippet,
 		}
 		listingsResult = append(listingsResult, sr)
 	}
 	return listingsResult, nil
 }
 
-func GetPriceFromLocationInString(price float64, location shared.GeoLocation) string {
-	if price == 0.0 {
-		return ""
-	}
-	zone := latlong.LookupZoneName(location.Latitude, location.Longitude)
-	if zone == "Asia/Kolkata" {
-		return fmt.Sprintf("₹ %.2f", price)
-	}
-
-	return fmt.Sprintf("$ %.2f", price)
-}
-
-func GetDistanceFromLocationInString(distance float64, location shared.GeoLocation) string {
-	zone := latlong.LookupZoneName(location.Latitude, location.Longitude)
-	var kms float64
-	if zone == "Asia/Kolkata" {
-		kms = distance * 1.609344
-		return fmt.Sprintf("%.2fkm", kms)
-	}
-
-	return fmt.Sprintf("%.2fmi", distance)
-}
-
 func (l *listingEngine) GetDateTimeRangeForWeeklyListing(searchDay string, listingStartTime string, listingEndTime string) (string, error) {
 	var buffer bytes.Buffer
+	loc := l.locale()
+
 	// determine startTime in format
-	sTime, err := shared.GetTimeIn12HourFormat(listingStartTime)
+	sTime, err := loc.Time(listingStartTime)
 	if err != nil {
 		return "", nil
 	}
 
 	// determine endTime in format
-	eTime, err := shared.GetTimeIn12HourFormat(listingEndTime)
+	eTime, err := loc.Time(listingEndTime)
 	if err != nil {
 		return "", nil
 	}
 
-	buffer.WriteString(strings.Title(searchDay) + " " + sTime + "-" + eTime)
+	day := strings.Title(searchDay)
+	if weekday, ok := shared.DayMap[searchDay]; ok {
+		day = strings.Title(loc.Weekday(time.Weekday(weekday), false))
+	}
+	buffer.WriteString(day + " " + sTime + "-" + eTime)
 	return buffer.String(), nil
 }
 
+// searchImages returns the card rendition of the primary image of listing
+// for the result lists of older apps, the detail renditions of its gallery,
+// every rendition of the primary image and whether it is a stock image,
+// which listings without a photo are shown with
+func (l *listingEngine) searchImages(listing shared.Listing, gallery []shared.GalleryImage, stock *stockLibrary) (string, []string, *shared.ImageVariants, bool, error) {
+	link, isStock := listing.ListingImage, false
+	if link == "" {
+		var err error
+		if link, err = stock.pick(listing.Category, listing.ListingID); err != nil {
+			return "", nil, nil, false, err
+		}
+		isStock = true
+	}
+
+	variants := l.imageVariants(link, listing.ImageWidth, listing.ImageHeight)
+	if len(gallery) == 0 {
+		return variants.Card.URL, []string{variants.Detail.URL}, variants, isStock, nil
+	}
+	return variants.Card.URL, galleryLinks(gallery), variants, isStock, nil
+}
+
 func (l *listingEngine) MassageAndPopulateSearchListingsWeekly(listings []shared.Listing, isFavorite bool, searchDay string) ([]shared.SearchListingResult, error) {
 	var listingsResult []shared.SearchListingResult
+	galleries, err := l.galleriesOf(listings)
+	if err != nil {
+		return nil, err
+	}
+	stock := l.stockLibrary()
 	for _, listing := range listings {
 
 		dateTimeRange, err := l.GetDateTimeRangeForWeeklyListing(searchDay, listing.StartTime, listing.EndTime)
@@ -612,6 +708,10 @@ func (l *listingEngine) MassageAndPopulateSearchListingsWeekly(listings []shared
 		}
 		//l.logger.Info().Msgf("dateTimeRange: %s", dateTimeRange)
 
+		listingImage, listingImages, imageVariants, isStockImage, err := l.searchImages(listing, galleries[listing.ListingID], stock)
+		if err != nil {
+			return nil, err
+		}
 		sr := shared.SearchListingResult{
 			ListingID:                  listing.ListingID,
 			ListingType:                listing.Type,
@@ -620,20 +720,23 @@ func (l *listingEngine) MassageAndPopulateSearchListingsWeekly(listings []shared
 			BusinessID:                 listing.BusinessID,
 			BusinessName:               listing.BusinessName,
 			Price:                      listing.NewPrice,
-			PriceInString:              GetPriceFromLocationInString(listing.NewPrice, listing.CurrentLocation),
+			PriceInString:              l.locale().Price(listing.NewPrice),
 			Discount:                   listing.Discount,
 			DiscountDescription:        listing.DiscountDescription,
 			DietaryRestrictions:        listing.DietaryRestrictions,
 			TimeLeft:                   0,
-			ListingImage:               listing.ListingImage,
-			ListingImages:              []string{listing.ListingImage},
+			ListingImage:               listingImage,
+			ListingImages:              listingImages,
+			ImageVariants:              imageVariants,
+			IsStockImage:               isStockImage,
 			DistanceFromLocation:       listing.DistanceFromLocation,
-			DistanceFromLocationString: GetDistanceFromLocationInString(listing.DistanceFromLocation, listing.CurrentLocation),
+			DistanceFromLocationString: l.locale().Distance(listing.DistanceFromLocation),
 			IsFavorite:                 listing.IsFavorite,
 			DateTimeRange:              dateTimeRange,
 			ListingDateID:              listing.ListingDateID,
 			Upvotes:                    listing.UpVotes,
 			IsUserVoted:                listing.IsUserVoted,
+			Snippet:                    listing.Snippet,
 		}
 		listingsResult = append(listingsResult, sr)
 	}
@@ -642,20 +745,34 @@ func (l *listingEngine) MassageAndPopulateSearchListingsWeekly(listings []shared
 
 func (l *listingEngine) MassageAndPopulateSearchListingsFavorites(listings []shared.Listing, isFavorite bool, searchDay string) ([]shared.SearchListingResult, error) {
 	var listingsResult []shared.SearchListingResult
+	galleries, err := l.galleriesOf(listings)
+	if err != nil {
+		return nil, err
+	}
+	stock := l.stockLibrary()
 	for _, listing := range listings {
 
 		// get recurring info
+		var err error
+		listing.RecurringDays, err = l.GetRecurringListing(listing.ListingID)
+		if err != nil {
+			return nil, err
+		}
 
-		recDays, err := l.GetRecurringListing(listing.ListingID)
+		listing.TimeWindows, err = l.getTimeWindows(listing.ListingID)
 		if err != nil {
-			return nil, nil
+			return nil, err
 		}
 
-		dateTimeRange, err := determineDealDateTimeRangeDetailsView(listing.StartTime, listing.EndTime, recDays)
+		dateTimeRange, err := determineDealDateTimeRangeDetailsView(listing)
 		if err != nil {
-			return nil, nil
+			return nil, err
 		}
 
+		listingImage, listingImages, imageVariants, isStockImage, err := l.searchImages(listing, galleries[listing.ListingID], stock)
+		if err != nil {
+			return nil, err
+		}
 		sr := shared.SearchListingResult{
 			ListingID:                  listing.ListingID,
 			ListingType:                listing.Type,
@@ -664,15 +781,17 @@ func (l *listingEngine) MassageAndPopulateSearchListingsFavorites(listings []sha
 			BusinessID:                 listing.BusinessID,
 			BusinessName:               listing.BusinessName,
 			Price:                      listing.NewPrice,
-			PriceInString:              GetPriceFromLocationInString(listing.NewPrice, listing.CurrentLocation),
+			PriceInString:              l.locale().Price(listing.NewPrice),
 			Discount:                   listing.Discount,
 			DiscountDescription:        listing.DiscountDescription,
 			DietaryRestrictions:        listing.DietaryRestrictions,
 			TimeLeft:                   0,
-			ListingImage:               listing.ListingImage,
-			ListingImages:              []string{listing.ListingImage},
+			ListingImage:               listingImage,
+			ListingImages:              listingImages,
+			ImageVariants:              imageVariants,
+			IsStockImage:               isStockImage,
 			DistanceFromLocation:       listing.DistanceFromLocation,
-			DistanceFromLocationString: GetDistanceFromLocationInString(listing.DistanceFromLocation, listing.CurrentLocation),
+			DistanceFromLocationString: l.locale().Distance(listing.DistanceFromLocation),
 			IsFavorite:                 listing.IsFavorite,
 			DateTimeRange:              dateTimeRange,
 			ListingDateID:              listing.ListingDateID,
@@ -690,7 +809,7 @@ func (l *listingEngine) calculateTimeLeftForSearch(listingDate string, listingSt
 	}
 
 	// get current date and time
-	timeInZone, err := getCurrentTimeInTimeZone(loc)
+	timeInZone, err := l.now(loc)
 	if err != nil {
 		return 0, nil
 	}
@@ -744,10 +863,22 @@ func calculateTimeLeft(listingDate string, listingEndTime string, loc shared.Geo
 		return 0, err
 	}
 
+	listingEndTimeFormatted, err := listingEndsAt(listingDate, listingEndTime)
+	if err != nil {
+		return 0, err
+	}
+
+	timeLeftInHours := listingEndTimeFormatted.Sub(currentDateTimeFormatted).Minutes()
+	return int(timeLeftInHours), nil
+}
+
+// listingEndsAt returns when a listing date ends, in the time zone of the
+// listing but parsed as UTC like the current time in calculateTimeLeft
+func listingEndsAt(listingDate string, listingEndTime string) (time.Time, error) {
 	lEndTime := getListingDateTime(listingDate, listingEndTime)
 	listingEndTimeFormatted, err := time.Parse(shared.DateTimeFormat, lEndTime)
 	if err != nil {
-		return 0, err
+		return time.Time{}, err
 	}
 
 	if strings.Contains(lEndTime, "T") {
@@ -760,9 +891,7 @@ func calculateTimeLeft(listingDate string, listingEndTime string, loc shared.Geo
 			}
 		}
 	}
-
-	timeLeftInHours := listingEndTimeFormatted.Sub(currentDateTimeFormatted).Minutes()
-	return int(timeLeftInHours), nil
+	return listingEndTimeFormatted, nil
 }
 
 func inTimeSpan(start, end, check time.Time) bool {
@@ -780,36 +909,33 @@ func (l *listingEngine) determineDealDateTimeRange(listingDate string, listingSt
 	if err != nil {
 		return "", "", nil
 	}
+	localized := l.locale()
 	if timeLeft == 0 {
 		// see if current day and listing day are same
 		var buffer bytes.Buffer
 		if !isFavorite {
-			buffer.WriteString(fmt.Sprintf("%s %d, ", listingDateFormatted.Month().String()[0:3], listingDateFormatted.Day()))
+			buffer.WriteString(localized.Date(listingDateFormatted) + ", ")
 		}
 
-		timeInZone, err := getCurrentTimeInTimeZone(loc)
+		timeInZone, err := l.now(loc)
 		if err != nil {
 			return "", "", nil
 		}
 
 		if timeInZone.Format(shared.DateFormat) != listingDateFormatted.Format(shared.DateFormat) {
-			if isSearch {
-				buffer.WriteString(listingDateFormatted.Weekday().String()[0:3] + ": ")
-			} else {
-				buffer.WriteString(listingDateFormatted.Weekday().String() + ": ")
-			}
+			buffer.WriteString(localized.Weekday(listingDateFormatted.Weekday(), isSearch) + ": ")
 		} else {
-			buffer.WriteString("Today: ")
+			buffer.WriteString(localized.Sprintf("Today") + ": ")
 		}
 
 		// determine startTime in format
-		sTime, err := shared.GetTimeIn12HourFormat(listingStartTime)
+		sTime, err := localized.Time(listingStartTime)
 		if err != nil {
 			return "", "", nil
 		}
 
 		// determine endTime in format
-		eTime, err := shared.GetTimeIn12HourFormat(listingEndTime)
+		eTime, err := localized.Time(listingEndTime)
 		if err != nil {
 			return "", "", nil
 		}
@@ -819,7 +945,7 @@ func (l *listingEngine) determineDealDateTimeRange(listingDate string, listingSt
 	} else {
 		weekDayToday := listingDateFormatted.Weekday().String()
 		if timeLeft < 50 {
-			return weekDayToday, fmt.Sprintf("%d mins left", timeLeft), nil
+			return weekDayToday, localized.Sprintf("%d mins left", timeLeft), nil
 		}
 
 		resMod := math.Mod(float64(timeLeft), 60)
@@ -827,34 +953,44 @@ func (l *listingEngine) determineDealDateTimeRange(listingDate string, listingSt
 			res := float64(timeLeft) / float64(60)
 			hrs := int(math.Floor(res))
 			if hrs == 1 {
-				return weekDayToday, fmt.Sprintf("%d hour left", int(math.Floor(res))), nil
+				return weekDayToday, localized.Sprintf("%d hour left", int(math.Floor(res))), nil
 			}
-			return weekDayToday, fmt.Sprintf("%d hours left", int(math.Floor(res))), nil
+			return weekDayToday, localized.Sprintf("%d hours left", int(math.Floor(res))), nil
 		}
 
 		res := float64(timeLeft) / float64(60)
 		hrs := int(math.Ceil(res))
 		if hrs == 1 {
-			return weekDayToday, fmt.Sprintf("%d hour left", int(math.Ceil(res))), nil
+			return weekDayToday, localized.Sprintf("%d hour left", int(math.Ceil(res))), nil
 		}
-		return weekDayToday, fmt.Sprintf("%d hours left", int(math.Ceil(res))), nil
+		return weekDayToday, localized.Sprintf("%d hours left", int(math.Ceil(res))), nil
 	}
 }
 
-func determineDealDateTimeRangeDetailsView(listingStartTime string, listingEndTime string, recurringDays []string) (string, error) {
-	var buffer bytes.Buffer
+func determineDealDateTimeRangeDetailsView(listing shared.Listing) (string, error) {
+	if len(listing.TimeWindows) > 0 {
+		return describeTimeWindows(listing)
+	}
+
 	// determine startTime in format
-	sTime, err := shared.GetTimeIn12HourFormat(listingStartTime)
+	sTime, err := shared.GetTimeIn12HourFormat(listing.StartTime)
 	if err != nil {
 		return "", nil
 	}
 
 	// determine endTime in format
-	eTime, err := shared.GetTimeIn12HourFormat(listingEndTime)
+	eTime, err := shared.GetTimeIn12HourFormat(listing.EndTime)
 	if err != nil {
 		return "", nil
 	}
 
+	return describeDays(listing.RecurringDays) + sTime + "-" + eTime, nil
+}
+
+// describeDays describes the recurring days of a listing, ready to be
+// followed by its times
+func describeDays(recurringDays []string) string {
+	var buffer bytes.Buffer
 	if len(recurringDays) == 7 {
 		buffer.WriteString("All Days ")
 	} else if len(recurringDays) == 6 || len(recurringDays) == 5 || len(recurringDays) == 4 {
@@ -873,36 +1009,7 @@ func determineDealDateTimeRangeDetailsView(listingStartTime string, listingEndTi
 		buffer.WriteString(": ")
 	}
 
-	buffer.WriteString(sTime + "-" + eTime)
-	return buffer.String(), nil
-}
-
-func isDistanceInRange(geoCode shared.GeoLocation) bool {
-	realLocation := haversine.Coord{Lat: geoCode.Latitude, Lon: geoCode.Longitude}
-	sunnyvaleLocation := haversine.Coord{Lat: float64(37.36883), Lon: float64(-122.0363496)}
-	mi, _ := haversine.Distance(realLocation, sunnyvaleLocation)
-	if mi < common.MaxRangeAroundSunnyvale {
-		return true
-	}
-
-	return false
-}
-
-func (l *listingEngine) LogSearchRequest(searchRequest shared.SearchRequest) error {
-	searchJSON, err := json.Marshal(searchRequest)
-	if err != nil {
-		return err
-	}
-
-	logSearchRequest := "INSERT INTO search(search_request,search_date) " +
-		"VALUES($1,$2);"
-
-	_, err = l.sql.Exec(logSearchRequest, string(searchJSON), time.Now())
-	if err != nil {
-		return helper.DatabaseError{DBError: err.Error()}
-	}
-
-	return nil
+	return buffer.String()
 }
 
 func (u *listingEngine) GetUpVotes(listingID int) (int, error) {
@@ -7,9 +7,7 @@ import (
 
 	"strings"
 
-	"database/sql"
-
-	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/model/common"
 	"github.com/phassans/banana/shared"
 	"github.com/umahmood/haversine"
@@ -20,7 +18,8 @@ type (
 		listings        []shared.Listing
 		sortingType     string
 		currentLocation shared.GeoLocation
-		sql             *sql.DB
+		sql             db.Querier
+		searchConfig    common.SearchConfig
 	}
 
 	// SortListingEngine interface
@@ -31,15 +30,15 @@ type (
 
 // NewSortListingEngine returns an instance of sortListingEngine
 func NewSortListingEngine(listings []shared.Listing, sortingType string,
-	currentLocation shared.GeoLocation, sql *sql.DB) SortListingEngine {
-	return &sortListingEngine{listings, sortingType, currentLocation, sql}
+	currentLocation shared.GeoLocation, sql db.Querier, searchConfig common.SearchConfig) SortListingEngine {
+	return &sortListingEngine{listings, sortingType, currentLocation, sql, searchConfig}
 }
 
 func (l *sortListingEngine) SortListings(isFuture bool, searchDay string, isSearch bool, isFavorite bool) ([]shared.Listing, error) {
 
 	// have to sort by distance, in order to calculate distanceFromLocation
 	if l.currentLocation.Latitude != 0 && l.currentLocation.Longitude != 0 {
-		l.sortListingsByDistance(isFuture, searchDay, isFavorite)
+		l.sortListingsByDistance(searchDay, isFavorite)
 	}
 
 	// for future listings always sort by timeLeft
@@ -60,6 +59,8 @@ func (l *sortListingEngine) SortListings(isFuture bool, searchDay string, isSear
 		}
 	} else if l.sortingType == shared.SortByMostPopular {
 		l.sortListingsByMostPopular()
+	} else if l.sortingType == shared.SortByRelevance {
+		l.sortListingsByRelevance()
 	}
 
 	return l.listings, nil
@@ -103,6 +104,22 @@ func (l *sortListingEngine) sortListingsByMostPopular() error {
 	return nil
 }
 
+func (l *sortListingEngine) sortListingsByRelevance() error {
+	var ll []shared.SortView
+	for _, listing := range l.listings {
+		s := shared.SortView{Listing: listing, Relevance: listing.Relevance}
+		ll = append(ll, s)
+	}
+
+	// put in listing struct
+	var listingsResult []shared.Listing
+	for _, view := range l.orderListings(ll, shared.SortByRelevance) {
+		listingsResult = append(listingsResult, view.Listing)
+	}
+	l.listings = listingsResult
+	return nil
+}
+
 func (l *sortListingEngine) sortListingsByPrice() error {
 	var ll []shared.SortView
 	var discountedListings []shared.Listing
@@ -130,19 +147,21 @@ func (l *sortListingEngine) sortListingsByPrice() error {
 	return nil
 }
 
-func (l *sortListingEngine) sortListingsByDistance(isFuture bool, searchDay string, isFavorite bool) error {
+func (l *sortListingEngine) sortListingsByDistance(searchDay string, isFavorite bool) error {
 	var ll []shared.SortView
 
 	for _, listing := range l.listings {
-		// append latLon
-		fromMobile := haversine.Coord{Lat: l.currentLocation.Latitude, Lon: l.currentLocation.Longitude}
-		fromDB := haversine.Coord{Lat: listing.Latitude, Lon: listing.Longitude}
-		mi, _ := haversine.Distance(fromMobile, fromDB)
-		if isFavorite || mi <= getMaxDistance(isFuture) {
+		// searches come with the distance from the database, already
+		// within the search radius
+		mi := listing.DistanceFromLocation
+		if isFavorite {
+			fromMobile := haversine.Coord{Lat: l.currentLocation.Latitude, Lon: l.currentLocation.Longitude}
+			fromDB := haversine.Coord{Lat: listing.Latitude, Lon: listing.Longitude}
+			mi, _ = haversine.Distance(fromMobile, fromDB)
 			listing.DistanceFromLocation = mi
-			s := shared.SortView{Listing: listing, Mile: mi}
-			ll = append(ll, s)
 		}
+		s := shared.SortView{Listing: listing, Mile: mi}
+		ll = append(ll, s)
 	}
 
 	// if favorites do not sort ByDistance
@@ -229,13 +248,6 @@ func (l *sortListingEngine) GroupListingsBasedOnSearchDay(searchDay string) erro
 	return nil
 }
 
-func getMaxDistance(isFuture bool) float64 {
-	if isFuture {
-		return common.MaxDistanceForFutureDeals
-	}
-	return common.MaxDistanceForTodaysDeals
-}
-
 func (l *sortListingEngine) sortListingsByDateAdded() error {
 	var ll []shared.SortView
 	for _, listing := range l.listings {
@@ -304,68 +316,15 @@ func (l *sortListingEngine) orderListings(listings []shared.SortView, orderType
 			return listings[i].UpVotes > listings[j].UpVotes
 		})
 		return listings
+	case shared.SortByRelevance:
+		sort.Slice(listings, func(i, j int) bool {
+			return listings[i].Relevance > listings[j].Relevance
+		})
+		return listings
 	}
 	return nil
 }
 
-func (l *sortListingEngine) GetAllListingsLatLon(businessIDs []string) ([]shared.AddressGeo, error) {
-
-	businesses := strings.Join(businessIDs, ",")
-
-	businessesQuery := fmt.Sprintf("SELECT address_id, business_id, latitude, longitude  FROM business_address WHERE business_id IN (%s)", businesses)
-	//fmt.Println(businessesQuery)
-
-	rows, err := l.sql.Query(businessesQuery)
-	defer rows.Close()
-
-	if err != nil {
-		return nil, helper.DatabaseError{DBError: err.Error()}
-	}
-
-	defer rows.Close()
-
-	var geos []shared.AddressGeo
-	for rows.Next() {
-		geo := shared.AddressGeo{}
-		err = rows.Scan(&geo.AddressID, &geo.BusinessID, &geo.Latitude, &geo.Longitude)
-		if err != nil {
-			return nil, helper.DatabaseError{DBError: err.Error()}
-		}
-		geos = append(geos, geo)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, helper.DatabaseError{DBError: err.Error()}
-	}
-
-	return geos, nil
-}
-
-func (l *sortListingEngine) GetListingsLatLon(businessID int) (shared.AddressGeo, error) {
-	rows, err := l.sql.Query("SELECT address_id, business_id, latitude, longitude  FROM business_address WHERE business_id = $1", businessID)
-	defer rows.Close()
-
-	if err != nil {
-		return shared.AddressGeo{}, helper.DatabaseError{DBError: err.Error()}
-	}
-
-	defer rows.Close()
-
-	geo := shared.AddressGeo{}
-	if rows.Next() {
-		err = rows.Scan(&geo.AddressID, &geo.BusinessID, &geo.Latitude, &geo.Longitude)
-		if err != nil {
-			return shared.AddressGeo{}, helper.DatabaseError{DBError: err.Error()}
-		}
-	}
-
-	if err = rows.Err(); err != nil {
-		return shared.AddressGeo{}, helper.DatabaseError{DBError: err.Error()}
-	}
-
-	return geo, nil
-}
-
 func getListingDateTime(endDate string, endTime string) string {
 	listingEndDate := strings.Split(endDate, "T")[0]
 	listingEndTime := strings.Split(endTime, "T")[1]
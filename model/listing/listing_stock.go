@@ -0,0 +1,94 @@
+package listing
+
+import (
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/stockimage"
+	"github.com/phassans/banana/shared"
+)
+
+// stockLibrary is the stock library listings without a photo are shown
+// with, loaded when the first of them is
+type stockLibrary struct {
+	engine  stockimage.StockImageEngine
+	library stockimage.Library
+}
+
+func (l *listingEngine) stockLibrary() *stockLibrary {
+	return &stockLibrary{engine: l.stockImages}
+}
+
+// pick returns the stock image of the listing listingID of category, or the
+// no image picture when the library has none
+func (s *stockLibrary) pick(category string, listingID int) (string, error) {
+	if s.library == nil {
+		library, err := s.engine.GetStockLibrary()
+		if err != nil {
+			return "", err
+		}
+		s.library = library
+	}
+	if link, ok := s.library.Pick(category, listingID); ok {
+		return link, nil
+	}
+	return stockimage.NoImageLink, nil
+}
+
+// tagListing sets the category of listing, derived from the keywords of the
+// categories its title and descriptions mention
+func (l *listingEngine) tagListing(listing *shared.Listing) error {
+	keywords, err := l.stockImages.GetCategoryKeywords()
+	if err != nil {
+		return err
+	}
+
+	listing.Category = categoryOf(keywords, *listing)
+	if _, err := l.sql.Exec("UPDATE listing SET category = $1 WHERE listing_id = $2;", listing.Category, listing.ListingID); err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	return nil
+}
+
+// TagListings derives the category of every listing again, after the
+// keywords of the categories changed, and returns how many changed
+func (l *listingEngine) TagListings() (int, error) {
+	keywords, err := l.stockImages.GetCategoryKeywords()
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := l.sql.Query("SELECT listing_id, title, COALESCE(discount_description, ''), COALESCE(description, ''), category FROM listing;")
+	if err != nil {
+		return 0, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	changed := make(map[int]string)
+	for rows.Next() {
+		var listing shared.Listing
+		err := rows.Scan(&listing.ListingID, &listing.Title, &listing.DiscountDescription, &listing.Description, &listing.Category)
+		if err != nil {
+			return 0, helper.DatabaseError{DBError: err.Error()}
+		}
+		if category := categoryOf(keywords, listing); category != listing.Category {
+			changed[listing.ListingID] = category
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return 0, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	for listingID, category := range changed {
+		if _, err := l.sql.Exec("UPDATE listing SET category = $1 WHERE listing_id = $2;", category, listingID); err != nil {
+			return 0, helper.DatabaseError{DBError: err.Error()}
+		}
+	}
+
+	l.logger.Info().Msgf("tagged %d listings with a new category", len(changed))
+	return len(changed), nil
+}
+
+// categoryOf returns the category of listing, its title weighing more than
+// its descriptions
+func categoryOf(keywords map[string][]string, listing shared.Listing) string {
+	return stockimage.Categorize(keywords, listing.Title, listing.DiscountDescription+" "+listing.Description)
+}
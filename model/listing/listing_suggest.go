@@ -0,0 +1,210 @@
+package listing
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/geohash"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/shared"
+)
+
+const (
+	// MaxSuggestions caps the suggestions returned for a prefix
+	MaxSuggestions = 20
+
+	// popularSearchDays is how far back past searches are suggested from
+	popularSearchDays = 30
+
+	// prefixBonus ranks suggestions starting with what was typed above the
+	// ones only containing a word starting with it
+	prefixBonus = 4.0
+)
+
+// suggestionWeights ranks the sources of suggestions before popularity is
+// taken into account, categories are what the app used to offer
+var suggestionWeights = map[string]float64{
+	shared.SuggestionCategory: 3,
+	shared.SuggestionCuisine:  2,
+	shared.SuggestionBusiness: 2,
+	shared.SuggestionQuery:    1,
+}
+
+// SuggestSearches returns up to limit completions of prefix drawn from the
+// categories, the names and cuisines of businesses nearby and the searches
+// made nearby in the last days, best first.
+func (l *listingEngine) SuggestSearches(prefix string, latitude float64, longitude float64, location string, limit int) ([]shared.Suggestion, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return []shared.Suggestion{}, nil
+	}
+	if limit <= 0 || limit > MaxSuggestions {
+		limit = MaxSuggestions
+	}
+
+	loc, err := l.DetermineCurrentLocation(location, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+	radius := l.searchConfig.MaxDistanceForFutureDeals
+
+	var candidates []shared.Suggestion
+	for _, suggest := range []func(string, shared.GeoLocation, float64, int) ([]shared.Suggestion, error){
+		l.suggestCategories,
+		l.suggestCuisines,
+		l.suggestBusinesses,
+		l.suggestQueries,
+	} {
+		suggestions, err := suggest(prefix, loc, radius, limit)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, suggestions...)
+	}
+
+	return rankSuggestions(prefix, candidates, limit), nil
+}
+
+func (l *listingEngine) suggestCategories(prefix string, loc shared.GeoLocation, radius float64, limit int) ([]shared.Suggestion, error) {
+	q := common.Select("category", "0").
+		From("category_to_keyword").
+		GroupBy("category").
+		OrderBy("category").
+		Limit(limit)
+	whereWordStartsWith(q, "category", prefix)
+	return l.querySuggestions(q, shared.SuggestionCategory)
+}
+
+func (l *listingEngine) suggestCuisines(prefix string, loc shared.GeoLocation, radius float64, limit int) ([]shared.Suggestion, error) {
+	q := common.Select("lower(business_cuisine.cuisine) as cuisine", "count(DISTINCT business_cuisine.business_id) as businesses").
+		From("business_cuisine INNER JOIN business_address ON business_cuisine.business_id = business_address.business_id").
+		GroupBy("lower(business_cuisine.cuisine)").
+		OrderBy("businesses DESC, cuisine").
+		Limit(limit)
+	whereWordStartsWith(q, "business_cuisine.cuisine", prefix)
+	addRadiusFilter(q, loc, radius)
+	return l.querySuggestions(q, shared.SuggestionCuisine)
+}
+
+func (l *listingEngine) suggestBusinesses(prefix string, loc shared.GeoLocation, radius float64, limit int) ([]shared.Suggestion, error) {
+	q := common.Select("business.name", "count(*) as addresses").
+		From("business INNER JOIN business_address ON business.business_id = business_address.business_id").
+		GroupBy("business.name").
+		OrderBy("addresses DESC, business.name").
+		Limit(limit)
+	whereWordStartsWith(q, "business.name", prefix)
+	addRadiusFilter(q, loc, radius)
+	return l.querySuggestions(q, shared.SuggestionBusiness)
+}
+
+func (l *listingEngine) suggestQueries(prefix string, loc shared.GeoLocation, radius float64, limit int) ([]shared.Suggestion, error) {
+	const keywords = "lower(search_request::json->>'keywords')"
+
+	q := common.Select(keywords+" as keywords", "count(*) as searches").
+		From("search").
+		Where("search_date >= ?", time.Now().AddDate(0, 0, -popularSearchDays)).
+		GroupBy(keywords).
+		OrderBy("searches DESC, keywords").
+		Limit(limit)
+	whereWordStartsWith(q, "search_request::json->>'keywords'", prefix)
+
+	if loc.Latitude != 0 || loc.Longitude != 0 {
+		if minLat, maxLat, minLon, maxLon, ok := geohash.BoundingBox(loc.Latitude, loc.Longitude, radius); ok {
+			q.Where("(search_request::json->>'latitude')::float BETWEEN ? AND ?", minLat, maxLat)
+			q.Where("(search_request::json->>'longitude')::float BETWEEN ? AND ?", minLon, maxLon)
+		}
+	}
+	return l.querySuggestions(q, shared.SuggestionQuery)
+}
+
+// whereWordStartsWith narrows q to the rows where a word of column starts
+// with prefix
+func whereWordStartsWith(q *common.Query, column string, prefix string) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	q.Where(column+" ILIKE ? OR "+column+" ILIKE ?", escaped+"%", "% "+escaped+"%")
+}
+
+// querySuggestions runs q, selecting a text and a count, for suggestions of
+// the given type
+func (l *listingEngine) querySuggestions(q *common.Query, suggestionType string) ([]shared.Suggestion, error) {
+	query, args := q.Build()
+	rows, err := l.sql.Query(query, args...)
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	defer rows.Close()
+
+	var suggestions []shared.Suggestion
+	for rows.Next() {
+		suggestion := shared.Suggestion{Type: suggestionType}
+		if err := rows.Scan(&suggestion.Text, &suggestion.Count); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	return suggestions, nil
+}
+
+// rankSuggestions orders suggestions by source and popularity, the ones
+// starting with prefix first, and returns the best limit of them. The same
+// text suggested by several sources is only kept once, ranked as its best.
+func rankSuggestions(prefix string, suggestions []shared.Suggestion, limit int) []shared.Suggestion {
+	prefix = strings.ToLower(prefix)
+	score := func(suggestion shared.Suggestion) float64 {
+		s := suggestionWeights[suggestion.Type] + math.Log2(1+float64(suggestion.Count))
+		if strings.HasPrefix(strings.ToLower(suggestion.Text), prefix) {
+			s += prefixBonus
+		}
+		return s
+	}
+
+	var ranked = make([]shared.Suggestion, 0)
+	var scores []float64
+	seen := make(map[string]int)
+	for _, suggestion := range suggestions {
+		text := strings.ToLower(strings.TrimSpace(suggestion.Text))
+		if text == "" {
+			continue
+		}
+
+		s := score(suggestion)
+		if i, ok := seen[text]; ok {
+			if s > scores[i] {
+				ranked[i], scores[i] = suggestion, s
+			}
+			continue
+		}
+		seen[text] = len(ranked)
+		ranked = append(ranked, suggestion)
+		scores = append(scores, s)
+	}
+
+	order := make([]int, len(ranked))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if scores[order[i]] != scores[order[j]] {
+			return scores[order[i]] > scores[order[j]]
+		}
+		return strings.ToLower(ranked[order[i]].Text) < strings.ToLower(ranked[order[j]].Text)
+	})
+
+	var result = make([]shared.Suggestion, 0, limit)
+	for _, i := range order {
+		if len(result) == limit {
+			break
+		}
+		result = append(result, ranked[i])
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package listing
+
+import (
+	"testing"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankSuggestions(t *testing.T) {
+	suggestions := rankSuggestions("Mar", []shared.Suggestion{
+		{Text: "Happy Hour Margaritas", Type: shared.SuggestionBusiness, Count: 1},
+		{Text: "Mexican", Type: shared.SuggestionCategory},
+		{Text: "margaritas", Type: shared.SuggestionQuery, Count: 40},
+		{Text: "Margaritas", Type: shared.SuggestionCuisine, Count: 2},
+		{Text: "Mariscos", Type: shared.SuggestionCuisine, Count: 2},
+		{Text: "marg", Type: shared.SuggestionQuery, Count: 1},
+	}, 4)
+
+	require.Equal(t, []shared.Suggestion{
+		// popular searches outrank the rest, the cuisine of the same name is dropped
+		{Text: "margaritas", Type: shared.SuggestionQuery, Count: 40},
+		{Text: "Mariscos", Type: shared.SuggestionCuisine, Count: 2},
+		{Text: "marg", Type: shared.SuggestionQuery, Count: 1},
+		{Text: "Happy Hour Margaritas", Type: shared.SuggestionBusiness, Count: 1},
+	}, suggestions)
+
+	require.Empty(t, rankSuggestions("x", nil, 5))
+}
@@ -0,0 +1,234 @@
+package listing
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+// error categories of the searches that failed
+const (
+	SearchErrorValidation = "validation"
+	SearchErrorLocation   = "location"
+	SearchErrorDatabase   = "database"
+	SearchErrorInternal   = "internal"
+)
+
+// searchLogStats counts the searches written to the search log, the ones
+// dropped because the queue was full and the ones lost to failed inserts.
+// They are published with the other expvars.
+var searchLogStats = expvar.NewMap("searchLog")
+
+// searchLogColumns are the columns of the search table written per search
+var searchLogColumns = []string{"search_request", "search_date", "search_filters",
+	"latitude", "longitude", "results", "duration_ms", "error_category"}
+
+type (
+	// SearchRecord is a search made through the app and its outcome
+	SearchRecord struct {
+		Request  shared.SearchRequest
+		Filters  shared.SearchFilters
+		Location shared.GeoLocation
+		Date     time.Time
+		Duration time.Duration
+
+		// Results is the number of listings found, before paging
+		Results       int
+		ErrorCategory string
+	}
+
+	// SearchLogger records the searches made through the app
+	SearchLogger interface {
+		Log(record SearchRecord) bool
+	}
+
+	// SearchLogWriter writes searches to the search log in batches, in the
+	// background. It never holds a search up: when the database falls
+	// behind and the queue is full, searches are dropped and counted.
+	SearchLogWriter struct {
+		sql           db.Querier
+		logger        zerolog.Logger
+		records       chan SearchRecord
+		batchSize     int
+		flushInterval time.Duration
+	}
+)
+
+// NewSearchLogWriter returns a SearchLogWriter writing once started
+func NewSearchLogWriter(psql db.Querier, logger zerolog.Logger, cfg common.SearchLogConfig) *SearchLogWriter {
+	return &SearchLogWriter{
+		sql:           psql,
+		logger:        logger,
+		records:       make(chan SearchRecord, cfg.QueueSize),
+		batchSize:     cfg.BatchSize,
+		flushInterval: time.Duration(cfg.FlushIntervalMS) * time.Millisecond,
+	}
+}
+
+// Log queues record to be written and reports whether there was room for it
+func (w *SearchLogWriter) Log(record SearchRecord) bool {
+	select {
+	case w.records <- record:
+		return true
+	default:
+		searchLogStats.Add("dropped", 1)
+		return false
+	}
+}
+
+// Run writes the queued searches whenever a batch is full or every flush
+// interval, until stop is closed. The searches still queued are written
+// before it returns.
+func (w *SearchLogWriter) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SearchRecord, 0, w.batchSize)
+	add := func(record SearchRecord) {
+		batch = append(batch, record)
+		if len(batch) == w.batchSize {
+			w.write(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case record := <-w.records:
+			add(record)
+		case <-ticker.C:
+			w.write(batch)
+			batch = batch[:0]
+		case <-stop:
+			for {
+				select {
+				case record := <-w.records:
+					add(record)
+				default:
+					w.write(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *SearchLogWriter) write(batch []SearchRecord) {
+	if len(batch) == 0 {
+		return
+	}
+
+	query, args, err := insertSearchRecords(batch)
+	if err == nil {
+		_, err = w.sql.Exec(query, args...)
+	}
+	if err != nil {
+		searchLogStats.Add("failed", int64(len(batch)))
+		w.logger.Error().Msgf("could not write %d searches to the search log: %s", len(batch), err)
+		return
+	}
+	searchLogStats.Add("written", int64(len(batch)))
+}
+
+// insertSearchRecords returns the insert of a batch of searches. Searches
+// that failed have no number of results, searches without a location no
+// coordinates.
+func insertSearchRecords(batch []SearchRecord) (string, []interface{}, error) {
+	var rows []string
+	var args []interface{}
+	for _, record := range batch {
+		request, err := json.Marshal(record.Request)
+		if err != nil {
+			return "", nil, err
+		}
+		filters, err := json.Marshal(record.Filters)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var latitude, longitude, results, errorCategory interface{}
+		if record.Location.Latitude != 0 || record.Location.Longitude != 0 {
+			latitude, longitude = record.Location.Latitude, record.Location.Longitude
+		}
+		if record.ErrorCategory == "" {
+			results = record.Results
+		} else {
+			errorCategory = record.ErrorCategory
+		}
+
+		placeholders := make([]string, len(searchLogColumns))
+		for i := range placeholders {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+		}
+		rows = append(rows, "("+strings.Join(placeholders, ",")+")")
+		args = append(args, string(request), record.Date, string(filters), latitude, longitude,
+			results, int64(record.Duration/time.Millisecond), errorCategory)
+	}
+
+	query := "INSERT INTO search(" + strings.Join(searchLogColumns, ",") + ") VALUES " +
+		strings.Join(rows, ",") + ";"
+	return query, args, nil
+}
+
+// searchErrorCategory returns the category of the error a search failed with
+func searchErrorCategory(err error) string {
+	switch err.(type) {
+	case nil:
+		return ""
+	case helper.ValidationError:
+		return SearchErrorValidation
+	case helper.LocationError:
+		return SearchErrorLocation
+	case helper.DatabaseError:
+		return SearchErrorDatabase
+	default:
+		return SearchErrorInternal
+	}
+}
+
+// normalizeSearchFilters returns the filters request is run with, searching
+// within radius
+func normalizeSearchFilters(request shared.SearchRequest, radius float64) shared.SearchFilters {
+	filters := shared.SearchFilters{
+		Keywords:       strings.Join(strings.Fields(strings.ToLower(request.Keywords)), " "),
+		ListingTypes:   normalizeList(request.ListingTypes),
+		DietaryFilters: normalizeList(request.DietaryFilters),
+		PriceFilter:    request.PriceFilter,
+		Radius:         radius,
+		SortBy:         strings.ToLower(strings.TrimSpace(request.SortBy)),
+		SearchDay:      strings.ToLower(strings.TrimSpace(request.SearchDay)),
+		Future:         request.Future,
+	}
+	if filters.SortBy == "" && request.Future {
+		// future deals are sorted by time left, see pageSearchResults
+		filters.SortBy = shared.SortByTimeLeft
+	}
+	if filters.SearchDay == "" {
+		filters.SearchDay = shared.SearchToday
+	}
+	return filters
+}
+
+// normalizeList lower cases, sorts and dedupes values
+func normalizeList(values []string) []string {
+	seen := make(map[string]bool)
+	var list []string
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && !seen[value] {
+			seen[value] = true
+			list = append(list, value)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,90 @@
+package listing
+
+import (
+	"database/sql"
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQuerier struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (f *fakeQuerier) Exec(query string, args ...interface{}) (sql.Result, error) {
+	f.queries = append(f.queries, query)
+	f.args = append(f.args, args)
+	return nil, nil
+}
+
+func (f *fakeQuerier) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, nil
+}
+
+func (f *fakeQuerier) QueryRow(query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func TestSearchLogWriter(t *testing.T) {
+	q := &fakeQuerier{}
+	w := NewSearchLogWriter(q, zerolog.Nop(), common.SearchLogConfig{QueueSize: 2, BatchSize: 10, FlushIntervalMS: 3600000})
+
+	found := SearchRecord{
+		Request:  shared.SearchRequest{Keywords: "Tacos", ListingTypes: []string{"Meal", "happyhour", "meal"}},
+		Location: shared.GeoLocation{Latitude: 37.3688, Longitude: -122.0363},
+		Date:     time.Now(),
+		Duration: 120 * time.Millisecond,
+		Results:  4,
+	}
+	found.Filters = normalizeSearchFilters(found.Request, 15)
+	require.Equal(t, shared.SearchFilters{
+		Keywords:     "tacos",
+		ListingTypes: []string{"happyhour", "meal"},
+		Radius:       15,
+		SearchDay:    shared.SearchToday,
+	}, found.Filters)
+
+	failed := SearchRecord{
+		Request:       shared.SearchRequest{Location: "nowhere"},
+		Date:          time.Now(),
+		ErrorCategory: searchErrorCategory(helper.LocationError{Message: "location not found"}),
+	}
+	require.Equal(t, SearchErrorLocation, failed.ErrorCategory)
+	require.Empty(t, searchErrorCategory(nil))
+
+	// a full queue drops searches rather than holding them up
+	dropped := func() int64 {
+		if count, ok := searchLogStats.Get("dropped").(*expvar.Int); ok {
+			return count.Value()
+		}
+		return 0
+	}
+	before := dropped()
+	require.True(t, w.Log(found))
+	require.True(t, w.Log(failed))
+	require.False(t, w.Log(found))
+	require.Equal(t, before+1, dropped())
+
+	// the searches still queued are written in one batch when stopped
+	stop := make(chan struct{})
+	close(stop)
+	w.Run(stop)
+
+	require.Len(t, q.queries, 1)
+	require.True(t, strings.HasPrefix(q.queries[0], "INSERT INTO search("))
+	require.Contains(t, q.queries[0], "($9,$10,$11,$12,$13,$14,$15,$16)")
+
+	args := q.args[0]
+	require.Len(t, args, 2*len(searchLogColumns))
+	require.Equal(t, []interface{}{37.3688, -122.0363, 4, int64(120), nil}, args[3:8])
+	// failed searches have neither coordinates nor results
+	require.Equal(t, []interface{}{nil, nil, nil, int64(0), SearchErrorLocation}, args[11:16])
+}
@@ -0,0 +1,365 @@
+package listing
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/model/common"
+	"github.com/phassans/banana/shared"
+)
+
+// weekOrder lists the days of the week in the order they are shown,
+// matching the days_of_month enum
+var weekOrder = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// setTimeWindows validates the time windows of a recurring listing and
+// normalizes their days and times. Days with a window recur even when they
+// are missing from the recurring days, and a listing given windows only
+// takes the first one as its own times.
+func setTimeWindows(listing *shared.Listing) error {
+	if !listing.Recurring || len(listing.TimeWindows) == 0 {
+		listing.TimeWindows = nil
+		return nil
+	}
+
+	for i, window := range listing.TimeWindows {
+		day := strings.ToLower(strings.TrimSpace(window.Day))
+		if _, ok := shared.DayMap[day]; !ok {
+			return helper.ValidationError{Message: fmt.Sprintf("invalid day %q in timeWindows", window.Day)}
+		}
+
+		startTime, err := shared.NormalizeTime(window.StartTime)
+		if err != nil {
+			return helper.ValidationError{Message: fmt.Sprintf("invalid startTime in timeWindows: %s", err)}
+		}
+		endTime, err := shared.NormalizeTime(window.EndTime)
+		if err != nil {
+			return helper.ValidationError{Message: fmt.Sprintf("invalid endTime in timeWindows: %s", err)}
+		}
+		if startTime == endTime {
+			return helper.ValidationError{Message: fmt.Sprintf("empty time window on %s", day)}
+		}
+
+		listing.TimeWindows[i] = shared.TimeWindow{Day: day, StartTime: startTime, EndTime: endTime}
+	}
+	sortTimeWindows(listing.TimeWindows)
+
+	for _, window := range listing.TimeWindows {
+		if !containsDay(listing.RecurringDays, window.Day) {
+			listing.RecurringDays = append(listing.RecurringDays, window.Day)
+		}
+	}
+
+	if listing.StartTime == "" && listing.EndTime == "" {
+		listing.StartTime = listing.TimeWindows[0].StartTime
+		listing.EndTime = listing.TimeWindows[0].EndTime
+	}
+	return nil
+}
+
+// windowsOn returns the time windows of a listing on a day of the week,
+// the listing times when the day has none of its own
+func windowsOn(listing shared.Listing, weekday time.Weekday) []shared.TimeWindow {
+	var windows []shared.TimeWindow
+	for _, window := range listing.TimeWindows {
+		if shared.DayMap[window.Day] == int(weekday) {
+			windows = append(windows, window)
+		}
+	}
+
+	if len(windows) == 0 {
+		windows = append(windows, shared.TimeWindow{StartTime: listing.StartTime, EndTime: listing.EndTime})
+	}
+	return windows
+}
+
+// addRecurringDays stores the recurring days of a listing, with a row per
+// time window for days that have them
+func (l *listingEngine) addRecurringDays(listing *shared.Listing) error {
+	for _, day := range listing.RecurringDays {
+		var windows []shared.TimeWindow
+		for _, window := range listing.TimeWindows {
+			if window.Day == strings.ToLower(day) {
+				windows = append(windows, window)
+			}
+		}
+
+		if len(windows) == 0 {
+			if err := l.AddRecurring(listing.ListingID, day); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, window := range windows {
+			if err := l.AddRecurringWindow(listing.ListingID, window); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddRecurringWindow adds a time window on one of the recurring days of a
+// listing
+func (l *listingEngine) AddRecurringWindow(listingID int, window shared.TimeWindow) error {
+	addListingRecurringSQL := "INSERT INTO listing_recurring(listing_id,day,start_time,end_time) " +
+		"VALUES($1,$2,$3,$4);"
+
+	_, err := l.sql.Exec(addListingRecurringSQL, listingID, window.Day, window.StartTime, window.EndTime)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+
+	l.logger.Info().Msgf("add recurring window successful for listing:%d", listingID)
+	return nil
+}
+
+// getTimeWindows returns the time windows of a listing, in week order
+func (l *listingEngine) getTimeWindows(listingID int) ([]shared.TimeWindow, error) {
+	rows, err := l.sql.Query("SELECT day, start_time, end_time FROM listing_recurring "+
+		"WHERE listing_id = $1 AND start_time IS NOT NULL AND end_time IS NOT NULL "+
+		"ORDER BY day, start_time;", listingID)
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	var windows []shared.TimeWindow
+	for rows.Next() {
+		var window shared.TimeWindow
+		if err := rows.Scan(&window.Day, &window.StartTime, &window.EndTime); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+
+		if window.StartTime, err = shared.NormalizeTime(window.StartTime); err != nil {
+			return nil, err
+		}
+		if window.EndTime, err = shared.NormalizeTime(window.EndTime); err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	return windows, nil
+}
+
+// dateTimes is the times of a listing date as read, null for dates of
+// listings without time windows
+type dateTimes struct {
+	startTime sql.NullString
+	endTime   sql.NullString
+}
+
+// isSplit reports whether the date is one half of a time window split at
+// midnight
+func (d dateTimes) isSplit() bool {
+	return d.isHead() || d.isTail()
+}
+
+// isHead reports whether the date is the first half of a split window, the
+// part before midnight
+func (d dateTimes) isHead() bool {
+	return d.endTime.Valid && clock(d.endTime.String) == endOfDay
+}
+
+// isTail reports whether the date is the second half of a split window, the
+// part after midnight
+func (d dateTimes) isTail() bool {
+	return d.startTime.Valid && clock(d.startTime.String) == midnight
+}
+
+// setDateTimes sets every listing to the times of its date, dates split at
+// midnight taking back the window they were split from, so that a deal
+// running from 10pm to 2am is not shown as ending at 11:59pm. The part after
+// midnight is moved to the day the window starts on, and left out when the
+// part before midnight was found too. windows holds the time windows of the
+// listings with a split date.
+func setDateTimes(listings []shared.Listing, dates []dateTimes, windows map[int][]shared.TimeWindow) []shared.Listing {
+	type window struct {
+		listingID int
+		date      string
+		startTime string
+	}
+	heads := make(map[window]bool)
+	tails := make(map[int]window)
+
+	for i := range listings {
+		listing, date := &listings[i], dates[i]
+		if !date.startTime.Valid || !date.endTime.Valid {
+			continue
+		}
+		// without windows of its own a listing runs at its own times
+		own := *listing
+		own.TimeWindows = windows[listing.ListingID]
+
+		listing.StartTime, listing.EndTime = date.startTime.String, date.endTime.String
+		if !date.isSplit() {
+			continue
+		}
+		day, err := time.Parse(shared.DateFormatSQL, strings.Split(listing.ListingDate, "T")[0])
+		if err != nil {
+			continue
+		}
+
+		if date.isHead() {
+			if w, ok := splitWindow(own, day, date, true); ok {
+				listing.StartTime, listing.EndTime = w.StartTime, w.EndTime
+				heads[window{listing.ListingID, day.Format(shared.DateFormatSQL), clock(w.StartTime)}] = true
+			}
+			continue
+		}
+
+		day = day.AddDate(0, 0, -1)
+		if w, ok := splitWindow(own, day, date, false); ok {
+			listing.ListingDate = day.Format(shared.DateFormatSQL)
+			listing.StartTime, listing.EndTime = w.StartTime, w.EndTime
+			tails[i] = window{listing.ListingID, listing.ListingDate, clock(w.StartTime)}
+		}
+	}
+
+	result := make([]shared.Listing, 0, len(listings))
+	for i, listing := range listings {
+		if tail, ok := tails[i]; ok && heads[tail] {
+			continue
+		}
+		result = append(result, listing)
+	}
+	return result
+}
+
+// splitWindow returns the window running past midnight that starts on day
+// and was split into date, matching its start when head is set and else its
+// end
+func splitWindow(listing shared.Listing, day time.Time, date dateTimes, head bool) (shared.TimeWindow, bool) {
+	for _, w := range windowsOn(listing, day.Weekday()) {
+		start, end := clock(w.StartTime), clock(w.EndTime)
+		if start == "" || end == "" || end > start {
+			continue
+		}
+		if head && start == clock(date.startTime.String) || !head && end == clock(date.endTime.String) {
+			return w, true
+		}
+	}
+	return shared.TimeWindow{}, false
+}
+
+// clock returns t as hh:mm:ss, empty when it is not a time
+func clock(t string) string {
+	normalized, _ := shared.NormalizeTime(t)
+	return normalized
+}
+
+// getSplitWindows returns the time windows, as stored, of the listings with
+// a date split at midnight
+func (l *listingEngine) getSplitWindows(listings []shared.Listing, dates []dateTimes) (map[int][]shared.TimeWindow, error) {
+	var ids []interface{}
+	seen := make(map[int]bool)
+	for i, listing := range listings {
+		if dates[i].isSplit() && !seen[listing.ListingID] {
+			seen[listing.ListingID] = true
+			ids = append(ids, listing.ListingID)
+		}
+	}
+	windows := make(map[int][]shared.TimeWindow)
+	if len(ids) == 0 {
+		return windows, nil
+	}
+
+	query, args := common.Select("listing_id", "day", "start_time", "end_time").
+		From("listing_recurring").
+		Where("start_time IS NOT NULL AND end_time IS NOT NULL").
+		WhereIn("listing_id", ids...).
+		Build()
+	rows, err := l.sql.Query(query, args...)
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var listingID int
+		var window shared.TimeWindow
+		if err := rows.Scan(&listingID, &window.Day, &window.StartTime, &window.EndTime); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		windows[listingID] = append(windows[listingID], window)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	return windows, nil
+}
+
+// describeTimeWindows describes when a recurring listing runs in the
+// details view, e.g. "Mon-Thu: 4pm-7pm, Fri,Sat: 4pm-7pm & 10pm-2am"
+func describeTimeWindows(listing shared.Listing) (string, error) {
+	var groups []string
+	daysOf := make(map[string][]string)
+	for _, day := range weekOrder {
+		if !containsDay(listing.RecurringDays, day) {
+			continue
+		}
+
+		var times []string
+		for _, window := range windowsOn(listing, time.Weekday(shared.DayMap[day])) {
+			startTime, err := shared.GetTimeIn12HourFormat(window.StartTime)
+			if err != nil {
+				return "", err
+			}
+			endTime, err := shared.GetTimeIn12HourFormat(window.EndTime)
+			if err != nil {
+				return "", err
+			}
+			times = append(times, startTime+"-"+endTime)
+		}
+
+		text := strings.Join(times, " & ")
+		if _, ok := daysOf[text]; !ok {
+			groups = append(groups, text)
+		}
+		daysOf[text] = append(daysOf[text], day)
+	}
+
+	var buffer bytes.Buffer
+	for i, text := range groups {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(describeDays(daysOf[text]))
+		buffer.WriteString(text)
+	}
+	return buffer.String(), nil
+}
+
+func sortTimeWindows(windows []shared.TimeWindow) {
+	position := make(map[string]int)
+	for i, day := range weekOrder {
+		position[day] = i
+	}
+	sort.SliceStable(windows, func(i, j int) bool {
+		if windows[i].Day != windows[j].Day {
+			return position[windows[i].Day] < position[windows[j].Day]
+		}
+		return windows[i].StartTime < windows[j].StartTime
+	})
+}
+
+func containsDay(days []string, day string) bool {
+	for _, d := range days {
+		if strings.ToLower(d) == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package listing
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeWindows(t *testing.T) {
+	listing := shared.Listing{
+		ListingID:     1,
+		Recurring:     true,
+		StartDate:     "09/03/2018", // a monday
+		RecurringDays: []string{"monday", "tuesday"},
+		TimeWindows: []shared.TimeWindow{
+			{Day: "Friday", StartTime: "22:00", EndTime: "02:00"},
+			{Day: "friday", StartTime: "15:00", EndTime: "18:00"},
+			{Day: "monday", StartTime: "16:00", EndTime: "19:00"},
+		},
+	}
+
+	require.NoError(t, setTimeWindows(&listing))
+	require.Equal(t, []shared.TimeWindow{
+		{Day: "monday", StartTime: "16:00:00", EndTime: "19:00:00"},
+		{Day: "friday", StartTime: "15:00:00", EndTime: "18:00:00"},
+		{Day: "friday", StartTime: "22:00:00", EndTime: "02:00:00"},
+	}, listing.TimeWindows)
+	require.Equal(t, []string{"monday", "tuesday", "friday"}, listing.RecurringDays)
+	// the listing takes the first window as its own times
+	require.Equal(t, "16:00:00", listing.StartTime)
+	require.Equal(t, "19:00:00", listing.EndTime)
+
+	dates, err := recurringDates(listing, time.Date(2018, 9, 3, 0, 0, 0, 0, time.UTC), time.Date(2018, 9, 9, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"09/03/2018 16:00:00-19:00:00",
+		// tuesday has no window of its own
+		"09/04/2018 16:00:00-19:00:00",
+		"09/07/2018 15:00:00-18:00:00",
+		"09/07/2018 22:00:00-23:59:59",
+		"09/08/2018 00:00:00-02:00:00",
+	}, listingDates(dates))
+
+	description, err := determineDealDateTimeRangeDetailsView(listing)
+	require.NoError(t, err)
+	require.Equal(t, "Mon,Tue: 4pm-7pm, Fri: 3pm-6pm & 10pm-2am", description)
+
+	listing.TimeWindows = []shared.TimeWindow{{Day: "someday", StartTime: "15:00", EndTime: "18:00"}}
+	require.Error(t, setTimeWindows(&listing))
+	listing.TimeWindows = []shared.TimeWindow{{Day: "friday", StartTime: "15:00", EndTime: "15:00"}}
+	require.Error(t, setTimeWindows(&listing))
+}
+
+func TestSearchShowsWindowsPastMidnight(t *testing.T) {
+	// times as read from the database
+	at := func(clock string) sql.NullString {
+		return sql.NullString{String: "0000-01-01T" + clock + "Z", Valid: true}
+	}
+	listing := shared.Listing{ListingID: 1, StartTime: "0000-01-01T16:00:00Z", EndTime: "0000-01-01T19:00:00Z"}
+	windows := map[int][]shared.TimeWindow{1: {
+		{Day: "monday", StartTime: "0000-01-01T16:00:00Z", EndTime: "0000-01-01T19:00:00Z"},
+		{Day: "friday", StartTime: "0000-01-01T22:00:00Z", EndTime: "0000-01-01T02:00:00Z"},
+	}}
+
+	friday, saturday, nextSaturday := listing, listing, listing
+	friday.ListingDate = "2018-09-07T00:00:00Z"
+	saturday.ListingDate = "2018-09-08T00:00:00Z"
+	nextSaturday.ListingDate = "2018-09-15T00:00:00Z"
+	listings := setDateTimes(
+		[]shared.Listing{friday, saturday, nextSaturday},
+		[]dateTimes{
+			{at("22:00:00"), at("23:59:59")},
+			{at("00:00:00"), at("02:00:00")},
+			// the part after midnight of a friday before the searched days
+			{at("00:00:00"), at("02:00:00")},
+		},
+		windows,
+	)
+
+	// the part after midnight of a window found whole is left out, the
+	// other one is shown as the window of the day before
+	require.Len(t, listings, 2)
+	l := &listingEngine{}
+	for i, date := range []string{"2018-09-07", "2018-09-14"} {
+		require.Equal(t, date, listings[i].ListingDate[:10])
+		require.Equal(t, "0000-01-01T22:00:00Z", listings[i].StartTime)
+		require.Equal(t, "0000-01-01T02:00:00Z", listings[i].EndTime)
+
+		_, dateTimeRange, err := l.determineDealDateTimeRange(listings[i].ListingDate, listings[i].StartTime,
+			listings[i].EndTime, true, 0, false, shared.GeoLocation{})
+		require.NoError(t, err)
+		require.Contains(t, dateTimeRange, "10pm-2am")
+	}
+}
@@ -0,0 +1,211 @@
+package notification
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/phassans/banana/clients/push"
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+type (
+	// ListingSearcher runs saved notification searches
+	ListingSearcher interface {
+		SearchListings(request shared.SearchRequest) ([]shared.SearchListingResult, error)
+	}
+
+	// Dispatcher periodically runs every saved notification search and pushes
+	// listings the phone has not been told about yet
+	Dispatcher struct {
+		logger   zerolog.Logger
+		store    SubscriptionStore
+		searcher ListingSearcher
+		provider push.Provider
+		interval time.Duration
+	}
+
+	// DispatchStatus describes a dispatch run
+	DispatchStatus struct {
+		StartedAt     time.Time `json:"startedAt"`
+		FinishedAt    time.Time `json:"finishedAt"`
+		Subscriptions int       `json:"subscriptions"`
+		Pushed        int       `json:"pushed"`
+		Failed        int       `json:"failed"`
+		Unregistered  int       `json:"unregistered"`
+		Error         string    `json:"error,omitempty"`
+	}
+)
+
+// NewDispatcher returns a Dispatcher running every interval once started
+func NewDispatcher(logger zerolog.Logger, store SubscriptionStore, searcher ListingSearcher, provider push.Provider, interval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		logger:   logger,
+		store:    store,
+		searcher: searcher,
+		provider: provider,
+		interval: interval,
+	}
+}
+
+// Run dispatches every interval until stop is closed
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if status := d.Dispatch(); status.Error != "" {
+				d.logger.Error().Msgf("notification dispatch failed: %s", status.Error)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Dispatch evaluates every subscription once. A subscription that fails is
+// counted and retried on the next run, it does not stop the others. Tokens
+// the push service no longer knows are forgotten rather than retried.
+func (d *Dispatcher) Dispatch() DispatchStatus {
+	status := DispatchStatus{StartedAt: time.Now()}
+
+	subscriptions, err := d.store.GetSubscriptions()
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	unregistered := make(map[string]bool)
+	for _, subscription := range subscriptions {
+		// the other notifications of a phone found unregistered this run
+		if unregistered[subscription.RegistrationToken] {
+			continue
+		}
+
+		status.Subscriptions++
+		pushed, err := d.dispatch(subscription)
+		if err == push.ErrUnregistered {
+			unregistered[subscription.RegistrationToken] = true
+			status.Unregistered++
+			d.logger.Info().Msgf("phone %s is no longer registered, removing its token", subscription.PhoneID)
+			if err := d.store.UnregisterPhone(subscription.RegistrationToken); err != nil {
+				d.logger.Error().Msgf("could not remove the token of phone %s: %s", subscription.PhoneID, err)
+			}
+			continue
+		}
+		if err != nil {
+			status.Failed++
+			d.logger.Error().Msgf("could not dispatch notification %d: %s", subscription.NotificationID, err)
+			continue
+		}
+		if pushed {
+			status.Pushed++
+		}
+	}
+
+	status.FinishedAt = time.Now()
+	d.logger.Info().Msgf("notification dispatch done. subscriptions: %d, pushed: %d, failed: %d, unregistered: %d",
+		status.Subscriptions, status.Pushed, status.Failed, status.Unregistered)
+	return status
+}
+
+func (d *Dispatcher) dispatch(subscription shared.Subscription) (bool, error) {
+	request, err := searchRequest(subscription.Notification)
+	if err != nil {
+		return false, err
+	}
+
+	listings, err := d.searcher.SearchListings(request)
+	if err != nil {
+		return false, err
+	}
+
+	sent, err := d.store.GetSentListings(subscription.NotificationID)
+	if err != nil {
+		return false, err
+	}
+
+	var unsent []shared.SearchListingResult
+	var listingIDs []int
+	for _, listing := range listings {
+		if sent[listing.ListingID] {
+			continue
+		}
+		// the same listing shows up once per matching date
+		sent[listing.ListingID] = true
+		unsent = append(unsent, listing)
+		listingIDs = append(listingIDs, listing.ListingID)
+	}
+
+	if len(unsent) == 0 {
+		return false, nil
+	}
+
+	if err := d.provider.Send(message(subscription, unsent)); err != nil {
+		return false, err
+	}
+
+	// a failure here means the phone may get these listings again
+	if err := d.store.RecordSent(subscription.NotificationID, listingIDs); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// searchRequest turns a saved notification into today's search for it
+func searchRequest(notification shared.Notification) (shared.SearchRequest, error) {
+	request := shared.SearchRequest{
+		ListingTypes:   []string{shared.ListingTypeMeal, shared.ListingTypeHappyHour},
+		Latitude:       notification.Latitude,
+		Longitude:      notification.Longitude,
+		DietaryFilters: notification.DietaryFilters,
+		DistanceFilter: notification.DistanceFilter,
+		Keywords:       notification.Keywords,
+		PhoneID:        notification.PhoneID,
+		Internal:       true,
+	}
+
+	// the location was already resolved when the notification was added
+	if request.Latitude == 0 && request.Longitude == 0 {
+		request.Location = notification.Location
+	}
+
+	if notification.PriceFilter != "" {
+		price, err := strconv.ParseFloat(notification.PriceFilter, 64)
+		if err != nil {
+			return shared.SearchRequest{}, fmt.Errorf("invalid price filter %q", notification.PriceFilter)
+		}
+		request.PriceFilter = price
+	}
+
+	return request, nil
+}
+
+func message(subscription shared.Subscription, listings []shared.SearchListingResult) push.Message {
+	name := subscription.NotificationName
+	if name == "" {
+		name = "your saved search"
+	}
+
+	title := fmt.Sprintf("New deal for %s", name)
+	if len(listings) > 1 {
+		title = fmt.Sprintf("%d new deals for %s", len(listings), name)
+	}
+
+	body := fmt.Sprintf("%s at %s", listings[0].Title, listings[0].BusinessName)
+	if len(listings) > 1 {
+		body = fmt.Sprintf("%s and %d more", body, len(listings)-1)
+	}
+
+	return push.Message{
+		Token: subscription.RegistrationToken,
+		Title: title,
+		Body:  body,
+		Data: map[string]string{
+			"notificationId": strconv.Itoa(subscription.NotificationID),
+			"listingId":      strconv.Itoa(listings[0].ListingID),
+		},
+	}
+}
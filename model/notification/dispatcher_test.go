@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phassans/banana/clients/push"
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	fakeStore struct {
+		subscriptions []shared.Subscription
+		sent          map[int]map[int]bool
+		unregistered  []string
+	}
+
+	fakeSearcher struct {
+		results  map[string][]shared.SearchListingResult
+		requests []shared.SearchRequest
+	}
+
+	// fakeProvider records messages instead of sending them
+	fakeProvider struct {
+		mu   sync.Mutex
+		sent []push.Message
+		// err, when set, is returned by Send and nothing is recorded
+		err error
+	}
+)
+
+func (f *fakeStore) GetSubscriptions() ([]shared.Subscription, error) {
+	return f.subscriptions, nil
+}
+
+func (f *fakeStore) GetSentListings(notificationID int) (map[int]bool, error) {
+	sent := make(map[int]bool)
+	for listingID := range f.sent[notificationID] {
+		sent[listingID] = true
+	}
+	return sent, nil
+}
+
+func (f *fakeStore) RecordSent(notificationID int, listingIDs []int) error {
+	if f.sent[notificationID] == nil {
+		f.sent[notificationID] = make(map[int]bool)
+	}
+	for _, listingID := range listingIDs {
+		f.sent[notificationID][listingID] = true
+	}
+	return nil
+}
+
+func (f *fakeStore) UnregisterPhone(registrationToken string) error {
+	f.unregistered = append(f.unregistered, registrationToken)
+	return nil
+}
+
+func (f *fakeSearcher) SearchListings(request shared.SearchRequest) ([]shared.SearchListingResult, error) {
+	f.requests = append(f.requests, request)
+	return f.results[request.Keywords], nil
+}
+
+func (f *fakeProvider) Send(msg push.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func (f *fakeProvider) Sent() []push.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]push.Message(nil), f.sent...)
+}
+
+func newTestDispatcher() (*Dispatcher, *fakeStore, *fakeSearcher, *fakeProvider) {
+	store := &fakeStore{
+		subscriptions: []shared.Subscription{
+			{
+				Notification:      shared.Notification{NotificationID: 1, NotificationName: "Tacos", PhoneID: "phone-1", Keywords: "tacos", PriceFilter: "10"},
+				RegistrationToken: "token-1",
+			},
+			{
+				Notification:      shared.Notification{NotificationID: 2, PhoneID: "phone-2", Keywords: "sushi"},
+				RegistrationToken: "token-2",
+			},
+		},
+		sent: make(map[int]map[int]bool),
+	}
+	searcher := &fakeSearcher{results: map[string][]shared.SearchListingResult{
+		"tacos": {
+			{ListingID: 10, Title: "Taco Tuesday", BusinessName: "Tacoria"},
+			{ListingID: 11, Title: "Two for one", BusinessName: "El Taco"},
+			// the same listing on another date
+			{ListingID: 10, Title: "Taco Tuesday", BusinessName: "Tacoria"},
+		},
+	}}
+	provider := &fakeProvider{}
+	return NewDispatcher(shared.GetLogger(), store, searcher, provider, time.Minute), store, searcher, provider
+}
+
+func TestDispatchPushesNewListingsOnce(t *testing.T) {
+	dispatcher, store, searcher, provider := newTestDispatcher()
+
+	status := dispatcher.Dispatch()
+	require.Equal(t, 2, status.Subscriptions)
+	require.Equal(t, 1, status.Pushed)
+	require.Equal(t, 0, status.Failed)
+
+	sent := provider.Sent()
+	require.Len(t, sent, 1)
+	require.Equal(t, "token-1", sent[0].Token)
+	require.Equal(t, "2 new deals for Tacos", sent[0].Title)
+	require.Equal(t, "Taco Tuesday at Tacoria and 1 more", sent[0].Body)
+	require.Equal(t, map[int]bool{10: true, 11: true}, store.sent[1])
+
+	require.True(t, searcher.requests[0].Internal)
+	require.Equal(t, 10.0, searcher.requests[0].PriceFilter)
+
+	// nothing new on the next run
+	status = dispatcher.Dispatch()
+	require.Equal(t, 0, status.Pushed)
+	require.Len(t, provider.Sent(), 1)
+
+	// only the new listing is pushed
+	searcher.results["tacos"] = append(searcher.results["tacos"], shared.SearchListingResult{ListingID: 12, Title: "Happy hour", BusinessName: "Tacoria"})
+	status = dispatcher.Dispatch()
+	require.Equal(t, 1, status.Pushed)
+	require.Equal(t, "New deal for Tacos", provider.Sent()[1].Title)
+}
+
+func TestDispatchRetriesFailedPushes(t *testing.T) {
+	dispatcher, store, _, provider := newTestDispatcher()
+
+	provider.err = errors.New("fcm is down")
+	status := dispatcher.Dispatch()
+	require.Equal(t, 1, status.Failed)
+	require.Empty(t, store.sent[1])
+
+	provider.err = nil
+	status = dispatcher.Dispatch()
+	require.Equal(t, 1, status.Pushed)
+	require.Len(t, provider.Sent(), 1)
+}
+
+func TestDispatchForgetsUnregisteredPhones(t *testing.T) {
+	dispatcher, store, _, provider := newTestDispatcher()
+	// a second notification of the same phone
+	store.subscriptions = append(store.subscriptions, shared.Subscription{
+		Notification:      shared.Notification{NotificationID: 3, PhoneID: "phone-1", Keywords: "tacos"},
+		RegistrationToken: "token-1",
+	})
+
+	provider.err = push.ErrUnregistered
+	status := dispatcher.Dispatch()
+	require.Equal(t, 0, status.Failed)
+	require.Equal(t, 1, status.Unregistered)
+	require.Equal(t, []string{"token-1"}, store.unregistered)
+}
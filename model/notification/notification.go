@@ -8,7 +8,8 @@ import (
 
 	"fmt"
 
-	"github.com/phassans/banana/clients"
+	"github.com/phassans/banana/clients/geocode"
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/model/business"
 	"github.com/phassans/banana/shared"
@@ -17,9 +18,10 @@ import (
 
 type (
 	notificationEngine struct {
-		sql            *sql.DB
+		sql            db.Querier
 		logger         zerolog.Logger
 		businessEngine business.BusinessEngine
+		geocoder       geocode.Geocoder
 	}
 
 	// NotificationEngine interface
@@ -39,12 +41,28 @@ type (
 		GetAllNotifications(phoneID string) ([]shared.Notification, error)
 		RegisterPhone(registrationToken string, phoneID string, phoneModel string) error
 		GetStats() (string, error)
+
+		SubscriptionStore
+	}
+
+	// SubscriptionStore holds what the Dispatcher pushes and what it has
+	// already pushed
+	SubscriptionStore interface {
+		// GetSubscriptions returns the notifications of every registered phone
+		GetSubscriptions() ([]shared.Subscription, error)
+		// GetSentListings returns the listings already pushed for a notification
+		GetSentListings(notificationID int) (map[int]bool, error)
+		// RecordSent remembers the listings were pushed for a notification
+		RecordSent(notificationID int, listingIDs []int) error
+		// UnregisterPhone forgets a registration token the push service no
+		// longer knows, along with the phone it was registered for
+		UnregisterPhone(registrationToken string) error
 	}
 )
 
 // NewNotificationEngine returns an instance of notificationEngine
-func NewNotificationEngine(psql *sql.DB, logger zerolog.Logger, businessEngine business.BusinessEngine) NotificationEngine {
-	return &notificationEngine{psql, logger, businessEngine}
+func NewNotificationEngine(psql db.Querier, logger zerolog.Logger, businessEngine business.BusinessEngine, geocoder geocode.Geocoder) NotificationEngine {
+	return &notificationEngine{psql, logger, businessEngine, geocoder}
 }
 
 func (l *notificationEngine) isPhoneRegistered(phoneID string) (bool, error) {
@@ -155,12 +173,15 @@ func (n *notificationEngine) AddNotification(
 ) error {
 	if location != "" {
 		// getLatLonFromLocation
-		resp, err := clients.GetLatLong(location)
+		geo, err := n.geocoder.Geocode(location)
+		if err == geocode.ErrNotFound {
+			return helper.LocationError{Message: "invalid location"}
+		}
 		if err != nil {
 			return err
 		}
-		latitude = resp.Lat
-		longitude = resp.Lon
+		latitude = geo.Latitude
+		longitude = geo.Longitude
 	}
 	n.logger.Info().Msgf("notification location - lat:%f, lon:%f", latitude, longitude)
 
@@ -205,6 +226,10 @@ func (n *notificationEngine) DeleteNotification(notificationID int) error {
 		return err
 	}
 
+	if err := n.DeleteNotificationSent(notificationID); err != nil {
+		return err
+	}
+
 	if err := n.DeleteNotificationInfo(notificationID); err != nil {
 		return err
 	}
@@ -228,10 +253,17 @@ func (n *notificationEngine) DeleteNotificationDietaryRestriction(notificationID
 	return err
 }
 
+func (n *notificationEngine) DeleteNotificationSent(notificationID int) error {
+	sqlStatement := `DELETE FROM notification_sent WHERE notification_id = $1;`
+	n.logger.Info().Msgf("deleting notification_sent with query: %s and notification: %d", sqlStatement, notificationID)
+
+	_, err := n.sql.Exec(sqlStatement, notificationID)
+	return err
+}
+
 func (n *notificationEngine) GetAllNotifications(phoneID string) ([]shared.Notification, error) {
 
-	q := fmt.Sprintf("SELECT notification_id, notification_name, phone_id, latitude, longitude, location, price_filter, distance_filter, keywords FROM notifications where phone_id = '%s';", phoneID)
-	rows, err := n.sql.Query(q)
+	rows, err := n.sql.Query("SELECT notification_id, notification_name, phone_id, latitude, longitude, location, price_filter, distance_filter, keywords FROM notifications where phone_id = $1;", phoneID)
 	if err != nil {
 		return nil, helper.DatabaseError{DBError: err.Error()}
 	}
@@ -313,3 +345,108 @@ func (n *notificationEngine) GetNotificationsDietaryRestriction(notificationID i
 
 	return rests, nil
 }
+
+func (n *notificationEngine) GetSubscriptions() ([]shared.Subscription, error) {
+	// one row per dietary restriction of a notification, or one without any
+	rows, err := n.sql.Query("SELECT notifications.notification_id, notifications.notification_name, notifications.phone_id, " +
+		"notifications.latitude, notifications.longitude, notifications.location, notifications.price_filter, " +
+		"notifications.distance_filter, notifications.keywords, register_phone.registration_token, " +
+		"notifications_dietary_restrictions.restriction " +
+		"FROM notifications INNER JOIN register_phone ON notifications.phone_id = register_phone.phone_id " +
+		"LEFT JOIN notifications_dietary_restrictions " +
+		"ON notifications.notification_id = notifications_dietary_restrictions.notification_id " +
+		"ORDER BY notifications.notification_id;")
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	defer rows.Close()
+
+	var subscriptions []shared.Subscription
+	for rows.Next() {
+		var subscription shared.Subscription
+		var name, location, priceFilter, distanceFilter, keywords, restriction sql.NullString
+		var latitude, longitude sql.NullFloat64
+		err = rows.Scan(
+			&subscription.NotificationID,
+			&name,
+			&subscription.PhoneID,
+			&latitude,
+			&longitude,
+			&location,
+			&priceFilter,
+			&distanceFilter,
+			&keywords,
+			&subscription.RegistrationToken,
+			&restriction,
+		)
+		if err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+
+		if last := len(subscriptions) - 1; last < 0 || subscriptions[last].NotificationID != subscription.NotificationID {
+			subscription.NotificationName = name.String
+			subscription.Latitude = latitude.Float64
+			subscription.Longitude = longitude.Float64
+			subscription.Location = location.String
+			subscription.PriceFilter = priceFilter.String
+			subscription.DistanceFilter = distanceFilter.String
+			subscription.Keywords = keywords.String
+			subscriptions = append(subscriptions, subscription)
+		}
+		if restriction.Valid {
+			last := &subscriptions[len(subscriptions)-1]
+			last.DietaryFilters = append(last.DietaryFilters, restriction.String)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	return subscriptions, nil
+}
+
+func (n *notificationEngine) GetSentListings(notificationID int) (map[int]bool, error) {
+	rows, err := n.sql.Query("SELECT listing_id FROM notification_sent WHERE notification_id = $1;", notificationID)
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	defer rows.Close()
+
+	sent := make(map[int]bool)
+	for rows.Next() {
+		var listingID int
+		if err = rows.Scan(&listingID); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		sent[listingID] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	return sent, nil
+}
+
+func (n *notificationEngine) RecordSent(notificationID int, listingIDs []int) error {
+	now := time.Now()
+	for _, listingID := range listingIDs {
+		_, err := n.sql.Exec("INSERT INTO notification_sent(notification_id,listing_id,sent_date) "+
+			"VALUES($1,$2,$3) ON CONFLICT DO NOTHING;", notificationID, listingID, now)
+		if err != nil {
+			return helper.DatabaseError{DBError: err.Error()}
+		}
+	}
+	return nil
+}
+
+func (n *notificationEngine) UnregisterPhone(registrationToken string) error {
+	_, err := n.sql.Exec("DELETE FROM register_phone WHERE registration_token = $1;", registrationToken)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	return nil
+}
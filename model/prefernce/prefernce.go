@@ -1,9 +1,7 @@
 package prefernce
 
 import (
-	"database/sql"
-	"fmt"
-
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/shared"
 
 	"github.com/phassans/banana/helper"
@@ -11,7 +9,7 @@ import (
 )
 
 type preferenceEngine struct {
-	sql    *sql.DB
+	sql    db.Querier
 	logger zerolog.Logger
 }
 
@@ -23,7 +21,7 @@ type PreferenceEngine interface {
 }
 
 // NewPreferenceEngine returns an instance of notificationEngine
-func NewPreferenceEngine(psql *sql.DB, logger zerolog.Logger) PreferenceEngine {
+func NewPreferenceEngine(psql db.Querier, logger zerolog.Logger) PreferenceEngine {
 	return &preferenceEngine{psql, logger}
 }
 
@@ -46,7 +44,7 @@ func (p *preferenceEngine) PreferenceAdd(phoneID string, cuisine string) error {
 	}
 	defer rows.Close()
 
-	p.logger.Info().Msgf("add preferences successful for phoneID:%d", phoneID)
+	p.logger.Info().Msgf("add preferences successful for phoneID:%s", phoneID)
 
 	return nil
 }
@@ -60,8 +58,7 @@ func (p *preferenceEngine) PreferenceDelete(phoneID string, cuisine string) erro
 }
 
 func (p *preferenceEngine) CheckIfPreferenceExists(phoneID string, cuisine string) (shared.Preference, error) {
-	q := fmt.Sprintf("SELECT prefernce_id, phone_id, cuisine FROM preferences where phone_id = '%s' AND cuisine = '%s';", phoneID, cuisine)
-	rows, err := p.sql.Query(q)
+	rows, err := p.sql.Query("SELECT prefernce_id, phone_id, cuisine FROM preferences where phone_id = $1 AND cuisine = $2;", phoneID, cuisine)
 	if err != nil {
 		return shared.Preference{}, helper.DatabaseError{DBError: err.Error()}
 	}
@@ -87,8 +84,7 @@ func (p *preferenceEngine) CheckIfPreferenceExists(phoneID string, cuisine strin
 }
 
 func (p *preferenceEngine) PreferenceAll(phoneID string) ([]shared.Preference, error) {
-	q := fmt.Sprintf("SELECT prefernce_id, phone_id, cuisine FROM preferences where phone_id = '%s';", phoneID)
-	rows, err := p.sql.Query(q)
+	rows, err := p.sql.Query("SELECT prefernce_id, phone_id, cuisine FROM preferences where phone_id = $1;", phoneID)
 	if err != nil {
 		return nil, helper.DatabaseError{DBError: err.Error()}
 	}
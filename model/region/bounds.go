@@ -0,0 +1,111 @@
+package region
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+	"github.com/umahmood/haversine"
+)
+
+// Normalize trims the names of region and puts its codes in their usual case
+func Normalize(region shared.Region) shared.Region {
+	region.Name = strings.TrimSpace(region.Name)
+	region.TimeZone = strings.TrimSpace(region.TimeZone)
+	region.Currency = strings.ToUpper(strings.TrimSpace(region.Currency))
+	region.DistanceUnit = strings.ToLower(strings.TrimSpace(region.DistanceUnit))
+	if region.DistanceUnit == "" {
+		region.DistanceUnit = shared.DistanceUnitMiles
+	}
+	return region
+}
+
+// Validate returns a helper.ValidationError when region cannot be served
+func Validate(region shared.Region) error {
+	if region.Name == "" {
+		return helper.ValidationError{Message: "region add failed, please provide 'name'"}
+	}
+	if region.Center.Latitude < -90 || region.Center.Latitude > 90 ||
+		region.Center.Longitude < -180 || region.Center.Longitude > 180 {
+		return helper.ValidationError{Message: "region add failed, invalid 'center'"}
+	}
+	if len(region.Polygon) == 0 && region.RadiusMiles <= 0 {
+		return helper.ValidationError{Message: "region add failed, please provide a positive 'radiusMiles' or a 'polygon'"}
+	}
+	if len(region.Polygon) > 0 && len(region.Polygon) < 3 {
+		return helper.ValidationError{Message: "region add failed, a 'polygon' needs at least 3 points"}
+	}
+	if _, err := time.LoadLocation(region.TimeZone); region.TimeZone == "" || err != nil {
+		return helper.ValidationError{Message: fmt.Sprintf("region add failed, invalid 'timeZone': %s", region.TimeZone)}
+	}
+	if len(region.Currency) != 3 {
+		return helper.ValidationError{Message: fmt.Sprintf("region add failed, invalid 'currency': %s", region.Currency)}
+	}
+	if region.DistanceUnit != shared.DistanceUnitMiles && region.DistanceUnit != shared.DistanceUnitKilometers {
+		return helper.ValidationError{Message: fmt.Sprintf("region add failed, 'distanceUnit' must be %s or %s", shared.DistanceUnitMiles, shared.DistanceUnitKilometers)}
+	}
+	if region.SearchRadiusMiles <= 0 {
+		return helper.ValidationError{Message: "region add failed, please provide a positive 'searchRadiusMiles'"}
+	}
+	return nil
+}
+
+// Contains tells whether location is in region, within its polygon when it
+// has one, or else within its radius
+func Contains(region shared.Region, location shared.GeoLocation) bool {
+	if len(region.Polygon) >= 3 {
+		return inPolygon(region.Polygon, location)
+	}
+	return miles(region.Center, location) <= region.RadiusMiles
+}
+
+// Find returns the region location is in, the one with the nearest center
+// when regions overlap. ok is false when location is in none.
+func Find(regions []shared.Region, location shared.GeoLocation) (region shared.Region, ok bool) {
+	nearest := 0.0
+	for _, r := range regions {
+		if !Contains(r, location) {
+			continue
+		}
+		if d := miles(r.Center, location); !ok || d < nearest {
+			region, nearest, ok = r, d, true
+		}
+	}
+	return region, ok
+}
+
+// Now returns the current time in the time zone of region
+func Now(region shared.Region) (time.Time, error) {
+	zone, err := time.LoadLocation(region.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(zone), nil
+}
+
+func miles(from shared.GeoLocation, to shared.GeoLocation) float64 {
+	mi, _ := haversine.Distance(
+		haversine.Coord{Lat: from.Latitude, Lon: from.Longitude},
+		haversine.Coord{Lat: to.Latitude, Lon: to.Longitude},
+	)
+	return mi
+}
+
+// inPolygon tells whether location is inside polygon by casting a ray east
+// of it and counting the edges it crosses. Regions are small enough for
+// coordinates to be treated as planar.
+func inPolygon(polygon []shared.GeoLocation, location shared.GeoLocation) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Latitude > location.Latitude) != (b.Latitude > location.Latitude) {
+			crossing := a.Longitude + (location.Latitude-a.Latitude)/(b.Latitude-a.Latitude)*(b.Longitude-a.Longitude)
+			if location.Longitude < crossing {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
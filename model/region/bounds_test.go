@@ -0,0 +1,70 @@
+package region
+
+import (
+	"testing"
+
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	bayArea := shared.Region{Name: "Bay Area", Center: shared.GeoLocation{Latitude: 37.36883, Longitude: -122.0363496}, RadiusMiles: 100}
+	toronto := shared.Region{Name: "Toronto", Center: shared.GeoLocation{Latitude: 43.6532, Longitude: -79.3832},
+		Polygon: []shared.GeoLocation{
+			{Latitude: 43.58, Longitude: -79.64},
+			{Latitude: 43.86, Longitude: -79.64},
+			{Latitude: 43.86, Longitude: -79.11},
+			{Latitude: 43.58, Longitude: -79.11},
+		}}
+	sanFrancisco := shared.Region{Name: "San Francisco", Center: shared.GeoLocation{Latitude: 37.7749, Longitude: -122.4194}, RadiusMiles: 10}
+	regions := []shared.Region{bayArea, toronto, sanFrancisco}
+
+	region, ok := Find(regions, shared.GeoLocation{Latitude: 37.3717, Longitude: -122.0230})
+	require.True(t, ok)
+	require.Equal(t, "Bay Area", region.Name)
+
+	// overlapping regions, the nearest center wins
+	region, ok = Find(regions, shared.GeoLocation{Latitude: 37.7599, Longitude: -122.4148})
+	require.True(t, ok)
+	require.Equal(t, "San Francisco", region.Name)
+
+	region, ok = Find(regions, shared.GeoLocation{Latitude: 43.6629, Longitude: -79.3957})
+	require.True(t, ok)
+	require.Equal(t, "Toronto", region.Name)
+
+	// close to toronto but out of its polygon
+	_, ok = Find(regions, shared.GeoLocation{Latitude: 43.5, Longitude: -79.4})
+	require.False(t, ok)
+	_, ok = Find(regions, shared.GeoLocation{Latitude: 40.7128, Longitude: -74.0060})
+	require.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	region := Normalize(shared.Region{
+		Name:              " Toronto ",
+		Center:            shared.GeoLocation{Latitude: 43.6532, Longitude: -79.3832},
+		RadiusMiles:       30,
+		TimeZone:          "America/Toronto",
+		Currency:          "cad",
+		DistanceUnit:      "KM",
+		SearchRadiusMiles: 10,
+	})
+	require.NoError(t, Validate(region))
+	require.Equal(t, "Toronto", region.Name)
+	require.Equal(t, "CAD", region.Currency)
+	require.Equal(t, shared.DistanceUnitKilometers, region.DistanceUnit)
+
+	for _, invalid := range []func(r *shared.Region){
+		func(r *shared.Region) { r.Name = "" },
+		func(r *shared.Region) { r.RadiusMiles = 0 },
+		func(r *shared.Region) { r.Polygon = []shared.GeoLocation{{}, {}} },
+		func(r *shared.Region) { r.TimeZone = "Canada/Nowhere" },
+		func(r *shared.Region) { r.Currency = "dollars" },
+		func(r *shared.Region) { r.DistanceUnit = "ft" },
+		func(r *shared.Region) { r.SearchRadiusMiles = 0 },
+	} {
+		r := region
+		invalid(&r)
+		require.Error(t, Validate(r))
+	}
+}
@@ -0,0 +1,186 @@
+package region
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+// regionsTTL is how long the regions are kept in memory, how long the other
+// servers take to see a region added or deleted through one of them
+const regionsTTL = time.Minute
+
+type (
+	regionEngine struct {
+		sql    db.Querier
+		logger zerolog.Logger
+		cache  *regionCache
+	}
+
+	// regionCache keeps the regions RegionFor looks locations up in for
+	// regionsTTL, or until a region is added or deleted
+	regionCache struct {
+		mu      sync.RWMutex
+		regions []shared.Region
+		loaded  time.Time
+		// version counts the changes, regions read before one are not kept
+		version int
+	}
+
+	// RegionEngine keeps the registry of the regions the service is
+	// available in
+	RegionEngine interface {
+		// GetRegions returns every region served
+		GetRegions() ([]shared.Region, error)
+
+		// AddRegion adds region, or updates the region of the same name,
+		// and returns its id
+		AddRegion(region shared.Region) (int, error)
+
+		// DeleteRegion stops serving a region
+		DeleteRegion(regionID int) error
+
+		// RegionFor returns the region location is in, ok is false when it
+		// is in none
+		RegionFor(location shared.GeoLocation) (region shared.Region, ok bool, err error)
+	}
+)
+
+// NewRegionEngine returns an instance of regionEngine
+func NewRegionEngine(psql db.Querier, logger zerolog.Logger) RegionEngine {
+	return &regionEngine{psql, logger, &regionCache{}}
+}
+
+func (r *regionEngine) GetRegions() ([]shared.Region, error) {
+	rows, err := r.sql.Query("SELECT region_id, name, latitude, longitude, radius_miles, polygon, time_zone, " +
+		"currency, distance_unit, search_radius_miles FROM region ORDER BY name;")
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	regions := make([]shared.Region, 0)
+	for rows.Next() {
+		var region shared.Region
+		var polygon sql.NullString
+		err := rows.Scan(
+			&region.RegionID,
+			&region.Name,
+			&region.Center.Latitude,
+			&region.Center.Longitude,
+			&region.RadiusMiles,
+			&polygon,
+			&region.TimeZone,
+			&region.Currency,
+			&region.DistanceUnit,
+			&region.SearchRadiusMiles,
+		)
+		if err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+
+		if polygon.Valid && polygon.String != "" {
+			if err := json.Unmarshal([]byte(polygon.String), &region.Polygon); err != nil {
+				r.logger.Error().Msgf("region %s has an invalid polygon: %s", region.Name, err)
+				continue
+			}
+		}
+		regions = append(regions, region)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	return regions, nil
+}
+
+func (r *regionEngine) AddRegion(region shared.Region) (int, error) {
+	region = Normalize(region)
+	if err := Validate(region); err != nil {
+		return 0, err
+	}
+
+	var polygon sql.NullString
+	if len(region.Polygon) > 0 {
+		b, err := json.Marshal(region.Polygon)
+		if err != nil {
+			return 0, err
+		}
+		polygon = sql.NullString{String: string(b), Valid: true}
+	}
+
+	var regionID int
+	err := r.sql.QueryRow("INSERT INTO region(name, latitude, longitude, radius_miles, polygon, time_zone, "+
+		"currency, distance_unit, search_radius_miles) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9) "+
+		"ON CONFLICT (name) DO UPDATE SET latitude = excluded.latitude, longitude = excluded.longitude, "+
+		"radius_miles = excluded.radius_miles, polygon = excluded.polygon, time_zone = excluded.time_zone, "+
+		"currency = excluded.currency, distance_unit = excluded.distance_unit, "+
+		"search_radius_miles = excluded.search_radius_miles RETURNING region_id;",
+		region.Name, region.Center.Latitude, region.Center.Longitude, region.RadiusMiles, polygon,
+		region.TimeZone, region.Currency, region.DistanceUnit, region.SearchRadiusMiles).Scan(&regionID)
+	if err != nil {
+		return 0, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	r.cache.invalidate()
+	r.logger.Info().Msgf("added region %s with id: %d", region.Name, regionID)
+	return regionID, nil
+}
+
+func (r *regionEngine) DeleteRegion(regionID int) error {
+	if _, err := r.sql.Exec("DELETE FROM region WHERE region_id = $1;", regionID); err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+
+	r.cache.invalidate()
+	r.logger.Info().Msgf("deleted region with id: %d", regionID)
+	return nil
+}
+
+func (r *regionEngine) RegionFor(location shared.GeoLocation) (shared.Region, bool, error) {
+	regions, ok, version := r.cache.get()
+	if !ok {
+		var err error
+		regions, err = r.GetRegions()
+		if err != nil {
+			return shared.Region{}, false, err
+		}
+		r.cache.put(regions, version)
+	}
+
+	region, ok := Find(regions, location)
+	return region, ok, nil
+}
+
+// get returns the regions kept, ok is false when there are none or they
+// expired. The version is the one to put the regions read then back with.
+func (c *regionCache) get() ([]shared.Region, bool, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ok := !c.loaded.IsZero() && time.Since(c.loaded) < regionsTTL
+	return c.regions, ok, c.version
+}
+
+// put keeps regions read at version, unless a region changed since
+func (c *regionCache) put(regions []shared.Region, version int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version == version {
+		c.regions = regions
+		c.loaded = time.Now()
+	}
+}
+
+func (c *regionCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.regions = nil
+	c.loaded = time.Time{}
+	c.version++
+}
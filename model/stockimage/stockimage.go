@@ -0,0 +1,231 @@
+package stockimage
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/phassans/banana/db"
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+	"github.com/rs/zerolog"
+)
+
+// DefaultCategory is the category of the stock images listings of no other
+// category are shown with
+const DefaultCategory = "default"
+
+// NoImageLink is shown for listings without a photo when the stock library
+// has no image for them
+const NoImageLink = "https://res.cloudinary.com/itshungryhour/image/upload/v1533011858/listing/NoPicAvailable.png"
+
+type (
+	stockImageEngine struct {
+		sql    db.Querier
+		logger zerolog.Logger
+	}
+
+	// StockImageEngine keeps the library of stock images listings without a
+	// photo of their own are shown with
+	StockImageEngine interface {
+		// GetStockImages returns every stock image by category
+		GetStockImages() ([]shared.StockImage, error)
+
+		// AddStockImage adds image to the library and returns its id
+		AddStockImage(image shared.StockImage) (int, error)
+
+		// DeleteStockImage removes an image from the library
+		DeleteStockImage(stockImageID int) error
+
+		// GetStockLibrary returns the images of the library by category
+		GetStockLibrary() (Library, error)
+
+		// GetCategoryKeywords returns the keywords of every category
+		GetCategoryKeywords() (map[string][]string, error)
+	}
+
+	// Library is the image links of every category of the stock library,
+	// by lower case category
+	Library map[string][]string
+)
+
+// NewStockImageEngine returns an instance of stockImageEngine
+func NewStockImageEngine(psql db.Querier, logger zerolog.Logger) StockImageEngine {
+	return &stockImageEngine{psql, logger}
+}
+
+func (s *stockImageEngine) GetStockImages() ([]shared.StockImage, error) {
+	rows, err := s.sql.Query("SELECT stock_image_id, category, url FROM stock_image ORDER BY category, stock_image_id;")
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	images := make([]shared.StockImage, 0)
+	for rows.Next() {
+		var image shared.StockImage
+		if err := rows.Scan(&image.StockImageID, &image.Category, &image.ImageLink); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		images = append(images, image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	return images, nil
+}
+
+func (s *stockImageEngine) AddStockImage(image shared.StockImage) (int, error) {
+	if err := Validate(image); err != nil {
+		return 0, err
+	}
+
+	var stockImageID int
+	err := s.sql.QueryRow("INSERT INTO stock_image(category, url) VALUES($1, $2) "+
+		"ON CONFLICT (category, url) DO UPDATE SET category = EXCLUDED.category "+
+		"RETURNING stock_image_id;", strings.TrimSpace(image.Category), strings.TrimSpace(image.ImageLink)).
+		Scan(&stockImageID)
+	if err != nil {
+		return 0, helper.DatabaseError{DBError: err.Error()}
+	}
+
+	s.logger.Info().Msgf("added stock image %d to category %s", stockImageID, image.Category)
+	return stockImageID, nil
+}
+
+func (s *stockImageEngine) DeleteStockImage(stockImageID int) error {
+	result, err := s.sql.Exec("DELETE FROM stock_image WHERE stock_image_id = $1;", stockImageID)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return helper.ValidationError{Message: fmt.Sprintf("stock image delete failed, no stock image %d", stockImageID)}
+	}
+	return nil
+}
+
+func (s *stockImageEngine) GetStockLibrary() (Library, error) {
+	images, err := s.GetStockImages()
+	if err != nil {
+		return nil, err
+	}
+
+	library := make(Library)
+	for _, image := range images {
+		category := strings.ToLower(image.Category)
+		library[category] = append(library[category], image.ImageLink)
+	}
+	return library, nil
+}
+
+func (s *stockImageEngine) GetCategoryKeywords() (map[string][]string, error) {
+	rows, err := s.sql.Query("SELECT category, keyword FROM category_to_keyword WHERE keyword IS NOT NULL;")
+	if err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	defer rows.Close()
+
+	keywords := make(map[string][]string)
+	for rows.Next() {
+		var category, keyword string
+		if err := rows.Scan(&category, &keyword); err != nil {
+			return nil, helper.DatabaseError{DBError: err.Error()}
+		}
+		keywords[category] = append(keywords[category], keyword)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, helper.DatabaseError{DBError: err.Error()}
+	}
+	return keywords, nil
+}
+
+// Validate checks image has a category and an http(s) image link
+func Validate(image shared.StockImage) error {
+	if strings.TrimSpace(image.Category) == "" {
+		return helper.ValidationError{Message: "stock image add failed, missing category"}
+	}
+	u, err := url.Parse(strings.TrimSpace(image.ImageLink))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return helper.ValidationError{Message: "stock image add failed, invalid imageLink"}
+	}
+	return nil
+}
+
+// Pick returns the stock image of listingID, an image of its category or
+// else of the default category, and false when there is none. A listing
+// keeps its image as long as the image stays in the library: of the images
+// of a category each listing gets the one that hashes highest with its id,
+// so adding an image to a category only moves the listings it wins.
+func (l Library) Pick(category string, listingID int) (string, bool) {
+	images := l[strings.ToLower(category)]
+	if len(images) == 0 {
+		images = l[DefaultCategory]
+	}
+	if len(images) == 0 {
+		return "", false
+	}
+
+	var picked string
+	var best uint64
+	for _, image := range images {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%d/%s", listingID, image)
+		if weight := h.Sum64(); picked == "" || weight > best || (weight == best && image < picked) {
+			picked, best = image, weight
+		}
+	}
+	return picked, true
+}
+
+// Categorize returns the category of a listing of title and description, the
+// category whose keywords its title mentions most, its description telling
+// categories its title mentions as much apart. Longer keywords weigh more,
+// ties go to the first category by name and listings that mention none have
+// no category.
+func Categorize(keywords map[string][]string, title string, description string) string {
+	title, description = normalize(title), normalize(description)
+
+	categories := make([]string, 0, len(keywords))
+	for category := range keywords {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var best string
+	var bestTitle, bestDescription int
+	for _, category := range categories {
+		inTitle, inDescription := 0, 0
+		for _, keyword := range keywords[category] {
+			keyword = normalize(keyword)
+			if keyword == " " {
+				continue
+			}
+			weight := len(strings.Fields(keyword))
+			if strings.Contains(title, keyword) {
+				inTitle += weight
+			}
+			if strings.Contains(description, keyword) {
+				inDescription += weight
+			}
+		}
+		if inTitle > bestTitle || (inTitle == bestTitle && inDescription > bestDescription) {
+			best, bestTitle, bestDescription = category, inTitle, inDescription
+		}
+	}
+	return best
+}
+
+// normalize lower cases text and turns everything but letters and digits
+// into single spaces, padding it with one so that words can be matched as
+// " word "
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
@@ -0,0 +1,74 @@
+package stockimage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/phassans/banana/helper"
+	"github.com/phassans/banana/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPick(t *testing.T) {
+	library := Library{"tacos": {"https://example.com/tacos/1.jpg", "https://example.com/tacos/2.jpg", "https://example.com/tacos/3.jpg"}}
+
+	_, ok := library.Pick("Wine", 1)
+	require.False(t, ok)
+
+	picked := make(map[int]string)
+	counts := make(map[string]int)
+	for listingID := 1; listingID <= 300; listingID++ {
+		image, ok := library.Pick("Tacos", listingID)
+		require.True(t, ok)
+		again, _ := library.Pick("tacos", listingID)
+		require.Equal(t, image, again)
+		picked[listingID] = image
+		counts[image]++
+	}
+	// every image is shown with some listings
+	require.Len(t, counts, 3)
+
+	// a new image only takes listings over, the others keep theirs
+	library["tacos"] = append(library["tacos"], "https://example.com/tacos/4.jpg")
+	for listingID, image := range picked {
+		now, _ := library.Pick("Tacos", listingID)
+		if now != image {
+			require.Equal(t, "https://example.com/tacos/4.jpg", now, fmt.Sprintf("listing %d", listingID))
+		}
+	}
+
+	// listings of categories without images get a default one
+	library[DefaultCategory] = []string{"https://example.com/default.jpg"}
+	image, ok := library.Pick("Wine", 1)
+	require.True(t, ok)
+	require.Equal(t, "https://example.com/default.jpg", image)
+}
+
+func TestCategorize(t *testing.T) {
+	keywords := map[string][]string{
+		"Beer":          {"beer", "pint", "ipa"},
+		"Tacos":         {"taco", "tacos"},
+		"Wings":         {"wings", "chicken wings"},
+		"Milk Tea Boba": {"milk tea", "boba"},
+	}
+
+	require.Equal(t, "Tacos", Categorize(keywords, "$2 Tacos Tuesday!", "with a pint of beer"))
+	require.Equal(t, "Beer", Categorize(keywords, "Happy hour", "$4 pints... no, $4 IPA and beer"))
+	require.Equal(t, "Wings", Categorize(keywords, "Chicken-wings & beer", ""))
+	require.Equal(t, "Milk Tea Boba", Categorize(keywords, "Milk tea", ""))
+	// words are matched whole
+	require.Equal(t, "", Categorize(keywords, "Tacobell", "Pinterest"))
+	// ties go to the first category by name
+	require.Equal(t, "Beer", Categorize(keywords, "Beer and tacos", ""))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(shared.StockImage{Category: "Tacos", ImageLink: "https://example.com/tacos.jpg"}))
+	for _, invalid := range []shared.StockImage{
+		{ImageLink: "https://example.com/tacos.jpg"},
+		{Category: "Tacos", ImageLink: "tacos.jpg"},
+		{Category: "Tacos", ImageLink: "ftp://example.com/tacos.jpg"},
+	} {
+		require.IsType(t, helper.ValidationError{}, Validate(invalid))
+	}
+}
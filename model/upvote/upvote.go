@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/model/listing"
 	"github.com/rs/zerolog"
@@ -11,7 +12,7 @@ import (
 
 type (
 	upvoteEngine struct {
-		sql           *sql.DB
+		sql           db.Querier
 		logger        zerolog.Logger
 		listingEngine listing.ListingEngine
 	}
@@ -25,7 +26,7 @@ type (
 )
 
 // NewUpvoteEngine returns an instance of upvoteEngine
-func NewUpvoteEngine(psql *sql.DB, logger zerolog.Logger, listingEngine listing.ListingEngine) UpvoteEngine {
+func NewUpvoteEngine(psql db.Querier, logger zerolog.Logger, listingEngine listing.ListingEngine) UpvoteEngine {
 	return &upvoteEngine{psql, logger, listingEngine}
 }
 
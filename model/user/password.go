@@ -0,0 +1,49 @@
+package user
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// legacyHashLength is the length of the hex encoded, unsalted md5 hashes
+// stored before passwords moved to bcrypt
+const legacyHashLength = md5.Size * 2
+
+// dummyHash is checked against when no user has the email logged in with, so
+// that unknown emails take as long to turn down as wrong passwords
+const dummyHash = "$2a$10$eVjb.p1sSpmm0aYE4Xaa4.eZPpbQVuOPzPfhgelQnL5kpiS4T588S"
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword reports whether password matches the stored hash, and
+// whether that hash is a legacy md5 one which should be replaced.
+func checkPassword(stored string, password string) (match bool, legacy bool) {
+	if isLegacyHash(stored) {
+		match = subtle.ConstantTimeCompare([]byte(stored), []byte(getMD5Hash(password))) == 1
+		return match, true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil, false
+}
+
+func isLegacyHash(stored string) bool {
+	if len(stored) != legacyHashLength {
+		return false
+	}
+	_, err := hex.DecodeString(stored)
+	return err == nil
+}
+
+func getMD5Hash(text string) string {
+	hasher := md5.New()
+	hasher.Write([]byte(text))
+	return hex.EncodeToString(hasher.Sum(nil))
+}
@@ -0,0 +1,37 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := hashPassword("banana123")
+	require.NoError(t, err)
+	require.NotEqual(t, "banana123", hash)
+
+	match, legacy := checkPassword(hash, "banana123")
+	require.True(t, match)
+	require.False(t, legacy)
+
+	match, _ = checkPassword(hash, "banana124")
+	require.False(t, match)
+
+	// unknown emails are checked against a real bcrypt hash
+	match, legacy = checkPassword(dummyHash, "banana123")
+	require.False(t, match)
+	require.False(t, legacy)
+}
+
+func TestCheckPasswordLegacy(t *testing.T) {
+	stored := getMD5Hash("banana123")
+
+	match, legacy := checkPassword(stored, "banana123")
+	require.True(t, match)
+	require.True(t, legacy)
+
+	match, legacy = checkPassword(stored, "banana124")
+	require.False(t, match)
+	require.True(t, legacy)
+}
@@ -1,20 +1,18 @@
 package user
 
 import (
-	"crypto/md5"
-	"database/sql"
-	"encoding/hex"
 	"time"
 
 	"fmt"
 
+	"github.com/phassans/banana/db"
 	"github.com/phassans/banana/helper"
 	"github.com/phassans/banana/shared"
 	"github.com/rs/zerolog"
 )
 
 type userEngine struct {
-	sql    *sql.DB
+	sql    db.Querier
 	logger zerolog.Logger
 }
 
@@ -24,13 +22,15 @@ type UserEngine interface {
 	UserEdit(userID int, name string, email string, password string, phone string) error
 	UserGet(userID int) (shared.BusinessUser, error)
 	UserVerify(email string, password string) (shared.BusinessUser, error)
+	UserOwnsBusiness(userID int, businessID int) (bool, error)
+	UserOwnsListing(userID int, listingID int) (bool, error)
 
 	ContactUs(phoneID string, uname string, email string, subject string, comments string) error
 	ReportInaccurate(phoneID string, listing int) error
 }
 
 // NewUserEngine returns an instance of userEngine
-func NewUserEngine(psql *sql.DB, logger zerolog.Logger) UserEngine {
+func NewUserEngine(psql db.Querier, logger zerolog.Logger) UserEngine {
 	return &userEngine{psql, logger}
 }
 
@@ -46,9 +46,14 @@ func (u *userEngine) UserAdd(name string, email string, password string, phone s
 		return helper.UserError{Message: fmt.Sprintf("user with %s is already registered!", email)}
 	}
 
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
 	err = u.sql.QueryRow("INSERT INTO business_user(name,email,password,phone) "+
 		"VALUES($1,$2,$3,$4) returning user_id;",
-		name, email, getMD5Hash(password), phone).Scan(&userID)
+		name, email, hash, phone).Scan(&userID)
 	if err != nil {
 		return helper.DatabaseError{DBError: err.Error()}
 	}
@@ -64,7 +69,12 @@ func (u *userEngine) UserEdit(userID int, name string, email string, password st
 	SET name = $1, email = $2, password = $3, phone = $4
 	WHERE user_id = $5;`
 
-	_, err := u.sql.Exec(updateBusinessUserSQL, name, email, getMD5Hash(password), phone, userID)
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.sql.Exec(updateBusinessUserSQL, name, email, hash, phone, userID)
 	if err != nil {
 		return err
 	}
@@ -73,7 +83,7 @@ func (u *userEngine) UserEdit(userID int, name string, email string, password st
 }
 
 func (u *userEngine) UserGet(userID int) (shared.BusinessUser, error) {
-	rows, err := u.sql.Query("SELECT user_id, name, email, phone FROM business_user where "+
+	rows, err := u.sql.Query("SELECT user_id, name, email, phone, role FROM business_user where "+
 		"user_id = $1;", userID)
 	if err != nil {
 		return shared.BusinessUser{}, helper.DatabaseError{DBError: err.Error()}
@@ -83,7 +93,7 @@ func (u *userEngine) UserGet(userID int) (shared.BusinessUser, error) {
 
 	var userInfo shared.BusinessUser
 	if rows.Next() {
-		err = rows.Scan(&userInfo.UserID, &userInfo.Name, &userInfo.Email, &userInfo.Phone)
+		err = rows.Scan(&userInfo.UserID, &userInfo.Name, &userInfo.Email, &userInfo.Phone, &userInfo.Role)
 		if err != nil {
 			return shared.BusinessUser{}, helper.DatabaseError{DBError: err.Error()}
 		}
@@ -125,9 +135,12 @@ func (u *userEngine) CheckEmail(email string) (int, error) {
 	return userID, nil
 }
 
+// UserVerify checks the password of the user registered with email. Users
+// still on a legacy md5 hash are moved to bcrypt on their next successful
+// login.
 func (u *userEngine) UserVerify(email string, password string) (shared.BusinessUser, error) {
-	rows, err := u.sql.Query("SELECT user_id, name, email, phone FROM business_user where "+
-		"email = $1 AND password = $2;", email, getMD5Hash(password))
+	rows, err := u.sql.Query("SELECT user_id, name, email, phone, role, password FROM business_user where "+
+		"email = $1;", email)
 	if err != nil {
 		return shared.BusinessUser{}, helper.DatabaseError{DBError: err.Error()}
 	}
@@ -135,26 +148,75 @@ func (u *userEngine) UserVerify(email string, password string) (shared.BusinessU
 	defer rows.Close()
 
 	var userInfo shared.BusinessUser
+	var stored string
 	if rows.Next() {
-		err = rows.Scan(&userInfo.UserID, &userInfo.Name, &userInfo.Email, &userInfo.Phone)
+		err = rows.Scan(&userInfo.UserID, &userInfo.Name, &userInfo.Email, &userInfo.Phone, &userInfo.Role, &stored)
 		if err != nil {
 			return shared.BusinessUser{}, helper.DatabaseError{DBError: err.Error()}
 		}
-	} else {
-		return shared.BusinessUser{}, helper.UserError{Message: fmt.Sprintf("user %s password mismatch", email)}
 	}
 
 	if err = rows.Err(); err != nil {
 		return shared.BusinessUser{}, helper.DatabaseError{DBError: err.Error()}
 	}
 
+	// unknown emails and wrong passwords are reported the same way, and
+	// take as long
+	if userInfo.UserID == 0 {
+		stored = dummyHash
+	}
+	match, legacy := checkPassword(stored, password)
+	if userInfo.UserID == 0 || !match {
+		return shared.BusinessUser{}, helper.AuthenticationError{Message: fmt.Sprintf("user %s password mismatch", email)}
+	}
+
+	if legacy {
+		if err := u.rehashPassword(userInfo.UserID, password); err != nil {
+			// the login itself succeeded, try again next time
+			u.logger.Error().Msgf("could not rehash password of user %d: %s", userInfo.UserID, err)
+		}
+	}
+
 	return userInfo, nil
 }
 
-func getMD5Hash(text string) string {
-	hasher := md5.New()
-	hasher.Write([]byte(text))
-	return hex.EncodeToString(hasher.Sum(nil))
+func (u *userEngine) rehashPassword(userID int, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.sql.Exec("UPDATE business_user SET password = $1 WHERE user_id = $2;", hash, userID)
+	if err != nil {
+		return helper.DatabaseError{DBError: err.Error()}
+	}
+
+	u.logger.Info().Msgf("moved password of user %d to bcrypt", userID)
+	return nil
+}
+
+// UserOwnsBusiness reports whether the user is associated with the business
+func (u *userEngine) UserOwnsBusiness(userID int, businessID int) (bool, error) {
+	var owns bool
+	err := u.sql.QueryRow("SELECT EXISTS(SELECT 1 FROM user_to_business WHERE "+
+		"user_id = $1 AND business_id = $2);", userID, businessID).Scan(&owns)
+	if err != nil {
+		return false, helper.DatabaseError{DBError: err.Error()}
+	}
+	return owns, nil
+}
+
+// UserOwnsListing reports whether the listing belongs to a business the user
+// is associated with
+func (u *userEngine) UserOwnsListing(userID int, listingID int) (bool, error) {
+	var owns bool
+	err := u.sql.QueryRow("SELECT EXISTS(SELECT 1 FROM listing "+
+		"INNER JOIN user_to_business ON listing.business_id = user_to_business.business_id "+
+		"WHERE user_to_business.user_id = $1 AND listing.listing_id = $2);", userID, listingID).Scan(&owns)
+	if err != nil {
+		return false, helper.DatabaseError{DBError: err.Error()}
+	}
+	return owns, nil
 }
 
 func (u *userEngine) ContactUs(phoneID string, uname string, email string, subject string, comments string) error {
@@ -0,0 +1,503 @@
+// Package recurrence parses and expands the subset of RFC 5545 recurrence
+// rules listings use. Occurrences are whole dates, the time of day comes
+// from the listing.
+//
+// Supported are RRULE lines with FREQ (DAILY, WEEKLY, MONTHLY or YEARLY),
+// INTERVAL, COUNT, UNTIL, BYDAY (with ordinals such as 1FR or -1SA for
+// monthly and yearly rules, counted within the year by yearly rules without
+// BYMONTH), BYMONTHDAY, BYMONTH and WKST, and EXDATE lines
+// listing dates to skip, e.g.
+//
+//	RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH
+//	EXDATE:20181225,20190101
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Frequency is how often a rule repeats
+type Frequency string
+
+// Supported frequencies
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+const dateLayout = "20060102"
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+type (
+	// Recurrence is a set of rules and the dates excluded from them
+	Recurrence struct {
+		Rules   []Rule
+		ExDates []time.Time
+	}
+
+	// Rule is a single RRULE
+	Rule struct {
+		Freq       Frequency
+		Interval   int
+		Count      int
+		Until      time.Time
+		ByDay      []WeekdayNum
+		ByMonthDay []int
+		ByMonth    []time.Month
+		WeekStart  time.Weekday
+	}
+
+	// WeekdayNum is a BYDAY entry. N is the ordinal within the month, or
+	// the year for yearly rules without BYMONTH, e.g. 1 for the first and -1
+	// for the last, 0 matches every such weekday.
+	WeekdayNum struct {
+		N       int
+		Weekday time.Weekday
+	}
+)
+
+// Parse reads a recurrence from RRULE and EXDATE content lines. A single
+// rule may be given without the RRULE: prefix.
+func Parse(text string) (Recurrence, error) {
+	var recurrence Recurrence
+	for _, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, value := "RRULE", line
+		if i := strings.Index(line, ":"); i >= 0 {
+			// parameters such as EXDATE;VALUE=DATE are ignored
+			name, value = strings.ToUpper(strings.SplitN(line[:i], ";", 2)[0]), line[i+1:]
+		}
+
+		switch name {
+		case "RRULE":
+			rule, err := ParseRule(value)
+			if err != nil {
+				return Recurrence{}, err
+			}
+			recurrence.Rules = append(recurrence.Rules, rule)
+		case "EXDATE":
+			for _, value := range strings.Split(value, ",") {
+				date, err := parseDate(value)
+				if err != nil {
+					return Recurrence{}, errors.Wrapf(err, "invalid EXDATE %q", value)
+				}
+				recurrence.ExDates = append(recurrence.ExDates, date)
+			}
+		default:
+			return Recurrence{}, errors.Errorf("unsupported recurrence property %s", name)
+		}
+	}
+
+	if len(recurrence.Rules) == 0 {
+		return Recurrence{}, errors.New("recurrence has no RRULE")
+	}
+	return recurrence, nil
+}
+
+// ParseRule reads the value of a single RRULE, e.g. FREQ=MONTHLY;BYDAY=1FR
+func ParseRule(value string) (Rule, error) {
+	rule := Rule{Interval: 1, WeekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			return Rule{}, errors.Errorf("invalid rule part %q", part)
+		}
+
+		key, val := strings.ToUpper(strings.TrimSpace(pair[0])), strings.ToUpper(strings.TrimSpace(pair[1]))
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(val)
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = errors.Errorf("unsupported FREQ %s", val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("INTERVAL must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("COUNT must be positive")
+			}
+		case "UNTIL":
+			rule.Until, err = parseDate(val)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseInts(val, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(val, 12)
+			for _, month := range months {
+				if month < 0 {
+					err = errors.New("BYMONTH must be positive")
+					break
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			weekday, ok := weekdays[val]
+			if !ok {
+				err = errors.Errorf("unknown weekday %s", val)
+			}
+			rule.WeekStart = weekday
+		default:
+			err = errors.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return Rule{}, errors.Wrapf(err, "invalid %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, errors.New("rule has no FREQ")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return Rule{}, errors.New("rule must not have both COUNT and UNTIL")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return Rule{}, errors.New("BYDAY ordinals need a MONTHLY or YEARLY rule")
+		}
+		// a month has 5 of a weekday at most, a year 53
+		if (day.N < -5 || day.N > 5) && !rule.byYear() {
+			return Rule{}, errors.Errorf("BYDAY ordinal %d is out of range", day.N)
+		}
+	}
+	return rule, nil
+}
+
+// Between returns the dates the recurrence occurs on from `from` to `to`,
+// both included, in order. start is the first date of the recurrence, the
+// DTSTART of the listing. COUNT is counted from start.
+func (r Recurrence) Between(start time.Time, from time.Time, to time.Time) []time.Time {
+	start, from, to = dateOf(start), dateOf(from), dateOf(to)
+
+	excluded := make(map[time.Time]bool)
+	for _, date := range r.ExDates {
+		excluded[dateOf(date)] = true
+	}
+
+	occurs := make(map[time.Time]bool)
+	for _, rule := range r.Rules {
+		count := 0
+		for day := start; !day.After(to); day = day.AddDate(0, 0, 1) {
+			if !rule.Until.IsZero() && day.After(dateOf(rule.Until)) {
+				break
+			}
+			if !rule.matches(start, day) {
+				continue
+			}
+			// excluded dates still count towards COUNT
+			count++
+			if rule.Count > 0 && count > rule.Count {
+				break
+			}
+			if !day.Before(from) && !excluded[day] {
+				occurs[day] = true
+			}
+		}
+	}
+
+	dates := make([]time.Time, 0, len(occurs))
+	for day := range occurs {
+		dates = append(dates, day)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// Weekdays returns the weekdays the recurrence may fall on, for describing it
+func (r Recurrence) Weekdays(start time.Time) []time.Weekday {
+	var days [7]bool
+	for _, rule := range r.Rules {
+		switch {
+		case len(rule.ByDay) > 0:
+			for _, day := range rule.ByDay {
+				days[day.Weekday] = true
+			}
+		case rule.Freq == Daily:
+			for i := range days {
+				days[i] = true
+			}
+		case rule.Freq == Weekly:
+			days[start.Weekday()] = true
+		}
+	}
+
+	var result []time.Weekday
+	for i, ok := range days {
+		if ok {
+			result = append(result, time.Weekday(i))
+		}
+	}
+	return result
+}
+
+// String formats the recurrence as content lines Parse reads back
+func (r Recurrence) String() string {
+	var lines []string
+	for _, rule := range r.Rules {
+		lines = append(lines, "RRULE:"+rule.String())
+	}
+	if len(r.ExDates) > 0 {
+		var dates []string
+		for _, date := range r.ExDates {
+			dates = append(dates, date.Format(dateLayout))
+		}
+		lines = append(lines, "EXDATE:"+strings.Join(dates, ","))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String formats the rule as an RRULE value
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format(dateLayout))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, day := range r.ByDay {
+			days = append(days, day.String())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		var months []int
+		for _, month := range r.ByMonth {
+			months = append(months, int(month))
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+WeekdayNum{Weekday: r.WeekStart}.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// String formats the entry as in BYDAY, e.g. 1FR
+func (w WeekdayNum) String() string {
+	for name, weekday := range weekdays {
+		if weekday == w.Weekday {
+			if w.N == 0 {
+				return name
+			}
+			return strconv.Itoa(w.N) + name
+		}
+	}
+	return ""
+}
+
+// matches reports whether day is an occurrence of the rule started on start
+func (r Rule) matches(start time.Time, day time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, day.Month()) {
+		return false
+	}
+
+	switch r.Freq {
+	case Daily:
+		if daysBetween(start, day)%r.Interval != 0 {
+			return false
+		}
+		return r.matchesWeekday(day) && r.matchesMonthDay(day)
+
+	case Weekly:
+		if daysBetween(r.weekOf(start), r.weekOf(day))/7%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return r.matchesWeekday(day)
+
+	case Monthly:
+		if monthsBetween(start, day)%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			return day.Day() == start.Day()
+		}
+		return r.matchesWeekday(day) && r.matchesMonthDay(day)
+
+	case Yearly:
+		if (day.Year()-start.Year())%r.Interval != 0 {
+			return false
+		}
+		// BYDAY and BYMONTHDAY repeat through the whole year, only a plain
+		// yearly rule is held to the month it started in
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			return (len(r.ByMonth) > 0 || day.Month() == start.Month()) && day.Day() == start.Day()
+		}
+		return r.matchesWeekday(day) && r.matchesMonthDay(day)
+	}
+	return false
+}
+
+// byYear reports whether BYDAY ordinals count within the year rather than
+// the month
+func (r Rule) byYear() bool {
+	return r.Freq == Yearly && len(r.ByMonth) == 0
+}
+
+// matchesWeekday checks BYDAY, ordinals count within the month or the year
+func (r Rule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+
+	nth, days := day.Day(), daysIn(day)
+	if r.byYear() {
+		nth, days = day.YearDay(), time.Date(day.Year(), time.December, 31, 0, 0, 0, 0, day.Location()).YearDay()
+	}
+	for _, byDay := range r.ByDay {
+		if byDay.Weekday != day.Weekday() {
+			continue
+		}
+		switch {
+		case byDay.N == 0:
+			return true
+		case byDay.N > 0 && (nth-1)/7+1 == byDay.N:
+			return true
+		case byDay.N < 0 && (days-nth)/7+1 == -byDay.N:
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthDay checks BYMONTHDAY, negative days count from the month end
+func (r Rule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay == day.Day() || monthDay < 0 && daysIn(day)+monthDay+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// weekOf returns the first day of the week day is in
+func (r Rule) weekOf(day time.Time) time.Time {
+	offset := (int(day.Weekday()) - int(r.WeekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, part := range strings.Split(value, ",") {
+		if len(part) < 2 {
+			return nil, errors.Errorf("invalid weekday %q", part)
+		}
+		weekday, ok := weekdays[part[len(part)-2:]]
+		if !ok {
+			return nil, errors.Errorf("unknown weekday %q", part)
+		}
+
+		day := WeekdayNum{Weekday: weekday}
+		if ordinal := part[:len(part)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, errors.Errorf("invalid weekday ordinal %q", part)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseInts reads a list of values between -max and max, 0 excluded
+func parseInts(value string, max int) ([]int, error) {
+	var result []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 || n < -max || n > max {
+			return nil, errors.Errorf("%d is out of range", n)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// parseDate reads a DATE or DATE-TIME value, only the date is kept
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < len(dateLayout) {
+		return time.Time{}, errors.Errorf("invalid date %q", value)
+	}
+	return time.Parse(dateLayout, value[:len(dateLayout)])
+}
+
+func joinInts(values []int) string {
+	var parts []string
+	for _, value := range values {
+		parts = append(parts, strconv.Itoa(value))
+	}
+	return strings.Join(parts, ",")
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, m := range months {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from time.Time, to time.Time) int {
+	return int(dateOf(to).Sub(dateOf(from)).Hours() / 24)
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+func daysIn(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
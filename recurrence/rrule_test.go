@@ -0,0 +1,143 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func dates(values []time.Time) []string {
+	var result []string
+	for _, value := range values {
+		result = append(result, value.Format("2006-01-02"))
+	}
+	return result
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		from  time.Time
+		to    time.Time
+		want  []string
+	}{
+		{
+			name:  "every other week on tuesday and thursday",
+			rule:  "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			start: date(2018, 9, 3),
+			from:  date(2018, 9, 1),
+			to:    date(2018, 9, 30),
+			want:  []string{"2018-09-04", "2018-09-06", "2018-09-18", "2018-09-20"},
+		},
+		{
+			name:  "first friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=1FR",
+			start: date(2018, 9, 1),
+			from:  date(2018, 9, 1),
+			to:    date(2018, 12, 31),
+			want:  []string{"2018-09-07", "2018-10-05", "2018-11-02", "2018-12-07"},
+		},
+		{
+			name:  "last saturday of the month",
+			rule:  "RRULE:FREQ=MONTHLY;BYDAY=-1SA",
+			start: date(2018, 9, 1),
+			from:  date(2018, 9, 1),
+			to:    date(2018, 11, 30),
+			want:  []string{"2018-09-29", "2018-10-27", "2018-11-24"},
+		},
+		{
+			name:  "every tuesday except holidays",
+			rule:  "RRULE:FREQ=WEEKLY;BYDAY=TU\nEXDATE;VALUE=DATE:20181225,20190101",
+			start: date(2018, 12, 1),
+			from:  date(2018, 12, 15),
+			to:    date(2019, 1, 10),
+			want:  []string{"2018-12-18", "2019-01-08"},
+		},
+		{
+			name:  "count is counted from the start",
+			rule:  "RRULE:FREQ=DAILY;COUNT=5",
+			start: date(2018, 9, 1),
+			from:  date(2018, 9, 4),
+			to:    date(2018, 9, 30),
+			want:  []string{"2018-09-04", "2018-09-05"},
+		},
+		{
+			name:  "until is included",
+			rule:  "RRULE:FREQ=WEEKLY;UNTIL=20180917T235959Z",
+			start: date(2018, 9, 3),
+			from:  date(2018, 9, 1),
+			to:    date(2018, 9, 30),
+			want:  []string{"2018-09-03", "2018-09-10", "2018-09-17"},
+		},
+		{
+			name:  "weekday and weekend rules",
+			rule:  "RRULE:FREQ=WEEKLY;BYDAY=FR\nRRULE:FREQ=MONTHLY;BYMONTHDAY=1,-1",
+			start: date(2018, 9, 1),
+			from:  date(2018, 9, 25),
+			to:    date(2018, 10, 5),
+			want:  []string{"2018-09-28", "2018-09-30", "2018-10-01", "2018-10-05"},
+		},
+		{
+			name:  "thanksgiving",
+			rule:  "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			start: date(2018, 1, 1),
+			from:  date(2018, 1, 1),
+			to:    date(2019, 12, 31),
+			want:  []string{"2018-11-22", "2019-11-28"},
+		},
+		{
+			name:  "yearly weekdays repeat through the year",
+			rule:  "RRULE:FREQ=YEARLY;BYDAY=MO",
+			start: date(2018, 1, 1),
+			from:  date(2018, 9, 1),
+			to:    date(2018, 9, 20),
+			want:  []string{"2018-09-03", "2018-09-10", "2018-09-17"},
+		},
+		{
+			name:  "yearly ordinals count within the year",
+			rule:  "RRULE:FREQ=YEARLY;BYDAY=20MO,-1FR",
+			start: date(2018, 1, 1),
+			from:  date(2018, 1, 1),
+			to:    date(2018, 12, 31),
+			want:  []string{"2018-05-14", "2018-12-28"},
+		},
+	}
+
+	for _, test := range tests {
+		recurrence, err := Parse(test.rule)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.want, dates(recurrence.Between(test.start, test.from, test.to)), test.name)
+
+		// formatting keeps the meaning
+		again, err := Parse(recurrence.String())
+		require.NoError(t, err, test.name)
+		require.Equal(t, recurrence, again, test.name)
+	}
+}
+
+func TestParseRejectsUnsupportedRules(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"EXDATE:20181225",
+		"RRULE:INTERVAL=2",
+		"RRULE:FREQ=HOURLY",
+		"RRULE:FREQ=WEEKLY;BYDAY=1TU",
+		"RRULE:FREQ=MONTHLY;BYDAY=6TU",
+		"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=20TH",
+		"RRULE:FREQ=MONTHLY;BYDAY=XX",
+		"RRULE:FREQ=MONTHLY;BYMONTHDAY=32",
+		"RRULE:FREQ=DAILY;COUNT=3;UNTIL=20181231",
+		"RRULE:FREQ=DAILY;BYSETPOS=1",
+		"DTSTART:20180901",
+	} {
+		_, err := Parse(rule)
+		require.Error(t, err, rule)
+	}
+}
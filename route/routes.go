@@ -7,17 +7,23 @@ import (
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/go-chi/chi"
+	"github.com/phassans/banana/auth"
+	"github.com/phassans/banana/clients/mail"
+	"github.com/phassans/banana/clients/media"
 	"github.com/phassans/banana/controller"
 	"github.com/phassans/banana/model"
+	"github.com/phassans/banana/model/listing"
 )
 
 // APIServerHandler returns a Gzip handler
-func APIServerHandler(engines model.Engine) http.Handler {
-	r := newAPIRouter(engines)
+func APIServerHandler(engines model.Engine, images *media.Pipeline, imagesCfg media.Config, mailer mail.Sender, tokens auth.TokenIssuer,
+	dateScheduler *listing.DateScheduler) http.Handler {
+	r := newAPIRouter(engines, images, imagesCfg, mailer, tokens, dateScheduler)
 	return gziphandler.GzipHandler(r)
 }
 
-func newAPIRouter(engines model.Engine) chi.Router {
+func newAPIRouter(engines model.Engine, images *media.Pipeline, imagesCfg media.Config, mailer mail.Sender, tokens auth.TokenIssuer,
+	dateScheduler *listing.DateScheduler) chi.Router {
 	r := chi.NewRouter()
 
 	r.Get("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
@@ -27,14 +33,23 @@ func newAPIRouter(engines model.Engine) chi.Router {
 		fmt.Fprintln(w, "OK")
 	})
 
-	r.Mount("/", controller.NewRESTRouter(engines))
-
-	// Register pprof handlers
-	r.HandleFunc("/debug/pprof/", pprof.Index)
-	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// images of the local store are served by the api server itself
+	if imagesCfg.Provider == media.ProviderLocal {
+		r.Mount(media.LocalPath, http.StripPrefix(media.LocalPath, media.LocalHandler(imagesCfg.LocalDir)))
+	}
+
+	r.Mount("/", controller.NewRESTRouter(engines, images, mailer, tokens, dateScheduler))
+
+	// Register pprof handlers, profiles expose internals and are admin only
+	r.Route("/debug/pprof", func(r chi.Router) {
+		r.Use(controller.Identify(tokens), controller.RequireRole(auth.RoleAdmin))
+		r.HandleFunc("/", pprof.Index)
+		r.HandleFunc("/cmdline", pprof.Cmdline)
+		r.HandleFunc("/profile", pprof.Profile)
+		r.HandleFunc("/symbol", pprof.Symbol)
+		r.HandleFunc("/trace", pprof.Trace)
+		r.HandleFunc("/{profile}", pprof.Index)
+	})
 
 	return r
 }
@@ -36,6 +36,27 @@ const (
 	// SortByMostPopular ...
 	SortByMostPopular = "mostpopular"
 
+	// SortByRelevance orders keyword searches by how well listings match
+	SortByRelevance = "relevance"
+
+	// SuggestionCategory is a category of category_to_keyword
+	SuggestionCategory = "category"
+
+	// SuggestionCuisine is a cuisine served nearby
+	SuggestionCuisine = "cuisine"
+
+	// SuggestionBusiness is the name of a nearby business
+	SuggestionBusiness = "business"
+
+	// SuggestionQuery is a popular past search nearby
+	SuggestionQuery = "query"
+
+	// DistanceUnitMiles shows distances in miles
+	DistanceUnitMiles = "mi"
+
+	// DistanceUnitKilometers shows distances in kilometers
+	DistanceUnitKilometers = "km"
+
 	// ListingTypeMeal ...
 	ListingTypeMeal = "meal"
 
@@ -70,38 +91,25 @@ const (
 	SearchNextWeek = "next week"
 )
 
+// statuses of happy hour submissions
+const (
+	// HappyHourPending submissions wait for a curator
+	HappyHourPending = "pending"
+
+	// HappyHourApproved submissions were imported as a business and listings
+	HappyHourApproved = "approved"
+
+	// HappyHourRejected submissions were turned down, with a reason
+	HappyHourRejected = "rejected"
+
+	// HappyHourDuplicate submissions were of a business already listed
+	HappyHourDuplicate = "duplicate"
+)
+
 var (
 	// DayMap of week days
 	DayMap = map[string]int{"sunday": 0, "monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6}
 
 	// ListingTypes possible
 	ListingTypes = []string{"", "meal", "happyhour"}
-
-	imagesMap = map[string]int{
-		"Asian Appetizers": 4,
-		"BBQ":              2,
-		"Bar snacks":       10,
-		"Beer":             22,
-		"Burger":           6,
-		"Chinese Food":     14,
-		"Cocktail":         9,
-		"Coffee":           1,
-		"Curry":            4,
-		"Ice cream":        1,
-		"Italian":          4,
-		"Mediterranean":    8,
-		"Mexican":          18,
-		"Milk Tea Boba":    1,
-		"Oysters":          11,
-		"Pizza":            8,
-		"Poke":             2,
-		"Ramen":            4,
-		"Skewers":          3,
-		"Special Drinks":   10,
-		"Sushi":            16,
-		"Tacos":            12,
-		"Thai":             5,
-		"Wine":             17,
-		"Wings":            8,
-	}
 )
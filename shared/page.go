@@ -0,0 +1,179 @@
+package shared
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+type (
+	// Page asks for one page of an ordered result. An empty Cursor asks for
+	// the first page, a Limit of 0 for all results after the cursor.
+	Page struct {
+		Cursor string
+		Limit  int
+	}
+
+	// PageKey is the place of a result in its ordering. Results are ordered
+	// by Rank, compared value by value, and then by ID, so that a cursor
+	// still finds its place after the results before it have changed.
+	PageKey struct {
+		Rank []float64 `json:"r"`
+		ID   int       `json:"i"`
+	}
+
+	// pageCursor is what an opaque cursor holds: the ordering it belongs to
+	// and the key of the last result handed out
+	pageCursor struct {
+		SortBy string `json:"s"`
+		// At is when the first page was handed out, for orderings that
+		// depend on the time
+		At int64 `json:"t,omitempty"`
+		PageKey
+	}
+)
+
+// ErrInvalidCursor is returned for cursors that were not handed out for the
+// same ordering
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Less reports whether k is ordered before o.
+func (k PageKey) Less(o PageKey) bool {
+	for i := 0; i < len(k.Rank) && i < len(o.Rank); i++ {
+		if k.Rank[i] != o.Rank[i] {
+			return k.Rank[i] < o.Rank[i]
+		}
+	}
+	if len(k.Rank) != len(o.Rank) {
+		return len(k.Rank) < len(o.Rank)
+	}
+	return k.ID < o.ID
+}
+
+// OrderByPageKey returns the indexes of keys in page key order. Results whose
+// keys are equal keep their order.
+func OrderByPageKey(keys []PageKey) []int {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return keys[order[i]].Less(keys[order[j]])
+	})
+	return order
+}
+
+// Paginate returns the range [from, to) of keys, which must be in page key
+// order, that page asks for, and the cursor of the page after it. The cursor
+// is empty on the last page. sortBy names the ordering, a cursor is only
+// valid for the ordering it was handed out for.
+func Paginate(keys []PageKey, sortBy string, page Page) (int, int, string, error) {
+	return PaginateAt(keys, sortBy, page, time.Time{})
+}
+
+// PaginateAt is Paginate for orderings that depend on the time, at being
+// when the first page was handed out, see PageTime. Its cursors carry at
+// over to the pages after.
+func PaginateAt(keys []PageKey, sortBy string, page Page, at time.Time) (int, int, string, error) {
+	from := 0
+	if page.Cursor != "" {
+		last, err := decodeCursor(page.Cursor, sortBy)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		from = sort.Search(len(keys), func(i int) bool {
+			return last.Less(keys[i])
+		})
+	}
+
+	to := len(keys)
+	if page.Limit > 0 && from+page.Limit < to {
+		to = from + page.Limit
+	}
+	if to == len(keys) {
+		return from, to, "", nil
+	}
+
+	next, err := encodeCursor(pageCursor{SortBy: sortBy, At: unix(at), PageKey: keys[to-1]})
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return from, to, next, nil
+}
+
+// PageTime returns when the first page of the pages page belongs to was
+// handed out, now for a first page, so that results ordered by the time
+// keep their place as time passes between pages
+func PageTime(page Page, now time.Time) time.Time {
+	c, err := readCursor(page.Cursor)
+	if err != nil || c.At == 0 {
+		return now
+	}
+	return time.Unix(c.At, 0).In(now.Location())
+}
+
+// Seek returns the key of the last result handed out before page, for
+// queries that page by key themselves, and false for the first page
+func Seek(page Page, sortBy string) (PageKey, bool, error) {
+	if page.Cursor == "" {
+		return PageKey{}, false, nil
+	}
+	last, err := decodeCursor(page.Cursor, sortBy)
+	if err != nil {
+		return PageKey{}, false, err
+	}
+	return last, true, nil
+}
+
+// SeekNext returns how many of keys, read after the key Seek returned with a
+// limit of one more than page asks for, belong to page, and the cursor of
+// the page after it, empty on the last page
+func SeekNext(keys []PageKey, sortBy string, page Page) (int, string, error) {
+	if page.Limit <= 0 || len(keys) <= page.Limit {
+		return len(keys), "", nil
+	}
+
+	next, err := encodeCursor(pageCursor{SortBy: sortBy, PageKey: keys[page.Limit-1]})
+	if err != nil {
+		return 0, "", err
+	}
+	return page.Limit, next, nil
+}
+
+func encodeCursor(c pageCursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string, sortBy string) (PageKey, error) {
+	c, err := readCursor(cursor)
+	if err != nil || c.SortBy != sortBy {
+		return PageKey{}, ErrInvalidCursor
+	}
+	return c.PageKey, nil
+}
+
+func readCursor(cursor string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pageCursor{}, ErrInvalidCursor
+	}
+
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,99 @@
+package shared
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func pageIDs(keys []PageKey, from int, to int) []int {
+	var ids []int
+	for _, key := range keys[from:to] {
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
+
+func TestPaginate(t *testing.T) {
+	keys := []PageKey{
+		{Rank: []float64{0, 1.5}, ID: 4},
+		{Rank: []float64{1, 0.5}, ID: 2},
+		{Rank: []float64{1, 0.5}, ID: 1},
+		{Rank: []float64{0, 2.5}, ID: 3},
+		{Rank: []float64{1, 3}, ID: 5},
+	}
+	order := OrderByPageKey(keys)
+	require.Equal(t, []int{0, 3, 2, 1, 4}, order)
+
+	var sorted []PageKey
+	for _, i := range order {
+		sorted = append(sorted, keys[i])
+	}
+
+	from, to, next, err := Paginate(sorted, SortByDistance, Page{Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 3}, pageIDs(sorted, from, to))
+	require.NotEmpty(t, next)
+
+	// the last result handed out is gone by the next page
+	remaining := append([]PageKey{sorted[0]}, sorted[2:]...)
+	from, to, next, err = Paginate(remaining, SortByDistance, Page{Cursor: next, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, pageIDs(remaining, from, to))
+	require.NotEmpty(t, next)
+
+	from, to, next, err = Paginate(remaining, SortByDistance, Page{Cursor: next, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []int{5}, pageIDs(remaining, from, to))
+	require.Empty(t, next)
+
+	// without a limit everything is one page
+	from, to, next, err = Paginate(sorted, SortByDistance, Page{})
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 3, 1, 2, 5}, pageIDs(sorted, from, to))
+	require.Empty(t, next)
+
+	_, _, next, err = Paginate(sorted, SortByDistance, Page{Limit: 1})
+	require.NoError(t, err)
+	_, _, _, err = Paginate(sorted, SortByPrice, Page{Cursor: next})
+	require.Equal(t, ErrInvalidCursor, err)
+	_, _, _, err = Paginate(sorted, SortByDistance, Page{Cursor: "not a cursor"})
+	require.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestSeek(t *testing.T) {
+	_, ok, err := Seek(Page{Limit: 2}, SortByDateAdded)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// rows are read with one more than the limit
+	keys := []PageKey{{ID: 1}, {ID: 2}, {ID: 3}}
+	n, next, err := SeekNext(keys, SortByDateAdded, Page{Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	last, ok, err := Seek(Page{Cursor: next, Limit: 2}, SortByDateAdded)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 2, last.ID)
+
+	n, next, err = SeekNext(keys[2:], SortByDateAdded, Page{Cursor: next, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Empty(t, next)
+
+	_, _, err = Seek(Page{Cursor: "not a cursor"}, SortByDateAdded)
+	require.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestPageTime(t *testing.T) {
+	first := time.Date(2018, 9, 3, 17, 0, 0, 0, time.UTC)
+	later := first.Add(time.Hour)
+	require.Equal(t, later, PageTime(Page{}, later))
+
+	keys := []PageKey{{ID: 1}, {ID: 2}}
+	_, _, next, err := PaginateAt(keys, SortByTimeLeft, Page{Limit: 1}, first)
+	require.NoError(t, err)
+	require.Equal(t, first, PageTime(Page{Cursor: next}, later))
+}
@@ -0,0 +1,38 @@
+package shared
+
+type (
+	// SearchStats are the searches made on a day
+	SearchStats struct {
+		Date               string        `json:"date"`
+		Searches           int           `json:"searches"`
+		KeywordSearches    int           `json:"keywordSearches"`
+		ZeroResultSearches int           `json:"zeroResultSearches"`
+		FailedSearches     int           `json:"failedSearches"`
+		TopQueries         []SearchCount `json:"topQueries"`
+		ZeroResultQueries  []SearchCount `json:"zeroResultQueries"`
+		Areas              []AreaDemand  `json:"areas"`
+		SortBy             []SearchCount `json:"sortBy"`
+		SearchDays         []SearchCount `json:"searchDays"`
+		Filters            []SearchCount `json:"filters"`
+	}
+
+	// SearchCount counts the searches having a value, and how many of them
+	// found nothing
+	SearchCount struct {
+		Value       string `json:"value"`
+		Searches    int    `json:"searches"`
+		ZeroResults int    `json:"zeroResults"`
+	}
+
+	// AreaDemand counts the searches made in an area. Searches with a
+	// location are grouped by geohash cell, the ones naming a place without
+	// coordinates by the place.
+	AreaDemand struct {
+		Geohash     string  `json:"geohash,omitempty"`
+		Latitude    float64 `json:"latitude,omitempty"`
+		Longitude   float64 `json:"longitude,omitempty"`
+		Location    string  `json:"location,omitempty"`
+		Searches    int     `json:"searches"`
+		ZeroResults int     `json:"zeroResults"`
+	}
+)
@@ -16,76 +16,101 @@ type (
 		Email    string `json:"email"`
 		Password string `json:"password,omitempty"`
 		Phone    string `json:"phone,omitempty"`
+		Role     string `json:"role,omitempty"`
 	}
 
 	// Listing all fields
 	Listing struct {
-		ListingID                  int           `json:"listingId"`
-		Title                      string        `json:"title"`
-		BusinessID                 int           `json:"businessId"`
-		BusinessName               string        `json:"businessName"`
-		OldPrice                   float64       `json:"oldPrice,omitempty"`
-		NewPrice                   float64       `json:"newPrice,omitempty"`
-		PriceInString              string        `json:"priceInString"`
-		Discount                   float64       `json:"discount,omitempty"`
-		DiscountDescription        string        `json:"discountDescription,omitempty"`
-		DietaryRestrictions        []string      `json:"dietaryRestrictions,omitempty"`
-		Description                string        `json:"description,omitempty"`
-		StartDate                  string        `json:"startDate"`
-		StartTime                  string        `json:"startTime"`
-		EndTime                    string        `json:"endTime"`
-		MultipleDays               bool          `json:"multipleDays"`
-		EndDate                    string        `json:"endDate,omitempty"`
-		Recurring                  bool          `json:"recurring"`
-		RecurringDays              []string      `json:"recurringDays,omitempty"`
-		RecurringEndDate           string        `json:"recurringEndDate,omitempty"`
-		Type                       string        `json:"listingType"`
-		ListingImage               string        `json:"listingImage,omitempty"`
-		ListingImages              []string      `json:"listingImages,omitempty"`
-		DistanceFromLocation       float64       `json:"distanceFromLocation"`
-		DistanceFromLocationString string        `json:"distanceFromLocationString"`
-		ListingDate                string        `json:"listingDate"`
-		ListingCreateDate          string        `json:"listingCreateDate"`
-		ListingStatus              string        `json:"listingStatus,omitempty"`
-		TimeLeft                   int           `json:"timeLeft"`
-		ImageLink                  string        `json:"imageLink,omitempty"`
-		Business                   *BusinessInfo `json:"businessInfo,omitempty"`
-		IsFavorite                 bool          `json:"isFavorite"`
-		DateTimeRange              string        `json:"dateTimeRange,omitempty"`
-		ListingWeekDay             string        `json:"listingWeekDay,omitempty"`
-		ListingDateID              int           `json:"listingDateId,omitempty"`
-		Favorite                   *Favorite     `json:"favorite,omitempty"`
-		Latitude                   float64       `json:"latitude,omitempty"`
-		Longitude                  float64       `json:"longitude,omitempty"`
-		UpVotes                    int           `json:"upvotes"`
-		IsUserVoted                bool          `json:"isUserUpVoted"`
-		SubmittedBy                string        `json:"submittedBy"`
-		CurrentLocation            GeoLocation   `json:"geoLocation,omitempty"`
+		ListingID                  int            `json:"listingId"`
+		Title                      string         `json:"title"`
+		BusinessID                 int            `json:"businessId"`
+		BusinessName               string         `json:"businessName"`
+		OldPrice                   float64        `json:"oldPrice,omitempty"`
+		NewPrice                   float64        `json:"newPrice,omitempty"`
+		PriceInString              string         `json:"priceInString"`
+		Discount                   float64        `json:"discount,omitempty"`
+		DiscountDescription        string         `json:"discountDescription,omitempty"`
+		DietaryRestrictions        []string       `json:"dietaryRestrictions,omitempty"`
+		Description                string         `json:"description,omitempty"`
+		StartDate                  string         `json:"startDate"`
+		StartTime                  string         `json:"startTime"`
+		EndTime                    string         `json:"endTime"`
+		MultipleDays               bool           `json:"multipleDays"`
+		EndDate                    string         `json:"endDate,omitempty"`
+		Recurring                  bool           `json:"recurring"`
+		RecurringDays              []string       `json:"recurringDays,omitempty"`
+		RecurringEndDate           string         `json:"recurringEndDate,omitempty"`
+		RecurrenceRule             string         `json:"recurrenceRule,omitempty"`
+		TimeWindows                []TimeWindow   `json:"timeWindows,omitempty"`
+		Type                       string         `json:"listingType"`
+		ListingImage               string         `json:"listingImage,omitempty"`
+		ListingImages              []string       `json:"listingImages,omitempty"`
+		DistanceFromLocation       float64        `json:"distanceFromLocation"`
+		DistanceFromLocationString string         `json:"distanceFromLocationString"`
+		Relevance                  float64        `json:"-"`
+		Snippet                    string         `json:"snippet,omitempty"`
+		ListingDate                string         `json:"listingDate"`
+		ListingCreateDate          string         `json:"listingCreateDate"`
+		ListingStatus              string         `json:"listingStatus,omitempty"`
+		TimeLeft                   int            `json:"timeLeft"`
+		ImageLink                  string         `json:"imageLink,omitempty"`
+		ImageWidth                 int            `json:"-"`
+		ImageHeight                int            `json:"-"`
+		ImageVariants              *ImageVariants `json:"imageVariants,omitempty"`
+		Gallery                    []GalleryImage `json:"gallery,omitempty"`
+		Category                   string         `json:"category,omitempty"`
+		IsStockImage               bool           `json:"isStockImage,omitempty"`
+		Business                   *BusinessInfo  `json:"businessInfo,omitempty"`
+		IsFavorite                 bool           `json:"isFavorite"`
+		DateTimeRange              string         `json:"dateTimeRange,omitempty"`
+		ListingWeekDay             string         `json:"listingWeekDay,omitempty"`
+		ListingDateID              int            `json:"listingDateId,omitempty"`
+		Favorite                   *Favorite      `json:"favorite,omitempty"`
+		Latitude                   float64        `json:"latitude,omitempty"`
+		Longitude                  float64        `json:"longitude,omitempty"`
+		UpVotes                    int            `json:"upvotes"`
+		IsUserVoted                bool           `json:"isUserUpVoted"`
+		SubmittedBy                string         `json:"submittedBy"`
+		CurrentLocation            GeoLocation    `json:"geoLocation,omitempty"`
+		Place                      *Place         `json:"place,omitempty"`
 	}
 
 	// SearchListingResult result of search
 	SearchListingResult struct {
-		ListingID                  int      `json:"listingId"`
-		ListingType                string   `json:"listingType"`
-		Title                      string   `json:"title"`
-		Description                string   `json:"description"`
-		BusinessID                 int      `json:"businessId"`
-		BusinessName               string   `json:"businessName"`
-		Price                      float64  `json:"price"`
-		PriceInString              string   `json:"priceInString"`
-		Discount                   float64  `json:"discount"`
-		DiscountDescription        string   `json:"discountDescription"`
-		DietaryRestrictions        []string `json:"dietaryRestrictions"`
-		TimeLeft                   int      `json:"timeLeft"`
-		ListingImage               string   `json:"listingImage"`
-		ListingImages              []string `json:"listingImages,omitempty"`
-		DistanceFromLocation       float64  `json:"distanceFromLocation"`
-		DistanceFromLocationString string   `json:"distanceFromLocationString"`
-		IsFavorite                 bool     `json:"isFavorite"`
-		DateTimeRange              string   `json:"dateTimeRange"`
-		ListingDateID              int      `json:"listingDateId,omitempty"`
-		Upvotes                    int      `json:"upvotes"`
-		IsUserVoted                bool     `json:"isUserUpVoted"`
+		ListingID                  int            `json:"listingId"`
+		ListingType                string         `json:"listingType"`
+		Title                      string         `json:"title"`
+		Description                string         `json:"description"`
+		BusinessID                 int            `json:"businessId"`
+		BusinessName               string         `json:"businessName"`
+		Price                      float64        `json:"price"`
+		PriceInString              string         `json:"priceInString"`
+		Discount                   float64        `json:"discount"`
+		DiscountDescription        string         `json:"discountDescription"`
+		DietaryRestrictions        []string       `json:"dietaryRestrictions"`
+		TimeLeft                   int            `json:"timeLeft"`
+		ListingImage               string         `json:"listingImage"`
+		ListingImages              []string       `json:"listingImages,omitempty"`
+		ImageVariants              *ImageVariants `json:"imageVariants,omitempty"`
+		IsStockImage               bool           `json:"isStockImage,omitempty"`
+		DistanceFromLocation       float64        `json:"distanceFromLocation"`
+		DistanceFromLocationString string         `json:"distanceFromLocationString"`
+		IsFavorite                 bool           `json:"isFavorite"`
+		DateTimeRange              string         `json:"dateTimeRange"`
+		ListingDateID              int            `json:"listingDateId,omitempty"`
+		Upvotes                    int            `json:"upvotes"`
+		IsUserVoted                bool           `json:"isUserUpVoted"`
+		Snippet                    string         `json:"snippet,omitempty"`
+	}
+
+	// Suggestion completes a search being typed
+	Suggestion struct {
+		Text string `json:"text"`
+		// Type is where the suggestion comes from, one of the Suggestion*
+		// constants
+		Type string `json:"type"`
+		// Count is how many nearby businesses or past searches back it
+		Count int `json:"count,omitempty"`
 	}
 
 	// ListingInfo combination of Business and Listing
@@ -94,6 +119,14 @@ type (
 		Listing  SearchListingResult `json:"listing"`
 	}
 
+	// TimeWindow is when a recurring listing runs on one day of the week. A
+	// day may have several windows, days without one use the listing times.
+	TimeWindow struct {
+		Day       string `json:"day"`
+		StartTime string `json:"startTime"`
+		EndTime   string `json:"endTime"`
+	}
+
 	// ListingDate fields
 	ListingDate struct {
 		ListingID   int
@@ -127,6 +160,13 @@ type (
 		Keywords         string   `json:"keywords,omitempty"`
 	}
 
+	// Subscription is a saved notification search of a phone that can be
+	// pushed to
+	Subscription struct {
+		Notification
+		RegistrationToken string
+	}
+
 	// Hours fields
 	Hours struct {
 		Day                 string
@@ -209,6 +249,7 @@ type (
 		TimeLeft    float64
 		ListingDate time.Time
 		UpVotes     int
+		Relevance   float64
 	}
 
 	// CurrentLocation ...
@@ -217,6 +258,106 @@ type (
 		Longitude float64
 	}
 
+	// Place names a location for people, e.g. "Mission District"
+	Place struct {
+		// Name is the neighborhood when known, else the city
+		Name         string `json:"name"`
+		Neighborhood string `json:"neighborhood,omitempty"`
+		City         string `json:"city,omitempty"`
+		State        string `json:"state,omitempty"`
+		Country      string `json:"country,omitempty"`
+	}
+
+	// SearchPage is a page of search results
+	SearchPage struct {
+		Results    []SearchListingResult
+		NextCursor string
+		// Place is where the search was made, when it could be named
+		Place *Place
+		// ComingSoon is set when the search was made outside every region
+		// served
+		ComingSoon bool
+	}
+
+	// Region is a metro area the service is available in
+	Region struct {
+		RegionID int         `json:"regionId,omitempty"`
+		Name     string      `json:"name"`
+		Center   GeoLocation `json:"center"`
+		// RadiusMiles bounds the region around its center, unless it has a
+		// polygon
+		RadiusMiles float64       `json:"radiusMiles,omitempty"`
+		Polygon     []GeoLocation `json:"polygon,omitempty"`
+		// TimeZone is the IANA name of the time zone of the region, e.g.
+		// America/Los_Angeles
+		TimeZone string `json:"timeZone"`
+		// Currency is the ISO 4217 code prices are in, e.g. USD
+		Currency string `json:"currency"`
+		// DistanceUnit is mi or km
+		DistanceUnit string `json:"distanceUnit"`
+		// SearchRadiusMiles is how far searches in the region look by
+		// default
+		SearchRadiusMiles float64 `json:"searchRadiusMiles"`
+	}
+
+	// HappyHourSubmission is a happy hour submitted from the app, waiting
+	// for a curator or moderated with Status. BusinessID is the business it
+	// was imported as, or the one it duplicates.
+	HappyHourSubmission struct {
+		HappyHourID    int      `json:"hhId"`
+		PhoneID        string   `json:"phoneId"`
+		Name           string   `json:"name,omitempty"`
+		Email          string   `json:"email,omitempty"`
+		BusinessOwner  bool     `json:"businessOwner"`
+		Restaurant     string   `json:"restaurant"`
+		City           string   `json:"city"`
+		Description    string   `json:"description,omitempty"`
+		Images         []string `json:"images"`
+		SubmissionDate string   `json:"submissionDate"`
+		Status         string   `json:"status"`
+		Reason         string   `json:"reason,omitempty"`
+		BusinessID     int      `json:"businessId,omitempty"`
+		ModeratedDate  string   `json:"moderatedDate,omitempty"`
+	}
+
+	// StockImage is an image of the stock library listings without a photo
+	// of their own are shown with, picked among the images of their category
+	StockImage struct {
+		StockImageID int    `json:"stockImageId,omitempty"`
+		Category     string `json:"category"`
+		ImageLink    string `json:"imageLink"`
+	}
+
+	// ImageVariant is a rendition of an image, of Width by Height pixels
+	// when known
+	ImageVariant struct {
+		URL    string `json:"url"`
+		Width  int    `json:"width,omitempty"`
+		Height int    `json:"height,omitempty"`
+	}
+
+	// ImageVariants are the renditions of an image for lists, cards and the
+	// details of a listing
+	ImageVariants struct {
+		Thumbnail ImageVariant `json:"thumbnail"`
+		Card      ImageVariant `json:"card"`
+		Detail    ImageVariant `json:"detail"`
+	}
+
+	// GalleryImage is one of the ordered images of a listing. The primary
+	// image is the one search results show.
+	GalleryImage struct {
+		ImageID  int            `json:"imageId,omitempty"`
+		URL      string         `json:"url"`
+		Position int            `json:"position"`
+		Caption  string         `json:"caption,omitempty"`
+		AltText  string         `json:"altText,omitempty"`
+		Primary  bool           `json:"primary"`
+		Variants *ImageVariants `json:"variants,omitempty"`
+		Width    int            `json:"-"`
+		Height   int            `json:"-"`
+	}
+
 	// Favorite ...
 	Favorite struct {
 		FavoriteID      int    `json:"favoriteId,omitempty"`
@@ -245,6 +386,26 @@ type (
 		SortBy         string   `json:"sortBy,omitempty"`
 		SearchDay      string   `json:"searchDay,omitempty"`
 		PhoneID        string   `json:"phoneId"`
+		Page           Page     `json:"-"`
+		// Language is the one results are shown in, see package locale
+		Language string `json:"-"`
+
+		// Internal searches are made by the service itself, e.g. to push
+		// notifications, and are not logged as user searches
+		Internal bool `json:"-"`
+	}
+
+	// SearchFilters are the filters a search was run with once normalized:
+	// text is lower cased, lists are sorted and defaults are filled in
+	SearchFilters struct {
+		Keywords       string   `json:"keywords,omitempty"`
+		ListingTypes   []string `json:"listingTypes,omitempty"`
+		DietaryFilters []string `json:"dietaryFilters,omitempty"`
+		PriceFilter    float64  `json:"priceFilter,omitempty"`
+		Radius         float64  `json:"radius,omitempty"`
+		SortBy         string   `json:"sortBy"`
+		SearchDay      string   `json:"searchDay"`
+		Future         bool     `json:"future,omitempty"`
 	}
 
 	Education struct {
@@ -18,6 +18,17 @@ func NewNullString(s string) sql.NullString {
 	}
 }
 
+// NewNullInt returns a null able sql int, null for 0
+func NewNullInt(i int) sql.NullInt64 {
+	if i == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{
+		Int64: int64(i),
+		Valid: true,
+	}
+}
+
 // GetTimeIn12HourFormat returns string time in 12 hour format
 func GetTimeIn12HourFormat(lTime string) (string, error) {
 	if lTime == "" {
@@ -25,7 +36,7 @@ func GetTimeIn12HourFormat(lTime string) (string, error) {
 	}
 
 	// determine startTime in format
-	st, err := time.Parse(TimeLayout24Hour, strings.TrimSuffix(strings.Split(lTime, "T")[1], "Z"))
+	st, err := time.Parse(TimeLayout24Hour, clockOf(lTime))
 	if err != nil {
 		fmt.Println(err)
 		return "", nil
@@ -50,6 +61,26 @@ func GetTimeIn12HourFormat(lTime string) (string, error) {
 	return rtime, nil
 }
 
+// NormalizeTime returns a time of day given as 15:04, 15:04:05 or as read
+// from the DB in TimeLayout24Hour
+func NormalizeTime(lTime string) (string, error) {
+	clock := clockOf(lTime)
+	t, err := time.Parse(TimeLayout24Hour, clock)
+	if err != nil {
+		t, err = time.Parse("15:04", clock)
+		if err != nil {
+			return "", fmt.Errorf("invalid time %q", lTime)
+		}
+	}
+	return t.Format(TimeLayout24Hour), nil
+}
+
+// clockOf strips the date times of day are read from the DB with
+func clockOf(lTime string) string {
+	parts := strings.Split(lTime, "T")
+	return strings.TrimSuffix(parts[len(parts)-1], "Z")
+}
+
 func ConvertDBDate(dbDate string) (string, error) {
 	listingDate, err := time.Parse(DateFormatSQL, strings.Split(dbDate, "T")[0])
 	if err != nil {
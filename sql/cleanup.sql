@@ -1,29 +0,0 @@
-DROP TABLE IF EXISTS user_to_business;
-DROP TABLE IF EXISTS business_user;
-DROP TABLE IF EXISTS business_hours;
-DROP TABLE IF EXISTS business_cuisine;
-DROP TABLE IF EXISTS business_address;
-DROP TABLE IF EXISTS business_country;
-
-DROP TABLE IF EXISTS listing_date;
-DROP TABLE IF EXISTS listing_recurring;
-DROP TABLE IF EXISTS listing_image;
-DROP TABLE IF EXISTS listing_dietary_restrictions;
-
-DROP TABLE IF EXISTS favorites;
-DROP TABLE IF EXISTS report_inaccurate;
-DROP TABLE IF EXISTS upvotes;
-
-DROP TABLE IF EXISTS listing;
-DROP TABLE IF EXISTS business;
-
-DROP TABLE IF EXISTS notifications_dietary_restrictions;
-DROP TABLE IF EXISTS notifications;
-
-DROP TYPE IF EXISTS dietary_restrictions;
-DROP TYPE IF EXISTS days_of_month;
-
-DROP TABLE IF EXISTS preferences;
-
-DROP TABLE IF EXISTS happyhour_images;
-DROP TABLE IF EXISTS happyhour;
\ No newline at end of file